@@ -3,10 +3,91 @@ package constants
 const (
 	ENUM_ROLE_ADMIN = "admin"
 	ENUM_ROLE_USER = "user"
+	ENUM_ROLE_OWNER = "owner"
+	ENUM_ROLE_KASIR = "kasir"
+	ENUM_ROLE_STOK = "stok"
+	ENUM_ROLE_KASIR_STOK = "kasirstok"
 
 	ENUM_RUN_PRODUCTION = "production"
 	ENUM_RUN_TESTING = "testing"
 
 	ENUM_PAGINATION_LIMIT = 10
 	ENUM_PAGINATION_PAGE = 1
+
+	ENUM_SYNC_ACCEPTED       = "accepted"
+	ENUM_SYNC_STOCK_CONFLICT = "stock_conflict"
+	ENUM_SYNC_PRICE_MISMATCH = "price_mismatch"
+	ENUM_SYNC_REJECTED       = "rejected"
+	ENUM_SYNC_PENDING        = "pending"
+
+	ENUM_BUCKET_HOUR  = "hour"
+	ENUM_BUCKET_DAY   = "day"
+	ENUM_BUCKET_WEEK  = "week"
+	ENUM_BUCKET_MONTH = "month"
+
+	ENUM_GROUP_BY_PRODUK = "produk"
+	ENUM_GROUP_BY_MERK   = "merk"
+	ENUM_GROUP_BY_JENIS  = "jenis"
+	ENUM_GROUP_BY_UKURAN = "ukuran"
+	ENUM_GROUP_BY_WARNA  = "warna"
+
+	ENUM_STOCK_ACTIVE = "active"
+	ENUM_STOCK_SLOW   = "slow"
+	ENUM_STOCK_DEAD   = "dead"
+
+	ENUM_REORDER_SOURCE_VARIANT    = "variant"
+	ENUM_REORDER_SOURCE_MERK_JENIS = "merk_jenis"
+	ENUM_REORDER_SOURCE_MERK       = "merk"
+	ENUM_REORDER_SOURCE_JENIS      = "jenis"
+	ENUM_REORDER_SOURCE_VELOCITY   = "velocity"
+
+	ENUM_NOTIFICATION_LOW_STOCK         = "low_stock"
+	ENUM_NOTIFICATION_LARGE_PENGELUARAN = "large_pengeluaran"
+	ENUM_NOTIFICATION_LARGE_RETURN      = "large_return"
+	ENUM_NOTIFICATION_PENDING_RESTOK    = "pending_restok"
+
+	ENUM_OUTBOX_PENDING  = "pending"
+	ENUM_OUTBOX_DIGEST   = "digest"
+	ENUM_OUTBOX_SENT     = "sent"
+	ENUM_OUTBOX_DIGESTED = "digested"
+	ENUM_OUTBOX_FAILED   = "failed"
+
+	ENUM_REPORT_NOTA        = "nota"
+	ENUM_REPORT_PRODUK      = "produk"
+	ENUM_REPORT_PENGELUARAN = "pengeluaran"
+	ENUM_REPORT_FINAL_STOK  = "final_stok"
+	ENUM_REPORT_PROFIT_LOSS = "profit_loss"
+
+	ENUM_REPORT_PERIOD_TODAY      = "today"
+	ENUM_REPORT_PERIOD_YESTERDAY  = "yesterday"
+	ENUM_REPORT_PERIOD_LAST_WEEK  = "last_week"
+	ENUM_REPORT_PERIOD_THIS_MONTH = "this_month"
+	ENUM_REPORT_PERIOD_LAST_MONTH = "last_month"
+
+	ENUM_REPORT_RUN_PENDING = "pending"
+	ENUM_REPORT_RUN_SUCCESS = "success"
+	ENUM_REPORT_RUN_FAILED  = "failed"
+
+	ENUM_EXPORT_NOTA        = "nota"
+	ENUM_EXPORT_PRODUK      = "produk"
+	ENUM_EXPORT_LOG_AKSES   = "log_akses"
+	ENUM_EXPORT_PENGELUARAN = "pengeluaran"
+	ENUM_EXPORT_FINAL_STOK  = "final_stok"
+
+	ENUM_EXPORT_QUEUED  = "queued"
+	ENUM_EXPORT_RUNNING = "running"
+	ENUM_EXPORT_DONE    = "done"
+	ENUM_EXPORT_FAILED  = "failed"
+	ENUM_EXPORT_EXPIRED = "expired"
+
+	ENUM_FORMAT_XLSX = "xlsx"
+	ENUM_FORMAT_CSV  = "csv"
+	ENUM_FORMAT_PDF  = "pdf"
+
+	ENUM_IMPORT_CREATE    = "create"
+	ENUM_IMPORT_UPDATE    = "update"
+	ENUM_IMPORT_UNCHANGED = "unchanged"
+
+	ENUM_ATTRIBUTE_UKURAN = "ukuran"
+	ENUM_ATTRIBUTE_WARNA  = "warna"
 )
\ No newline at end of file
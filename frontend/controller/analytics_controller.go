@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	AnalyticsController interface {
+		GetSalesAnalytics(ctx *gin.Context)
+		GetProductAnalytics(ctx *gin.Context)
+		GetProfitLoss(ctx *gin.Context)
+		DownloadProfitLoss(ctx *gin.Context)
+		GetStockAging(ctx *gin.Context)
+		DownloadStockAging(ctx *gin.Context)
+	}
+
+	analyticsController struct {
+		analyticsService service.AnalyticsService
+	}
+)
+
+func NewAnalyticsController(as service.AnalyticsService) AnalyticsController {
+	return &analyticsController{
+		analyticsService: as,
+	}
+}
+
+func (c *analyticsController) GetSalesAnalytics(ctx *gin.Context) {
+	var req dto.AnalyticsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.analyticsService.GetSalesAnalytics(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_SALES_ANALYTICS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_SALES_ANALYTICS, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *analyticsController) GetProductAnalytics(ctx *gin.Context) {
+	var req dto.AnalyticsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.analyticsService.GetProductAnalytics(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_PRODUCT_ANALYTICS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_PRODUCT_ANALYTICS, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *analyticsController) GetProfitLoss(ctx *gin.Context) {
+	var req dto.AnalyticsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.analyticsService.GetProfitLoss(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_PROFIT_LOSS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_PROFIT_LOSS, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *analyticsController) DownloadProfitLoss(ctx *gin.Context) {
+	var req dto.AnalyticsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	file, err := c.analyticsService.DownloadProfitLoss(ctx.Request.Context(), req, format)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_PROFIT_LOSS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sendDownload(ctx, "laba_rugi", format, file)
+}
+
+func (c *analyticsController) GetStockAging(ctx *gin.Context) {
+	var req dto.StockAgingRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.analyticsService.GetStockAging(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_STOCK_AGING, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_STOCK_AGING, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *analyticsController) DownloadStockAging(ctx *gin.Context) {
+	var req dto.StockAgingRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	file, err := c.analyticsService.DownloadStockAging(ctx.Request.Context(), req, format)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_STOCK_AGING, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sendDownload(ctx, "umur_stok", format, file)
+}
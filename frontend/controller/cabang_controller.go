@@ -140,14 +140,17 @@ func (c *cabangController) DeleteCabang(ctx *gin.Context) {
 }
 
 func (c *cabangController) DownloadDataCabang(ctx *gin.Context) {
-	result, err := c.cabangService.DownloadDataCabang(ctx.Request.Context())
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := c.cabangService.DownloadDataCabang(ctx.Request.Context(), format)
 	if err != nil {
 		res := utils.BuildResponseFailed("Failed to download data cabang", err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", "attachment; filename=data_cabang.xlsx")
-	ctx.Data(http.StatusOK, "application/octet-stream", result)
+	sendDownload(ctx, "data_cabang", format, result)
 }
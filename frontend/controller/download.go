@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// downloadFormat reads the format query parameter of a download endpoint:
+// xlsx (the default), csv or pdf. It aborts the request and reports false
+// when the format is not supported.
+func downloadFormat(ctx *gin.Context) (string, bool) {
+	format, err := utils.TableFormat(ctx.Query("format"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return "", false
+	}
+
+	return format, true
+}
+
+// sendDownload sends a rendered table as an attachment named after the
+// report, with the extension and content type of its format.
+func sendDownload(ctx *gin.Context, name string, format string, file []byte) {
+	ctx.Header("Content-Description", "File Transfer")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format))
+	ctx.Data(http.StatusOK, utils.TableContentType(format), file)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ExportController interface {
+		CreateExport(ctx *gin.Context)
+		GetExports(ctx *gin.Context)
+		GetExport(ctx *gin.Context)
+		DownloadExport(ctx *gin.Context)
+	}
+
+	exportController struct {
+		exportService service.ExportService
+	}
+)
+
+func NewExportController(es service.ExportService) ExportController {
+	return &exportController{
+		exportService: es,
+	}
+}
+
+func (c *exportController) CreateExport(ctx *gin.Context) {
+	var req dto.ExportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.exportService.CreateExport(ctx.Request.Context(), req, userID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_EXPORT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CREATE_EXPORT, result)
+	ctx.JSON(http.StatusAccepted, res)
+}
+
+func (c *exportController) GetExports(ctx *gin.Context) {
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.exportService.GetExports(ctx.Request.Context(), userID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_EXPORT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_EXPORT, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *exportController) GetExport(ctx *gin.Context) {
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.exportService.GetExport(ctx.Request.Context(), ctx.Param("export_id"), userID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_EXPORT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_EXPORT, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *exportController) DownloadExport(ctx *gin.Context) {
+	userID := ctx.MustGet("user_id").(string)
+	path, fileName, err := c.exportService.GetExportFile(ctx.Request.Context(), ctx.Param("export_id"), userID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DOWNLOAD_EXPORT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	ctx.FileAttachment(path, fileName)
+}
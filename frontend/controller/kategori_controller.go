@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	KategoriController interface {
+		GetKategoriTree(ctx *gin.Context)
+		UpdateKategori(ctx *gin.Context)
+		GetKategoriAttributes(ctx *gin.Context)
+
+		GetAllAttributeSets(ctx *gin.Context)
+		GetAttributeSetByID(ctx *gin.Context)
+		CreateAttributeSet(ctx *gin.Context)
+		UpdateAttributeSet(ctx *gin.Context)
+		DeleteAttributeSet(ctx *gin.Context)
+	}
+
+	kategoriController struct {
+		kategoriService service.KategoriService
+	}
+)
+
+func NewKategoriController(ks service.KategoriService) KategoriController {
+	return &kategoriController{
+		kategoriService: ks,
+	}
+}
+
+func (c *kategoriController) GetKategoriTree(ctx *gin.Context) {
+	result, err := c.kategoriService.GetKategoriTree(ctx.Request.Context())
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_KATEGORI_TREE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_KATEGORI_TREE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) UpdateKategori(ctx *gin.Context) {
+	var req dto.KategoriRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("jenis_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_KATEGORI, "Invalid jenis ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.kategoriService.UpdateKategori(ctx.Request.Context(), req, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_KATEGORI, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_KATEGORI, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) GetKategoriAttributes(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("jenis_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_KATEGORI_ATTRIBUTES, "Invalid jenis ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.kategoriService.GetKategoriAttributes(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_KATEGORI_ATTRIBUTES, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_KATEGORI_ATTRIBUTES, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) GetAllAttributeSets(ctx *gin.Context) {
+	result, err := c.kategoriService.GetAllAttributeSets(ctx.Request.Context())
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_ATTRIBUTE_SET, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_ATTRIBUTE_SET, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) GetAttributeSetByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("set_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_ATTRIBUTE_SET, "Invalid set ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.kategoriService.GetAttributeSetByID(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_ATTRIBUTE_SET, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_ATTRIBUTE_SET, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) CreateAttributeSet(ctx *gin.Context) {
+	var req dto.AttributeSetRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.kategoriService.CreateAttributeSet(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_ATTRIBUTE_SET, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CREATE_ATTRIBUTE_SET, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) UpdateAttributeSet(ctx *gin.Context) {
+	var req dto.AttributeSetRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("set_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_ATTRIBUTE_SET, "Invalid set ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.kategoriService.UpdateAttributeSet(ctx.Request.Context(), req, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_ATTRIBUTE_SET, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_ATTRIBUTE_SET, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *kategoriController) DeleteAttributeSet(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("set_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_ATTRIBUTE_SET, "Invalid set ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.kategoriService.DeleteAttributeSet(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_ATTRIBUTE_SET, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_ATTRIBUTE_SET, nil)
+	ctx.JSON(http.StatusOK, res)
+}
@@ -5,6 +5,7 @@ import (
 	"bumisubur-be/service"
 	"bumisubur-be/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +14,9 @@ type (
 	LogAksesController interface {
 		GetAll(ctx *gin.Context)
 		Download(ctx *gin.Context)
+		Stats(ctx *gin.Context)
+		GetSessions(ctx *gin.Context)
+		GetSession(ctx *gin.Context)
 	}
 
 	logAksesController struct {
@@ -53,14 +57,71 @@ func (c *logAksesController) Download(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.logAksesService.Download(ctx.Request.Context(), filter)
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := c.logAksesService.Download(ctx.Request.Context(), filter, format)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_ALL_LOG, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", "attachment; filename=data_cabang.xlsx")
-	ctx.Data(http.StatusOK, "application/octet-stream", result)
+	sendDownload(ctx, "log_akses", format, result)
+}
+
+func (c *logAksesController) Stats(ctx *gin.Context) {
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_LOG_STATS, c.logAksesService.Stats())
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *logAksesController) GetSessions(ctx *gin.Context) {
+	var filter dto.LogAksesSessionRequest
+	if err := ctx.ShouldBind(&filter); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.logAksesService.GetSessions(ctx.Request.Context(), filter)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_LOG_SESSIONS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_LOG_SESSIONS, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *logAksesController) GetSession(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("log_akses_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_LOG_SESSION, "Invalid log akses ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var page dto.PaginationRequest
+	if err := ctx.ShouldBind(&page); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.logAksesService.GetSession(ctx.Request.Context(), id, page)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == dto.ErrLogSessionNotFound {
+			status = http.StatusNotFound
+		}
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_LOG_SESSION, err.Error(), nil)
+		ctx.JSON(status, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_LOG_SESSION, result)
+	ctx.JSON(http.StatusOK, res)
 }
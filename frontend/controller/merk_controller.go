@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	MerkController interface {
+		GetAllMerk(ctx *gin.Context)
+		GetMerkByID(ctx *gin.Context)
+		CreateMerk(ctx *gin.Context)
+		UpdateMerk(ctx *gin.Context)
+		DeleteMerk(ctx *gin.Context)
+		MergeMerk(ctx *gin.Context)
+	}
+
+	merkController struct {
+		merkService service.MerkService
+	}
+)
+
+func NewMerkController(ms service.MerkService) MerkController {
+	return &merkController{
+		merkService: ms,
+	}
+}
+
+func (c *merkController) GetAllMerk(ctx *gin.Context) {
+	result, err := c.merkService.GetAllMerk(ctx.Request.Context(), ctx.Query("search"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_ALL_MERK, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_ALL_MERK, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *merkController) GetMerkByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("merk_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_MERK_BY_ID, "Invalid merk ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.merkService.GetMerkByID(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_MERK_BY_ID, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_MERK_BY_ID, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *merkController) CreateMerk(ctx *gin.Context) {
+	var req dto.MerkNameRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.merkService.CreateMerk(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_MERK, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CREATE_MERK, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *merkController) UpdateMerk(ctx *gin.Context) {
+	var req dto.MerkNameRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("merk_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_MERK, "Invalid merk ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.merkService.UpdateMerk(ctx.Request.Context(), req, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_MERK, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_MERK, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *merkController) DeleteMerk(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("merk_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_MERK, "Invalid merk ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.merkService.DeleteMerk(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_MERK, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_MERK, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *merkController) MergeMerk(ctx *gin.Context) {
+	var req dto.MergeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.merkService.MergeMerk(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_MERGE_MERK, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_MERGE_MERK, result)
+	ctx.JSON(http.StatusOK, res)
+}
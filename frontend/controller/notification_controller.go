@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	NotificationController interface {
+		GetRules(ctx *gin.Context)
+		CreateRule(ctx *gin.Context)
+		UpdateRule(ctx *gin.Context)
+		DeleteRule(ctx *gin.Context)
+		GetOutbox(ctx *gin.Context)
+	}
+
+	notificationController struct {
+		notificationService service.NotificationService
+	}
+)
+
+func NewNotificationController(ns service.NotificationService) NotificationController {
+	return &notificationController{
+		notificationService: ns,
+	}
+}
+
+func (c *notificationController) GetRules(ctx *gin.Context) {
+	result, err := c.notificationService.GetRules(ctx.Request.Context())
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_NOTIFICATION_RULES, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_NOTIFICATION_RULES, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *notificationController) CreateRule(ctx *gin.Context) {
+	var req dto.NotificationRuleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.notificationService.CreateRule(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_NOTIFICATION_RULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CREATE_NOTIFICATION_RULE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *notificationController) UpdateRule(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("rule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_NOTIFICATION_RULE, "Invalid rule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var req dto.NotificationRuleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.notificationService.UpdateRule(ctx.Request.Context(), id, req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_NOTIFICATION_RULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_NOTIFICATION_RULE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *notificationController) DeleteRule(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("rule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_NOTIFICATION_RULE, "Invalid rule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.notificationService.DeleteRule(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_NOTIFICATION_RULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_NOTIFICATION_RULE, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *notificationController) GetOutbox(ctx *gin.Context) {
+	var req dto.NotificationOutboxRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.notificationService.GetOutbox(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_NOTIFICATION_OUTBOX, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_NOTIFICATION_OUTBOX, result)
+	ctx.JSON(http.StatusOK, res)
+}
@@ -144,14 +144,17 @@ func (c *pengeluaranController) DownloadDataPengeluaran(ctx *gin.Context) {
 	filter := ctx.Query("filter")
 	start_date := ctx.Query("start_date")
 	end_date := ctx.Query("end_date")
-	result, err := c.pengeluaranService.DownloadDataPengeluaran(ctx.Request.Context(), filter, start_date, end_date)
+	cabang, _ := strconv.Atoi(ctx.Query("cabang"))
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+	result, err := c.pengeluaranService.DownloadDataPengeluaran(ctx.Request.Context(), filter, start_date, end_date, cabang, format)
 	if err != nil {
 		res := utils.BuildResponseFailed("Failed to download data pengeluaran", err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", "attachment; filename=data_pengeluaran.xlsx")
-	ctx.Data(http.StatusOK, "application/octet-stream", result)
+	sendDownload(ctx, "data_pengeluaran", format, result)
 }
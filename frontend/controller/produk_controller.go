@@ -4,6 +4,7 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/service"
 	"bumisubur-be/utils"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -30,9 +31,13 @@ type (
 
 		GetIndexFinalStok(ctx *gin.Context)
 		FinalStokProduk(ctx *gin.Context)
+		DownloadFinalStok(ctx *gin.Context)
 
 		InsertProduk(ctx *gin.Context)
 
+		ImportProduk(ctx *gin.Context)
+		DownloadImportTemplate(ctx *gin.Context)
+
 	}
 
 	produkController struct {
@@ -264,3 +269,69 @@ func (pc *produkController) FinalStokProduk(ctx *gin.Context) {
 	res := utils.BuildResponseSuccess("Sukses mendapatkan stok produk final", result)
 	ctx.JSON(http.StatusOK, res)
 }
+
+func (pc *produkController) DownloadFinalStok(ctx *gin.Context) {
+	var filter dto.FilterFinalStok
+	if err := ctx.ShouldBind(&filter); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := pc.produkService.DownloadFinalStok(ctx.Request.Context(), filter, format)
+	if err != nil {
+		res := utils.BuildResponseFailed("Gagal mengunduh stok produk final", err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sendDownload(ctx, "stok_final", format, result)
+}
+
+func (pc *produkController) ImportProduk(ctx *gin.Context) {
+	var req dto.ProdukImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := pc.produkService.ImportProduk(ctx.Request.Context(), req)
+	if err != nil {
+		var data any
+		if errors.Is(err, dto.ErrImportHasErrors) {
+			data = result
+		}
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_IMPORT_PRODUK, err.Error(), data)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	message := dto.MESSAGE_SUCCESS_PREVIEW_IMPORT
+	if result.Confirmed {
+		message = dto.MESSAGE_SUCCESS_IMPORT_PRODUK
+	}
+	res := utils.BuildResponseSuccess(message, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (pc *produkController) DownloadImportTemplate(ctx *gin.Context) {
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := pc.produkService.ImportProdukTemplate(ctx.Request.Context(), format)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DOWNLOAD_IMPORT_FORMAT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sendDownload(ctx, "template_impor_produk", format, result)
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ReorderController interface {
+		GetReorderRules(ctx *gin.Context)
+		SaveReorderRule(ctx *gin.Context)
+		DeleteReorderRule(ctx *gin.Context)
+		UpdateVariantReorder(ctx *gin.Context)
+		GetReplenishment(ctx *gin.Context)
+	}
+
+	reorderController struct {
+		reorderService service.ReorderService
+	}
+)
+
+func NewReorderController(rs service.ReorderService) ReorderController {
+	return &reorderController{
+		reorderService: rs,
+	}
+}
+
+func (c *reorderController) GetReorderRules(ctx *gin.Context) {
+	result, err := c.reorderService.GetReorderRules(ctx.Request.Context())
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_REORDER_RULES, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_REORDER_RULES, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reorderController) SaveReorderRule(ctx *gin.Context) {
+	var req dto.ReorderRuleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.reorderService.SaveReorderRule(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_SAVE_REORDER_RULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_SAVE_REORDER_RULE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reorderController) DeleteReorderRule(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("rule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_REORDER_RULE, "Invalid rule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.reorderService.DeleteReorderRule(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_REORDER_RULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_REORDER_RULE, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reorderController) UpdateVariantReorder(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("detail_produk_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_VARIANT_REORDER, "Invalid detail produk ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var req dto.VariantReorderRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.reorderService.UpdateVariantReorder(ctx.Request.Context(), id, req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_VARIANT_REORDER, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_VARIANT_REORDER, req)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reorderController) GetReplenishment(ctx *gin.Context) {
+	var req dto.ReplenishmentRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.reorderService.GetReplenishment(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_REPLENISHMENT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_REPLENISHMENT, result)
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	ReportScheduleController interface {
+		GetSchedules(ctx *gin.Context)
+		CreateSchedule(ctx *gin.Context)
+		UpdateSchedule(ctx *gin.Context)
+		DeleteSchedule(ctx *gin.Context)
+		RunNow(ctx *gin.Context)
+		GetRuns(ctx *gin.Context)
+	}
+
+	reportScheduleController struct {
+		reportScheduleService service.ReportScheduleService
+	}
+)
+
+func NewReportScheduleController(rs service.ReportScheduleService) ReportScheduleController {
+	return &reportScheduleController{
+		reportScheduleService: rs,
+	}
+}
+
+func (c *reportScheduleController) GetSchedules(ctx *gin.Context) {
+	result, err := c.reportScheduleService.GetSchedules(ctx.Request.Context())
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_REPORT_SCHEDULES, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_REPORT_SCHEDULES, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reportScheduleController) CreateSchedule(ctx *gin.Context) {
+	var req dto.ReportScheduleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.reportScheduleService.CreateSchedule(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_REPORT_SCHEDULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CREATE_REPORT_SCHEDULE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reportScheduleController) UpdateSchedule(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("schedule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_REPORT_SCHEDULE, "Invalid schedule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var req dto.ReportScheduleRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.reportScheduleService.UpdateSchedule(ctx.Request.Context(), id, req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_REPORT_SCHEDULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_REPORT_SCHEDULE, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reportScheduleController) DeleteSchedule(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("schedule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_REPORT_SCHEDULE, "Invalid schedule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.reportScheduleService.DeleteSchedule(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_REPORT_SCHEDULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DELETE_REPORT_SCHEDULE, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reportScheduleController) RunNow(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("schedule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RUN_REPORT_SCHEDULE, "Invalid schedule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.reportScheduleService.RunNow(ctx.Request.Context(), id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RUN_REPORT_SCHEDULE, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_RUN_REPORT_SCHEDULE, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *reportScheduleController) GetRuns(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("schedule_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_REPORT_RUNS, "Invalid schedule ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var req dto.ReportRunRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.reportScheduleService.GetRuns(ctx.Request.Context(), id, req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_REPORT_RUNS, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_REPORT_RUNS, result)
+	ctx.JSON(http.StatusOK, res)
+}
@@ -4,6 +4,7 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/service"
 	"bumisubur-be/utils"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -18,12 +19,19 @@ type (
 		GetSupplierByID(ctx *gin.Context)
 		UpdateSupplier(ctx *gin.Context)
 		UpdateSupplierSupply(ctx *gin.Context)
+		GetSupplyDiscounts(ctx *gin.Context)
 		DeleteSupplier(ctx *gin.Context)
 
 		CreateJenis(ctx *gin.Context)
 		DeleteJenis(ctx *gin.Context)
 		GetAllJenis(ctx *gin.Context)
+		GetJenisByID(ctx *gin.Context)
+		UpdateJenis(ctx *gin.Context)
+		MergeJenis(ctx *gin.Context)
 		DownloadDataSupplier(ctx *gin.Context)
+
+		ImportSupplier(ctx *gin.Context)
+		DownloadImportTemplate(ctx *gin.Context)
 	}
 
 	supplierController struct {
@@ -45,7 +53,8 @@ func (c *supplierController) CreateSupplier(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.supplierService.CreateSupplier(ctx.Request.Context(), supplier)
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.supplierService.CreateSupplier(ctx.Request.Context(), supplier, userID)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CREATE_SUPPLIER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -123,7 +132,8 @@ func (c *supplierController) UpdateSupplier(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.supplierService.UpdateSupplier(ctx.Request.Context(), supplier, id)
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.supplierService.UpdateSupplier(ctx.Request.Context(), supplier, id, userID)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_SUPPLIER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -150,7 +160,8 @@ func (c *supplierController) UpdateSupplierSupply(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.supplierService.UpdateSupplierSupply(ctx.Request.Context(), supplier, id)
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.supplierService.UpdateSupplierSupply(ctx.Request.Context(), supplier, id, userID)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_SUPPLIER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -161,6 +172,25 @@ func (c *supplierController) UpdateSupplierSupply(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (c *supplierController) GetSupplyDiscounts(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("supplier_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DISCOUNT_HISTORY, "Invalid supplier ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.supplierService.GetSupplyDiscounts(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DISCOUNT_HISTORY, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_DISCOUNT_HISTORY, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
 func (c *supplierController) DeleteSupplier(ctx *gin.Context) {
 	supplierID := ctx.Param("supplier_id")
 	id, err := strconv.Atoi(supplierID)
@@ -219,26 +249,137 @@ func (c *supplierController) DeleteJenis(ctx *gin.Context) {
 }
 
 func (c *supplierController) GetAllJenis(ctx *gin.Context) {
-	result, err := c.supplierService.GetAllJenis(ctx.Request.Context())
+	result, err := c.supplierService.GetAllJenis(ctx.Request.Context(), ctx.Query("search"))
 	if err != nil {
-		res := utils.BuildResponseFailed("sukses mengambil semua jenis", err.Error(), nil)
+		res := utils.BuildResponseFailed("gagal mengambil semua jenis", err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	res := utils.BuildResponseSuccess("gagal mengambil semua jenis", result)
+	res := utils.BuildResponseSuccess("sukses mengambil semua jenis", result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *supplierController) GetJenisByID(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("jenis_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal mengambil jenis/kategori", "Invalid jenis ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.supplierService.GetJenisByID(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal mengambil jenis/kategori", err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess("sukses mengambil jenis/kategori", result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *supplierController) UpdateJenis(ctx *gin.Context) {
+	var jenis dto.JenisRequest
+	if err := ctx.ShouldBind(&jenis); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("jenis_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal memperbarui jenis/kategori", "Invalid jenis ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.supplierService.UpdateJenis(ctx.Request.Context(), jenis, id)
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal memperbarui jenis/kategori", err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess("sukses memperbarui jenis/kategori", result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *supplierController) MergeJenis(ctx *gin.Context) {
+	var req dto.MergeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.supplierService.MergeJenis(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal menggabungkan jenis/kategori", err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess("sukses menggabungkan jenis/kategori", result)
 	ctx.JSON(http.StatusOK, res)
 }
 
 func (c *supplierController) DownloadDataSupplier(ctx *gin.Context) {
-	result, err := c.supplierService.DownloadDataSupplier(ctx.Request.Context())
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := c.supplierService.DownloadDataSupplier(ctx.Request.Context(), format)
 	if err != nil {
 		res := utils.BuildResponseFailed("Failed to download data supplier", err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", "attachment; filename=data_supplier.xlsx")
-	ctx.Data(http.StatusOK, "application/octet-stream", result)
+	sendDownload(ctx, "data_supplier", format, result)
+}
+
+func (c *supplierController) ImportSupplier(ctx *gin.Context) {
+	var req dto.SupplierImportRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	userID := ctx.MustGet("user_id").(string)
+	result, err := c.supplierService.ImportSupplier(ctx.Request.Context(), req, userID)
+	if err != nil {
+		var data any
+		if errors.Is(err, dto.ErrImportHasErrors) {
+			data = result
+		}
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_IMPORT_SUPPLIER, err.Error(), data)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	message := dto.MESSAGE_SUCCESS_PREVIEW_IMPORT
+	if result.Confirmed {
+		message = dto.MESSAGE_SUCCESS_IMPORT_SUPPLIER
+	}
+	res := utils.BuildResponseSuccess(message, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *supplierController) DownloadImportTemplate(ctx *gin.Context) {
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	result, err := c.supplierService.ImportSupplierTemplate(ctx.Request.Context(), format)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DOWNLOAD_IMPORT_FORMAT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	sendDownload(ctx, "template_impor_supplier", format, result)
 }
@@ -4,7 +4,6 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/service"
 	"bumisubur-be/utils"
-	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +13,7 @@ type (
 	TransaksiController interface {
 		Index(ctx *gin.Context)
 		CreateTransaksi(ctx *gin.Context)
+		SyncTransaksi(ctx *gin.Context)
 		GetHistoryTransaksi(ctx *gin.Context)
 		PrintMobile(ctx *gin.Context)
 		DownloadData(ctx *gin.Context)
@@ -63,6 +63,27 @@ func (c *transaksiController) CreateTransaksi(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (c *transaksiController) SyncTransaksi(ctx *gin.Context) {
+	var req dto.SyncTransaksiRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	userIDStr := ctx.MustGet("user_id").(string)
+
+	result, err := c.transaksiService.SyncTransaksi(ctx, req, userIDStr)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_SYNC_TRANSAKSI, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_SYNC_TRANSAKSI, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
 func (c *transaksiController) GetHistoryTransaksi(ctx *gin.Context) {
 	var req dto.TransactionPaginationRequest
 	if err := ctx.ShouldBind(&req); err != nil {
@@ -89,15 +110,17 @@ func (c *transaksiController) DownloadData(ctx *gin.Context) {
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
 		return
 	}
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
 	var err error
 	var file []byte
 
 	if req.Filter == "nota" {
-		fmt.Println("downloading nota")
-		file, err = c.transaksiService.DownloadByNota(ctx, req)
+		file, err = c.transaksiService.DownloadByNota(ctx, req, format)
 	} else if req.Filter == "produk" {
-		fmt.Println("downloading produk")
-		file, err = c.transaksiService.DownloadByProduk(ctx, req)
+		file, err = c.transaksiService.DownloadByProduk(ctx, req, format)
 	} else {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format"})
 		return
@@ -108,9 +131,7 @@ func (c *transaksiController) DownloadData(ctx *gin.Context) {
 		return
 	}
 
-	ctx.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	ctx.Header("Content-Disposition", "attachment; filename=transaksi.xlsx")
-	ctx.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", file)
+	sendDownload(ctx, "transaksi", format, file)
 }
 
 func (c *transaksiController) PrintMobile(ctx *gin.Context) {
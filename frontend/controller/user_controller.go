@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -23,6 +24,28 @@ type (
 		// VerifyEmail(ctx *gin.Context)
 		Update(ctx *gin.Context)
 		Delete(ctx *gin.Context)
+		Refresh(ctx *gin.Context)
+		Logout(ctx *gin.Context)
+		LogoutAll(ctx *gin.Context)
+		RevokeSessions(ctx *gin.Context)
+		ForgotPassword(ctx *gin.Context)
+		ResetPassword(ctx *gin.Context)
+		FirstLoginPassword(ctx *gin.Context)
+		UpdateMe(ctx *gin.Context)
+		ChangePassword(ctx *gin.Context)
+		AdminResetPassword(ctx *gin.Context)
+		Unlock(ctx *gin.Context)
+		UpdatePermissions(ctx *gin.Context)
+		LoginTwoFactor(ctx *gin.Context)
+		SetupTwoFactorLogin(ctx *gin.Context)
+		TwoFactorStatus(ctx *gin.Context)
+		EnrollTwoFactor(ctx *gin.Context)
+		ConfirmTwoFactor(ctx *gin.Context)
+		DisableTwoFactor(ctx *gin.Context)
+		RegenerateRecoveryCodes(ctx *gin.Context)
+		ResetTwoFactor(ctx *gin.Context)
+		GetTwoFactorPolicy(ctx *gin.Context)
+		UpdateTwoFactorPolicy(ctx *gin.Context)
 		DownloadDataKaryawan(ctx *gin.Context)
 	}
 
@@ -50,13 +73,20 @@ func (c *userController) Register(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.userService.RegisterUser(ctx.Request.Context(), user)
+	actorID, err := currentUserID(ctx)
 	if err != nil {
-		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REGISTER_USER, err.Error(), nil)
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REGISTER_USER, "Invalid user ID", nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
+	result, err := c.userService.RegisterUser(ctx.Request.Context(), actorID, user)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REGISTER_USER, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
 	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_REGISTER_USER, result)
 	ctx.JSON(http.StatusOK, res)
 }
@@ -69,7 +99,14 @@ func (c *userController) GetAllUser(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.userService.GetAllUserWithPagination(ctx.Request.Context(), req)
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_LIST_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.GetAllUserWithPagination(ctx.Request.Context(), actorID, req)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_LIST_USER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -95,7 +132,14 @@ func (c *userController) GetUserById(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.userService.GetUserById(ctx.Request.Context(), id)
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.GetUserById(ctx.Request.Context(), actorID, id)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_USER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -113,7 +157,7 @@ func (c *userController) Me(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user_id format"})
 		return
 	}
-	result, err := c.userService.GetUserById(ctx.Request.Context(), userID)
+	result, err := c.userService.GetUserById(ctx.Request.Context(), userID, userID)
 	if err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_USER, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
@@ -132,19 +176,230 @@ func (c *userController) Login(ctx *gin.Context) {
 		return
 	}
 
-	result, err := c.userService.Verify(ctx.Request.Context(), req)
+	result, err := c.userService.Verify(ctx.Request.Context(), req, sessionClient(ctx))
 	if err != nil {
+		status := loginErrorStatus(ctx, err)
+		if err == dto.ErrPasswordChangeRequired {
+			status = http.StatusForbidden
+		}
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_LOGIN, err.Error(), nil)
+		ctx.JSON(status, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(loginMessage(result, dto.MESSAGE_SUCCESS_LOGIN), result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) Refresh(ctx *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.RefreshToken(ctx.Request.Context(), req, sessionClient(ctx))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REFRESH_TOKEN, err.Error(), nil)
+		ctx.JSON(http.StatusUnauthorized, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_REFRESH_TOKEN, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) Logout(ctx *gin.Context) {
+	token := ctx.MustGet("token").(string)
+
+	if err := c.userService.Logout(ctx.Request.Context(), token); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_LOGOUT, err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_LOGIN, result)
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_LOGOUT, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) LogoutAll(ctx *gin.Context) {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_LOGOUT, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.RevokeUserSessions(ctx.Request.Context(), userID, userID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_LOGOUT, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_LOGOUT, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) RevokeSessions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REVOKE_SESSIONS, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REVOKE_SESSIONS, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.RevokeUserSessions(ctx.Request.Context(), actorID, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REVOKE_SESSIONS, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_REVOKE_SESSIONS, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) Unlock(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UNLOCK_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UNLOCK_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.UnlockUser(ctx.Request.Context(), actorID, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UNLOCK_USER, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UNLOCK_USER, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) UpdatePermissions(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_PERMISSION, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	var req dto.UserPermissionRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_PERMISSION, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.SetUserPermissions(ctx.Request.Context(), req, actorID, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_PERMISSION, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_PERMISSION, result)
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (c *userController) ForgotPassword(ctx *gin.Context) {
+	var req dto.ForgotPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.ForgotPassword(ctx.Request.Context(), req, sessionClient(ctx)); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_FORGOT_PASSWORD, err.Error(), nil)
+		ctx.JSON(loginErrorStatus(ctx, err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_FORGOT_PASSWORD, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) ResetPassword(ctx *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.ResetPassword(ctx.Request.Context(), req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RESET_PASSWORD, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_RESET_PASSWORD, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) FirstLoginPassword(ctx *gin.Context) {
+	var req dto.FirstLoginPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.FirstLoginPassword(ctx.Request.Context(), req, sessionClient(ctx))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CHANGE_PASSWORD, err.Error(), nil)
+		ctx.JSON(loginErrorStatus(ctx, err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(loginMessage(result, dto.MESSAGE_SUCCESS_CHANGE_PASSWORD), result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// loginMessage tells the client to ask for a 2FA code when the login
+// stopped at a challenge.
+func loginMessage(result dto.UserLoginResponse, message string) string {
+	if result.Challenge != nil {
+		return dto.MESSAGE_SUCCESS_TWO_FACTOR_REQUIRED
+	}
+	return message
+}
+
+func sessionClient(ctx *gin.Context) dto.SessionClient {
+	return dto.SessionClient{
+		IP:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+}
+
 func (c *userController) Update(ctx *gin.Context) {
-	var req dto.UserCreateRequest
+	var req dto.UserUpdateRequest
 	if err := ctx.ShouldBind(&req); err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
 		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
@@ -154,22 +409,134 @@ func (c *userController) Update(ctx *gin.Context) {
 	userId := ctx.Param("user_id")
 	id, err := strconv.Atoi(userId)
 	if err != nil {
-		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_CABANG_BY_ID, "Invalid cabang ID", nil)
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_USER, "Invalid user ID", nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	result, err := c.userService.UpdateUser(ctx.Request.Context(), req, id)
+	c.updateUser(ctx, req, id)
+}
+
+func (c *userController) UpdateMe(ctx *gin.Context) {
+	var req dto.UserUpdateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := currentUserID(ctx)
 	if err != nil {
-		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_USER, err.Error(), nil)
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	c.updateUser(ctx, req, id)
+}
+
+func (c *userController) updateUser(ctx *gin.Context, req dto.UserUpdateRequest, id int) {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_USER, "Invalid user ID", nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
+	result, err := c.userService.UpdateUser(ctx.Request.Context(), req, actorID, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_USER, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
 	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_USER, result)
 	ctx.JSON(http.StatusOK, res)
 }
 
+func (c *userController) ChangePassword(ctx *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CHANGE_PASSWORD, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	token := ctx.MustGet("token").(string)
+	if err := c.userService.ChangePassword(ctx.Request.Context(), req, id, token); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CHANGE_PASSWORD, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CHANGE_PASSWORD, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) AdminResetPassword(ctx *gin.Context) {
+	var req dto.AdminResetPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_ADMIN_RESET_PASSWORD, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_ADMIN_RESET_PASSWORD, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.AdminResetPassword(ctx.Request.Context(), req, actorID, id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_ADMIN_RESET_PASSWORD, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_ADMIN_RESET_PASSWORD, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// currentUserID is the ID of the user the access token belongs to.
+func currentUserID(ctx *gin.Context) (int, error) {
+	return strconv.Atoi(ctx.MustGet("user_id").(string))
+}
+
+// loginErrorStatus answers 429 with a Retry-After header while logins are
+// throttled and 400 for everything else.
+func loginErrorStatus(ctx *gin.Context, err error) int {
+	var throttled *dto.LoginThrottledError
+	if errors.As(err, &throttled) {
+		ctx.Header("Retry-After", strconv.Itoa(throttled.RetryAfterSeconds()))
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
+
+// userErrorStatus answers 403 when the caller is not allowed to act on
+// another user and 400 for everything else.
+func userErrorStatus(err error) int {
+	if err == dto.ErrUserNotOwner || err == dto.ErrUserFieldOwnerOnly {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
 func (c *userController) Delete(ctx *gin.Context) {
 	userId := ctx.Param("user_id")
 
@@ -180,9 +547,16 @@ func (c *userController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	if err := c.userService.DeleteUser(ctx.Request.Context(), id); err != nil {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_USER, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.DeleteUser(ctx.Request.Context(), actorID, id); err != nil {
 		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DELETE_USER, err.Error(), nil)
-		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		ctx.AbortWithStatusJSON(userErrorStatus(err), res)
 		return
 	}
 
@@ -191,14 +565,247 @@ func (c *userController) Delete(ctx *gin.Context) {
 }
 
 func (c *userController) DownloadDataKaryawan(ctx *gin.Context) {
-	result, err := c.userService.DownloadDataKaryawan(ctx.Request.Context())
+	format, ok := downloadFormat(ctx)
+	if !ok {
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed("gagal download data karyawan", "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.DownloadDataKaryawan(ctx.Request.Context(), format, actorID)
 	if err != nil {
 		res := utils.BuildResponseFailed("gagal download data karyawan", err.Error(), nil)
 		ctx.JSON(http.StatusBadRequest, res)
 		return
 	}
 
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Disposition", "attachment; filename=data_cabang.xlsx")
-	ctx.Data(http.StatusOK, "application/octet-stream", result)
+	sendDownload(ctx, "data_karyawan", format, result)
+}
+
+func (c *userController) LoginTwoFactor(ctx *gin.Context) {
+	var req dto.TwoFactorLoginRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.VerifyTwoFactor(ctx.Request.Context(), req, sessionClient(ctx))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_TWO_FACTOR_LOGIN, err.Error(), nil)
+		ctx.JSON(loginErrorStatus(ctx, err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_LOGIN, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) SetupTwoFactorLogin(ctx *gin.Context) {
+	var req dto.TwoFactorSetupRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.SetupTwoFactorLogin(ctx.Request.Context(), req)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_TWO_FACTOR_SETUP, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_TWO_FACTOR_SETUP, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) TwoFactorStatus(ctx *gin.Context) {
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_TWO_FACTOR, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.GetTwoFactorStatus(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_TWO_FACTOR, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_TWO_FACTOR, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) EnrollTwoFactor(ctx *gin.Context) {
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_TWO_FACTOR_SETUP, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.EnrollTwoFactor(ctx.Request.Context(), id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_TWO_FACTOR_SETUP, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_TWO_FACTOR_SETUP, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) ConfirmTwoFactor(ctx *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CONFIRM_TWO_FACTOR, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.ConfirmTwoFactor(ctx.Request.Context(), req, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_CONFIRM_TWO_FACTOR, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_CONFIRM_TWO_FACTOR, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) DisableTwoFactor(ctx *gin.Context) {
+	var req dto.TwoFactorDisableRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DISABLE_TWO_FACTOR, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.DisableTwoFactor(ctx.Request.Context(), req, id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DISABLE_TWO_FACTOR, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_DISABLE_TWO_FACTOR, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req dto.TwoFactorCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	id, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REGENERATE_RECOVERY_CODES, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.RegenerateRecoveryCodes(ctx.Request.Context(), req, id)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_REGENERATE_RECOVERY_CODES, err.Error(), nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_REGENERATE_RECOVERY_CODES, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) ResetTwoFactor(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("user_id"))
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RESET_TWO_FACTOR, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RESET_TWO_FACTOR, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	if err := c.userService.ResetTwoFactor(ctx.Request.Context(), actorID, id); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_RESET_TWO_FACTOR, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_RESET_TWO_FACTOR, nil)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) GetTwoFactorPolicy(ctx *gin.Context) {
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_TWO_FACTOR_POLICY, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.GetTwoFactorPolicy(ctx.Request.Context(), actorID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_TWO_FACTOR_POLICY, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_GET_TWO_FACTOR_POLICY, result)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *userController) UpdateTwoFactorPolicy(ctx *gin.Context) {
+	var req dto.TwoFactorPolicyRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_GET_DATA_FROM_BODY, err.Error(), nil)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, res)
+		return
+	}
+
+	actorID, err := currentUserID(ctx)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_TWO_FACTOR_POLICY, "Invalid user ID", nil)
+		ctx.JSON(http.StatusBadRequest, res)
+		return
+	}
+
+	result, err := c.userService.UpdateTwoFactorPolicy(ctx.Request.Context(), req, actorID)
+	if err != nil {
+		res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_UPDATE_TWO_FACTOR_POLICY, err.Error(), nil)
+		ctx.JSON(userErrorStatus(err), res)
+		return
+	}
+
+	res := utils.BuildResponseSuccess(dto.MESSAGE_SUCCESS_UPDATE_TWO_FACTOR_POLICY, result)
+	ctx.JSON(http.StatusOK, res)
 }
@@ -0,0 +1,235 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MESSAGE_FAILED_GET_SALES_ANALYTICS   = "gagal mengambil data analitik penjualan"
+	MESSAGE_FAILED_GET_PRODUCT_ANALYTICS = "gagal mengambil data analitik produk"
+	MESSAGE_FAILED_GET_PROFIT_LOSS       = "gagal mengambil laporan laba rugi"
+	MESSAGE_FAILED_GET_STOCK_AGING       = "gagal mengambil laporan umur stok"
+
+	MESSAGE_SUCCESS_GET_SALES_ANALYTICS   = "berhasil mengambil data analitik penjualan"
+	MESSAGE_SUCCESS_GET_PRODUCT_ANALYTICS = "berhasil mengambil data analitik produk"
+	MESSAGE_SUCCESS_GET_PROFIT_LOSS       = "berhasil mengambil laporan laba rugi"
+	MESSAGE_SUCCESS_GET_STOCK_AGING       = "berhasil mengambil laporan umur stok"
+)
+
+var (
+	ErrAnalyticsInvalidBucket  = errors.New("bucket harus salah satu dari hour, day, week, month")
+	ErrAnalyticsInvalidGroupBy = errors.New("group_by harus salah satu dari produk, merk, jenis, ukuran, warna")
+	ErrAnalyticsInvalidPeriod  = errors.New("tanggal akhir tidak boleh sebelum tanggal mulai")
+	ErrAnalyticsTooManyBuckets = errors.New("rentang waktu terlalu panjang untuk bucket yang dipilih")
+	ErrProfitLossTooManyMonths = errors.New("laporan laba rugi maksimal 36 bulan")
+	ErrStockAgingInvalidStatus = errors.New("status harus salah satu dari active, slow, dead")
+)
+
+type (
+	AnalyticsRequest struct {
+		Range     string `form:"range"`
+		StartDate string `form:"start_date"`
+		EndDate   string `form:"end_date"`
+		Cabang    int    `form:"cabang"`
+		Bucket    string `form:"bucket"`
+		GroupBy   string `form:"group_by"`
+		Limit     int    `form:"limit"`
+	}
+
+	// AnalyticsFilter is the resolved period and cabang that the analytics
+	// queries run against. Cabang 0 means all cabang.
+	AnalyticsFilter struct {
+		Start  time.Time
+		End    time.Time
+		Cabang int
+	}
+
+	AnalyticsPeriod struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	}
+
+	SalesSummary struct {
+		GrossSales       float64 `json:"gross_sales"`
+		Discount         float64 `json:"discount"`
+		NetSales         float64 `json:"net_sales"`
+		COGS             float64 `json:"cogs"`
+		GrossMargin      float64 `json:"gross_margin"`
+		GrossMarginPct   float64 `json:"gross_margin_pct"`
+		TransactionCount int64   `json:"transaction_count"`
+		ItemsSold        int64   `json:"items_sold"`
+		AverageBasket    float64 `json:"average_basket"`
+		AverageItems     float64 `json:"average_items"`
+	}
+
+	SalesBucket struct {
+		Bucket time.Time `json:"bucket"`
+		SalesSummary
+	}
+
+	// SalesComparison holds the percentage change against the previous
+	// period. A nil value means the previous period had nothing to compare.
+	SalesComparison struct {
+		GrossSales       *float64 `json:"gross_sales"`
+		Discount         *float64 `json:"discount"`
+		NetSales         *float64 `json:"net_sales"`
+		COGS             *float64 `json:"cogs"`
+		GrossMargin      *float64 `json:"gross_margin"`
+		TransactionCount *float64 `json:"transaction_count"`
+		ItemsSold        *float64 `json:"items_sold"`
+		AverageBasket    *float64 `json:"average_basket"`
+	}
+
+	SalesAnalyticsResponse struct {
+		Bucket         string          `json:"bucket"`
+		Period         AnalyticsPeriod `json:"period"`
+		PreviousPeriod AnalyticsPeriod `json:"previous_period"`
+		Summary        SalesSummary    `json:"summary"`
+		Previous       SalesSummary    `json:"previous"`
+		Comparison     SalesComparison `json:"comparison"`
+		Series         []SalesBucket   `json:"series"`
+	}
+
+	ProductRanking struct {
+		Key         string  `json:"key"`
+		Label       string  `json:"label"`
+		ItemsSold   int64   `json:"items_sold"`
+		NetSales    float64 `json:"net_sales"`
+		COGS        float64 `json:"cogs"`
+		GrossMargin float64 `json:"gross_margin"`
+		// PreviousNetSales is the net sales of the same key in the previous
+		// period, used by the front-end to show movement.
+		PreviousNetSales float64 `json:"previous_net_sales"`
+	}
+
+	ProductAnalyticsResponse struct {
+		GroupBy        string           `json:"group_by"`
+		Period         AnalyticsPeriod  `json:"period"`
+		PreviousPeriod AnalyticsPeriod  `json:"previous_period"`
+		Top            []ProductRanking `json:"top"`
+		Bottom         []ProductRanking `json:"bottom"`
+	}
+
+	// ProfitLossAmountRow is a monthly total of sold or refunded lines at
+	// selling price after discount, with the matching harga beli.
+	ProfitLossAmountRow struct {
+		Month  string
+		Amount float64
+		COGS   float64
+	}
+
+	ProfitLossExpenseRow struct {
+		Month    string
+		Kategori string
+		Jumlah   float64
+	}
+
+	ProfitLossExpense struct {
+		Kategori string  `json:"kategori"`
+		Jumlah   float64 `json:"jumlah"`
+	}
+
+	ProfitLossStatement struct {
+		Sales         float64             `json:"sales"`
+		Refunds       float64             `json:"refunds"`
+		NetSales      float64             `json:"net_sales"`
+		COGS          float64             `json:"cogs"`
+		GrossProfit   float64             `json:"gross_profit"`
+		Expenses      []ProfitLossExpense `json:"expenses"`
+		TotalExpenses float64             `json:"total_expenses"`
+		NetProfit     float64             `json:"net_profit"`
+	}
+
+	// ProfitLossMonth is one month-over-month column. The changes are in
+	// percent against the month before and nil when that month was zero.
+	ProfitLossMonth struct {
+		Month           string    `json:"month"`
+		Start           time.Time `json:"start"`
+		End             time.Time `json:"end"`
+		NetSalesChange  *float64  `json:"net_sales_change"`
+		NetProfitChange *float64  `json:"net_profit_change"`
+		ProfitLossStatement
+	}
+
+	ProfitLossResponse struct {
+		Period AnalyticsPeriod     `json:"period"`
+		Cabang int                 `json:"cabang"`
+		Months []ProfitLossMonth   `json:"months"`
+		Total  ProfitLossStatement `json:"total"`
+	}
+
+	StockAgingRequest struct {
+		Merk        string `form:"merk"`
+		Jenis       string `form:"jenis"`
+		JenisID     int    `form:"jenis_id"`
+		Ukuran      string `form:"ukuran"`
+		Warna       string `form:"warna"`
+		Cabang      int    `form:"cabang"`
+		NoSalesDays int    `form:"no_sales_days"`
+		Status      string `form:"status"`
+	}
+
+	StockAgingVariantRow struct {
+		DetailProdukID int
+		ProdukID       int
+		NamaProduk     string
+		BarcodeID      string
+		Merk           string
+		Jenis          string
+		CabangID       int
+		Ukuran         string
+		Warna          string
+		Stok           int
+		HargaBeli      float64
+		CreatedAt      time.Time
+		LastSoldAt     *time.Time
+		SoldInWindow   int
+	}
+
+	StockReceiptRow struct {
+		DetailProdukID int
+		TanggalRestok  time.Time
+		Jumlah         int
+	}
+
+	StockAgingBucket struct {
+		Label string  `json:"label"`
+		Qty   int     `json:"qty"`
+		Value float64 `json:"value"`
+	}
+
+	StockAgingItem struct {
+		DetailProdukID    int                `json:"detail_produk_id"`
+		ProdukID          int                `json:"produk_id"`
+		NamaProduk        string             `json:"nama_produk"`
+		BarcodeID         string             `json:"barcode_id"`
+		Merk              string             `json:"merk"`
+		Jenis             string             `json:"jenis"`
+		CabangID          int                `json:"cabang_id"`
+		Ukuran            string             `json:"ukuran"`
+		Warna             string             `json:"warna"`
+		Stok              int                `json:"stok"`
+		HargaBeli         float64            `json:"harga_beli"`
+		Value             float64            `json:"value"`
+		OldestReceipt     time.Time          `json:"oldest_receipt"`
+		LastSoldAt        *time.Time         `json:"last_sold_at"`
+		DaysSinceLastSale *int               `json:"days_since_last_sale"`
+		SoldInWindow      int                `json:"sold_in_window"`
+		DaysOfCover       *float64           `json:"days_of_cover"`
+		Status            string             `json:"status"`
+		Aging             []StockAgingBucket `json:"aging"`
+	}
+
+	StockAgingResponse struct {
+		NoSalesDays int                `json:"no_sales_days"`
+		TotalQty    int                `json:"total_qty"`
+		TotalValue  float64            `json:"total_value"`
+		DeadQty     int                `json:"dead_qty"`
+		DeadValue   float64            `json:"dead_value"`
+		SlowQty     int                `json:"slow_qty"`
+		SlowValue   float64            `json:"slow_value"`
+		Aging       []StockAgingBucket `json:"aging"`
+		Items       []StockAgingItem   `json:"items"`
+	}
+)
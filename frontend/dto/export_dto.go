@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MESSAGE_FAILED_CREATE_EXPORT   = "gagal membuat export"
+	MESSAGE_FAILED_GET_EXPORT      = "gagal mengambil status export"
+	MESSAGE_FAILED_DOWNLOAD_EXPORT = "gagal mengunduh export"
+
+	MESSAGE_SUCCESS_CREATE_EXPORT = "export sedang diproses"
+	MESSAGE_SUCCESS_GET_EXPORT    = "berhasil mengambil status export"
+)
+
+var (
+	ErrExportNotFound    = errors.New("export tidak ditemukan")
+	ErrExportInvalidType = errors.New("type harus salah satu dari nota, produk, log_akses, pengeluaran, final_stok")
+	ErrExportNotReady    = errors.New("export belum selesai")
+	ErrExportExpired     = errors.New("export sudah kedaluwarsa, silakan buat ulang")
+	ErrExportCabang      = errors.New("export produk membutuhkan cabang")
+)
+
+type (
+	// ExportRequest carries the filters of every export type. Each type only
+	// reads the fields its download endpoint already accepts.
+	ExportRequest struct {
+		Type      string `json:"type" form:"type" binding:"required"`
+		Format    string `json:"format" form:"format"`
+		Search    string `json:"search" form:"search"`
+		Filter    string `json:"filter" form:"filter"`
+		Range     string `json:"range" form:"range"`
+		StartDate string `json:"start_date" form:"start_date"`
+		EndDate   string `json:"end_date" form:"end_date"`
+		Cabang    int    `json:"cabang" form:"cabang"`
+		Merk      string `json:"merk" form:"merk"`
+		Jenis     string `json:"jenis" form:"jenis"`
+		JenisID   int    `json:"jenis_id" form:"jenis_id"`
+	}
+
+	ExportJobResponse struct {
+		ID         string     `json:"id"`
+		Type       string     `json:"type"`
+		Status     string     `json:"status"`
+		Rows       int        `json:"rows"`
+		Error      string     `json:"error,omitempty"`
+		FileName   string     `json:"file_name,omitempty"`
+		CreatedAt  time.Time  `json:"created_at"`
+		FinishedAt *time.Time `json:"finished_at"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+	}
+
+	ExportNotaRow struct {
+		IDTransaksi      int64
+		TanggalTransaksi time.Time
+		TotalPendapatan  float64
+		TotalProduk      int
+		Merk             string
+		NamaProduk       string
+		Jenis            string
+		Ukuran           string
+		JumlahItem       int
+		HargaProduk      float64
+	}
+
+	ExportLogAksesRow struct {
+		ID        int
+		Name      string
+		Email     string
+		IP        string
+		Activity  string
+		Token     string
+		Payload   string
+		CreatedAt time.Time
+	}
+)
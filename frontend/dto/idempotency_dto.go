@@ -0,0 +1,13 @@
+package dto
+
+import "errors"
+
+const (
+	MESSAGE_FAILED_IDEMPOTENCY = "gagal memproses idempotency key"
+)
+
+var (
+	ErrIdempotencyKeyTooLong    = errors.New("idempotency key terlalu panjang")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key sudah dipakai untuk request yang berbeda")
+	ErrIdempotencyKeyInProgress = errors.New("request dengan idempotency key ini masih diproses")
+)
@@ -0,0 +1,113 @@
+package dto
+
+import (
+	"errors"
+	"mime/multipart"
+)
+
+const (
+	MESSAGE_FAILED_IMPORT_PRODUK          = "gagal mengimpor produk"
+	MESSAGE_FAILED_IMPORT_SUPPLIER        = "gagal mengimpor katalog supplier"
+	MESSAGE_FAILED_DOWNLOAD_IMPORT_FORMAT = "gagal mengunduh template impor"
+
+	MESSAGE_SUCCESS_IMPORT_PRODUK   = "berhasil mengimpor produk"
+	MESSAGE_SUCCESS_IMPORT_SUPPLIER = "berhasil mengimpor katalog supplier"
+	MESSAGE_SUCCESS_PREVIEW_IMPORT  = "pratinjau impor berhasil dibuat"
+)
+
+var (
+	ErrImportHasErrors     = errors.New("file masih berisi baris yang tidak valid, tidak ada data yang disimpan")
+	ErrImportTooManyRows   = errors.New("file berisi terlalu banyak baris")
+	ErrImportMissingColumn = errors.New("kolom wajib tidak ditemukan di header")
+	ErrImportTemplatePDF   = errors.New("template impor hanya tersedia dalam xlsx atau csv")
+	ErrImportTanggalRestok = errors.New("tanggal_restok harus berformat YYYY-MM-DD")
+)
+
+type (
+	// ImportRowError points at a cell of the uploaded file. Row counts the
+	// header as row 1, as a spreadsheet does.
+	ImportRowError struct {
+		Row     int    `json:"row"`
+		Column  string `json:"column,omitempty"`
+		Message string `json:"message"`
+	}
+
+	// ProdukImportRequest uploads a product file. Without Confirm the file is
+	// only checked; with it the rows are saved as pending restoks.
+	ProdukImportRequest struct {
+		File          *multipart.FileHeader `form:"file" binding:"required"`
+		TanggalRestok string                `form:"tanggal_restok"`
+		Confirm       bool                  `form:"confirm"`
+	}
+
+	ProdukImportResponse struct {
+		Confirmed bool                 `json:"confirmed"`
+		Rows      int                  `json:"rows"`
+		Errors    []ImportRowError     `json:"errors"`
+		Produks   []ProdukImportResult `json:"produks"`
+	}
+
+	// ProdukImportResult is one product of the file. Rows sharing a barcode
+	// are the variants of one product; Existing products get a restok of
+	// those variants instead of being created.
+	ProdukImportResult struct {
+		ProdukID   int             `json:"produk_id,omitempty"`
+		BarcodeID  string          `json:"barcode_id"`
+		NamaProduk string          `json:"nama_produk"`
+		Merk       string          `json:"merk"`
+		Jenis      string          `json:"jenis"`
+		Supplier   string          `json:"supplier"`
+		Cabang     string          `json:"cabang"`
+		HargaJual  float64         `json:"harga_jual"`
+		Existing   bool            `json:"existing"`
+		Rows       []int           `json:"rows"`
+		Qty        int             `json:"qty"`
+		Details    []DetailRequest `json:"details"`
+	}
+
+	// SupplierImportRequest uploads a supplier price list of supplier, merk,
+	// jenis and discount rows. Without Confirm only the changes are shown.
+	// EffectiveDate dates the discount changes, today when empty.
+	SupplierImportRequest struct {
+		File          *multipart.FileHeader `form:"file" binding:"required"`
+		Confirm       bool                  `form:"confirm"`
+		EffectiveDate string                `form:"effective_date"`
+	}
+
+	// SupplierImportResponse lists what the file would change, or has
+	// changed once Confirmed. Nothing that is missing from the file is
+	// removed.
+	SupplierImportResponse struct {
+		Confirmed bool                   `json:"confirmed"`
+		Rows      int                    `json:"rows"`
+		Errors    []ImportRowError       `json:"errors"`
+		Suppliers []SupplierImportChange `json:"suppliers"`
+		Merks     []MasterImportChange   `json:"merks"`
+		Jenis     []MasterImportChange   `json:"jenis"`
+		Supplies  []SupplyImportChange   `json:"supplies"`
+	}
+
+	SupplierImportChange struct {
+		Action  string `json:"action"`
+		ID      int    `json:"id,omitempty"`
+		Name    string `json:"name"`
+		NoHp    string `json:"no_hp"`
+		OldNoHp string `json:"old_no_hp,omitempty"`
+	}
+
+	MasterImportChange struct {
+		Action string `json:"action"`
+		ID     int    `json:"id,omitempty"`
+		Name   string `json:"name"`
+	}
+
+	SupplyImportChange struct {
+		Action      string `json:"action"`
+		Supplier    string `json:"supplier"`
+		Merk        string `json:"merk"`
+		Jenis       string `json:"jenis"`
+		Discount    int    `json:"discount"`
+		OldDiscount *int   `json:"old_discount,omitempty"`
+		Rows        []int  `json:"rows"`
+	}
+)
@@ -0,0 +1,88 @@
+package dto
+
+import "errors"
+
+const (
+	MESSAGE_FAILED_GET_KATEGORI_TREE       = "gagal mengambil pohon kategori"
+	MESSAGE_FAILED_UPDATE_KATEGORI         = "gagal memperbarui kategori"
+	MESSAGE_FAILED_GET_KATEGORI_ATTRIBUTES = "gagal mengambil atribut kategori"
+	MESSAGE_FAILED_GET_ATTRIBUTE_SET       = "gagal mengambil set atribut"
+	MESSAGE_FAILED_CREATE_ATTRIBUTE_SET    = "gagal membuat set atribut"
+	MESSAGE_FAILED_UPDATE_ATTRIBUTE_SET    = "gagal memperbarui set atribut"
+	MESSAGE_FAILED_DELETE_ATTRIBUTE_SET    = "gagal menghapus set atribut"
+
+	MESSAGE_SUCCESS_GET_KATEGORI_TREE       = "berhasil mengambil pohon kategori"
+	MESSAGE_SUCCESS_UPDATE_KATEGORI         = "berhasil memperbarui kategori"
+	MESSAGE_SUCCESS_GET_KATEGORI_ATTRIBUTES = "berhasil mengambil atribut kategori"
+	MESSAGE_SUCCESS_GET_ATTRIBUTE_SET       = "berhasil mengambil set atribut"
+	MESSAGE_SUCCESS_CREATE_ATTRIBUTE_SET    = "berhasil membuat set atribut"
+	MESSAGE_SUCCESS_UPDATE_ATTRIBUTE_SET    = "berhasil memperbarui set atribut"
+	MESSAGE_SUCCESS_DELETE_ATTRIBUTE_SET    = "berhasil menghapus set atribut"
+)
+
+var (
+	ErrKategoriCycle           = errors.New("parent tidak boleh jenis itu sendiri atau turunannya")
+	ErrAttributeSetNotFound    = errors.New("set atribut tidak ditemukan")
+	ErrAttributeInvalid        = errors.New("attribute harus salah satu dari ukuran, warna")
+	ErrAttributeSetMismatch    = errors.New("set atribut tidak sesuai dengan atribut yang diisi")
+	ErrAttributeSetInUse       = errors.New("set atribut masih digunakan oleh jenis")
+	ErrAttributeValueEmpty     = errors.New("nilai atribut tidak boleh kosong")
+	ErrAttributeValueDuplicate = errors.New("nilai atribut tidak boleh duplikat")
+	ErrVariantUkuran           = errors.New("ukuran tidak ada dalam skala ukuran jenis")
+	ErrVariantWarna            = errors.New("warna tidak ada dalam palet warna jenis")
+)
+
+type (
+	// KategoriRequest places a jenis in the category tree and assigns its
+	// attribute sets. Every field is replaced; null moves the jenis to the
+	// root or makes it inherit the set of its parent.
+	KategoriRequest struct {
+		ParentID    *int `json:"parent_id"`
+		UkuranSetID *int `json:"ukuran_set_id"`
+		WarnaSetID  *int `json:"warna_set_id"`
+	}
+
+	KategoriNode struct {
+		ID          int            `json:"id"`
+		NamaJenis   string         `json:"nama_jenis"`
+		Path        string         `json:"path"`
+		ParentID    *int           `json:"parent_id"`
+		UkuranSetID *int           `json:"ukuran_set_id"`
+		WarnaSetID  *int           `json:"warna_set_id"`
+		Children    []KategoriNode `json:"children"`
+	}
+
+	// KategoriAttributesResponse holds the sets a jenis uses, its own or
+	// inherited, as the standard values for variants and filters.
+	KategoriAttributesResponse struct {
+		JenisID   int                   `json:"jenis_id"`
+		NamaJenis string                `json:"nama_jenis"`
+		Path      string                `json:"path"`
+		Ukuran    *AttributeSetResponse `json:"ukuran"`
+		Warna     *AttributeSetResponse `json:"warna"`
+	}
+
+	AttributeSetRequest struct {
+		Name      string                  `json:"name" binding:"required"`
+		Attribute string                  `json:"attribute" binding:"required"`
+		Values    []AttributeValueRequest `json:"values" binding:"required"`
+	}
+
+	AttributeValueRequest struct {
+		Value string `json:"value"`
+		Code  string `json:"code"`
+	}
+
+	AttributeSetResponse struct {
+		ID        int                      `json:"id"`
+		Name      string                   `json:"name"`
+		Attribute string                   `json:"attribute"`
+		Values    []AttributeValueResponse `json:"values"`
+	}
+
+	AttributeValueResponse struct {
+		Value  string `json:"value"`
+		Code   string `json:"code,omitempty"`
+		Urutan int    `json:"urutan"`
+	}
+)
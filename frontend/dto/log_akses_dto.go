@@ -2,22 +2,58 @@ package dto
 
 import (
 	"errors"
+	"time"
 )
 
 const (
 	MESSAGE_FAILED_GET_ALL_LOG  = "gagal mengambil semua data log akses"
 	MESSAGE_SUCCESS_GET_ALL_LOG = "sukses mengambil semua data log akses"
+
+	MESSAGE_SUCCESS_GET_LOG_STATS = "sukses mengambil status antrean log akses"
+
+	MESSAGE_FAILED_GET_LOG_SESSIONS  = "gagal mengambil sesi log akses"
+	MESSAGE_SUCCESS_GET_LOG_SESSIONS = "sukses mengambil sesi log akses"
+	MESSAGE_FAILED_GET_LOG_SESSION   = "gagal mengambil detail sesi log akses"
+	MESSAGE_SUCCESS_GET_LOG_SESSION  = "sukses mengambil detail sesi log akses"
 )
 
 var (
-	ErrGetAllLog = errors.New("gagal mengambil semua data log akses")
+	ErrGetAllLog           = errors.New("gagal mengambil semua data log akses")
+	ErrLogStatusInvalid    = errors.New("status harus berupa kode seperti 404 atau kelas seperti 4xx")
+	ErrLogDateInvalid      = errors.New("tanggal harus berformat YYYY-MM-DD")
+	ErrLogSessionNotFound  = errors.New("sesi log akses tidak ditemukan")
+	ErrLogOrderInvalid     = errors.New("urutan harus asc atau desc")
+	ErrLogRetentionInvalid = errors.New("mode retensi log harus archive atau purge")
 )
 
 type (
+	// LogAksesPaginationRequest filters the access log. Search matches the
+	// user name, email, IP address and activity. Status is a code such as
+	// 404 or a class such as 4xx, Route a route group such as transaksi
+	// for everything under /api/transaksi. Dates are Asia/Jakarta days and
+	// both ends are included. Order is desc, newest first, by default.
 	LogAksesPaginationRequest struct {
+		Search     string `form:"search"`
+		StartDate  string `json:"start_date" form:"start_date"`
+		EndDate    string `json:"end_date" form:"end_date"`
+		Status     string `json:"status" form:"status"`
+		Route      string `json:"route" form:"route"`
+		UserID     int    `json:"user_id" form:"user_id"`
+		LogAksesID int    `json:"log_akses_id" form:"log_akses_id"`
+		Order      string `json:"order" form:"order"`
+		Page       int    `form:"page"`
+		PerPage    int    `form:"per_page"`
+	}
+
+	// LogAksesSessionRequest filters the sessions of the access log, one per
+	// log akses. Dates match the login time.
+	LogAksesSessionRequest struct {
 		Search    string `form:"search"`
+		UserID    int    `json:"user_id" form:"user_id"`
 		StartDate string `json:"start_date" form:"start_date"`
 		EndDate   string `json:"end_date" form:"end_date"`
+		Page      int    `form:"page"`
+		PerPage   int    `form:"per_page"`
 	}
 
 	FilterLogAkses struct {
@@ -26,7 +62,58 @@ type (
 	}
 
 	LogAksesPaginationResponse struct {
-		Data []LogAksesResponse `json:"data"`
+		Data               []LogAksesResponse `json:"data"`
+		PaginationResponse `json:"pagination"`
+	}
+
+	LogAksesSessionResponse struct {
+		ID         int    `json:"id"`
+		UserID     int    `json:"user_id"`
+		Name       string `json:"name"`
+		Email      string `json:"email"`
+		LoginTime  string `json:"login_time"`
+		LogoutTime string `json:"logout_time"`
+		Active     bool   `json:"active"`
+		Actions    int64  `json:"actions"`
+	}
+
+	LogAksesSessionPaginationResponse struct {
+		Data               []LogAksesSessionResponse `json:"data"`
+		PaginationResponse `json:"pagination"`
+	}
+
+	// LogAksesSessionDetailResponse is one session with a page of the
+	// actions performed in it, oldest first.
+	LogAksesSessionDetailResponse struct {
+		Session LogAksesSessionResponse    `json:"session"`
+		Actions LogAksesPaginationResponse `json:"actions"`
+	}
+
+	// LogRetentionResult is what one run of the retention policy removed.
+	// Archive is the file the rows were written to, empty when purging.
+	LogRetentionResult struct {
+		Before  time.Time `json:"before"`
+		Mode    string    `json:"mode"`
+		Removed int64     `json:"removed"`
+		Archive string    `json:"archive,omitempty"`
+	}
+
+	// AccessLogStatsResponse shows how the access log queue keeps up.
+	// Overflowed entries found the queue full and FallbackWritten entries
+	// went to the fallback file instead of the database, either because of
+	// that or because an insert failed. Lost entries could not be written
+	// anywhere.
+	AccessLogStatsResponse struct {
+		QueueLength     int        `json:"queue_length"`
+		QueueCapacity   int        `json:"queue_capacity"`
+		Enqueued        int64      `json:"enqueued"`
+		Written         int64      `json:"written"`
+		Batches         int64      `json:"batches"`
+		Overflowed      int64      `json:"overflowed"`
+		FallbackWritten int64      `json:"fallback_written"`
+		Lost            int64      `json:"lost"`
+		LastError       string     `json:"last_error,omitempty"`
+		LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
 	}
 
 	LogAksesResponse struct {
@@ -37,6 +124,8 @@ type (
 		Activity   string `json:"activity"`
 		Token      string `json:"token"`
 		Payload    string `json:"payload"`
+		Status     string `json:"status"`
+		LogAksesID *int   `json:"log_akses_id"`
 		Created_At string `json:"created_at"`
 	}
 )
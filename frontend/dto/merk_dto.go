@@ -0,0 +1,45 @@
+package dto
+
+import "errors"
+
+const (
+	MESSAGE_FAILED_GET_ALL_MERK   = "gagal mengambil semua data merk"
+	MESSAGE_FAILED_GET_MERK_BY_ID = "gagal mengambil data merk berdasarkan id"
+	MESSAGE_FAILED_CREATE_MERK    = "gagal membuat merk"
+	MESSAGE_FAILED_UPDATE_MERK    = "gagal memperbarui merk"
+	MESSAGE_FAILED_DELETE_MERK    = "gagal menghapus merk"
+	MESSAGE_FAILED_MERGE_MERK     = "gagal menggabungkan merk"
+
+	MESSAGE_SUCCESS_GET_ALL_MERK   = "berhasil mengambil semua data merk"
+	MESSAGE_SUCCESS_GET_MERK_BY_ID = "berhasil mengambil data merk berdasarkan id"
+	MESSAGE_SUCCESS_CREATE_MERK    = "berhasil membuat merk"
+	MESSAGE_SUCCESS_UPDATE_MERK    = "berhasil memperbarui merk"
+	MESSAGE_SUCCESS_DELETE_MERK    = "berhasil menghapus merk"
+	MESSAGE_SUCCESS_MERGE_MERK     = "berhasil menggabungkan merk"
+)
+
+var (
+	ErrMerkNotFound      = errors.New("merk tidak ditemukan")
+	ErrMerkAlreadyExists = errors.New("merk dengan nama tersebut sudah terdaftar, gunakan gabung merk")
+	ErrMerkInUse         = errors.New("merk masih digunakan supplier atau aturan reorder, gabungkan ke merk lain untuk menghapusnya")
+
+	ErrMergeSelf = errors.New("data tidak dapat digabungkan ke dirinya sendiri")
+)
+
+type (
+	MerkNameRequest struct {
+		NamaMerk string `json:"nama_merk" form:"nama_merk" binding:"required"`
+	}
+
+	MerkDataResponse struct {
+		ID       int    `json:"id"`
+		NamaMerk string `json:"nama_merk"`
+	}
+
+	// MergeRequest folds every source record into the target, which keeps
+	// its name. Used for both merk and jenis.
+	MergeRequest struct {
+		SourceIDs []int `json:"source_ids" form:"source_ids" binding:"required"`
+		TargetID  int   `json:"target_id" form:"target_id" binding:"required"`
+	}
+)
@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MESSAGE_FAILED_GET_NOTIFICATION_RULES   = "gagal mengambil aturan notifikasi"
+	MESSAGE_FAILED_CREATE_NOTIFICATION_RULE = "gagal membuat aturan notifikasi"
+	MESSAGE_FAILED_UPDATE_NOTIFICATION_RULE = "gagal memperbarui aturan notifikasi"
+	MESSAGE_FAILED_DELETE_NOTIFICATION_RULE = "gagal menghapus aturan notifikasi"
+	MESSAGE_FAILED_GET_NOTIFICATION_OUTBOX  = "gagal mengambil antrian notifikasi"
+
+	MESSAGE_SUCCESS_GET_NOTIFICATION_RULES   = "berhasil mengambil aturan notifikasi"
+	MESSAGE_SUCCESS_CREATE_NOTIFICATION_RULE = "berhasil membuat aturan notifikasi"
+	MESSAGE_SUCCESS_UPDATE_NOTIFICATION_RULE = "berhasil memperbarui aturan notifikasi"
+	MESSAGE_SUCCESS_DELETE_NOTIFICATION_RULE = "berhasil menghapus aturan notifikasi"
+	MESSAGE_SUCCESS_GET_NOTIFICATION_OUTBOX  = "berhasil mengambil antrian notifikasi"
+)
+
+var (
+	ErrNotificationRuleNotFound  = errors.New("aturan notifikasi tidak ditemukan")
+	ErrNotificationInvalidEvent  = errors.New("event harus salah satu dari low_stock, large_pengeluaran, large_return, pending_restok")
+	ErrNotificationNoRecipient   = errors.New("minimal satu penerima wajib diisi")
+	ErrNotificationInvalidEmail  = errors.New("alamat email penerima tidak valid")
+	ErrNotificationNegativeLimit = errors.New("threshold tidak boleh negatif")
+)
+
+type (
+	NotificationRuleRequest struct {
+		Name       string   `json:"name" binding:"required"`
+		Event      string   `json:"event" binding:"required"`
+		Threshold  float64  `json:"threshold"`
+		CabangID   *int     `json:"cabang_id"`
+		Recipients []string `json:"recipients"`
+		Digest     bool     `json:"digest"`
+		Active     *bool    `json:"active"`
+	}
+
+	NotificationRuleResponse struct {
+		ID         int      `json:"id"`
+		Name       string   `json:"name"`
+		Event      string   `json:"event"`
+		Threshold  float64  `json:"threshold"`
+		CabangID   *int     `json:"cabang_id"`
+		Recipients []string `json:"recipients"`
+		Digest     bool     `json:"digest"`
+		Active     bool     `json:"active"`
+	}
+
+	NotificationOutboxRequest struct {
+		Status  string `form:"status"`
+		PerPage int    `form:"per_page"`
+	}
+
+	NotificationVariantRow struct {
+		DetailProdukID int
+		NamaProduk     string
+		Merk           string
+		Ukuran         string
+		Warna          string
+		Stok           int
+		CabangID       int
+	}
+
+	PendingRestokRow struct {
+		RestokID      int64
+		TanggalRestok time.Time
+		NamaProduk    string
+		Supplier      string
+		CabangID      int
+		Jumlah        int
+	}
+)
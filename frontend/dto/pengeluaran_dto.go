@@ -39,6 +39,7 @@ type (
 		KategoriPengeluaran string    `json:"kategori_pengeluaran" form:"kategori_pengeluaran"`
 		Jumlah              float64   `json:"jumlah" form:"jumlah"`
 		Tujuan              string    `json:"tujuan" form:"tujuan"`
+		CabangID            *int      `json:"cabang_id" form:"cabang_id"`
 	}
 
 	PengeluaranResponse struct {
@@ -50,6 +51,7 @@ type (
 		KategoriPengeluaran string    `json:"kategori_pengeluaran" form:"kategori_pengeluaran"`
 		Jumlah              float64   `json:"jumlah" form:"jumlah"`
 		Tujuan              string    `json:"tujuan" form:"tujuan"`
+		CabangID            *int      `json:"cabang_id" form:"cabang_id"`
 	}
 
 	GetAllPengeluaranRepositoryResponse struct {
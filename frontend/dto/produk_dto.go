@@ -101,6 +101,10 @@ type (
 
 	ProdukPaginationRequest struct {
 		Search  string `json:"search" form:"search"`
+		Jenis   string `json:"jenis" form:"jenis"`
+		JenisID int    `json:"jenis_id" form:"jenis_id"`
+		Ukuran  string `json:"ukuran" form:"ukuran"`
+		Warna   string `json:"warna" form:"warna"`
 		Page    int    `json:"page" form:"page"`
 		PerPage int    `json:"per_page" form:"per_page"`
 		Order   string `json:"order" form:"order"`
@@ -286,7 +290,11 @@ type (
 		EndDate   string `json:"end_date" form:"end_date"`
 		Merk      string `json:"merk" form:"merk"`
 		Jenis     string `json:"jenis" form:"jenis"`
+		JenisID   int    `json:"jenis_id" form:"jenis_id"`
+		Ukuran    string `json:"ukuran" form:"ukuran"`
+		Warna     string `json:"warna" form:"warna"`
 		Filter    string `json:"filter" form:"filter"`
+		Cabang    int    `json:"cabang" form:"cabang"`
 	}
 
 	FinalStokResponse struct {
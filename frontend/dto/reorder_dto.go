@@ -0,0 +1,130 @@
+package dto
+
+import "errors"
+
+const (
+	MESSAGE_FAILED_GET_REORDER_RULES      = "gagal mengambil aturan reorder"
+	MESSAGE_FAILED_SAVE_REORDER_RULE      = "gagal menyimpan aturan reorder"
+	MESSAGE_FAILED_DELETE_REORDER_RULE    = "gagal menghapus aturan reorder"
+	MESSAGE_FAILED_UPDATE_VARIANT_REORDER = "gagal memperbarui reorder varian"
+	MESSAGE_FAILED_GET_REPLENISHMENT      = "gagal mengambil saran restok"
+
+	MESSAGE_SUCCESS_GET_REORDER_RULES      = "berhasil mengambil aturan reorder"
+	MESSAGE_SUCCESS_SAVE_REORDER_RULE      = "berhasil menyimpan aturan reorder"
+	MESSAGE_SUCCESS_DELETE_REORDER_RULE    = "berhasil menghapus aturan reorder"
+	MESSAGE_SUCCESS_UPDATE_VARIANT_REORDER = "berhasil memperbarui reorder varian"
+	MESSAGE_SUCCESS_GET_REPLENISHMENT      = "berhasil mengambil saran restok"
+)
+
+var (
+	ErrReorderRuleScope    = errors.New("merk_id atau jenis_id wajib diisi")
+	ErrReorderRuleNotFound = errors.New("aturan reorder tidak ditemukan")
+	ErrReorderNegative     = errors.New("min_stok dan reorder_qty tidak boleh negatif")
+	ErrVariantNotFound     = errors.New("varian produk tidak ditemukan")
+)
+
+type (
+	ReorderRuleRequest struct {
+		MerkID     *int `json:"merk_id" form:"merk_id"`
+		JenisID    *int `json:"jenis_id" form:"jenis_id"`
+		MinStok    int  `json:"min_stok" form:"min_stok"`
+		ReorderQty int  `json:"reorder_qty" form:"reorder_qty"`
+	}
+
+	ReorderRuleResponse struct {
+		ID         int    `json:"id"`
+		MerkID     *int   `json:"merk_id"`
+		Merk       string `json:"merk"`
+		JenisID    *int   `json:"jenis_id"`
+		Jenis      string `json:"jenis"`
+		MinStok    int    `json:"min_stok"`
+		ReorderQty int    `json:"reorder_qty"`
+	}
+
+	// VariantReorderRequest sets the per-variant override. Sending null
+	// falls back to the merk/jenis rule again.
+	VariantReorderRequest struct {
+		MinStok    *int `json:"min_stok"`
+		ReorderQty *int `json:"reorder_qty"`
+	}
+
+	ReplenishmentRequest struct {
+		Cabang    int    `form:"cabang"`
+		Merk      string `form:"merk"`
+		Jenis     string `form:"jenis"`
+		JenisID   int    `form:"jenis_id"`
+		Days      int    `form:"days"`
+		LeadDays  int    `form:"lead_days"`
+		CoverDays int    `form:"cover_days"`
+	}
+
+	ReplenishmentVariantRow struct {
+		DetailProdukID int
+		ProdukID       int
+		NamaProduk     string
+		BarcodeID      string
+		CabangID       int
+		MerkID         int
+		Merk           string
+		JenisID        int
+		Jenis          string
+		SupplierID     int
+		Ukuran         string
+		Warna          string
+		Stok           int
+		HargaBeli      float64
+		MinStok        *int
+		ReorderQty     *int
+		SoldInWindow   int
+	}
+
+	MerkJenisSupplierRow struct {
+		MerkID           int
+		JenisID          int
+		SupplierID       int
+		Name             string
+		NoHp             string
+		Discount         int
+		SupplierDiscount int
+	}
+
+	ReplenishmentSupplier struct {
+		SupplierID       int    `json:"supplier_id"`
+		Name             string `json:"name"`
+		NoHp             string `json:"no_hp"`
+		Discount         int    `json:"discount"`
+		SupplierDiscount int    `json:"supplier_discount"`
+		Current          bool   `json:"current"`
+	}
+
+	ReplenishmentSuggestion struct {
+		DetailProdukID int                     `json:"detail_produk_id"`
+		ProdukID       int                     `json:"produk_id"`
+		NamaProduk     string                  `json:"nama_produk"`
+		BarcodeID      string                  `json:"barcode_id"`
+		CabangID       int                     `json:"cabang_id"`
+		Merk           string                  `json:"merk"`
+		Jenis          string                  `json:"jenis"`
+		Ukuran         string                  `json:"ukuran"`
+		Warna          string                  `json:"warna"`
+		Stok           int                     `json:"stok"`
+		SoldInWindow   int                     `json:"sold_in_window"`
+		DailyVelocity  float64                 `json:"daily_velocity"`
+		DaysOfCover    *float64                `json:"days_of_cover"`
+		ReorderPoint   int                     `json:"reorder_point"`
+		SuggestedQty   int                     `json:"suggested_qty"`
+		RuleSource     string                  `json:"rule_source"`
+		HargaBeli      float64                 `json:"harga_beli"`
+		EstimatedCost  float64                 `json:"estimated_cost"`
+		Suppliers      []ReplenishmentSupplier `json:"suppliers"`
+	}
+
+	ReplenishmentResponse struct {
+		Days      int                       `json:"days"`
+		LeadDays  int                       `json:"lead_days"`
+		CoverDays int                       `json:"cover_days"`
+		TotalQty  int                       `json:"total_qty"`
+		TotalCost float64                   `json:"total_cost"`
+		Items     []ReplenishmentSuggestion `json:"items"`
+	}
+)
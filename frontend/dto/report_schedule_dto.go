@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MESSAGE_FAILED_GET_REPORT_SCHEDULES   = "gagal mengambil jadwal laporan"
+	MESSAGE_FAILED_CREATE_REPORT_SCHEDULE = "gagal membuat jadwal laporan"
+	MESSAGE_FAILED_UPDATE_REPORT_SCHEDULE = "gagal memperbarui jadwal laporan"
+	MESSAGE_FAILED_DELETE_REPORT_SCHEDULE = "gagal menghapus jadwal laporan"
+	MESSAGE_FAILED_RUN_REPORT_SCHEDULE    = "gagal menjalankan jadwal laporan"
+	MESSAGE_FAILED_GET_REPORT_RUNS        = "gagal mengambil riwayat pengiriman laporan"
+
+	MESSAGE_SUCCESS_GET_REPORT_SCHEDULES   = "berhasil mengambil jadwal laporan"
+	MESSAGE_SUCCESS_CREATE_REPORT_SCHEDULE = "berhasil membuat jadwal laporan"
+	MESSAGE_SUCCESS_UPDATE_REPORT_SCHEDULE = "berhasil memperbarui jadwal laporan"
+	MESSAGE_SUCCESS_DELETE_REPORT_SCHEDULE = "berhasil menghapus jadwal laporan"
+	MESSAGE_SUCCESS_RUN_REPORT_SCHEDULE    = "laporan dijadwalkan untuk dikirim"
+	MESSAGE_SUCCESS_GET_REPORT_RUNS        = "berhasil mengambil riwayat pengiriman laporan"
+)
+
+var (
+	ErrReportScheduleNotFound = errors.New("jadwal laporan tidak ditemukan")
+	ErrReportInvalidType      = errors.New("report harus salah satu dari nota, produk, pengeluaran, final_stok, profit_loss")
+	ErrReportInvalidPeriod    = errors.New("period harus salah satu dari today, yesterday, last_week, this_month, last_month")
+	ErrReportCronNeverFires   = errors.New("cron tidak pernah berjalan")
+	ErrReportCabangRequired   = errors.New("laporan produk membutuhkan cabang_id")
+)
+
+type (
+	ReportScheduleRequest struct {
+		Name       string   `json:"name" binding:"required"`
+		Report     string   `json:"report" binding:"required"`
+		Period     string   `json:"period"`
+		Cron       string   `json:"cron" binding:"required"`
+		CabangID   *int     `json:"cabang_id"`
+		Recipients []string `json:"recipients"`
+		Active     *bool    `json:"active"`
+	}
+
+	ReportScheduleResponse struct {
+		ID         int        `json:"id"`
+		Name       string     `json:"name"`
+		Report     string     `json:"report"`
+		Period     string     `json:"period"`
+		Cron       string     `json:"cron"`
+		CabangID   *int       `json:"cabang_id"`
+		Recipients []string   `json:"recipients"`
+		Active     bool       `json:"active"`
+		NextRunAt  *time.Time `json:"next_run_at"`
+		LastRunAt  *time.Time `json:"last_run_at"`
+	}
+
+	ReportRunRequest struct {
+		PerPage int `form:"per_page"`
+	}
+)
@@ -54,6 +54,7 @@ type (
 		Ukuran            string  `json:"ukuran"`
 		JumlahItem        int     `json:"jumlah_item"`
 		HargaProduk       float64 `json:"harga_produk"`
+		CabangID          int     `json:"cabang_id"`
 	}
 
 	CreateReturnUser struct {
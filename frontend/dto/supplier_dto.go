@@ -3,6 +3,7 @@ package dto
 import (
 	"bumisubur-be/entity"
 	"errors"
+	"time"
 )
 
 const (
@@ -13,6 +14,7 @@ const (
 	MESSAGE_FAILED_UPDATE_SUPPLIER       = "gagal memperbarui data supplier"
 	MESSAGE_FAILED_DELETE_SUPPLIER       = "gagal menghapus supplier"
 	MESSAGE_FAILED_GET_HISTORY_TRANSAKSI = "gagal mengambil history transaksi"
+	MESSAGE_FAILED_GET_DISCOUNT_HISTORY  = "gagal mengambil riwayat discount supplier"
 
 	MESSAGE_SUCCESS_CREATE_SUPPLIER       = "berhasil membuat supplier"
 	MESSAGE_SUCCESS_GET_INDEX_SUPPLIER    = "berhasil mengambil index data supplier"
@@ -21,6 +23,7 @@ const (
 	MESSAGE_SUCCESS_UPDATE_SUPPLIER       = "berhasil memperbarui data supplier"
 	MESSAGE_SUCCESS_DELETE_SUPPLIER       = "berhasil menghapus supplier"
 	MESSAGE_SUCCESS_GET_HISTORY_TRANSAKSI = "berhasil mengambil history transaksi"
+	MESSAGE_SUCCESS_GET_DISCOUNT_HISTORY  = "berhasil mengambil riwayat discount supplier"
 )
 
 var (
@@ -31,6 +34,8 @@ var (
 	ErrDeleteSupplier        = errors.New("gagal menghapus supplier")
 	ErrSupplierAlreadyExists = errors.New("supplier sudah terdaftar")
 	ErrSupplierNotFound      = errors.New("supplier tidak ditemukan")
+	ErrSupplyEffectiveDate   = errors.New("effective_date harus berformat YYYY-MM-DD")
+	ErrSupplyDiscount        = errors.New("discount harus 0 sampai 100")
 
 	ErrCreateJenis        = errors.New("gagal membuat Jenis")
 	ErrGetAllJenis        = errors.New("gagal mengambil semua data Jenis")
@@ -39,6 +44,9 @@ var (
 	ErrDeleteJenis        = errors.New("gagal menghapus Jenis")
 	ErrJenisAlreadyExists = errors.New("jenis sudah terdaftar")
 	ErrJenisNotFound      = errors.New("jenis tidak ditemukan")
+	ErrJenisNameEmpty     = errors.New("nama_jenis wajib diisi")
+	ErrJenisAmbiguous     = errors.New("nama jenis ada di lebih dari satu kategori, tulis lengkap seperti \"Kaos > Lengan Pendek\"")
+	ErrJenisInUse         = errors.New("jenis masih digunakan supplier, aturan reorder atau sub-jenis, gabungkan ke jenis lain untuk menghapusnya")
 )
 
 type (
@@ -47,6 +55,10 @@ type (
 		NoHp        string        `json:"no_hp" form:"no_hp"`
 		Discount    int           `json:"discount" form:"discount"`
 		MerkRequest []MerkRequest `json:"merk_request" form:"merk_request"`
+
+		// EffectiveDate is when discount changes in MerkRequest apply,
+		// today when empty.
+		EffectiveDate string `json:"effective_date" form:"effective_date"`
 	}
 
 	MerkRequest struct {
@@ -57,10 +69,13 @@ type (
 
 	JenisRequest struct {
 		NamaJenis string `json:"nama_jenis" form:"nama_jenis"`
+		// ParentID places a jenis created on its own in the category tree.
+		ParentID *int `json:"parent_id" form:"parent_id"`
 	}
 
 	UpdateSupplyRequest struct {
-		Data []MerkRequest `json:"data" form:"data"`
+		Data          []MerkRequest `json:"data" form:"data"`
+		EffectiveDate string        `json:"effective_date" form:"effective_date"`
 	}
 
 	IndexResponse struct {
@@ -92,6 +107,43 @@ type (
 		NoHp     string         `json:"no_hp"`
 		Discount int            `json:"discount"`
 		Merk     []MerkResponse `json:"merk"`
+
+		Changes *SupplyChanges `json:"changes,omitempty"`
+	}
+
+	// SupplyChanges is the diff a supply update applied. Kept lists the
+	// supplies left out of the request that were not removed because
+	// products still reference them.
+	SupplyChanges struct {
+		Added   []SupplyChange `json:"added"`
+		Updated []SupplyChange `json:"updated"`
+		Removed []SupplyChange `json:"removed"`
+		Kept    []SupplyChange `json:"kept"`
+	}
+
+	SupplyChange struct {
+		DetailMerkSupplierID int    `json:"detail_merk_supplier_id"`
+		Merk                 string `json:"merk"`
+		Jenis                string `json:"jenis"`
+		Discount             int    `json:"discount"`
+		OldDiscount          *int   `json:"old_discount,omitempty"`
+	}
+
+	SupplyDiscountRow struct {
+		entity.SupplyDiscount `gorm:"embedded"`
+		Merk                  string
+		Jenis                 string
+	}
+
+	SupplyDiscountResponse struct {
+		DetailMerkSupplierID int       `json:"detail_merk_supplier_id"`
+		Merk                 string    `json:"merk"`
+		Jenis                string    `json:"jenis"`
+		Discount             int       `json:"discount"`
+		OldDiscount          *int      `json:"old_discount"`
+		EffectiveFrom        string    `json:"effective_from"`
+		ChangedBy            string    `json:"changed_by"`
+		CreatedAt            time.Time `json:"created_at"`
 	}
 
 	MerkResponse struct {
@@ -103,6 +155,7 @@ type (
 	JenisResponse struct {
 		ID        int    `json:"id"`
 		NamaJenis string `json:"nama_jenis" form:"nama_jenis"`
+		ParentID  *int   `json:"parent_id"`
 	}
 
 	MerkRestok struct {
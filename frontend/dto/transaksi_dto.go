@@ -12,6 +12,7 @@ const (
 	MESSAGE_FAILED_GET_TRANSAKSI_BY_ID = "gagal mengambil data transaksi berdasarkan id"
 	MESSAGE_FAILED_UPDATE_TRANSAKSI    = "gagal memperbarui data transaksi"
 	MESSAGE_FAILED_DELETE_TRANSAKSI    = "gagal menghapus transaksi"
+	MESSAGE_FAILED_SYNC_TRANSAKSI      = "gagal sinkronisasi transaksi offline"
 
 	MESSAGE_SUCCESS_CREATE_TRANSAKSI    = "berhasil membuat transaksi"
 	MESSAGE_SUCCESS_GET_INDEX_TRANSAKSI = "berhasil mengambil index data transaksi"
@@ -19,6 +20,7 @@ const (
 	MESSAGE_SUCCESS_GET_TRANSAKSI_BY_ID = "berhasil mengambil data transaksi berdasarkan id"
 	MESSAGE_SUCCESS_UPDATE_TRANSAKSI    = "berhasil memperbarui data transaksi"
 	MESSAGE_SUCCESS_DELETE_TRANSAKSI    = "berhasil menghapus transaksi"
+	MESSAGE_SUCCESS_SYNC_TRANSAKSI      = "berhasil sinkronisasi transaksi offline"
 )
 
 var (
@@ -29,6 +31,11 @@ var (
 	ErrDeleteTransaksi        = errors.New("gagal menghapus transaksi")
 	ErrTransaksiAlreadyExists = errors.New("transaksi sudah terdaftar")
 	ErrTransaksiNotFound      = errors.New("transaksi tidak ditemukan")
+	ErrTransaksiPriceMismatch = errors.New("total harga tidak sesuai")
+	ErrTransaksiStokKurang    = errors.New("stok tidak mencukupi")
+	ErrSyncClientIDRequired   = errors.New("client_id wajib diisi")
+	ErrSyncClientIDInvalid    = errors.New("client_id harus berupa UUID")
+	ErrSyncInProgress         = errors.New("transaksi dengan client_id ini sedang diproses")
 )
 
 type (
@@ -147,4 +154,27 @@ type (
 		Stok           int    `json:"stok"`
 	}
 
+	SyncTransaksiRequest struct {
+		Transaksi []OfflineTransaksi `json:"transaksi" binding:"required"`
+	}
+
+	OfflineTransaksi struct {
+		ClientID         string    `json:"client_id"`
+		TanggalTransaksi time.Time `json:"tanggal_transaksi"`
+		CreateTransaksi
+	}
+
+	SyncTransaksiResult struct {
+		ClientID    string `json:"client_id"`
+		Status      string `json:"status"`
+		TransaksiID *int64 `json:"transaksi_id,omitempty"`
+		Message     string `json:"message,omitempty"`
+	}
+
+	SyncTransaksiResponse struct {
+		Accepted int                   `json:"accepted"`
+		Rejected int                   `json:"rejected"`
+		Results  []SyncTransaksiResult `json:"results"`
+	}
+
 )
@@ -0,0 +1,96 @@
+package dto
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MESSAGE_FAILED_TWO_FACTOR_LOGIN          = "gagal verifikasi 2FA"
+	MESSAGE_FAILED_TWO_FACTOR_SETUP          = "gagal menyiapkan 2FA"
+	MESSAGE_FAILED_GET_TWO_FACTOR            = "gagal mengambil status 2FA"
+	MESSAGE_FAILED_CONFIRM_TWO_FACTOR        = "gagal mengaktifkan 2FA"
+	MESSAGE_FAILED_DISABLE_TWO_FACTOR        = "gagal menonaktifkan 2FA"
+	MESSAGE_FAILED_REGENERATE_RECOVERY_CODES = "gagal membuat ulang kode pemulihan"
+	MESSAGE_FAILED_RESET_TWO_FACTOR          = "gagal mereset 2FA user"
+	MESSAGE_FAILED_GET_TWO_FACTOR_POLICY     = "gagal mengambil kebijakan 2FA"
+	MESSAGE_FAILED_UPDATE_TWO_FACTOR_POLICY  = "gagal memperbarui kebijakan 2FA"
+
+	MESSAGE_SUCCESS_TWO_FACTOR_REQUIRED       = "password benar, masukkan kode 2FA"
+	MESSAGE_SUCCESS_TWO_FACTOR_SETUP          = "sukses menyiapkan 2FA, pindai kode QR lalu masukkan kodenya"
+	MESSAGE_SUCCESS_GET_TWO_FACTOR            = "sukses mengambil status 2FA"
+	MESSAGE_SUCCESS_CONFIRM_TWO_FACTOR        = "sukses mengaktifkan 2FA, simpan kode pemulihan di tempat aman"
+	MESSAGE_SUCCESS_DISABLE_TWO_FACTOR        = "sukses menonaktifkan 2FA"
+	MESSAGE_SUCCESS_REGENERATE_RECOVERY_CODES = "sukses membuat ulang kode pemulihan"
+	MESSAGE_SUCCESS_RESET_TWO_FACTOR          = "sukses mereset 2FA user"
+	MESSAGE_SUCCESS_GET_TWO_FACTOR_POLICY     = "sukses mengambil kebijakan 2FA"
+	MESSAGE_SUCCESS_UPDATE_TWO_FACTOR_POLICY  = "sukses memperbarui kebijakan 2FA"
+)
+
+var (
+	ErrTwoFactorCodeInvalid      = errors.New("kode 2FA tidak valid")
+	ErrTwoFactorChallengeInvalid = errors.New("verifikasi 2FA tidak valid atau kadaluarsa, silakan login kembali")
+	ErrTwoFactorNotEnrolled      = errors.New("2FA belum disiapkan")
+	ErrTwoFactorNotEnabled       = errors.New("2FA belum aktif")
+	ErrTwoFactorAlreadyEnabled   = errors.New("2FA sudah aktif")
+	ErrTwoFactorRequiredForRole  = errors.New("2FA wajib untuk jabatan ini")
+	ErrTwoFactorRoleEmpty        = errors.New("jabatan tidak boleh kosong")
+)
+
+type (
+	// TwoFactorChallengeResponse is returned by login instead of tokens when
+	// a TOTP code is needed. Setup means the user has to enroll first.
+	TwoFactorChallengeResponse struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Setup     bool      `json:"setup"`
+	}
+
+	// TwoFactorLoginRequest finishes a login with a TOTP code, or with a
+	// recovery code once 2FA is enabled.
+	TwoFactorLoginRequest struct {
+		ChallengeToken string `json:"challenge_token" form:"challenge_token" binding:"required"`
+		Code           string `json:"code" form:"code" binding:"required"`
+	}
+
+	TwoFactorSetupRequest struct {
+		ChallengeToken string `json:"challenge_token" form:"challenge_token" binding:"required"`
+	}
+
+	// TwoFactorEnrollResponse holds the new secret to add to an
+	// authenticator app, as text and as an otpauth URI for a QR code.
+	TwoFactorEnrollResponse struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+
+	TwoFactorCodeRequest struct {
+		Code string `json:"code" form:"code" binding:"required"`
+	}
+
+	TwoFactorDisableRequest struct {
+		Password string `json:"password" form:"password" binding:"required"`
+		Code     string `json:"code" form:"code" binding:"required"`
+	}
+
+	// RecoveryCodesResponse shows recovery codes once; only their hashes are
+	// kept.
+	RecoveryCodesResponse struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	TwoFactorStatusResponse struct {
+		Enabled           bool  `json:"enabled"`
+		Required          bool  `json:"required"`
+		RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+	}
+
+	// TwoFactorPolicyRequest lists every role for which 2FA is mandatory.
+	TwoFactorPolicyRequest struct {
+		Roles []string `json:"roles" form:"roles"`
+	}
+
+	TwoFactorPolicyResponse struct {
+		Roles []string `json:"roles"`
+	}
+)
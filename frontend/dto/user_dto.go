@@ -2,6 +2,7 @@ package dto
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"bumisubur-be/entity"
@@ -25,6 +26,15 @@ const (
 	MESSAGE_FAILED_VERIFY_EMAIL                 = "gagal verifikasi email"
 	MESSAGE_FAILED_UPDATE_DETAILED_PRODUK_BY_ID = "gagal memperbarui pending produk"
 	MESSAGE_FAILED_GET_TRANSAKSI_BY_NOTA_ID     = "gagal mendapatkan transaksi berdasarkan nota id"
+	MESSAGE_FAILED_REFRESH_TOKEN                = "gagal memperbarui token"
+	MESSAGE_FAILED_LOGOUT                       = "gagal logout"
+	MESSAGE_FAILED_REVOKE_SESSIONS              = "gagal mengakhiri sesi user"
+	MESSAGE_FAILED_FORGOT_PASSWORD              = "gagal memproses lupa password"
+	MESSAGE_FAILED_RESET_PASSWORD               = "gagal reset password"
+	MESSAGE_FAILED_CHANGE_PASSWORD              = "gagal mengganti password"
+	MESSAGE_FAILED_ADMIN_RESET_PASSWORD         = "gagal mereset password user"
+	MESSAGE_FAILED_UNLOCK_USER                  = "gagal membuka kunci login user"
+	MESSAGE_FAILED_UPDATE_PERMISSION            = "gagal memperbarui izin user"
 
 	// Success
 	MESSAGE_SUCCESS_REGISTER_USER                = "sukses mendaftarkan akun baru"
@@ -37,27 +47,71 @@ const (
 	MESSAGE_SUCCESS_VERIFY_EMAIL                 = "sukses verifikasi email"
 	MESSAGE_SUCCESS_UPDATE_DETAILED_PRODUK_BY_ID = "sukses memperbarui pending produk"
 	MESSAGE_SUCCESS_GET_TRANSAKSI_BY_NOTA_ID     = "sukses mendapatkan transaksi berdasarkan nota id"
+	MESSAGE_SUCCESS_REFRESH_TOKEN                = "sukses memperbarui token"
+	MESSAGE_SUCCESS_LOGOUT                       = "sukses logout"
+	MESSAGE_SUCCESS_REVOKE_SESSIONS              = "sukses mengakhiri sesi user"
+	MESSAGE_SUCCESS_FORGOT_PASSWORD              = "jika email terdaftar, link reset password sudah dikirim"
+	MESSAGE_SUCCESS_RESET_PASSWORD               = "sukses reset password, silakan login kembali"
+	MESSAGE_SUCCESS_CHANGE_PASSWORD              = "sukses mengganti password"
+	MESSAGE_SUCCESS_ADMIN_RESET_PASSWORD         = "sukses mereset password user, user wajib menggantinya saat login"
+	MESSAGE_SUCCESS_UNLOCK_USER                  = "sukses membuka kunci login user"
+	MESSAGE_SUCCESS_UPDATE_PERMISSION            = "sukses memperbarui izin user"
 )
 
 var (
-	ErrCreateUser             = errors.New("gagal mendaftarkan akun baru")
-	ErrGetAllUser             = errors.New("gagal mengambil semua data user")
-	ErrGetUserById            = errors.New("gagal mengambil data user berdasarkan id")
-	ErrGetUserByEmail         = errors.New("gagal mengambil data user berdasarkan email")
-	ErrEmailAlreadyExists     = errors.New("email sudah terdaftar")
-	ErrUpdateUser             = errors.New("gagal memperbarui data user")
-	ErrUserNotAdmin           = errors.New("user bukan admin")
-	ErrUserNotFound           = errors.New("user tidak ditemukan")
-	ErrEmailNotFound          = errors.New("email tidak ditemukan")
-	ErrDeleteUser             = errors.New("gagal menghapus user")
-	ErrPasswordNotMatch       = errors.New("password tidak cocok")
-	ErrEmailOrPassword        = errors.New("email atau password salah")
-	ErrAccountNotVerified     = errors.New("akun belum terverifikasi")
-	ErrTokenInvalid           = errors.New("token tidak valid")
-	ErrTokenExpired           = errors.New("token kadaluarsa")
-	ErrAccountAlreadyVerified = errors.New("akun sudah terverifikasi")
+	ErrCreateUser                = errors.New("gagal mendaftarkan akun baru")
+	ErrGetAllUser                = errors.New("gagal mengambil semua data user")
+	ErrGetUserById               = errors.New("gagal mengambil data user berdasarkan id")
+	ErrGetUserByEmail            = errors.New("gagal mengambil data user berdasarkan email")
+	ErrEmailAlreadyExists        = errors.New("email sudah terdaftar")
+	ErrUpdateUser                = errors.New("gagal memperbarui data user")
+	ErrUserNotAdmin              = errors.New("user bukan admin")
+	ErrUserNotFound              = errors.New("user tidak ditemukan")
+	ErrEmailNotFound             = errors.New("email tidak ditemukan")
+	ErrDeleteUser                = errors.New("gagal menghapus user")
+	ErrPasswordNotMatch          = errors.New("password tidak cocok")
+	ErrEmailOrPassword           = errors.New("email atau password salah")
+	ErrAccountNotVerified        = errors.New("akun belum terverifikasi")
+	ErrTokenInvalid              = errors.New("token tidak valid")
+	ErrTokenExpired              = errors.New("token kadaluarsa")
+	ErrAccountAlreadyVerified    = errors.New("akun sudah terverifikasi")
+	ErrSessionRevoked            = errors.New("sesi sudah berakhir, silakan login kembali")
+	ErrRefreshTokenInvalid       = errors.New("refresh token tidak valid atau kadaluarsa")
+	ErrRefreshTokenReused        = errors.New("refresh token sudah pernah dipakai, sesi diakhiri")
+	ErrPasswordPolicy            = errors.New("password minimal 8 karakter dan harus berisi huruf dan angka")
+	ErrPasswordSameAsEmail       = errors.New("password tidak boleh sama dengan email")
+	ErrPasswordNotChanged        = errors.New("password baru tidak boleh sama dengan password lama")
+	ErrPasswordChangeRequired    = errors.New("password harus diganti sebelum login")
+	ErrPasswordChangeNotRequired = errors.New("password tidak wajib diganti, gunakan menu ganti password")
+	ErrUserNotOwner              = errors.New("hanya owner yang dapat melakukan aksi ini")
+	ErrUserFieldOwnerOnly        = errors.New("hanya owner yang dapat mengubah NIK, jabatan dan tanggal masuk")
+	ErrUserFieldEmpty            = errors.New("data user tidak boleh kosong")
+	ErrResetTokenInvalid         = errors.New("link reset password tidak valid atau kadaluarsa")
+	ErrLoginThrottled            = errors.New("terlalu banyak percobaan login")
+	ErrNIKAlreadyExists          = errors.New("NIK sudah terdaftar")
+	ErrUserRoleInvalid           = errors.New("role harus salah satu dari owner, admin, user, kasir, stok atau kasirstok")
 )
 
+// LoginThrottledError is returned while an account or IP address has to
+// wait before it may try to log in again. It wraps ErrLoginThrottled.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("%s, coba lagi dalam %d detik", ErrLoginThrottled, e.RetryAfterSeconds())
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds for the
+// Retry-After header.
+func (e *LoginThrottledError) RetryAfterSeconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
 type (
 	UserCreateRequest struct {
 		NIK          string    `json:"nik" form:"nik" binding:"required"`
@@ -83,6 +137,7 @@ type (
 		TempatLahir  string `json:"tempat_lahir"`
 		TanggalLahir string `json:"tanggal_lahir"`
 		Alamat       string `json:"alamat"`
+		CanExportPII bool   `json:"can_export_pii"`
 	}
 
 	UserPaginationResponse struct {
@@ -106,11 +161,29 @@ type (
 		PaginationResponse
 	}
 
+	// UserUpdateRequest is a partial profile update: fields left out keep
+	// their value. Passwords are changed through their own endpoints.
 	UserUpdateRequest struct {
-		Name   string `json:"name" form:"name" binding:"required"`
-		Email  string `json:"email" form:"email" binding:"required"`
-		NoHp   string `json:"no_hp" form:"no_hp" binding:"required"`
-		Alamat string `json:"alamat" form:"alamat" binding:"required"`
+		NIK          *string    `json:"nik" form:"nik"`
+		Name         *string    `json:"name" form:"name"`
+		Email        *string    `json:"email" form:"email"`
+		NoHp         *string    `json:"no_hp" form:"no_hp"`
+		Role         *string    `json:"role" form:"role"`
+		TanggalMasuk *time.Time `json:"tanggal_masuk" form:"tanggal_masuk"`
+		TempatLahir  *string    `json:"tempat_lahir" form:"tempat_lahir"`
+		TanggalLahir *time.Time `json:"tanggal_lahir" form:"tanggal_lahir"`
+		Alamat       *string    `json:"alamat" form:"alamat"`
+	}
+
+	ChangePasswordRequest struct {
+		OldPassword string `json:"old_password" form:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" form:"new_password" binding:"required"`
+	}
+
+	// AdminResetPasswordRequest sets a temporary password that the user has
+	// to replace at the next login.
+	AdminResetPasswordRequest struct {
+		Password string `json:"password" form:"password" binding:"required"`
 	}
 
 	SendVerificationEmailRequest struct {
@@ -131,9 +204,66 @@ type (
 		Password string `json:"password" form:"password" binding:"required"`
 	}
 
+	// UserLoginResponse is returned by login and refresh. Token is the
+	// short-lived access token; RefreshToken is single use and must be
+	// replaced by the one returned from each refresh.
+	// UserLoginResponse carries either the tokens of a new session or, when
+	// the user still has to pass 2FA, only Challenge. RecoveryCodes is set
+	// once, on the login that finished a mandatory 2FA enrollment.
 	UserLoginResponse struct {
-		Token string `json:"token"`
-		Role  string `json:"role"`
+		Token            string                      `json:"token"`
+		Role             string                      `json:"role"`
+		ExpiresAt        time.Time                   `json:"expires_at"`
+		RefreshToken     string                      `json:"refresh_token"`
+		RefreshExpiresAt time.Time                   `json:"refresh_expires_at"`
+		Challenge        *TwoFactorChallengeResponse `json:"challenge,omitempty"`
+		RecoveryCodes    []string                    `json:"recovery_codes,omitempty"`
+	}
+
+	ForgotPasswordRequest struct {
+		Email string `json:"email" form:"email" binding:"required"`
+	}
+
+	ResetPasswordRequest struct {
+		Token    string `json:"token" form:"token" binding:"required"`
+		Password string `json:"password" form:"password" binding:"required"`
+	}
+
+	// FirstLoginPasswordRequest replaces the password an owner chose for a
+	// new account and logs the user in.
+	FirstLoginPasswordRequest struct {
+		Email       string `json:"email" form:"email" binding:"required"`
+		OldPassword string `json:"old_password" form:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" form:"new_password" binding:"required"`
+	}
+
+	RefreshTokenRequest struct {
+		RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
+	}
+
+	// SessionClient is the device a session is opened or refreshed from.
+	SessionClient struct {
+		IP        string
+		UserAgent string
+	}
+
+	RevokeSessionsResponse struct {
+		Revoked int64 `json:"revoked"`
+	}
+
+	UnlockUserResponse struct {
+		UserID   int  `json:"user_id"`
+		Unlocked bool `json:"unlocked"`
+	}
+
+	// UserPermissionRequest sets what a user may do beyond their role.
+	UserPermissionRequest struct {
+		CanExportPII *bool `json:"can_export_pii" form:"can_export_pii" binding:"required"`
+	}
+
+	UserPermissionResponse struct {
+		UserID       int  `json:"user_id"`
+		CanExportPII bool `json:"can_export_pii"`
 	}
 
 	UpdateStatusIsVerifiedRequest struct {
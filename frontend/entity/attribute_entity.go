@@ -0,0 +1,25 @@
+package entity
+
+type (
+	// AttributeSet is a list of standard values for one variant attribute,
+	// such as a letter size scale, a numeric size scale or a colour palette.
+	// A jenis uses the sets assigned to it or to its nearest ancestor.
+	AttributeSet struct {
+		ID        int    `gorm:"primaryKey;autoIncrement" json:"id"`
+		Name      string `gorm:"not null" json:"name"`
+		Attribute string `gorm:"not null;index" json:"attribute"`
+
+		Values []AttributeValue `json:"values,omitempty" gorm:"foreignKey:AttributeSetID;constraint:onDelete:CASCADE"`
+		Timestamp
+	}
+
+	// AttributeValue is one value of a set. Code is optional, for example the
+	// hex colour of a palette entry; Urutan orders sizes from small to large.
+	AttributeValue struct {
+		ID             int    `gorm:"primaryKey;autoIncrement" json:"id"`
+		AttributeSetID int    `gorm:"not null;index" json:"attribute_set_id"`
+		Value          string `gorm:"not null" json:"value"`
+		Code           string `json:"code"`
+		Urutan         int    `json:"urutan"`
+	}
+)
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// ExportJob is an export file, in any of the download formats, that is built
+// by the export worker instead of inside the request. The ID is random so it
+// can double as the polling and download handle.
+type ExportJob struct {
+	ID          string     `gorm:"type:varchar(32);primaryKey" json:"id"`
+	Type        string     `gorm:"not null" json:"type"`
+	Params      string     `gorm:"type:text" json:"-"`
+	Status      string     `gorm:"index" json:"status"`
+	RequestedBy string     `gorm:"index" json:"-"`
+	FileName    string     `json:"file_name"`
+	FilePath    string     `json:"-"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error"`
+	StartedAt   *time.Time `gorm:"type:timestamptz" json:"started_at"`
+	FinishedAt  *time.Time `gorm:"type:timestamptz" json:"finished_at"`
+	ExpiresAt   *time.Time `gorm:"type:timestamptz;index" json:"expires_at"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// IdempotencyKey stores the first response produced for an Idempotency-Key
+// header so that retries of the same mutating request can be replayed. While
+// the first request is still running, LockedUntil is how long it holds the
+// key.
+type IdempotencyKey struct {
+	ID          int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key         string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	UserID      int        `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Method      string     `json:"method"`
+	Path        string     `json:"path"`
+	RequestHash string     `json:"request_hash"`
+	StatusCode  int        `json:"status_code"`
+	ContentType string     `json:"content_type"`
+	Response    []byte     `gorm:"type:bytea" json:"-"`
+	ExpiresAt   time.Time  `gorm:"type:timestamptz;index" json:"expires_at"`
+	LockedUntil *time.Time `gorm:"type:timestamptz" json:"locked_until"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+}
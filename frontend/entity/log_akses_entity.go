@@ -18,13 +18,16 @@ type (
 		Timestamp
 	}
 
+	// DetailAkses is one request of a user. LogAksesID is nil for failed
+	// logins, which happen outside any session. Token is only a fingerprint
+	// of the access token and Payload is redacted, see utils.Redactor.
 	DetailAkses struct {
 		ID         int    `gorm:"primaryKey;autoIncrement" json:"id"`
 		Activity   string `json:"activity"`
 		IP         string `json:"ip_address"`
 		Token      string `json:"token"`
 		Payload    string `json:"payload"`
-		LogAksesID int    `json:"log_akses_id"`
+		LogAksesID *int   `json:"log_akses_id"`
 		Status     string `json:"status"`
 		Timestamp
 	}
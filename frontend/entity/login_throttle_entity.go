@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// LoginThrottle counts the recent failed logins of one key, either
+// "ip:<address>" or "email:<lowercased email>". Failures are counted from the
+// first failure of the current window, and LockedUntil blocks every login of
+// the key until it passes.
+type LoginThrottle struct {
+	ID            int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key           string     `gorm:"type:varchar(320);not null;uniqueIndex" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	WindowStart   time.Time  `gorm:"type:timestamptz" json:"window_start"`
+	LastFailureAt time.Time  `gorm:"type:timestamptz" json:"last_failure_at"`
+	LockedUntil   *time.Time `gorm:"type:timestamptz" json:"locked_until"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+type (
+	// NotificationRule sends an email to Recipients when Event happens with a
+	// value at or past Threshold. What the threshold means depends on the
+	// event: remaining stok, pengeluaran or refund amount, or days a restok
+	// has been pending. Digest rules are collected into one daily email.
+	NotificationRule struct {
+		ID         int     `gorm:"primaryKey;autoIncrement" json:"id"`
+		Name       string  `json:"name"`
+		Event      string  `gorm:"index;not null" json:"event"`
+		Threshold  float64 `gorm:"type:decimal(19,2)" json:"threshold"`
+		CabangID   *int    `json:"cabang_id"`
+		Recipients string  `json:"recipients"`
+		Digest     bool    `json:"digest"`
+		Active     bool    `json:"active"`
+
+		Timestamp
+	}
+
+	// NotificationOutbox is one queued email to one recipient. Rows are
+	// delivered by the notification worker, never on the request path.
+	NotificationOutbox struct {
+		ID            int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		RuleID        int        `gorm:"index" json:"rule_id"`
+		Event         string     `json:"event"`
+		Recipient     string     `json:"recipient"`
+		Subject       string     `json:"subject"`
+		Body          string     `gorm:"type:text" json:"body"`
+		DedupKey      *string    `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+		Status        string     `gorm:"index" json:"status"`
+		Attempts      int        `json:"attempts"`
+		NextAttemptAt time.Time  `gorm:"type:timestamptz;index" json:"next_attempt_at"`
+		SentAt        *time.Time `gorm:"type:timestamptz" json:"sent_at"`
+		LastError     string     `json:"last_error"`
+
+		CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+		UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+	}
+)
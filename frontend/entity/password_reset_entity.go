@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// PasswordReset is a single-use password reset link sent by email. Only the
+// SHA-256 hash of the token is stored.
+type PasswordReset struct {
+	ID        int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    int        `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"type:timestamptz" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"type:timestamptz" json:"used_at"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+}
@@ -14,6 +14,7 @@ type (
 		Tujuan              string    `json:"tujuan"`
 		TanggalPengeluaran  time.Time `gorm:"type:timestamptz" json:"tanggal_pengeluaran"`
 		Description         string    `json:"description"`
+		CabangID            *int      `gorm:"index" json:"cabang_id"`
 
 		Timestamp
 	}
@@ -21,6 +21,11 @@ type (
 		Status    int     `gorm:"type:int" json:"status_produk"`
 		HargaBeli float64 `gorm:"type:decimal(19,2)" json:"harga_beli"`
 
+		// MinStok and ReorderQty override the merk/jenis ReorderRule for
+		// this variant when set.
+		MinStok    *int `json:"min_stok"`
+		ReorderQty *int `json:"reorder_qty"`
+
 		ProdukID             int `gorm:"type:int;not null" json:"produk_id"`
 		DetailMerkSupplierID int `gorm:"not null" json:"detail_merk_supplier_id"`
 
@@ -38,9 +43,15 @@ type (
 		Timestamp
 	}
 
+	// Jenis is a node of the category tree, e.g. Pakaian > Kaos > Lengan
+	// Pendek. UkuranSetID and WarnaSetID are inherited by child jenis that
+	// do not set their own.
 	Jenis struct {
-		ID        int    `gorm:"primaryKey;autoIncrement;start:100" json:"id"`
-		NamaJenis string `json:"nama_jenis"`
+		ID          int    `gorm:"primaryKey;autoIncrement;start:100" json:"id"`
+		NamaJenis   string `json:"nama_jenis"`
+		ParentID    *int   `gorm:"index" json:"parent_id"`
+		UkuranSetID *int   `json:"ukuran_set_id"`
+		WarnaSetID  *int   `json:"warna_set_id"`
 
 		DetailMerkSuppliers []DetailMerkSupplier `json:"detail_merk_suppliers,omitempty" gorm:"foreignKey:JenisID;constraint:onDelete:CASCADE"`
 		Timestamp
@@ -57,5 +68,7 @@ type (
 		Merk         Merk           `gorm:"foreignKey:MerkID;references:ID;constraint:onDelete:CASCADE"`
 		Jenis        Jenis          `gorm:"foreignKey:JenisID;references:ID;constraint:onDelete:CASCADE"`
 		DetailProduk []DetailProduk `json:"DetailProduk,omitempty" gorm:"foreignKey:DetailMerkSupplierID;constraint:onDelete:CASCADE"`
+
+		Discounts []SupplyDiscount `json:"discounts,omitempty" gorm:"foreignKey:DetailMerkSupplierID;constraint:onDelete:CASCADE"`
 	}
 )
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// ReorderRule is the default minimum stock and reorder quantity for every
+// variant of a merk, a jenis, or a merk and jenis combination. At least one
+// of MerkID and JenisID is set; the most specific rule wins.
+type ReorderRule struct {
+	ID         int  `gorm:"primaryKey;autoIncrement" json:"id"`
+	MerkID     *int `gorm:"index" json:"merk_id"`
+	JenisID    *int `gorm:"index" json:"jenis_id"`
+	MinStok    int  `json:"min_stok"`
+	ReorderQty int  `json:"reorder_qty"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+}
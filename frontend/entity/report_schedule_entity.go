@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+type (
+	// ReportSchedule emails Report as an Excel attachment to Recipients every
+	// time Cron fires. Period picks the dates the report covers, counted from
+	// the moment the schedule fired.
+	ReportSchedule struct {
+		ID         int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		Name       string     `json:"name"`
+		Report     string     `gorm:"not null" json:"report"`
+		Period     string     `json:"period"`
+		Cron       string     `gorm:"not null" json:"cron"`
+		CabangID   *int       `json:"cabang_id"`
+		Recipients string     `json:"recipients"`
+		Active     bool       `json:"active"`
+		NextRunAt  *time.Time `gorm:"type:timestamptz;index" json:"next_run_at"`
+		LastRunAt  *time.Time `gorm:"type:timestamptz" json:"last_run_at"`
+
+		Timestamp
+	}
+
+	// ReportRun is one delivery of a schedule. SentTo lists the recipients
+	// that already got the file so a retry only mails the rest.
+	ReportRun struct {
+		ID            int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		ScheduleID    int        `gorm:"not null;uniqueIndex:idx_report_run_schedule" json:"schedule_id"`
+		ScheduledFor  time.Time  `gorm:"type:timestamptz;not null;uniqueIndex:idx_report_run_schedule" json:"scheduled_for"`
+		Manual        bool       `json:"manual"`
+		Status        string     `gorm:"index" json:"status"`
+		Attempts      int        `json:"attempts"`
+		NextAttemptAt time.Time  `gorm:"type:timestamptz;index" json:"next_attempt_at"`
+		FileName      string     `json:"file_name"`
+		SentTo        string     `json:"sent_to"`
+		LastError     string     `json:"last_error"`
+		FinishedAt    *time.Time `gorm:"type:timestamptz" json:"finished_at"`
+
+		CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+		UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+	}
+)
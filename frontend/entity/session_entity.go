@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Session is one login of a user. Access tokens carry its ID and are only
+// accepted while it is active, so revoking it logs the device out at once.
+// Only SHA-256 hashes of refresh tokens are kept; PreviousTokenHash is the
+// token replaced by the last rotation, used to detect a stolen token.
+type Session struct {
+	ID                int        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID            int        `gorm:"not null;index" json:"user_id"`
+	LogAksesID        int        `json:"log_akses_id"`
+	RefreshTokenHash  string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	PreviousTokenHash string     `gorm:"type:varchar(64);index" json:"-"`
+	IP                string     `json:"ip_address"`
+	UserAgent         string     `json:"user_agent"`
+	ExpiresAt         time.Time  `gorm:"type:timestamptz;index" json:"expires_at"`
+	LastUsedAt        time.Time  `gorm:"type:timestamptz" json:"last_used_at"`
+	RevokedAt         *time.Time `gorm:"type:timestamptz" json:"revoked_at"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz" json:"updated_at"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
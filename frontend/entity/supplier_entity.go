@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type Supplier struct {
 	ID       int    `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name     string `json:"name"`
@@ -10,3 +12,17 @@ type Supplier struct {
 	Restok              []Restok             `json:"restok,omitempty" gorm:"foreignKey:SupplierID;constraint:onDelete:CASCADE"`
 	Timestamp
 }
+
+// SupplyDiscount versions the discount of a DetailMerkSupplier. A row is
+// written when a supply is added and whenever its discount changes, so the
+// HargaBeli of a restok can be traced to the discount effective on its date.
+type SupplyDiscount struct {
+	ID                   int       `gorm:"primaryKey;autoIncrement" json:"id"`
+	DetailMerkSupplierID int       `gorm:"not null;index" json:"detail_merk_supplier_id"`
+	Discount             int       `json:"discount"`
+	OldDiscount          *int      `json:"old_discount"`
+	EffectiveFrom        time.Time `gorm:"type:date;not null" json:"effective_from"`
+	ChangedBy            string    `json:"changed_by"`
+
+	CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+}
@@ -24,6 +24,11 @@ type (
 		ID           int `gorm:"primaryKey;autoIncrement" json:"id"`
 		JumlahProduk int `json:"jumlah_produk"`
 
+		// HargaJual and HargaBeli are the prices at the time of the sale, so
+		// reports do not change when a produk is repriced later.
+		HargaJual float64 `gorm:"type:decimal(19,2)" json:"harga_jual"`
+		HargaBeli float64 `gorm:"type:decimal(19,2)" json:"harga_beli"`
+
 		TransaksiID    int64 `gorm:"type:uuid" json:"-"`
 		DetailProdukID int   `gorm:"type:uuid" json:"-"`
 
@@ -31,4 +36,20 @@ type (
 		
 		Timestamp
 	}
+
+	// TransaksiSync records the outcome of a transaksi uploaded by a till
+	// that was offline, keyed by the UUID generated on the client. A pending
+	// row is held by the upload that reserved it at ReservedAt.
+	TransaksiSync struct {
+		ID               int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		ClientID         string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"client_id"`
+		TransaksiID      *int64     `gorm:"type:bigint" json:"transaksi_id"`
+		Status           string     `json:"status"`
+		Message          string     `json:"message"`
+		TanggalTransaksi time.Time  `gorm:"type:timestamptz" json:"tanggal_transaksi"`
+		CreatedBy        string     `json:"created_by"`
+		ReservedAt       *time.Time `gorm:"type:timestamptz" json:"reserved_at"`
+
+		Timestamp
+	}
 )
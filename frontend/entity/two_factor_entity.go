@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+type (
+	// RecoveryCode is a single-use code that stands in for a TOTP code when
+	// the phone is lost. Only the SHA-256 hash is stored.
+	RecoveryCode struct {
+		ID       int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		UserID   int        `gorm:"not null;index" json:"user_id"`
+		CodeHash string     `gorm:"type:varchar(64);not null;index" json:"-"`
+		UsedAt   *time.Time `gorm:"type:timestamptz" json:"used_at"`
+
+		CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	}
+
+	// LoginChallenge is the second step of a login that passed the password
+	// check and still needs a TOTP code. Setup is set when the user has to
+	// enroll first because 2FA is mandatory for their role.
+	LoginChallenge struct {
+		ID        int        `gorm:"primaryKey;autoIncrement" json:"id"`
+		UserID    int        `gorm:"not null;index" json:"user_id"`
+		TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+		Setup     bool       `json:"setup"`
+		Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+		ExpiresAt time.Time  `gorm:"type:timestamptz" json:"expires_at"`
+		UsedAt    *time.Time `gorm:"type:timestamptz" json:"used_at"`
+
+		CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	}
+
+	// TwoFactorPolicy marks a role whose users must use 2FA to log in.
+	TwoFactorPolicy struct {
+		Role string `gorm:"type:varchar(50);primaryKey" json:"role"`
+
+		CreatedAt time.Time `gorm:"type:timestamptz" json:"created_at"`
+	}
+)
@@ -2,23 +2,44 @@ package entity
 
 import (
 	"bumisubur-be/helpers"
+	"bumisubur-be/utils"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// User keeps NIK, NoHp, TanggalLahir and Alamat encrypted at rest.
+// NIKIndex is the blind index of NIK and has to be set whenever NIK is.
 type User struct {
 	ID           int       `gorm:"primaryKey;autoIncrement" json:"id"`
-	NIK          string    `json:"nik"`
+	NIK          string    `gorm:"type:text;serializer:encrypted" json:"nik"`
+	NIKIndex     string    `gorm:"column:nik_index;type:varchar(64);index" json:"-"`
 	Name         string    `json:"name"`
 	Email        string    `json:"email"`
 	Password     string    `json:"password"`
-	NoHp         string    `json:"no_hp"`
+	NoHp         string    `gorm:"type:text;serializer:encrypted" json:"no_hp"`
 	Role         string    `json:"role"`
 	TanggalMasuk time.Time `gorm:"type:timestamptz" json:"tanggal_masuk"`
-	TanggalLahir time.Time `gorm:"type:timestamptz" json:"tanggal_lahir"`
+	TanggalLahir time.Time `gorm:"type:text;serializer:encrypted" json:"tanggal_lahir"`
 	TempatLahir  string    `json:"tempat_lahir"`
-	Alamat       string    `json:"alamat"`
+	Alamat       string    `gorm:"type:text;serializer:encrypted" json:"alamat"`
+
+	// CanExportPII lets the user download karyawan data unmasked. The owner
+	// grants it, to others or to themselves; no role has it by default.
+	CanExportPII bool `gorm:"not null;default:false" json:"can_export_pii"`
+
+	// MustChangePassword is set for accounts whose password was chosen by
+	// someone else; such users have to pick their own before logging in.
+	MustChangePassword bool       `json:"must_change_password"`
+	PasswordChangedAt  *time.Time `gorm:"type:timestamptz" json:"password_changed_at"`
+
+	// TwoFactorSecret is the AES encrypted TOTP secret. It is set while the
+	// user enrolls and only checked at login once TwoFactorEnabled is on.
+	// TwoFactorLastCounter is the period of the last accepted code, so a
+	// code cannot be used twice.
+	TwoFactorEnabled     bool   `json:"two_factor_enabled"`
+	TwoFactorSecret      string `json:"-"`
+	TwoFactorLastCounter int64  `json:"-"`
 	Timestamp
 
 	LogAkses []LogAkses `json:"LogAkses,omitempty"`
@@ -31,5 +52,6 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if err != nil {
 		return err
 	}
+	u.NIKIndex = utils.BlindIndex(u.NIK)
 	return nil
 }
@@ -0,0 +1,99 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP codes follow RFC 6238 with the defaults every authenticator app
+// supports: HMAC-SHA1, 6 digits and a 30 second period.
+const (
+	TOTP_PERIOD      = 30
+	TOTP_DIGITS      = 6
+	TOTP_SECRET_SIZE = 20
+	// TOTP_SKEW is how many periods before and after now are accepted, to
+	// allow for clock drift on the phone.
+	TOTP_SKEW = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random secret in base32, the form shown
+// to the user and put in the provisioning URI.
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, TOTP_SECRET_SIZE)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI returns the otpauth URI that authenticator apps read
+// from a QR code.
+func TOTPProvisioningURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	query.Set("period", fmt.Sprint(TOTP_PERIOD))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPCode returns the code of secret for the given period counter.
+func TOTPCode(secret string, counter int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTP_DIGITS; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, value%mod), nil
+}
+
+// ValidateTOTP checks code against secret around now and returns the
+// counter of the period it matched, so callers can refuse a code that was
+// already used.
+func ValidateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+
+	current := now.Unix() / TOTP_PERIOD
+	for counter := current - TOTP_SKEW; counter <= current+TOTP_SKEW; counter++ {
+		expected, err := TOTPCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
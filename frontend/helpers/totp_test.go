@@ -0,0 +1,88 @@
+package helpers
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, in base32.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// The RFC lists 8 digit codes; these are their last 6 digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, tt.unix/TOTP_PERIOD)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, code, "T=%d", tt.unix)
+	}
+}
+
+func TestTOTPCodeSecretForm(t *testing.T) {
+	code, err := TOTPCode(" "+strings.ToLower(rfc6238Secret)+" ", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "287082", code)
+
+	_, err = TOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / TOTP_PERIOD
+
+	code := func(counter int64) string {
+		value, err := TOTPCode(rfc6238Secret, counter)
+		assert.NoError(t, err)
+		return value
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		wantCounter int64
+		wantOK      bool
+	}{
+		{"current period", code(current), current, true},
+		{"previous period", code(current - 1), current - 1, true},
+		{"next period", code(current + 1), current + 1, true},
+		{"outside the skew", code(current - 2), 0, false},
+		{"spaces are ignored", " 081 804 ", current, true},
+		{"too short", "08180", 0, false},
+		{"too long", "0818040", 0, false},
+		{"wrong code", "000000", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := ValidateTOTP(rfc6238Secret, tt.code, now)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantCounter, counter)
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Bumi Subur", "kasir@example.com", rfc6238Secret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Bumi%20Subur:kasir@example.com?"))
+	assert.Contains(t, uri, "secret="+rfc6238Secret)
+	assert.Contains(t, uri, "issuer=Bumi+Subur")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"bumisubur-be/config"
 	"bumisubur-be/controller"
@@ -11,6 +18,7 @@ import (
 	"bumisubur-be/repository"
 	"bumisubur-be/routes"
 	"bumisubur-be/service"
+	"bumisubur-be/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -18,19 +26,33 @@ import (
 
 func main() {
 	var (
-		db         *gorm.DB           = config.SetUpDatabaseConnection()
-		jwtService service.JWTService = service.NewJWTService()
+		db                *gorm.DB                     = config.SetUpDatabaseConnection()
+		keyring           *utils.Keyring               = utils.MustLoadKeyring()
+		sessionRepository repository.SessionRepository = repository.NewSessionRepository(db)
+		jwtService        service.JWTService           = service.NewJWTService(sessionRepository, keyring)
+
+		keyRotationRepository repository.KeyRotationRepository = repository.NewKeyRotationRepository(db)
+		keyRotationService    service.KeyRotationService       = service.NewKeyRotationService(keyRotationRepository)
+
+		idempotencyRepository repository.IdempotencyRepository = repository.NewIdempotencyRepository(db)
+		idempotencyService    service.IdempotencyService       = service.NewIdempotencyService(idempotencyRepository)
+
+		notificationRepository repository.NotificationRepository = repository.NewNotificationRepository(db)
+		notificationService    service.NotificationService       = service.NewNotificationService(notificationRepository)
+		notificationController controller.NotificationController = controller.NewNotificationController(notificationService)
 
 		logAksesRepository repository.LogAksesRepository = repository.NewLogAksesRepository(db)
 		logAksesService    service.LogAksesService       = service.NewLogAksesService(logAksesRepository)
 		logAksesController controller.LogAksesController = controller.NewLogAksesController(logAksesService)
 
-		userRepository repository.UserRepository = repository.NewUserRepository(db)
-		userService    service.UserService       = service.NewUserService(userRepository, logAksesRepository, jwtService)
-		userController controller.UserController = controller.NewUserController(userService)
+		userRepository          repository.UserRepository          = repository.NewUserRepository(db)
+		loginThrottleRepository repository.LoginThrottleRepository = repository.NewLoginThrottleRepository(db)
+		twoFactorRepository     repository.TwoFactorRepository     = repository.NewTwoFactorRepository(db)
+		userService             service.UserService                = service.NewUserService(userRepository, logAksesRepository, sessionRepository, loginThrottleRepository, twoFactorRepository, jwtService)
+		userController          controller.UserController          = controller.NewUserController(userService)
 
 		pengeluaranRepository repository.PengeluaranRepository = repository.NewPengeluaranRepository(db)
-		pengeluaranService    service.PengeluaranService       = service.NewPengeluaranService(pengeluaranRepository)
+		pengeluaranService    service.PengeluaranService       = service.NewPengeluaranService(pengeluaranRepository, notificationService)
 		pengeluaranController controller.PengeluaranController = controller.NewPengeluaranController(pengeluaranService)
 
 		cabangRepository repository.CabangRepository = repository.NewCabangRepository(db)
@@ -39,44 +61,102 @@ func main() {
 
 		jenisRepository repository.JenisRepository = repository.NewJenisRepository(db)
 		merkRepository  repository.MerkRepository  = repository.NewMerkRepository(db)
+		merkService     service.MerkService        = service.NewMerkService(merkRepository)
+		merkController  controller.MerkController  = controller.NewMerkController(merkService)
+
+		attributeRepository repository.AttributeRepository = repository.NewAttributeRepository(db)
+		kategoriService     service.KategoriService        = service.NewKategoriService(jenisRepository, attributeRepository)
+		kategoriController  controller.KategoriController  = controller.NewKategoriController(kategoriService)
 
 		supplierRepository repository.SupplierRepository = repository.NewSupplierRepository(db)
 		supplierService    service.SupplierService       = service.NewSupplierService(supplierRepository, jenisRepository, merkRepository, jwtService)
 		supplierController controller.SupplierController = controller.NewSupplierController(supplierService)
 
 		produkRepository repository.ProdukRepository = repository.NewProdukRepository(db)
-		produkService    service.ProdukService       = service.NewProdukService(produkRepository, jenisRepository, merkRepository, supplierRepository)
+		produkService    service.ProdukService       = service.NewProdukService(produkRepository, jenisRepository, merkRepository, supplierRepository, attributeRepository)
 		produkController controller.ProdukController = controller.NewProdukController(produkService)
 
 		transaksiRepository repository.TransaksiRepository = repository.NewTransaksiRepository(db)
-		transaksiService    service.TransaksiService       = service.NewTransaksiService(transaksiRepository, jwtService)
+		transaksiService    service.TransaksiService       = service.NewTransaksiService(transaksiRepository, jwtService, notificationService)
 		transaksiController controller.TransaksiController = controller.NewTransaksiController(transaksiService)
 
 		returnRepository repository.ReturnRepository = repository.NewReturnRepository(db)
-		returnService    service.ReturnService       = service.NewReturnService(returnRepository, jenisRepository, merkRepository, supplierRepository)
+		returnService    service.ReturnService       = service.NewReturnService(returnRepository, jenisRepository, merkRepository, supplierRepository, notificationService)
 		returnController controller.ReturnController = controller.NewReturnController(returnService)
+
+		analyticsRepository repository.AnalyticsRepository = repository.NewAnalyticsRepository(db)
+		analyticsService    service.AnalyticsService       = service.NewAnalyticsService(analyticsRepository)
+		analyticsController controller.AnalyticsController = controller.NewAnalyticsController(analyticsService)
+
+		reorderRepository repository.ReorderRepository = repository.NewReorderRepository(db)
+		reorderService    service.ReorderService       = service.NewReorderService(reorderRepository)
+		reorderController controller.ReorderController = controller.NewReorderController(reorderService)
+
+		reportScheduleRepository repository.ReportScheduleRepository = repository.NewReportScheduleRepository(db)
+		reportScheduleService    service.ReportScheduleService       = service.NewReportScheduleService(reportScheduleRepository, transaksiService, pengeluaranService, produkService, analyticsService)
+		reportScheduleController controller.ReportScheduleController = controller.NewReportScheduleController(reportScheduleService)
+
+		exportRepository repository.ExportRepository = repository.NewExportRepository(db)
+		exportService    service.ExportService       = service.NewExportService(exportRepository)
+		exportController controller.ExportController = controller.NewExportController(exportService)
 	)
 
 	server := gin.Default()
 	server.Use(middleware.CORSMiddleware())
-	server.Use(middleware.LogUserActivityMiddleware(logAksesService, jwtService))
+	server.Use(middleware.LogUserActivityMiddleware(logAksesService))
 
-	routes.Pengeluaran(server, pengeluaranController, jwtService)
+	routes.Pengeluaran(server, pengeluaranController, jwtService, idempotencyService)
 	routes.LogAkses(server, logAksesController, jwtService)
 	routes.User(server, userController, jwtService)
 	routes.Cabang(server, cabangController, jwtService)
 	routes.Supplier(server, supplierController, jwtService)
 	routes.Jenis(server, supplierController, jwtService)
-	routes.Produk(server, produkController, jwtService)
-	routes.Transaksi(server, transaksiController, jwtService)
-	routes.Return(server, returnController, jwtService)
+	routes.Merk(server, merkController, jwtService)
+	routes.Kategori(server, kategoriController, jwtService)
+	routes.Produk(server, produkController, jwtService, idempotencyService)
+	routes.Transaksi(server, transaksiController, jwtService, idempotencyService)
+	routes.Return(server, returnController, jwtService, idempotencyService)
+	routes.Analytics(server, analyticsController, jwtService)
+	routes.Reorder(server, reorderController, jwtService)
+	routes.Notification(server, notificationController, jwtService)
+	routes.ReportSchedule(server, reportScheduleController, jwtService)
+	routes.Export(server, exportController, jwtService)
+
+	if err := migrations.Migrate(db); err != nil {
+		log.Fatalf("error migration: %v", err)
+	}
 
 	if err := migrations.Seeder(db); err != nil {
 		log.Fatalf("error migration seeder: %v", err)
 	}
 
-	if err := migrations.Migrate(db); err != nil {
-		log.Fatalf("error migration: %v", err)
+	go func() {
+		changed, err := keyRotationService.Reencrypt(context.Background())
+		if err != nil {
+			log.Printf("error re-encrypting data: %v", err)
+		}
+		if changed > 0 {
+			log.Printf("re-encrypted %d values with the active key", changed)
+		}
+	}()
+
+	// The workers run until the server has shut down. The access log and
+	// notification workers then drain their queues before they return.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, worker := range []func(ctx context.Context){
+		logAksesService.StartWorker,
+		logAksesService.StartRetentionWorker,
+		notificationService.StartWorker,
+		reportScheduleService.StartWorker,
+		exportService.StartWorker,
+		idempotencyService.StartCleanupWorker,
+	} {
+		workers.Add(1)
+		go func(worker func(ctx context.Context)) {
+			defer workers.Done()
+			worker(workerCtx)
+		}(worker)
 	}
 
 	server.Static("/assets", "./assets")
@@ -86,7 +166,29 @@ func main() {
 		port = "8888"
 	}
 
-	if err := server.Run(":" + port); err != nil {
-		log.Fatalf("error running server: %v", err)
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: server,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("error running server: %v", err)
+		}
+	}()
+
+	quit, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-quit.Done()
+
+	log.Println("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error shutting down server: %v", err)
 	}
+
+	// No request is running anymore, so the worker queues are complete.
+	stopWorkers()
+	workers.Wait()
 }
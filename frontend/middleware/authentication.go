@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"bumisubur-be/dto"
@@ -11,6 +12,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// LOG_AKSES_ID_KEY holds the log akses of the session a request was made
+// in, for the access log.
+const LOG_AKSES_ID_KEY = "log_akses_id"
+
 func Authenticate(jwtService service.JWTService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
@@ -31,7 +36,7 @@ func Authenticate(jwtService service.JWTService) gin.HandlerFunc {
 		tokenString := strings.TrimSpace(strings.Replace(authHeader, "Bearer ", "", 1))
 
 		// Validate the token
-		token, err := jwtService.ValidateToken(tokenString)
+		token, session, err := jwtService.ValidateSession(ctx.Request.Context(), tokenString)
 		if err != nil {
 			response := utils.BuildResponseFailed(dto.MESSAGE_FAILED_PROSES_REQUEST, "Invalid token", nil)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, response)
@@ -45,17 +50,13 @@ func Authenticate(jwtService service.JWTService) gin.HandlerFunc {
 			return
 		}
 
-		// Get user ID from the token
-		userId, err := jwtService.GetUserIDByToken(tokenString)
-		if err != nil {
-			response := utils.BuildResponseFailed(dto.MESSAGE_FAILED_PROSES_REQUEST, "Could not extract user ID from token", nil)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, response)
-			return
-		}
+		// The session the token belongs to gives the user ID
+		userId := strconv.Itoa(session.UserID)
 
-		// Pass token and userId to the context
+		// Pass token, userId and the log akses of the session to the context
 		ctx.Set("token", tokenString)
 		ctx.Set("user_id", userId)
+		ctx.Set(LOG_AKSES_ID_KEY, session.LogAksesID)
 		ctx.Next()
 	}
 }
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"bumisubur-be/dto"
+	"bumisubur-be/service"
+	"bumisubur-be/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const IDEMPOTENCY_HEADER = "Idempotency-Key"
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// outcomeContext is used to store or release a key once the handler is done.
+// The client may have hung up by then, which cancels the request context,
+// while the key still has to be written.
+func outcomeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), service.IDEMPOTENCY_WRITE_TIMEOUT)
+}
+
+// Idempotency replays the stored response when a request is repeated with the
+// same Idempotency-Key header. It must run after Authenticate because keys
+// are scoped per user. Requests without the header are passed through.
+func Idempotency(idempotencyService service.IdempotencyService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IDEMPOTENCY_HEADER)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+
+		userID, err := strconv.Atoi(ctx.MustGet("user_id").(string))
+		if err != nil {
+			res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_IDEMPOTENCY, err.Error(), nil)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, res)
+			return
+		}
+
+		bodyBytes, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_IDEMPOTENCY, "Unable to read request body", nil)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, res)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		record, replay, err := idempotencyService.Begin(ctx.Request.Context(), userID, key, ctx.Request.Method, ctx.Request.URL.Path, bodyBytes)
+		if err != nil {
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, dto.ErrIdempotencyKeyInProgress):
+				status = http.StatusConflict
+			case errors.Is(err, dto.ErrIdempotencyKeyReused), errors.Is(err, dto.ErrIdempotencyKeyTooLong):
+				status = http.StatusUnprocessableEntity
+			}
+
+			res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_IDEMPOTENCY, err.Error(), nil)
+			ctx.AbortWithStatusJSON(status, res)
+			return
+		}
+
+		if replay {
+			ctx.Header("Idempotent-Replayed", "true")
+			ctx.Data(record.StatusCode, record.ContentType, record.Response)
+			ctx.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer, body: &bytes.Buffer{}}
+		ctx.Writer = recorder
+
+		// A handler that panics never completes the key, so it is released
+		// before the panic reaches the recovery middleware. Otherwise the
+		// key would stay in progress until it expires.
+		defer func() {
+			if r := recover(); r != nil {
+				releaseCtx, cancel := outcomeContext()
+				defer cancel()
+				if err := idempotencyService.Release(releaseCtx, record.ID); err != nil {
+					log.Printf("failed to release idempotency key %d: %v", record.ID, err)
+				}
+				panic(r)
+			}
+		}()
+
+		ctx.Next()
+
+		outcomeCtx, cancel := outcomeContext()
+		defer cancel()
+
+		// Server errors are not remembered so the client can retry them.
+		statusCode := ctx.Writer.Status()
+		if statusCode >= http.StatusInternalServerError {
+			if err := idempotencyService.Release(outcomeCtx, record.ID); err != nil {
+				log.Printf("failed to release idempotency key %d: %v", record.ID, err)
+			}
+			return
+		}
+
+		contentType := ctx.Writer.Header().Get("Content-Type")
+		if err := idempotencyService.Complete(outcomeCtx, record.ID, statusCode, contentType, recorder.body.Bytes()); err != nil {
+			log.Printf("failed to store idempotent response %d: %v", record.ID, err)
+		}
+	}
+}
@@ -14,10 +14,39 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func LogUserActivityMiddleware(logAksesService service.LogAksesService, jwtService service.JWTService) gin.HandlerFunc {
+// publicPaths are the routes used without an access token.
+var publicPaths = map[string]bool{
+	"/api/user/login":           true,
+	"/api/user/login/2fa":       true,
+	"/api/user/login/2fa/setup": true,
+	"/api/user/refresh":         true,
+	"/api/user/first-login":     true,
+	"/api/user/forgot-password": true,
+	"/api/user/reset-password":  true,
+}
+
+// LOG_SKIP_PAYLOAD_KEY is set on the context of routes whose payload must
+// never reach the access log.
+const LOG_SKIP_PAYLOAD_KEY = "log_skip_payload"
+
+// SkipPayloadLog keeps the payload of a route out of the access log, for
+// endpoints whose whole body is sensitive. The request itself is still
+// logged.
+func SkipPayloadLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(LOG_SKIP_PAYLOAD_KEY, true)
+		ctx.Next()
+	}
+}
+
+// LogUserActivityMiddleware queues every authenticated request for the
+// access log. Only a fingerprint of the token is kept, and the payload is
+// redacted and cut to size before it is stored. The user and log akses come
+// from Authenticate, so a request it turned away is not logged.
+func LogUserActivityMiddleware(logAksesService service.LogAksesService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		if ctx.Request.URL.Path == "/api/user/login" {
+		if publicPaths[ctx.Request.URL.Path] {
 			ctx.Next()
 			return
 		}
@@ -40,35 +69,34 @@ func LogUserActivityMiddleware(logAksesService service.LogAksesService, jwtServi
 		// Restore the request body so it can be read again by subsequent handlers
 		ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
-		userID, err := jwtService.GetUserIDByToken(tokenString)
-		if err != nil {
-			res := utils.BuildResponseFailed(dto.MESSAGE_FAILED_DENIED_ACCESS, err.Error(), nil)
-			ctx.JSON(http.StatusUnauthorized, res)
-			ctx.Abort()
+		ctx.Next()
+
+		userID := ctx.GetString("user_id")
+		if userID == "" {
 			return
 		}
 
-		ctx.Next()
-
 		statusCode := ctx.Writer.Status()
 		id, _ := strconv.Atoi(userID)
-		logAksesID := logAksesService.GetActiveLogAksesForUser(ctx, id)
 		var payload string
-		if ctx.Request.URL.RawQuery != "" {
-			payload = ctx.Request.URL.RawQuery
-		} else {
-			payload = string(bodyBytes)
+		switch {
+		case ctx.GetBool(LOG_SKIP_PAYLOAD_KEY):
+			payload = utils.PAYLOAD_NOT_LOGGED
+		default:
+			payload = utils.DefaultRedactor().RedactRequest(ctx.Request.URL.RawQuery, bodyBytes, ctx.ContentType())
 		}
 
 		detailAkses := entity.DetailAkses{
-			Activity:   ctx.Request.URL.Path,
-			LogAksesID: logAksesID,
-			Token:      tokenString,
-			IP:         ctx.ClientIP(),
-			Payload:    payload,
-			Status:     strconv.Itoa(statusCode),
+			Activity: ctx.Request.URL.Path,
+			Token:    utils.TokenFingerprint(tokenString),
+			IP:       ctx.ClientIP(),
+			Payload:  payload,
+			Status:   strconv.Itoa(statusCode),
+		}
+		if logAksesID := ctx.GetInt(LOG_AKSES_ID_KEY); logAksesID != 0 {
+			detailAkses.LogAksesID = &logAksesID
 		}
 
-		logAksesService.LogDetailAkses(ctx, detailAkses)
+		logAksesService.RecordAccess(id, detailAkses)
 	}
 }
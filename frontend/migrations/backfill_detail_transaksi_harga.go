@@ -0,0 +1,18 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// BackfillDetailTransaksiHarga fills in the prices of lines sold before
+// detail_transaksis stored them. The price at the time of those sales is
+// not known anymore, so the current price of the produk is the best there
+// is. Lines that already have a price are left alone.
+func BackfillDetailTransaksiHarga(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE detail_transaksis dt
+		SET harga_jual = p.harga_jual, harga_beli = dp.harga_beli
+		FROM detail_produks dp
+		JOIN produks p ON dp.produk_id = p.id
+		WHERE dt.detail_produk_id = dp.id
+		AND dt.harga_jual IS NULL
+	`).Error
+}
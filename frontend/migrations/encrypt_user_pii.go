@@ -0,0 +1,92 @@
+package migrations
+
+import (
+	"bumisubur-be/entity"
+	"bumisubur-be/utils"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+const userPIIBatchSize = 200
+
+// userPIIColumns are the columns of entity.User written by
+// EncryptUserPII.
+var userPIIColumns = []string{"nik", "nik_index", "no_hp", "tanggal_lahir", "alamat"}
+
+type userPIIRow struct {
+	ID           int
+	NIK          sql.NullString `gorm:"column:nik"`
+	NoHp         sql.NullString `gorm:"column:no_hp"`
+	TanggalLahir sql.NullString `gorm:"column:tanggal_lahir"`
+	Alamat       sql.NullString `gorm:"column:alamat"`
+}
+
+// EncryptUserPII encrypts the user fields that were stored in plaintext
+// before they were encrypted, and fills in missing or outdated NIK blind
+// indexes. Rows that are already up to date are left alone, so it is cheap
+// to run at every startup.
+func EncryptUserPII(db *gorm.DB) error {
+	afterID := 0
+	for {
+		var users []entity.User
+		if err := db.Unscoped().
+			Where("id > ?", afterID).
+			Order("id ASC").
+			Limit(userPIIBatchSize).
+			Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		ids := make([]int, 0, len(users))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+
+		var rows []userPIIRow
+		if err := db.Table("users").
+			Select("id, nik, no_hp, tanggal_lahir, alamat").
+			Where("id IN ?", ids).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		plaintext := make(map[int]bool, len(rows))
+		for _, row := range rows {
+			plaintext[row.ID] = row.hasPlaintext()
+		}
+
+		for _, user := range users {
+			afterID = user.ID
+
+			index := utils.BlindIndex(user.NIK)
+			if !plaintext[user.ID] && user.NIKIndex == index {
+				continue
+			}
+
+			user.NIKIndex = index
+			if err := db.Unscoped().Model(&user).Select(userPIIColumns).Updates(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(users) < userPIIBatchSize {
+			return nil
+		}
+	}
+}
+
+// hasPlaintext reports whether any field of the row is still stored in
+// plaintext. A tanggal_lahir column that AutoMigrate turned from timestamptz
+// into text holds values like "1990-05-17 00:00:00+07", which count too.
+func (row userPIIRow) hasPlaintext() bool {
+	for _, value := range []sql.NullString{row.NIK, row.NoHp, row.TanggalLahir, row.Alamat} {
+		if value.String != "" && !utils.AESIsCiphertext(value.String) {
+			return true
+		}
+	}
+	return false
+}
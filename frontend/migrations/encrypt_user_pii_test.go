@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"bumisubur-be/entity"
+	"bumisubur-be/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+func userField(t *testing.T, name string) *schema.Field {
+	t.Helper()
+
+	s, err := schema.Parse(&entity.User{}, &sync.Map{}, schema.NamingStrategy{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return s.LookUpField(name)
+}
+
+// scanUserField reads dbValue into the field of user the way gorm does when
+// it loads a row.
+func scanUserField(t *testing.T, user *entity.User, name string, dbValue any) error {
+	t.Helper()
+
+	field := userField(t, name)
+	value := field.NewValuePool.Get()
+	if err := value.(sql.Scanner).Scan(dbValue); err != nil {
+		return err
+	}
+	return field.Set(context.Background(), reflect.ValueOf(user).Elem(), value)
+}
+
+func encryptedString(t *testing.T, value string) sql.NullString {
+	t.Helper()
+
+	encrypted, err := utils.AESEncrypt(value)
+	assert.NoError(t, err)
+	return sql.NullString{String: encrypted, Valid: true}
+}
+
+func TestUserPIIRowHasPlaintext(t *testing.T) {
+	plain := func(value string) sql.NullString {
+		return sql.NullString{String: value, Valid: true}
+	}
+	encrypted := userPIIRow{
+		NIK:          encryptedString(t, "3201010101010001"),
+		NoHp:         encryptedString(t, "081234567890"),
+		TanggalLahir: encryptedString(t, "1990-05-17T00:00:00+07:00"),
+		Alamat:       encryptedString(t, "Jl. Merdeka No. 5"),
+	}
+
+	tests := []struct {
+		name string
+		row  func() userPIIRow
+		want bool
+	}{
+		{"all encrypted", func() userPIIRow { return encrypted }, false},
+		{"all empty", func() userPIIRow { return userPIIRow{} }, false},
+		{"empty strings", func() userPIIRow {
+			return userPIIRow{NIK: plain(""), NoHp: plain(""), TanggalLahir: plain(""), Alamat: plain("")}
+		}, false},
+		{"plaintext nik", func() userPIIRow { row := encrypted; row.NIK = plain("3201010101010001"); return row }, true},
+		{"plaintext no hp", func() userPIIRow { row := encrypted; row.NoHp = plain("081234567890"); return row }, true},
+		{"plaintext alamat", func() userPIIRow { row := encrypted; row.Alamat = plain("Jl. Merdeka: No. 5"); return row }, true},
+		{"timestamptz cast to text", func() userPIIRow {
+			row := encrypted
+			row.TanggalLahir = plain("1990-05-17 00:00:00+07")
+			return row
+		}, true},
+		{"timestamptz with fraction cast to text", func() userPIIRow {
+			row := encrypted
+			row.TanggalLahir = plain("1990-05-17 00:00:00.123456+07")
+			return row
+		}, true},
+		{"date cast to text", func() userPIIRow { row := encrypted; row.TanggalLahir = plain("1990-05-17"); return row }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.row().hasPlaintext())
+		})
+	}
+}
+
+// A tanggal_lahir that AutoMigrate turned from timestamptz into text is read
+// as the same date, written back encrypted and read again unchanged.
+func TestUserTanggalLahirMigratedFromTimestamptz(t *testing.T) {
+	want := time.Date(1990, 5, 17, 0, 0, 0, 0, time.FixedZone("", 7*60*60))
+
+	for _, legacy := range []any{
+		"1990-05-17 00:00:00+07",
+		[]byte("1990-05-17 00:00:00+07"),
+		"1990-05-16 17:00:00+00",
+		"1990-05-17T00:00:00+07:00",
+		// Before AutoMigrate the driver still hands over a time.
+		want,
+	} {
+		var user entity.User
+		if !assert.NoError(t, scanUserField(t, &user, "TanggalLahir", legacy), "%v", legacy) {
+			continue
+		}
+		assert.True(t, want.Equal(user.TanggalLahir), "%v: %v", legacy, user.TanggalLahir)
+
+		field := userField(t, "TanggalLahir")
+		stored, err := utils.EncryptedSerializer{}.Value(context.Background(), field, reflect.ValueOf(&user).Elem(), user.TanggalLahir)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.False(t, userPIIRow{TanggalLahir: sql.NullString{String: stored.(string), Valid: true}}.hasPlaintext())
+
+		var reread entity.User
+		assert.NoError(t, scanUserField(t, &reread, "TanggalLahir", stored))
+		assert.True(t, want.Equal(reread.TanggalLahir), "%v: %v", legacy, reread.TanggalLahir)
+	}
+}
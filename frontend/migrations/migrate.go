@@ -17,19 +17,48 @@ func Migrate(db *gorm.DB) error {
 		&entity.DetailProduk{},
 		&entity.Jenis{},
 		&entity.Merk{},
+		&entity.AttributeSet{},
+		&entity.AttributeValue{},
 		&entity.Produk{},
 		&entity.Pengeluaran{},
 		&entity.LogAkses{},
 		&entity.DetailAkses{},
+		&entity.Session{},
+		&entity.PasswordReset{},
+		&entity.LoginThrottle{},
+		&entity.RecoveryCode{},
+		&entity.LoginChallenge{},
+		&entity.TwoFactorPolicy{},
 		&entity.DetailMerkSupplier{},
+		&entity.SupplyDiscount{},
 		&entity.DetailRestok{},
 		&entity.ReturnUser{},
 		&entity.ReturnSupplier{},
 		&entity.DetailReturnSupplier{},
 		&entity.DetailReturnUser{},
+		&entity.TransaksiSync{},
+		&entity.IdempotencyKey{},
+		&entity.ReorderRule{},
+		&entity.NotificationRule{},
+		&entity.NotificationOutbox{},
+		&entity.ReportSchedule{},
+		&entity.ReportRun{},
+		&entity.ExportJob{},
 	); err != nil {
 		return err
 	}
 
+	if err := EncryptUserPII(db); err != nil {
+		return err
+	}
+
+	if err := RedactDetailAkses(db); err != nil {
+		return err
+	}
+
+	if err := BackfillDetailTransaksiHarga(db); err != nil {
+		return err
+	}
+
 	return nil
 }
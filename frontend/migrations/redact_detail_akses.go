@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"bumisubur-be/entity"
+	"bumisubur-be/utils"
+
+	"gorm.io/gorm"
+)
+
+const detailAksesBatchSize = 500
+
+// RedactDetailAkses fingerprints the tokens and redacts the payloads of
+// access log entries written before the log was redacted. Those are the
+// entries whose token is not a fingerprint yet; failed logins have no token
+// and keep the email they logged.
+func RedactDetailAkses(db *gorm.DB) error {
+	redactor := utils.DefaultRedactor()
+
+	afterID := 0
+	for {
+		var details []entity.DetailAkses
+		if err := db.Unscoped().
+			Select("id", "token", "payload").
+			Where("id > ? AND token <> '' AND token NOT LIKE ?", afterID, utils.TOKEN_FINGERPRINT_PREFIX+"%").
+			Order("id ASC").
+			Limit(detailAksesBatchSize).
+			Find(&details).Error; err != nil {
+			return err
+		}
+
+		for _, detail := range details {
+			afterID = detail.ID
+
+			if err := db.Unscoped().Model(&entity.DetailAkses{}).
+				Where("id = ?", detail.ID).
+				Updates(map[string]any{
+					"token":   utils.TokenFingerprint(detail.Token),
+					"payload": redactor.Redact([]byte(detail.Payload), ""),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(details) < detailAksesBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package repository
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// analyticsLines joins every sold line with its produk so the analytics
+// queries can filter on period and cabang. Lines are valued at the prices
+// stored on detail_transaksis when they were sold. Discount is stored on the
+// transaksi as a percentage and applied per line.
+const analyticsLines = `
+	FROM transaksis t
+	JOIN detail_transaksis dt ON dt.transaksi_id = t.id
+	JOIN detail_produks dp ON dt.detail_produk_id = dp.id
+	JOIN produks p ON dp.produk_id = p.id
+	WHERE t.created_at BETWEEN @start AND @end
+	AND (@cabang = 0 OR p.cabang_id = @cabang)
+`
+
+const analyticsTotals = `
+	COALESCE(SUM(dt.harga_jual * dt.jumlah_produk), 0) AS gross_sales,
+	COALESCE(SUM(dt.harga_jual * dt.jumlah_produk * CAST(t.diskon AS DECIMAL(5, 2)) / 100), 0) AS discount,
+	COALESCE(SUM(dt.harga_beli * dt.jumlah_produk), 0) AS cogs,
+	COUNT(DISTINCT t.id) AS transaction_count,
+	COALESCE(SUM(dt.jumlah_produk), 0) AS items_sold
+`
+
+var analyticsBucketStep = map[string]string{
+	constants.ENUM_BUCKET_HOUR:  "1 hour",
+	constants.ENUM_BUCKET_DAY:   "1 day",
+	constants.ENUM_BUCKET_WEEK:  "1 week",
+	constants.ENUM_BUCKET_MONTH: "1 month",
+}
+
+var analyticsGroupBy = map[string]struct {
+	key   string
+	label string
+}{
+	constants.ENUM_GROUP_BY_PRODUK: {"CAST(p.id AS TEXT)", "p.nama_produk"},
+	constants.ENUM_GROUP_BY_MERK:   {"CAST(m.id AS TEXT)", "m.nama"},
+	constants.ENUM_GROUP_BY_JENIS:  {"CAST(j.id AS TEXT)", "j.nama_jenis"},
+	constants.ENUM_GROUP_BY_UKURAN: {"dp.ukuran", "dp.ukuran"},
+	constants.ENUM_GROUP_BY_WARNA:  {"dp.warna", "dp.warna"},
+}
+
+type (
+	AnalyticsRepository interface {
+		GetSalesSummary(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) (dto.SalesSummary, error)
+		GetSalesSeries(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter, bucket string) ([]dto.SalesBucket, error)
+		GetProductRanking(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter, previous dto.AnalyticsFilter, groupBy string, ascending bool, limit int) ([]dto.ProductRanking, error)
+
+		GetMonthlySales(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossAmountRow, error)
+		GetMonthlyRefunds(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossAmountRow, error)
+		GetMonthlyExpenses(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossExpenseRow, error)
+
+		GetStockAgingVariants(ctx context.Context, tx *gorm.DB, req dto.StockAgingRequest, since time.Time) ([]dto.StockAgingVariantRow, error)
+		GetStockReceipts(ctx context.Context, tx *gorm.DB, detailProdukIDs []int) ([]dto.StockReceiptRow, error)
+	}
+
+	analyticsRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{
+		db: db,
+	}
+}
+
+func analyticsArgs(filter dto.AnalyticsFilter) map[string]any {
+	return map[string]any{
+		"start":  filter.Start,
+		"end":    filter.End,
+		"cabang": filter.Cabang,
+	}
+}
+
+func (r *analyticsRepository) GetSalesSummary(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) (dto.SalesSummary, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result dto.SalesSummary
+	err := tx.WithContext(ctx).Raw(`SELECT `+analyticsTotals+analyticsLines, analyticsArgs(filter)).Scan(&result).Error
+	if err != nil {
+		return dto.SalesSummary{}, err
+	}
+
+	return result, nil
+}
+
+// GetSalesSeries returns one row per bucket between the filter start and end,
+// including buckets without any sales so charts do not skip gaps.
+func (r *analyticsRepository) GetSalesSeries(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter, bucket string) ([]dto.SalesBucket, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	step, ok := analyticsBucketStep[bucket]
+	if !ok {
+		return nil, dto.ErrAnalyticsInvalidBucket
+	}
+
+	args := analyticsArgs(filter)
+	args["bucket"] = bucket
+	args["step"] = step
+
+	var result []dto.SalesBucket
+	err := tx.WithContext(ctx).Raw(`
+		SELECT
+			b.bucket,
+			COALESCE(s.gross_sales, 0) AS gross_sales,
+			COALESCE(s.discount, 0) AS discount,
+			COALESCE(s.cogs, 0) AS cogs,
+			COALESCE(s.transaction_count, 0) AS transaction_count,
+			COALESCE(s.items_sold, 0) AS items_sold
+		FROM generate_series(
+			date_trunc(@bucket, CAST(@start AS timestamptz)),
+			CAST(@end AS timestamptz),
+			CAST(@step AS interval)
+		) AS b(bucket)
+		LEFT JOIN (
+			SELECT date_trunc(@bucket, t.created_at) AS bucket, `+analyticsTotals+analyticsLines+`
+			GROUP BY 1
+		) s ON s.bucket = b.bucket
+		ORDER BY b.bucket
+	`, args).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetProductRanking ranks the sold lines by net sales in filter, grouped by
+// groupBy. Net sales of the same key in previous are returned alongside so
+// the caller can show movement without a second round trip.
+func (r *analyticsRepository) GetProductRanking(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter, previous dto.AnalyticsFilter, groupBy string, ascending bool, limit int) ([]dto.ProductRanking, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	group, ok := analyticsGroupBy[groupBy]
+	if !ok {
+		return nil, dto.ErrAnalyticsInvalidGroupBy
+	}
+
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+
+	args := map[string]any{
+		"start":      filter.Start,
+		"end":        filter.End,
+		"prev_start": previous.Start,
+		"prev_end":   previous.End,
+		"cabang":     filter.Cabang,
+		"limit":      limit,
+	}
+
+	current := "t.created_at BETWEEN @start AND @end"
+	net := "dt.harga_jual * dt.jumlah_produk * (1 - CAST(t.diskon AS DECIMAL(5, 2)) / 100)"
+	cogs := "dt.harga_beli * dt.jumlah_produk"
+
+	query := fmt.Sprintf(`
+		SELECT
+			%[1]s AS key,
+			MAX(%[2]s) AS label,
+			COALESCE(SUM(CASE WHEN %[3]s THEN dt.jumlah_produk END), 0) AS items_sold,
+			COALESCE(SUM(CASE WHEN %[3]s THEN %[4]s END), 0) AS net_sales,
+			COALESCE(SUM(CASE WHEN %[3]s THEN %[5]s END), 0) AS cogs,
+			COALESCE(SUM(CASE WHEN %[3]s THEN %[4]s - %[5]s END), 0) AS gross_margin,
+			COALESCE(SUM(CASE WHEN NOT (%[3]s) THEN %[4]s END), 0) AS previous_net_sales
+		FROM transaksis t
+		JOIN detail_transaksis dt ON dt.transaksi_id = t.id
+		JOIN detail_produks dp ON dt.detail_produk_id = dp.id
+		JOIN produks p ON dp.produk_id = p.id
+		JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id
+		JOIN merks m ON dms.merk_id = m.id
+		JOIN jenis j ON dms.jenis_id = j.id
+		WHERE (t.created_at BETWEEN @start AND @end OR t.created_at BETWEEN @prev_start AND @prev_end)
+		AND (@cabang = 0 OR p.cabang_id = @cabang)
+		GROUP BY 1
+		HAVING SUM(CASE WHEN %[3]s THEN dt.jumlah_produk ELSE 0 END) > 0
+		ORDER BY net_sales %[6]s, items_sold %[6]s, key
+		LIMIT @limit
+	`, group.key, group.label, current, net, cogs, order)
+
+	var result []dto.ProductRanking
+	if err := tx.WithContext(ctx).Raw(query, args).Scan(&result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetMonthlySales sums sales per month as they were rung up. A user return
+// lowers detail_transaksis.jumlah_produk in place, so the returned quantity
+// is added back here and reported as a refund in the month of the return.
+func (r *analyticsRepository) GetMonthlySales(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossAmountRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.ProfitLossAmountRow
+	err := tx.WithContext(ctx).Raw(`
+		SELECT
+			to_char(date_trunc('month', t.created_at), 'YYYY-MM') AS month,
+			COALESCE(SUM(dt.harga_jual * (dt.jumlah_produk + COALESCE(ret.jumlah, 0)) * (1 - CAST(t.diskon AS DECIMAL(5, 2)) / 100)), 0) AS amount,
+			COALESCE(SUM(dt.harga_beli * (dt.jumlah_produk + COALESCE(ret.jumlah, 0))), 0) AS cogs
+		FROM transaksis t
+		JOIN detail_transaksis dt ON dt.transaksi_id = t.id
+		JOIN detail_produks dp ON dt.detail_produk_id = dp.id
+		JOIN produks p ON dp.produk_id = p.id
+		LEFT JOIN (
+			SELECT detail_transaksi_id, SUM(jumlah_produk) AS jumlah
+			FROM detail_return_users
+			WHERE deleted_at IS NULL
+			GROUP BY detail_transaksi_id
+		) ret ON ret.detail_transaksi_id = dt.id
+		WHERE t.created_at BETWEEN @start AND @end
+		AND (@cabang = 0 OR p.cabang_id = @cabang)
+		GROUP BY 1
+	`, analyticsArgs(filter)).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetMonthlyRefunds sums returned lines per month of the return, valued with
+// the prices and discount of the original transaksi. Returned items go back
+// into stock, so their harga beli is taken off COGS.
+func (r *analyticsRepository) GetMonthlyRefunds(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossAmountRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.ProfitLossAmountRow
+	err := tx.WithContext(ctx).Raw(`
+		SELECT
+			to_char(date_trunc('month', ru.created_at), 'YYYY-MM') AS month,
+			COALESCE(SUM(dt.harga_jual * dru.jumlah_produk * (1 - CAST(t.diskon AS DECIMAL(5, 2)) / 100)), 0) AS amount,
+			COALESCE(SUM(dt.harga_beli * dru.jumlah_produk), 0) AS cogs
+		FROM return_users ru
+		JOIN detail_return_users dru ON dru.return_user_id = ru.id
+		JOIN detail_transaksis dt ON dru.detail_transaksi_id = dt.id
+		JOIN transaksis t ON ru.transaksi_id = t.id
+		JOIN detail_produks dp ON dru.detail_produk_id = dp.id
+		JOIN produks p ON dp.produk_id = p.id
+		WHERE ru.deleted_at IS NULL AND dru.deleted_at IS NULL
+		AND ru.created_at BETWEEN @start AND @end
+		AND (@cabang = 0 OR p.cabang_id = @cabang)
+		GROUP BY 1
+	`, analyticsArgs(filter)).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetMonthlyExpenses sums pengeluaran per month and kategori. When a cabang
+// is given only pengeluaran booked on that cabang are counted; shared
+// pengeluaran without a cabang only show up in the all-cabang report.
+func (r *analyticsRepository) GetMonthlyExpenses(ctx context.Context, tx *gorm.DB, filter dto.AnalyticsFilter) ([]dto.ProfitLossExpenseRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.ProfitLossExpenseRow
+	err := tx.WithContext(ctx).Raw(`
+		SELECT
+			to_char(date_trunc('month', tanggal_pengeluaran), 'YYYY-MM') AS month,
+			COALESCE(NULLIF(TRIM(kategori_pengeluaran), ''), 'Lainnya') AS kategori,
+			COALESCE(SUM(jumlah), 0) AS jumlah
+		FROM pengeluarans
+		WHERE deleted_at IS NULL
+		AND tanggal_pengeluaran BETWEEN @start AND @end
+		AND (@cabang = 0 OR cabang_id = @cabang)
+		GROUP BY 1, 2
+	`, analyticsArgs(filter)).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetStockAgingVariants returns every active variant that still has stock,
+// with its last sale and the quantity sold since since.
+func (r *analyticsRepository) GetStockAgingVariants(ctx context.Context, tx *gorm.DB, req dto.StockAgingRequest, since time.Time) ([]dto.StockAgingVariantRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Table("detail_produks dp").
+		Select(`
+			dp.id AS detail_produk_id,
+			p.id AS produk_id,
+			p.nama_produk,
+			p.barcode_id,
+			m.nama AS merk,
+			j.nama_jenis AS jenis,
+			p.cabang_id,
+			dp.ukuran,
+			dp.warna,
+			dp.stok,
+			dp.harga_beli,
+			dp.created_at,
+			sales.last_sold_at,
+			COALESCE(sales.sold_in_window, 0) AS sold_in_window
+		`).
+		Joins("JOIN produks p ON dp.produk_id = p.id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Joins("JOIN jenis j ON dms.jenis_id = j.id").
+		Joins(`LEFT JOIN (
+			SELECT
+				dt.detail_produk_id,
+				MAX(t.created_at) AS last_sold_at,
+				SUM(CASE WHEN t.created_at >= ? THEN dt.jumlah_produk ELSE 0 END) AS sold_in_window
+			FROM detail_transaksis dt
+			JOIN transaksis t ON dt.transaksi_id = t.id
+			WHERE dt.jumlah_produk > 0
+			GROUP BY dt.detail_produk_id
+		) sales ON sales.detail_produk_id = dp.id`, since).
+		Where("dp.status = 1 AND dp.stok > 0").
+		Where("dp.deleted_at IS NULL AND p.deleted_at IS NULL").
+		Order("m.nama, p.nama_produk, dp.ukuran, dp.warna")
+
+	if req.Merk != "" {
+		query = query.Where("m.nama = ?", req.Merk)
+	}
+
+	query = jenisFilter(query, "j.id", req.JenisID, req.Jenis)
+
+	if req.Ukuran != "" {
+		query = query.Where("LOWER(dp.ukuran) = LOWER(?)", req.Ukuran)
+	}
+
+	if req.Warna != "" {
+		query = query.Where("LOWER(dp.warna) = LOWER(?)", req.Warna)
+	}
+
+	if req.Cabang != 0 {
+		query = query.Where("p.cabang_id = ?", req.Cabang)
+	}
+
+	var result []dto.StockAgingVariantRow
+	if err := query.Scan(&result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetStockReceipts returns the restok lines of the given variants, newest
+// first per variant.
+func (r *analyticsRepository) GetStockReceipts(ctx context.Context, tx *gorm.DB, detailProdukIDs []int) ([]dto.StockReceiptRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.StockReceiptRow
+	for start := 0; start < len(detailProdukIDs); start += 1000 {
+		end := start + 1000
+		if end > len(detailProdukIDs) {
+			end = len(detailProdukIDs)
+		}
+
+		var rows []dto.StockReceiptRow
+		err := tx.WithContext(ctx).Table("detail_restoks dr").
+			Select("dr.detail_produk_id, r.tanggal_restok, dr.jumlah").
+			Joins("JOIN restoks r ON dr.restok_id = r.id").
+			Where("dr.detail_produk_id IN ?", detailProdukIDs[start:end]).
+			Where("dr.deleted_at IS NULL AND r.deleted_at IS NULL").
+			Order("dr.detail_produk_id, r.tanggal_restok DESC, dr.id DESC").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, rows...)
+	}
+
+	return result, nil
+}
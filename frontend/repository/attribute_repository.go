@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"bumisubur-be/entity"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type (
+	AttributeRepository interface {
+		GetAllAttributeSets(ctx context.Context, tx *gorm.DB) ([]entity.AttributeSet, error)
+		GetAttributeSetByID(ctx context.Context, tx *gorm.DB, setID int) (entity.AttributeSet, error)
+		CreateAttributeSet(ctx context.Context, tx *gorm.DB, set entity.AttributeSet) (entity.AttributeSet, error)
+		UpdateAttributeSet(ctx context.Context, tx *gorm.DB, set entity.AttributeSet) (entity.AttributeSet, error)
+		DeleteAttributeSet(ctx context.Context, tx *gorm.DB, setID int) error
+		CountAttributeSetUsage(ctx context.Context, tx *gorm.DB, setID int) (int64, error)
+	}
+
+	attributeRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewAttributeRepository(db *gorm.DB) AttributeRepository {
+	return &attributeRepository{
+		db: db,
+	}
+}
+
+func orderedValues(db *gorm.DB) *gorm.DB {
+	return db.Order("urutan, id")
+}
+
+func (r *attributeRepository) GetAllAttributeSets(ctx context.Context, tx *gorm.DB) ([]entity.AttributeSet, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var sets []entity.AttributeSet
+	if err := tx.WithContext(ctx).Preload("Values", orderedValues).Order("attribute, name").Find(&sets).Error; err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+func (r *attributeRepository) GetAttributeSetByID(ctx context.Context, tx *gorm.DB, setID int) (entity.AttributeSet, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var set entity.AttributeSet
+	if err := tx.WithContext(ctx).Preload("Values", orderedValues).Where("id = ?", setID).Take(&set).Error; err != nil {
+		return entity.AttributeSet{}, err
+	}
+
+	return set, nil
+}
+
+func (r *attributeRepository) CreateAttributeSet(ctx context.Context, tx *gorm.DB, set entity.AttributeSet) (entity.AttributeSet, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Create(&set).Error; err != nil {
+		return entity.AttributeSet{}, err
+	}
+
+	return set, nil
+}
+
+// UpdateAttributeSet saves the name and attribute of a set and replaces its
+// values with set.Values.
+func (r *attributeRepository) UpdateAttributeSet(ctx context.Context, tx *gorm.DB, set entity.AttributeSet) (entity.AttributeSet, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	err := tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.AttributeSet{}).Where("id = ?", set.ID).Updates(map[string]any{
+			"name":      set.Name,
+			"attribute": set.Attribute,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&entity.AttributeValue{}, "attribute_set_id = ?", set.ID).Error; err != nil {
+			return err
+		}
+
+		for i := range set.Values {
+			set.Values[i].AttributeSetID = set.ID
+		}
+		if len(set.Values) > 0 {
+			return tx.Create(&set.Values).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.AttributeSet{}, err
+	}
+
+	return set, nil
+}
+
+func (r *attributeRepository) DeleteAttributeSet(ctx context.Context, tx *gorm.DB, setID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Delete(&entity.AttributeSet{}, "id = ?", setID).Error
+}
+
+// CountAttributeSetUsage counts the jenis that have the set assigned.
+func (r *attributeRepository) CountAttributeSetUsage(ctx context.Context, tx *gorm.DB, setID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var count int64
+	if err := tx.WithContext(ctx).Model(&entity.Jenis{}).
+		Where("ukuran_set_id = ? OR warna_set_id = ?", setID, setID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
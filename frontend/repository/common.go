@@ -1,6 +1,38 @@
 package repository
 
-import "gorm.io/gorm"
+import (
+	"bumisubur-be/entity"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// jenisSubtree is the condition for a jenis ID column to match the jenis
+// with the given ID or any jenis below it in the category tree, so a filter
+// on "Pakaian" also finds the products under "Pakaian > Kaos".
+const jenisSubtree = ` IN (
+	WITH RECURSIVE kategori AS (
+		SELECT id FROM jenis WHERE id = ? AND deleted_at IS NULL
+		UNION
+		SELECT c.id FROM jenis c JOIN kategori k ON c.parent_id = k.id WHERE c.deleted_at IS NULL
+	)
+	SELECT id FROM kategori
+)`
+
+// jenisFilter narrows query on a jenis ID column. A jenis_id filter takes
+// in the subtree of that jenis. The older filter on the jenis name only
+// matches jenis with exactly that name: names repeat under different
+// parents, so a name does not identify a subtree.
+func jenisFilter(query *gorm.DB, column string, jenisID int, jenis string) *gorm.DB {
+	if jenisID != 0 {
+		return query.Where(column+jenisSubtree, jenisID)
+	}
+	if jenis != "" {
+		return query.Where(column+" IN (SELECT id FROM jenis WHERE nama_jenis = ? AND deleted_at IS NULL)", jenis)
+	}
+
+	return query
+}
 
 func Paginate(page, perPage int) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
@@ -8,3 +40,106 @@ func Paginate(page, perPage int) func(db *gorm.DB) *gorm.DB {
 		return db.Offset(offset).Limit(perPage)
 	}
 }
+
+// mergeSupplies moves every supply and reorder rule of one merk or jenis to
+// another; column is "merk_id" or "jenis_id". A supply the target already
+// has for the same supplier is folded into that one: its variants and
+// discount history move over and the duplicate row is removed. A reorder
+// rule the target already has for the same scope wins over the moved one.
+func mergeSupplies(tx *gorm.DB, column string, sourceID int, targetID int) error {
+	other := "jenis_id"
+	if column == "jenis_id" {
+		other = "merk_id"
+	}
+
+	var supplies []entity.DetailMerkSupplier
+	if err := tx.Where(column+" = ?", sourceID).Find(&supplies).Error; err != nil {
+		return err
+	}
+
+	for _, supply := range supplies {
+		otherID := supply.JenisID
+		if column == "jenis_id" {
+			otherID = supply.MerkID
+		}
+
+		var existing entity.DetailMerkSupplier
+		err := tx.Where(column+" = ? AND "+other+" = ? AND supplier_id = ?", targetID, otherID, supply.SupplierID).
+			Take(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Model(&entity.DetailMerkSupplier{}).
+				Where("detail_merk_supplier_id = ?", supply.DetailMerkSupplierID).
+				Update(column, targetID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&entity.DetailProduk{}).
+			Where("detail_merk_supplier_id = ?", supply.DetailMerkSupplierID).
+			Update("detail_merk_supplier_id", existing.DetailMerkSupplierID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.SupplyDiscount{}).
+			Where("detail_merk_supplier_id = ?", supply.DetailMerkSupplierID).
+			Update("detail_merk_supplier_id", existing.DetailMerkSupplierID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entity.DetailMerkSupplier{}, "detail_merk_supplier_id = ?", supply.DetailMerkSupplierID).Error; err != nil {
+			return err
+		}
+	}
+
+	var rules []entity.ReorderRule
+	if err := tx.Where(column+" = ?", sourceID).Find(&rules).Error; err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		otherID := rule.JenisID
+		if column == "jenis_id" {
+			otherID = rule.MerkID
+		}
+
+		query := tx.Model(&entity.ReorderRule{}).Where(column+" = ?", targetID)
+		if otherID == nil {
+			query = query.Where(other + " IS NULL")
+		} else {
+			query = query.Where(other+" = ?", *otherID)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if count > 0 {
+			err = tx.Delete(&entity.ReorderRule{}, "id = ?", rule.ID).Error
+		} else {
+			err = tx.Model(&entity.ReorderRule{}).Where("id = ?", rule.ID).Update(column, targetID).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// countSupplyReferences counts the supplies and reorder rules that use a
+// merk or jenis; column is "merk_id" or "jenis_id".
+func countSupplyReferences(tx *gorm.DB, column string, id int) (int64, error) {
+	var supplies, rules int64
+	if err := tx.Model(&entity.DetailMerkSupplier{}).Where(column+" = ?", id).Count(&supplies).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&entity.ReorderRule{}).Where(column+" = ?", id).Count(&rules).Error; err != nil {
+		return 0, err
+	}
+
+	return supplies + rules, nil
+}
@@ -0,0 +1,418 @@
+package repository
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	ExportRepository interface {
+		CreateJob(ctx context.Context, tx *gorm.DB, job entity.ExportJob) (entity.ExportJob, error)
+		GetJob(ctx context.Context, tx *gorm.DB, jobID string) (entity.ExportJob, error)
+		GetJobsByUser(ctx context.Context, tx *gorm.DB, userID string, limit int) ([]entity.ExportJob, error)
+		ClaimNextJob(ctx context.Context, tx *gorm.DB, now time.Time) (entity.ExportJob, bool, error)
+		UpdateJob(ctx context.Context, tx *gorm.DB, job entity.ExportJob) error
+		RequeueRunningJobs(ctx context.Context, tx *gorm.DB) error
+		GetExpiredJobs(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.ExportJob, error)
+		GetJobsByFileDir(ctx context.Context, tx *gorm.DB, dir string) ([]entity.ExportJob, error)
+
+		StreamNota(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.ExportNotaRow) error) error
+		StreamProduk(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.GetTransaksiProduk) error) error
+		StreamLogAkses(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.ExportLogAksesRow) error) error
+		StreamPengeluaran(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(entity.Pengeluaran) error) error
+		StreamFinalStok(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.FinalStokResponse) error) error
+	}
+
+	exportRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{
+		db: db,
+	}
+}
+
+func (r *exportRepository) CreateJob(ctx context.Context, tx *gorm.DB, job entity.ExportJob) (entity.ExportJob, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Create(&job).Error; err != nil {
+		return entity.ExportJob{}, err
+	}
+
+	return job, nil
+}
+
+func (r *exportRepository) GetJob(ctx context.Context, tx *gorm.DB, jobID string) (entity.ExportJob, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var job entity.ExportJob
+	if err := tx.WithContext(ctx).Where("id = ?", jobID).Take(&job).Error; err != nil {
+		return entity.ExportJob{}, err
+	}
+
+	return job, nil
+}
+
+func (r *exportRepository) GetJobsByUser(ctx context.Context, tx *gorm.DB, userID string, limit int) ([]entity.ExportJob, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var jobs []entity.ExportJob
+	err := tx.WithContext(ctx).
+		Where("requested_by = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ClaimNextJob marks the oldest queued job as running and returns it. SKIP
+// LOCKED keeps two workers from picking the same job.
+func (r *exportRepository) ClaimNextJob(ctx context.Context, tx *gorm.DB, now time.Time) (entity.ExportJob, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var jobs []entity.ExportJob
+	err := tx.WithContext(ctx).Raw(`
+		UPDATE export_jobs SET status = @running, started_at = @now, updated_at = @now
+		WHERE id = (
+			SELECT id FROM export_jobs
+			WHERE status = @queued
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`, map[string]any{
+		"running": constants.ENUM_EXPORT_RUNNING,
+		"queued":  constants.ENUM_EXPORT_QUEUED,
+		"now":     now,
+	}).Scan(&jobs).Error
+	if err != nil {
+		return entity.ExportJob{}, false, err
+	}
+
+	if len(jobs) == 0 {
+		return entity.ExportJob{}, false, nil
+	}
+
+	return jobs[0], true, nil
+}
+
+func (r *exportRepository) UpdateJob(ctx context.Context, tx *gorm.DB, job entity.ExportJob) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.ExportJob{}).
+		Where("id = ?", job.ID).
+		Updates(map[string]any{
+			"status":      job.Status,
+			"file_name":   job.FileName,
+			"file_path":   job.FilePath,
+			"rows":        job.Rows,
+			"error":       job.Error,
+			"finished_at": job.FinishedAt,
+			"expires_at":  job.ExpiresAt,
+		}).Error
+}
+
+// RequeueRunningJobs puts jobs that were cut off by a restart back in the
+// queue.
+func (r *exportRepository) RequeueRunningJobs(ctx context.Context, tx *gorm.DB) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.ExportJob{}).
+		Where("status = ?", constants.ENUM_EXPORT_RUNNING).
+		Update("status", constants.ENUM_EXPORT_QUEUED).Error
+}
+
+func (r *exportRepository) GetExpiredJobs(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.ExportJob, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var jobs []entity.ExportJob
+	err := tx.WithContext(ctx).
+		Where("status IN ? AND expires_at <= ?", []string{constants.ENUM_EXPORT_DONE, constants.ENUM_EXPORT_FAILED}, now).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// GetJobsByFileDir returns the jobs whose file is stored in dir.
+func (r *exportRepository) GetJobsByFileDir(ctx context.Context, tx *gorm.DB, dir string) ([]entity.ExportJob, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var jobs []entity.ExportJob
+	err := tx.WithContext(ctx).
+		Where("file_path LIKE ?", dir+"/%").
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// StreamNota returns one row per item sold, ordered by nota, with the nota
+// totals repeated on every row so the caller can write them while streaming.
+func (r *exportRepository) StreamNota(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.ExportNotaRow) error) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	start, end, err := exportTransaksiRange(req)
+	if err != nil {
+		return err
+	}
+
+	query := tx.WithContext(ctx).Table("transaksis t").
+		Select(`
+			t.id AS id_transaksi,
+			t.created_at AS tanggal_transaksi,
+			t.total_harga AS total_pendapatan,
+			SUM(dt.jumlah_produk) OVER (PARTITION BY t.id) AS total_produk,
+			m.nama AS merk,
+			p.nama_produk,
+			j.nama_jenis AS jenis,
+			dp.ukuran,
+			dt.jumlah_produk AS jumlah_item,
+			p.harga_jual AS harga_produk
+		`).
+		Joins("JOIN detail_transaksis dt ON dt.transaksi_id = t.id").
+		Joins("JOIN detail_produks dp ON dt.detail_produk_id = dp.id").
+		Joins("JOIN produks p ON dp.produk_id = p.id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Joins("JOIN jenis j ON dms.jenis_id = j.id").
+		Where("t.created_at BETWEEN ? AND ?", start, end).
+		Order("t.id, dt.id")
+
+	if req.Search != "" {
+		query = query.Where("CAST(t.id AS TEXT) LIKE ?", "%"+req.Search+"%")
+	}
+
+	if req.Cabang != 0 {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM detail_transaksis cdt
+			JOIN detail_produks cdp ON cdt.detail_produk_id = cdp.id
+			JOIN produks cp ON cdp.produk_id = cp.id
+			WHERE cdt.transaksi_id = t.id AND cp.cabang_id = ?
+		)`, req.Cabang)
+	}
+
+	return streamRows(query, fn)
+}
+
+func (r *exportRepository) StreamProduk(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.GetTransaksiProduk) error) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	start, end, err := exportTransaksiRange(req)
+	if err != nil {
+		return err
+	}
+
+	query := tx.WithContext(ctx).Table("produks p").
+		Select(`
+			t.id AS nomor_nota,
+			p.id AS produk_id,
+			p.barcode_id,
+			dp.id AS detail_id,
+			p.nama_produk,
+			m.nama AS merk,
+			j.nama_jenis AS jenis,
+			dp.ukuran,
+			dp.warna,
+			MAX(t.created_at) AS tanggal_transaksi,
+			COALESCE(SUM(dt.jumlah_produk), 0) AS total_barang,
+			COALESCE(SUM(p.harga_jual * dt.jumlah_produk), 0) AS total_pendapatan,
+			COALESCE(SUM((p.harga_jual * dt.jumlah_produk * (1 - (CAST(t.diskon AS DECIMAL(5, 2)) / 100))) - (dp.harga_beli * dt.jumlah_produk)), 0) AS total_profit
+		`).
+		Joins("JOIN detail_produks dp ON p.id = dp.produk_id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Joins("JOIN jenis j ON dms.jenis_id = j.id").
+		Joins("JOIN detail_transaksis dt ON dp.id = dt.detail_produk_id").
+		Joins("JOIN transaksis t ON dt.transaksi_id = t.id").
+		Where("t.created_at BETWEEN ? AND ?", start, end).
+		Where("p.cabang_id = ?", req.Cabang).
+		Group("p.id, t.id, dp.id, m.nama, j.nama_jenis").
+		Order("t.id ASC")
+
+	if req.Search != "" {
+		query = query.Where("p.nama_produk LIKE ?", "%"+req.Search+"%")
+	}
+
+	return streamRows(query, fn)
+}
+
+// StreamLogAkses uses the same date handling as GetAllLogAkses: dates are
+// Asia/Jakarta days, both included.
+func (r *exportRepository) StreamLogAkses(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.ExportLogAksesRow) error) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Table("detail_akses").
+		Select("detail_akses.id, users.name, users.email, detail_akses.ip, detail_akses.activity, detail_akses.token, detail_akses.payload, detail_akses.created_at").
+		Joins("JOIN log_akses ON log_akses.id = detail_akses.log_akses_id").
+		Joins("JOIN users ON log_akses.user_id = users.id").
+		Order("detail_akses.created_at ASC")
+
+	startDate, endDate, err := jakartaDayRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return err
+	}
+
+	if !startDate.IsZero() {
+		query = query.Where("detail_akses.created_at >= ?", startDate)
+	}
+
+	if !endDate.IsZero() {
+		query = query.Where("detail_akses.created_at < ?", endDate)
+	}
+
+	if req.Search != "" {
+		query = query.Where("(users.name LIKE ? OR users.email LIKE ? OR detail_akses.activity LIKE ?)", "%"+req.Search+"%", "%"+req.Search+"%", "%"+req.Search+"%")
+	}
+
+	return streamRows(query, fn)
+}
+
+func (r *exportRepository) StreamPengeluaran(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(entity.Pengeluaran) error) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Model(&entity.Pengeluaran{}).Order("created_at ASC")
+
+	if req.Search != "" {
+		query = query.Where("nama_pengeluaran LIKE ?", "%"+req.Search+"%")
+	}
+
+	switch req.Filter {
+	case "day":
+		query = query.Where("DATE(tanggal_pengeluaran) = CURRENT_DATE")
+	case "month":
+		query = query.Where("DATE_TRUNC('month', tanggal_pengeluaran) = DATE_TRUNC('month', CURRENT_DATE)")
+	case "year":
+		query = query.Where("DATE_TRUNC('year', tanggal_pengeluaran) = DATE_TRUNC('year', CURRENT_DATE)")
+	}
+
+	if req.StartDate != "" && req.EndDate != "" {
+		query = query.Where("tanggal_pengeluaran BETWEEN ? AND ?", req.StartDate+" 00:00:00", req.EndDate+" 23:59:59")
+	}
+
+	if req.Cabang != 0 {
+		query = query.Where("cabang_id = ?", req.Cabang)
+	}
+
+	return streamRows(query, fn)
+}
+
+func (r *exportRepository) StreamFinalStok(ctx context.Context, tx *gorm.DB, req dto.ExportRequest, fn func(dto.FinalStokResponse) error) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Table("produks p").
+		Select("p.id as produk_id, p.nama_produk, p.barcode_id, m.id as merk_id, m.nama as merk_nama, j.nama_jenis, dp.stok, dp.ukuran, dp.warna, p.harga_jual, (dp.stok * p.harga_jual) as total_notional").
+		Joins("JOIN detail_produks dp ON p.id = dp.produk_id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Joins("JOIN jenis j ON dms.jenis_id = j.id").
+		Order("m.id, p.id")
+
+	if req.Merk != "" {
+		query = query.Where("m.nama = ?", req.Merk)
+	}
+
+	query = jenisFilter(query, "j.id", req.JenisID, req.Jenis)
+
+	if req.Cabang != 0 {
+		query = query.Where("p.cabang_id = ?", req.Cabang)
+	}
+
+	return streamRows(query, fn)
+}
+
+// streamRows scans query one row at a time so an export never holds the
+// whole result in memory.
+func streamRows[T any](query *gorm.DB, fn func(T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return err
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// exportTransaksiRange resolves the period like GetTransaksiByNota and
+// GetTransaksiByProduk: an inclusive date range or a named range. Without
+// either it exports the current month instead of nothing.
+func exportTransaksiRange(req dto.ExportRequest) (time.Time, time.Time, error) {
+	now := time.Now()
+
+	switch {
+	case req.StartDate != "" && req.EndDate != "":
+		start, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		end, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+
+		return start, end.Add(24 * time.Hour).Add(-time.Second), nil
+	case req.Range == "today":
+		start := now.Truncate(24 * time.Hour)
+		return start, start.Add(24 * time.Hour).Add(-time.Second), nil
+	case req.Range == "week":
+		start := now.AddDate(0, 0, -int(now.Weekday())).Truncate(24 * time.Hour)
+		return start, start.AddDate(0, 0, 7).Add(-time.Second), nil
+	default:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0).Add(-time.Second), nil
+	}
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	IdempotencyRepository interface {
+		ReserveKey(ctx context.Context, tx *gorm.DB, key entity.IdempotencyKey) (entity.IdempotencyKey, bool, error)
+		CompleteKey(ctx context.Context, tx *gorm.DB, id int, statusCode int, contentType string, response []byte) error
+		DeleteKey(ctx context.Context, tx *gorm.DB, id int) error
+		DeleteExpiredKeys(ctx context.Context, tx *gorm.DB, now time.Time) error
+	}
+
+	idempotencyRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+// ReserveKey inserts the key if no row exists yet for the same user. The
+// returned flag is false when the key was already taken, in which case the
+// existing row is returned instead.
+func (r *idempotencyRepository) ReserveKey(ctx context.Context, tx *gorm.DB, key entity.IdempotencyKey) (entity.IdempotencyKey, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+	if result.Error != nil {
+		return entity.IdempotencyKey{}, false, result.Error
+	}
+
+	if result.RowsAffected == 1 {
+		return key, true, nil
+	}
+
+	var existing entity.IdempotencyKey
+	if err := tx.WithContext(ctx).Where("user_id = ? AND key = ?", key.UserID, key.Key).Take(&existing).Error; err != nil {
+		return entity.IdempotencyKey{}, false, err
+	}
+
+	return existing, false, nil
+}
+
+func (r *idempotencyRepository) CompleteKey(ctx context.Context, tx *gorm.DB, id int, statusCode int, contentType string, response []byte) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"response":     response,
+			"locked_until": nil,
+		}).Error
+}
+
+func (r *idempotencyRepository) DeleteKey(ctx context.Context, tx *gorm.DB, id int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Delete(&entity.IdempotencyKey{}, "id = ?", id).Error
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(ctx context.Context, tx *gorm.DB, now time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Where("expires_at < ?", now).Delete(&entity.IdempotencyKey{}).Error
+}
@@ -3,19 +3,24 @@ package repository
 import (
 	"bumisubur-be/entity"
 	"context"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 type (
 	JenisRepository interface {
-		CreateJenis(ctx context.Context, tx *gorm.DB, jenis entity.Jenis) (entity.Jenis, error)
 		GetAllJenis(ctx context.Context, tx *gorm.DB) ([]entity.Jenis, error)
 		GetJenisByID(ctx context.Context, tx *gorm.DB, jenisID int) (entity.Jenis, error)
 		GetJenisByMerkID(ctx context.Context, tx *gorm.DB, merkID int) ([]entity.Jenis, error)
-		CheckJenisName(ctx context.Context, tx *gorm.DB, jenisName string) (entity.Jenis, bool, error)
+		InsertJenis(ctx context.Context, tx *gorm.DB, jenis entity.Jenis) (entity.Jenis, error)
+		CheckJenisName(ctx context.Context, tx *gorm.DB, jenisName string, parentID *int) (entity.Jenis, bool, error)
 		UpdateJenis(ctx context.Context, tx *gorm.DB, jenis entity.Jenis) (entity.Jenis, error)
 		DeleteJenis(ctx context.Context, tx *gorm.DB, jenisID int) error
+		SearchJenis(ctx context.Context, tx *gorm.DB, search string) ([]entity.Jenis, error)
+		CountJenisReferences(ctx context.Context, tx *gorm.DB, jenisID int) (int64, error)
+		MergeJenis(ctx context.Context, tx *gorm.DB, sourceIDs []int, targetID int) error
+		UpdateJenisKategori(ctx context.Context, tx *gorm.DB, jenisID int, parentID *int, ukuranSetID *int, warnaSetID *int) error
 	}
 
 	jenisRepository struct {
@@ -28,18 +33,15 @@ func NewJenisRepository(db *gorm.DB) JenisRepository {
 		db: db,
 	}
 }
-func (r *jenisRepository) CreateJenis(ctx context.Context, tx *gorm.DB, jenis entity.Jenis) (entity.Jenis, error) {
+
+// InsertJenis creates the jenis as given. The caller checks that its name is
+// not taken among its siblings, or that it does not name a jenis yet.
+func (r *jenisRepository) InsertJenis(ctx context.Context, tx *gorm.DB, jenis entity.Jenis) (entity.Jenis, error) {
 	if tx == nil {
 		tx = r.db
 	}
 
-	var existingJenis entity.Jenis
-	if err := tx.WithContext(ctx).Where("nama_jenis = ?", jenis.NamaJenis).Take(&existingJenis).Error; err == nil {
-		return existingJenis, nil
-	} else if err != gorm.ErrRecordNotFound {
-		return entity.Jenis{}, err
-	}
-	
+	jenis.NamaJenis = strings.TrimSpace(jenis.NamaJenis)
 	if err := tx.WithContext(ctx).Create(&jenis).Error; err != nil {
 		return entity.Jenis{}, err
 	}
@@ -86,14 +88,28 @@ func (r *jenisRepository) GetJenisByMerkID(ctx context.Context, tx *gorm.DB, mer
 	return jeniss, nil
 }
 
-func (r *jenisRepository) CheckJenisName(ctx context.Context, tx *gorm.DB, jenisName string) (entity.Jenis, bool, error) {
+// CheckJenisName looks for a jenis with the name among the children of
+// parentID, or among the top level jenis when parentID is nil. The same name
+// may be used under different parents, like "Kaos" under "Pria" and
+// "Wanita".
+func (r *jenisRepository) CheckJenisName(ctx context.Context, tx *gorm.DB, jenisName string, parentID *int) (entity.Jenis, bool, error) {
 	if tx == nil {
 		tx = r.db
 	}
 
+	query := tx.WithContext(ctx).Where("LOWER(nama_jenis) = LOWER(?)", strings.TrimSpace(jenisName))
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
 	var jenis entity.Jenis
-	if err := tx.WithContext(ctx).Where("name = ?", jenisName).Take(&jenis).Error; err != nil {
-		return entity.Jenis{}, false, nil
+	if err := query.Take(&jenis).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return entity.Jenis{}, false, nil
+		}
+		return entity.Jenis{}, false, err
 	}
 
 	return jenis, true, nil
@@ -122,3 +138,114 @@ func (r *jenisRepository) DeleteJenis(ctx context.Context, tx *gorm.DB, jenisID
 
 	return nil
 }
+
+func (r *jenisRepository) SearchJenis(ctx context.Context, tx *gorm.DB, search string) ([]entity.Jenis, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Order("nama_jenis")
+	if search != "" {
+		query = query.Where("LOWER(nama_jenis) LIKE LOWER(?)", "%"+search+"%")
+	}
+
+	var jeniss []entity.Jenis
+	if err := query.Find(&jeniss).Error; err != nil {
+		return nil, err
+	}
+
+	return jeniss, nil
+}
+
+// CountJenisReferences counts the supplies, reorder rules and child jenis
+// using the jenis.
+func (r *jenisRepository) CountJenisReferences(ctx context.Context, tx *gorm.DB, jenisID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	references, err := countSupplyReferences(tx.WithContext(ctx), "jenis_id", jenisID)
+	if err != nil {
+		return 0, err
+	}
+
+	var children int64
+	if err := tx.WithContext(ctx).Model(&entity.Jenis{}).Where("parent_id = ?", jenisID).Count(&children).Error; err != nil {
+		return 0, err
+	}
+
+	return references + children, nil
+}
+
+// MergeJenis moves everything that uses the source jeniss to the target and
+// soft deletes the sources, all in one transaction.
+func (r *jenisRepository) MergeJenis(ctx context.Context, tx *gorm.DB, sourceIDs []int, targetID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, sourceID := range sourceIDs {
+			if err := mergeSupplies(tx, "jenis_id", sourceID, targetID); err != nil {
+				return err
+			}
+			if err := reparentJenisChildren(tx, sourceID, targetID); err != nil {
+				return err
+			}
+			if err := tx.Delete(&entity.Jenis{}, "id = ?", sourceID).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// UpdateJenisKategori sets the parent and attribute sets of a jenis; nil
+// clears them.
+func (r *jenisRepository) UpdateJenisKategori(ctx context.Context, tx *gorm.DB, jenisID int, parentID *int, ukuranSetID *int, warnaSetID *int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.Jenis{}).Where("id = ?", jenisID).Updates(map[string]any{
+		"parent_id":     parentID,
+		"ukuran_set_id": ukuranSetID,
+		"warna_set_id":  warnaSetID,
+	}).Error
+}
+
+// reparentJenisChildren moves the children of a merged jenis under the
+// target. When the target sits below the source in the tree that would make
+// a cycle, so they move up to the parent of the source instead.
+func reparentJenisChildren(tx *gorm.DB, sourceID int, targetID int) error {
+	var nodes []entity.Jenis
+	if err := tx.Select("id", "parent_id").Find(&nodes).Error; err != nil {
+		return err
+	}
+
+	parents := make(map[int]*int, len(nodes))
+	for _, node := range nodes {
+		parents[node.ID] = node.ParentID
+	}
+
+	newParent := &targetID
+	seen := map[int]bool{}
+	for id := parents[targetID]; id != nil && !seen[*id]; id = parents[*id] {
+		seen[*id] = true
+		if *id == sourceID {
+			newParent = parents[sourceID]
+			break
+		}
+	}
+
+	if err := tx.Model(&entity.Jenis{}).
+		Where("parent_id = ? AND id <> ?", sourceID, targetID).
+		Update("parent_id", newParent).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&entity.Jenis{}).
+		Where("id = ? AND parent_id = ?", targetID, sourceID).
+		Update("parent_id", parents[sourceID]).Error
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type (
+	KeyRotationRepository interface {
+		GetStaleCiphertexts(ctx context.Context, tx *gorm.DB, table string, column string, activePrefix string, afterID int, limit int) ([]Ciphertext, error)
+		UpdateCiphertext(ctx context.Context, tx *gorm.DB, table string, column string, id int, old string, value string) (bool, error)
+	}
+
+	// Ciphertext is one encrypted value stored in a row.
+	Ciphertext struct {
+		ID    int
+		Value string
+	}
+
+	keyRotationRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewKeyRotationRepository(db *gorm.DB) KeyRotationRepository {
+	return &keyRotationRepository{
+		db: db,
+	}
+}
+
+// GetStaleCiphertexts pages through the rows of table whose column holds a
+// value that was not encrypted with the active key. table and column come
+// from code, never from a request.
+func (r *keyRotationRepository) GetStaleCiphertexts(ctx context.Context, tx *gorm.DB, table string, column string, activePrefix string, afterID int, limit int) ([]Ciphertext, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var values []Ciphertext
+	err := tx.WithContext(ctx).Table(table).
+		Select("id, "+column+" AS value").
+		Where("id > ? AND "+column+" <> '' AND "+column+" NOT LIKE ?", afterID, activePrefix+"%").
+		Order("id ASC").
+		Limit(limit).
+		Scan(&values).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// UpdateCiphertext replaces old with value unless the row was changed in the
+// meantime.
+func (r *keyRotationRepository) UpdateCiphertext(ctx context.Context, tx *gorm.DB, table string, column string, id int, old string, value string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Table(table).
+		Where("id = ? AND "+column+" = ?", id, old).
+		Update(column, value)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -4,16 +4,35 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"context"
+	"math"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+const (
+	ACCESS_LOG_INSERT_BATCH = 100
+
+	// LOG_AKSES_PER_PAGE_DEFAULT and LOG_AKSES_PER_PAGE_MAX bound a page of
+	// the access log, which is large and read by hand.
+	LOG_AKSES_PER_PAGE_DEFAULT = 10
+	LOG_AKSES_PER_PAGE_MAX     = 100
+)
+
 type LogAksesRepository interface {
-	CreateDetailAkses(ctx context.Context, tx *gorm.DB, detailAkses entity.DetailAkses)
+	CreateDetailAkses(ctx context.Context, tx *gorm.DB, detailAkses entity.DetailAkses) error
+	CreateDetailAksesBatch(ctx context.Context, tx *gorm.DB, details []entity.DetailAkses) error
 	FindActiveLogAksesByUserID(ctx context.Context, tx *gorm.DB, userID int) int
 	LogAkses(ctx context.Context, tx *gorm.DB, userId int) (entity.LogAkses, error)
+	ExtendLogAkses(ctx context.Context, tx *gorm.DB, logAksesID int, until time.Time) error
+	EndLogAkses(ctx context.Context, tx *gorm.DB, logAksesID int) error
 	GetAllLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) (dto.LogAksesPaginationResponse, error)
+	ListLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) ([]dto.LogAksesResponse, error)
+	GetLogAksesSessions(ctx context.Context, filter dto.LogAksesSessionRequest) (dto.LogAksesSessionPaginationResponse, error)
+	GetLogAksesSession(ctx context.Context, logAksesID int) (dto.LogAksesSessionResponse, error)
+	GetDetailAksesBefore(ctx context.Context, tx *gorm.DB, before time.Time, limit int) ([]entity.DetailAkses, error)
+	DeleteDetailAkses(ctx context.Context, tx *gorm.DB, ids []int) (int64, error)
 }
 
 type logAksesRepository struct {
@@ -24,8 +43,26 @@ func NewLogAksesRepository(db *gorm.DB) LogAksesRepository {
 	return &logAksesRepository{db: db}
 }
 
-func (r *logAksesRepository) CreateDetailAkses(ctx context.Context, tx *gorm.DB, detailAkses entity.DetailAkses) {
-	r.db.Create(&detailAkses)
+func (r *logAksesRepository) CreateDetailAkses(ctx context.Context, tx *gorm.DB, detailAkses entity.DetailAkses) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Create(&detailAkses).Error
+}
+
+// CreateDetailAksesBatch inserts details with one statement per
+// ACCESS_LOG_INSERT_BATCH rows.
+func (r *logAksesRepository) CreateDetailAksesBatch(ctx context.Context, tx *gorm.DB, details []entity.DetailAkses) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(details) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).CreateInBatches(&details, ACCESS_LOG_INSERT_BATCH).Error
 }
 
 func (r *logAksesRepository) FindActiveLogAksesByUserID(ctx context.Context, tx *gorm.DB, userID int) int {
@@ -56,7 +93,7 @@ func (r *logAksesRepository) LogAkses(ctx context.Context, tx *gorm.DB, userId i
 
 			detail := entity.DetailAkses{
 				Activity:   "Login",
-				LogAksesID: logAkses.ID,
+				LogAksesID: &logAkses.ID,
 				Status:     "200",
 			}
 
@@ -64,9 +101,11 @@ func (r *logAksesRepository) LogAkses(ctx context.Context, tx *gorm.DB, userId i
 				return err
 			}
 		} else {
+			logAkses.ID = id
+
 			detail := entity.DetailAkses{
 				Activity:   "Login",
-				LogAksesID: id,
+				LogAksesID: &id,
 				Status:     "200",
 			}
 
@@ -86,53 +125,309 @@ func (r *logAksesRepository) LogAkses(ctx context.Context, tx *gorm.DB, userId i
 	return logAkses, nil
 }
 
+// ExtendLogAkses moves the log out time of a log akses forward to until
+// while a session on it is still being refreshed.
+func (r *logAksesRepository) ExtendLogAkses(ctx context.Context, tx *gorm.DB, logAksesID int, until time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.LogAkses{}).
+		Where("id = ? AND log_out_time < ?", logAksesID, until).
+		Update("log_out_time", until).Error
+}
+
+// EndLogAkses records the log out of a log akses now.
+func (r *logAksesRepository) EndLogAkses(ctx context.Context, tx *gorm.DB, logAksesID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	now := time.Now()
+	return tx.WithContext(ctx).Model(&entity.LogAkses{}).
+		Where("id = ? AND log_out_time > ?", logAksesID, now).
+		Update("log_out_time", now).Error
+}
+
 func (r *logAksesRepository) GetAllLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) (dto.LogAksesPaginationResponse, error) {
-	var logAksesResponses []dto.LogAksesResponse
+	filter.Page, filter.PerPage = logAksesPage(filter.Page, filter.PerPage)
+
+	query, err := r.detailAksesQuery(ctx, filter)
+	if err != nil {
+		return dto.LogAksesPaginationResponse{}, err
+	}
 
-	var err error
 	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return dto.LogAksesPaginationResponse{}, err
+	}
 
-	query := r.db.Table("detail_akses").
-		Select("detail_akses.id, users.name, users.email, detail_akses.ip as ip_address, detail_akses.activity, detail_akses.token, detail_akses.created_at").
-		Joins("JOIN log_akses ON log_akses.id = detail_akses.log_akses_id").
-		Joins("JOIN users ON log_akses.user_id = users.id").
-		Order("detail_akses.created_at ASC")
+	logAksesResponses := []dto.LogAksesResponse{}
+	err = query.Select(detailAksesColumns).
+		Order(detailAksesOrder(filter.Order)).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Scan(&logAksesResponses).Error
+	if err != nil {
+		return dto.LogAksesPaginationResponse{}, err
+	}
 
-	loc, _ := time.LoadLocation("Asia/Jakarta")
-	startDate, _ := time.Parse("2006-01-02", filter.StartDate)
-	endDate, _ := time.Parse("2006-01-02", filter.EndDate)
+	return dto.LogAksesPaginationResponse{
+		Data: logAksesResponses,
+		PaginationResponse: dto.PaginationResponse{
+			Page:    filter.Page,
+			PerPage: filter.PerPage,
+			MaxPage: int64(math.Ceil(float64(count) / float64(filter.PerPage))),
+			Count:   count,
+		},
+	}, nil
+}
 
-	if !startDate.IsZero() {
-		startDate = startDate.Add(-7 * time.Hour).In(loc)
+// logAksesPage fills in the first page and the default page size, and keeps
+// the page size at most LOG_AKSES_PER_PAGE_MAX.
+func logAksesPage(page int, perPage int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = LOG_AKSES_PER_PAGE_DEFAULT
+	}
+	if perPage > LOG_AKSES_PER_PAGE_MAX {
+		perPage = LOG_AKSES_PER_PAGE_MAX
 	}
+	return page, perPage
+}
 
-	if !endDate.IsZero() {
-		endDate = endDate.Add(16*time.Hour + 59*time.Minute + 59*time.Second).In(loc)
+// ListLogAkses is GetAllLogAkses without paging, for downloads.
+func (r *logAksesRepository) ListLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) ([]dto.LogAksesResponse, error) {
+	query, err := r.detailAksesQuery(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	if !startDate.IsZero() && !endDate.IsZero() {
-		query = query.Where("detail_akses.created_at BETWEEN ? AND ?", startDate, endDate)
+	var logAksesResponses []dto.LogAksesResponse
+	err = query.Select(detailAksesColumns).
+		Order(detailAksesOrder(filter.Order)).
+		Scan(&logAksesResponses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return logAksesResponses, nil
+}
+
+const detailAksesColumns = "detail_akses.id, COALESCE(users.name, '') as name, COALESCE(users.email, detail_akses.payload) as email, detail_akses.ip as ip_address, detail_akses.activity, detail_akses.token, detail_akses.payload, detail_akses.status, detail_akses.log_akses_id, detail_akses.created_at"
+
+func detailAksesOrder(order string) string {
+	if order == "asc" {
+		return "detail_akses.created_at ASC, detail_akses.id ASC"
 	}
+	return "detail_akses.created_at DESC, detail_akses.id DESC"
+}
+
+// detailAksesQuery applies every filter of the access log. Failed logins have
+// no log akses; their email is the payload.
+func (r *logAksesRepository) detailAksesQuery(ctx context.Context, filter dto.LogAksesPaginationRequest) (*gorm.DB, error) {
+	query := r.db.WithContext(ctx).Table("detail_akses").
+		Joins("LEFT JOIN log_akses ON log_akses.id = detail_akses.log_akses_id").
+		Joins("LEFT JOIN users ON log_akses.user_id = users.id").
+		Where("detail_akses.deleted_at IS NULL")
 
-	if !startDate.IsZero() && endDate.IsZero() {
+	startDate, endDate, err := jakartaDayRange(filter.StartDate, filter.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if !startDate.IsZero() {
 		query = query.Where("detail_akses.created_at >= ?", startDate)
 	}
+	if !endDate.IsZero() {
+		query = query.Where("detail_akses.created_at < ?", endDate)
+	}
 
-	if startDate.IsZero() && !endDate.IsZero() {
-		query = query.Where("detail_akses.created_at <= ?", endDate)
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("(LOWER(users.name) LIKE LOWER(?) OR LOWER(COALESCE(users.email, detail_akses.payload)) LIKE LOWER(?) OR LOWER(detail_akses.ip) LIKE LOWER(?) OR LOWER(detail_akses.activity) LIKE LOWER(?))", search, search, search, search)
 	}
 
-	err = query.Count(&count).Error
+	if filter.Status != "" {
+		if class, ok := strings.CutSuffix(strings.ToLower(filter.Status), "xx"); ok {
+			query = query.Where("detail_akses.status LIKE ?", class+"%")
+		} else {
+			query = query.Where("detail_akses.status = ?", filter.Status)
+		}
+	}
+
+	if group := strings.Trim(strings.TrimPrefix(strings.Trim(filter.Route, "/"), "api/"), "/"); group != "" {
+		prefix := "/api/" + group
+		query = query.Where("(detail_akses.activity = ? OR detail_akses.activity LIKE ?)", prefix, prefix+"/%")
+	}
+
+	if filter.UserID != 0 {
+		query = query.Where("log_akses.user_id = ?", filter.UserID)
+	}
+
+	if filter.LogAksesID != 0 {
+		query = query.Where("detail_akses.log_akses_id = ?", filter.LogAksesID)
+	}
+
+	return query, nil
+}
+
+// GetLogAksesSessions lists log akses newest first, with the number of
+// actions of each.
+func (r *logAksesRepository) GetLogAksesSessions(ctx context.Context, filter dto.LogAksesSessionRequest) (dto.LogAksesSessionPaginationResponse, error) {
+	filter.Page, filter.PerPage = logAksesPage(filter.Page, filter.PerPage)
+
+	query := r.db.WithContext(ctx).Table("log_akses").
+		Joins("LEFT JOIN users ON users.id = log_akses.user_id").
+		Where("log_akses.deleted_at IS NULL")
+
+	startDate, endDate, err := jakartaDayRange(filter.StartDate, filter.EndDate)
 	if err != nil {
-		return dto.LogAksesPaginationResponse{}, err
+		return dto.LogAksesSessionPaginationResponse{}, err
+	}
+	if !startDate.IsZero() {
+		query = query.Where("log_akses.login_time >= ?", startDate)
+	}
+	if !endDate.IsZero() {
+		query = query.Where("log_akses.login_time < ?", endDate)
 	}
 
-	err = query.Scan(&logAksesResponses).Error
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("(LOWER(users.name) LIKE LOWER(?) OR LOWER(users.email) LIKE LOWER(?))", search, search)
+	}
+
+	if filter.UserID != 0 {
+		query = query.Where("log_akses.user_id = ?", filter.UserID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return dto.LogAksesSessionPaginationResponse{}, err
+	}
+
+	var rows []logAksesSessionRow
+	err = query.Select(logAksesSessionColumns).
+		Order("log_akses.login_time DESC, log_akses.id DESC").
+		Offset((filter.Page - 1) * filter.PerPage).
+		Limit(filter.PerPage).
+		Scan(&rows).Error
 	if err != nil {
-		return dto.LogAksesPaginationResponse{}, err
+		return dto.LogAksesSessionPaginationResponse{}, err
 	}
 
-	return dto.LogAksesPaginationResponse{
-		Data: logAksesResponses,
+	sessions := make([]dto.LogAksesSessionResponse, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, row.response())
+	}
+
+	return dto.LogAksesSessionPaginationResponse{
+		Data: sessions,
+		PaginationResponse: dto.PaginationResponse{
+			Page:    filter.Page,
+			PerPage: filter.PerPage,
+			MaxPage: int64(math.Ceil(float64(count) / float64(filter.PerPage))),
+			Count:   count,
+		},
 	}, nil
 }
+
+func (r *logAksesRepository) GetLogAksesSession(ctx context.Context, logAksesID int) (dto.LogAksesSessionResponse, error) {
+	var row logAksesSessionRow
+	err := r.db.WithContext(ctx).Table("log_akses").
+		Joins("LEFT JOIN users ON users.id = log_akses.user_id").
+		Where("log_akses.id = ? AND log_akses.deleted_at IS NULL", logAksesID).
+		Select(logAksesSessionColumns).
+		Take(&row).Error
+	if err != nil {
+		return dto.LogAksesSessionResponse{}, err
+	}
+
+	return row.response(), nil
+}
+
+const logAksesSessionColumns = "log_akses.id, log_akses.user_id, COALESCE(users.name, '') as name, COALESCE(users.email, '') as email, log_akses.login_time, log_akses.log_out_time, " +
+	"(SELECT COUNT(*) FROM detail_akses WHERE detail_akses.log_akses_id = log_akses.id AND detail_akses.deleted_at IS NULL) as actions"
+
+type logAksesSessionRow struct {
+	ID         int
+	UserID     int
+	Name       string
+	Email      string
+	LoginTime  time.Time
+	LogOutTime time.Time
+	Actions    int64
+}
+
+func (row logAksesSessionRow) response() dto.LogAksesSessionResponse {
+	return dto.LogAksesSessionResponse{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		Name:       row.Name,
+		Email:      row.Email,
+		LoginTime:  row.LoginTime.Format(time.RFC3339),
+		LogoutTime: row.LogOutTime.Format(time.RFC3339),
+		Active:     row.LogOutTime.After(time.Now()),
+		Actions:    row.Actions,
+	}
+}
+
+// GetDetailAksesBefore returns up to limit of the oldest details created
+// before before, soft deleted ones included.
+func (r *logAksesRepository) GetDetailAksesBefore(ctx context.Context, tx *gorm.DB, before time.Time, limit int) ([]entity.DetailAkses, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var details []entity.DetailAkses
+	err := tx.WithContext(ctx).Unscoped().
+		Where("created_at < ?", before).
+		Order("id ASC").
+		Limit(limit).
+		Find(&details).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+// DeleteDetailAkses removes details for good.
+func (r *logAksesRepository) DeleteDetailAkses(ctx context.Context, tx *gorm.DB, ids []int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := tx.WithContext(ctx).Unscoped().Where("id IN ?", ids).Delete(&entity.DetailAkses{})
+	return result.RowsAffected, result.Error
+}
+
+// jakartaDayRange turns the YYYY-MM-DD dates of a filter into the start of
+// startDate and the end of endDate, as Asia/Jakarta days. The end is
+// exclusive. Empty dates give zero times.
+func jakartaDayRange(startDate string, endDate string) (time.Time, time.Time, error) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	var start, end time.Time
+	if startDate != "" {
+		if start, err = time.ParseInLocation("2006-01-02", startDate, loc); err != nil {
+			return time.Time{}, time.Time{}, dto.ErrLogDateInvalid
+		}
+	}
+	if endDate != "" {
+		if end, err = time.ParseInLocation("2006-01-02", endDate, loc); err != nil {
+			return time.Time{}, time.Time{}, dto.ErrLogDateInvalid
+		}
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"bumisubur-be/entity"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	LoginThrottleRepository interface {
+		GetLoginThrottles(ctx context.Context, tx *gorm.DB, keys []string) ([]entity.LoginThrottle, error)
+		GetLoginThrottleForUpdate(ctx context.Context, tx *gorm.DB, key string) (entity.LoginThrottle, bool, error)
+		SaveLoginThrottle(ctx context.Context, tx *gorm.DB, throttle entity.LoginThrottle) error
+		DeleteLoginThrottle(ctx context.Context, tx *gorm.DB, key string) (bool, error)
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+	}
+
+	loginThrottleRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewLoginThrottleRepository(db *gorm.DB) LoginThrottleRepository {
+	return &loginThrottleRepository{
+		db: db,
+	}
+}
+
+func (r *loginThrottleRepository) GetLoginThrottles(ctx context.Context, tx *gorm.DB, keys []string) ([]entity.LoginThrottle, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var throttles []entity.LoginThrottle
+	if err := tx.WithContext(ctx).Where("key IN ?", keys).Find(&throttles).Error; err != nil {
+		return nil, err
+	}
+
+	return throttles, nil
+}
+
+// GetLoginThrottleForUpdate locks the row of key until the transaction ends
+// so concurrent failures are all counted. The bool is false when the key has
+// no failures yet.
+func (r *loginThrottleRepository) GetLoginThrottleForUpdate(ctx context.Context, tx *gorm.DB, key string) (entity.LoginThrottle, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var throttle entity.LoginThrottle
+	err := tx.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(&throttle, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.LoginThrottle{Key: key}, false, nil
+	}
+	if err != nil {
+		return entity.LoginThrottle{}, false, err
+	}
+
+	return throttle, true, nil
+}
+
+func (r *loginThrottleRepository) SaveLoginThrottle(ctx context.Context, tx *gorm.DB, throttle entity.LoginThrottle) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"failures", "window_start", "last_failure_at", "locked_until", "updated_at"}),
+	}).Create(&throttle).Error
+}
+
+// DeleteLoginThrottle clears the failures and lock of key. The bool reports
+// whether there was anything to clear.
+func (r *loginThrottleRepository) DeleteLoginThrottle(ctx context.Context, tx *gorm.DB, key string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Where("key = ?", key).Delete(&entity.LoginThrottle{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *loginThrottleRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"bumisubur-be/entity"
 	"context"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +17,9 @@ type (
 		UpdateMerk(ctx context.Context, tx *gorm.DB, merk entity.Merk) (entity.Merk, error)
 		DeleteMerk(ctx context.Context, tx *gorm.DB, merkID int) error
 		GetMerkBySupplierID(ctx context.Context, tx *gorm.DB, supplierID int) ([]entity.Merk, error)
+		SearchMerk(ctx context.Context, tx *gorm.DB, search string) ([]entity.Merk, error)
+		CountMerkReferences(ctx context.Context, tx *gorm.DB, merkID int) (int64, error)
+		MergeMerk(ctx context.Context, tx *gorm.DB, sourceIDs []int, targetID int) error
 	}
 
 	merkRepository struct {
@@ -33,9 +37,12 @@ func (r *merkRepository) CreateMerk(ctx context.Context, tx *gorm.DB, merk entit
 		tx = r.db
 	}
 
-	var existingMerk entity.Merk
+	// Names are matched ignoring case and surrounding spaces so "Nike " and
+	// "nike" do not become separate merks.
+	merk.Nama = strings.TrimSpace(merk.Nama)
 
-	if err := tx.WithContext(ctx).Where("nama = ?", merk.Nama).Take(&existingMerk).Error; err == nil {
+	var existingMerk entity.Merk
+	if err := tx.WithContext(ctx).Where("LOWER(nama) = LOWER(?)", merk.Nama).Order("id").Take(&existingMerk).Error; err == nil {
 		return existingMerk, nil
 	} else if err != gorm.ErrRecordNotFound {
 		return entity.Merk{}, err
@@ -53,8 +60,10 @@ func (r *merkRepository) GetAllMerk(ctx context.Context, tx *gorm.DB) ([]entity.
 		tx = r.db
 	}
 
+	// One row per name, keeping the oldest, with its ID so callers can look
+	// merks up by name.
 	var merks []entity.Merk
-	if err := tx.WithContext(ctx).Distinct("nama").Find(&merks).Error; err != nil {
+	if err := tx.WithContext(ctx).Select("DISTINCT ON (nama) *").Order("nama, id").Find(&merks).Error; err != nil {
 		return []entity.Merk{}, err
 	}
 
@@ -80,7 +89,7 @@ func (r *merkRepository) CheckMerkName(ctx context.Context, tx *gorm.DB, merkNam
 	}
 
 	var merk entity.Merk
-	if err := tx.WithContext(ctx).Where("name = ?", merkName).Take(&merk).Error; err != nil {
+	if err := tx.WithContext(ctx).Where("LOWER(nama) = LOWER(?)", strings.TrimSpace(merkName)).Take(&merk).Error; err != nil {
 		return entity.Merk{}, false, nil
 	}
 
@@ -123,3 +132,51 @@ func (r *merkRepository) GetMerkBySupplierID(ctx context.Context, tx *gorm.DB, s
 
 	return merks, nil
 }
+
+func (r *merkRepository) SearchMerk(ctx context.Context, tx *gorm.DB, search string) ([]entity.Merk, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Order("nama")
+	if search != "" {
+		query = query.Where("LOWER(nama) LIKE LOWER(?)", "%"+search+"%")
+	}
+
+	var merks []entity.Merk
+	if err := query.Find(&merks).Error; err != nil {
+		return nil, err
+	}
+
+	return merks, nil
+}
+
+// CountMerkReferences counts the supplies and reorder rules using the merk.
+func (r *merkRepository) CountMerkReferences(ctx context.Context, tx *gorm.DB, merkID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return countSupplyReferences(tx.WithContext(ctx), "merk_id", merkID)
+}
+
+// MergeMerk moves everything that uses the source merks to the target and
+// soft deletes the sources, all in one transaction.
+func (r *merkRepository) MergeMerk(ctx context.Context, tx *gorm.DB, sourceIDs []int, targetID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, sourceID := range sourceIDs {
+			if err := mergeSupplies(tx, "merk_id", sourceID, targetID); err != nil {
+				return err
+			}
+			if err := tx.Delete(&entity.Merk{}, "id = ?", sourceID).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
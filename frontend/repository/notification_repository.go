@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"context"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	NotificationRepository interface {
+		GetRules(ctx context.Context, tx *gorm.DB) ([]entity.NotificationRule, error)
+		GetActiveRulesByEvent(ctx context.Context, tx *gorm.DB, event string) ([]entity.NotificationRule, error)
+		GetRuleByID(ctx context.Context, tx *gorm.DB, ruleID int) (entity.NotificationRule, error)
+		SaveRule(ctx context.Context, tx *gorm.DB, rule entity.NotificationRule) (entity.NotificationRule, error)
+		DeleteRule(ctx context.Context, tx *gorm.DB, ruleID int) error
+
+		EnqueueNotifications(ctx context.Context, tx *gorm.DB, notifications []entity.NotificationOutbox) error
+		GetOutbox(ctx context.Context, tx *gorm.DB, status string, limit int) ([]entity.NotificationOutbox, error)
+		ClaimDueNotifications(ctx context.Context, tx *gorm.DB, now time.Time, lockedUntil time.Time, limit int) ([]entity.NotificationOutbox, error)
+		GetDigestNotifications(ctx context.Context, tx *gorm.DB) ([]entity.NotificationOutbox, error)
+		UpdateNotification(ctx context.Context, tx *gorm.DB, notification entity.NotificationOutbox) error
+		MarkNotificationsDigested(ctx context.Context, tx *gorm.DB, ids []int, sentAt time.Time) error
+
+		GetNotificationVariants(ctx context.Context, tx *gorm.DB, detailProdukIDs []int) ([]dto.NotificationVariantRow, error)
+		GetPendingRestoks(ctx context.Context, tx *gorm.DB, olderThan time.Time) ([]dto.PendingRestokRow, error)
+	}
+
+	notificationRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+func (r *notificationRepository) GetRules(ctx context.Context, tx *gorm.DB) ([]entity.NotificationRule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var rules []entity.NotificationRule
+	if err := tx.WithContext(ctx).Order("event, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *notificationRepository) GetActiveRulesByEvent(ctx context.Context, tx *gorm.DB, event string) ([]entity.NotificationRule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var rules []entity.NotificationRule
+	if err := tx.WithContext(ctx).Where("event = ? AND active = ?", event, true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *notificationRepository) GetRuleByID(ctx context.Context, tx *gorm.DB, ruleID int) (entity.NotificationRule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var rule entity.NotificationRule
+	if err := tx.WithContext(ctx).Where("id = ?", ruleID).Take(&rule).Error; err != nil {
+		return entity.NotificationRule{}, err
+	}
+
+	return rule, nil
+}
+
+func (r *notificationRepository) SaveRule(ctx context.Context, tx *gorm.DB, rule entity.NotificationRule) (entity.NotificationRule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Save(&rule).Error; err != nil {
+		return entity.NotificationRule{}, err
+	}
+
+	return rule, nil
+}
+
+func (r *notificationRepository) DeleteRule(ctx context.Context, tx *gorm.DB, ruleID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Where("id = ?", ruleID).Delete(&entity.NotificationRule{}).Error
+}
+
+// EnqueueNotifications inserts the notifications, skipping the ones whose
+// dedup key is already queued so an alert is not repeated.
+func (r *notificationRepository) EnqueueNotifications(ctx context.Context, tx *gorm.DB, notifications []entity.NotificationOutbox) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error
+}
+
+func (r *notificationRepository) GetOutbox(ctx context.Context, tx *gorm.DB, status string, limit int) ([]entity.NotificationOutbox, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Order("id DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var result []entity.NotificationOutbox
+	if err := query.Find(&result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ClaimDueNotifications takes up to limit pending notifications that are due
+// and moves their next attempt to lockedUntil, so no other worker picks them
+// up while they are being sent. Rows another worker is claiming at the same
+// moment are skipped. A notification whose sender died is due again once
+// lockedUntil has passed.
+func (r *notificationRepository) ClaimDueNotifications(ctx context.Context, tx *gorm.DB, now time.Time, lockedUntil time.Time, limit int) ([]entity.NotificationOutbox, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []entity.NotificationOutbox
+	err := tx.WithContext(ctx).Raw(`
+		UPDATE notification_outboxes
+		SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM notification_outboxes
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		lockedUntil, now, constants.ENUM_OUTBOX_PENDING, now, limit,
+	).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (r *notificationRepository) GetDigestNotifications(ctx context.Context, tx *gorm.DB) ([]entity.NotificationOutbox, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []entity.NotificationOutbox
+	err := tx.WithContext(ctx).
+		Where("status = ?", constants.ENUM_OUTBOX_DIGEST).
+		Order("recipient, id").
+		Find(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *notificationRepository) UpdateNotification(ctx context.Context, tx *gorm.DB, notification entity.NotificationOutbox) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.NotificationOutbox{}).
+		Where("id = ?", notification.ID).
+		Updates(map[string]any{
+			"status":          notification.Status,
+			"attempts":        notification.Attempts,
+			"next_attempt_at": notification.NextAttemptAt,
+			"sent_at":         notification.SentAt,
+			"last_error":      notification.LastError,
+		}).Error
+}
+
+func (r *notificationRepository) MarkNotificationsDigested(ctx context.Context, tx *gorm.DB, ids []int, sentAt time.Time) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.WithContext(ctx).Model(&entity.NotificationOutbox{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":  constants.ENUM_OUTBOX_DIGESTED,
+			"sent_at": sentAt,
+		}).Error
+}
+
+func (r *notificationRepository) GetNotificationVariants(ctx context.Context, tx *gorm.DB, detailProdukIDs []int) ([]dto.NotificationVariantRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(detailProdukIDs) == 0 {
+		return nil, nil
+	}
+
+	var result []dto.NotificationVariantRow
+	err := tx.WithContext(ctx).Table("detail_produks dp").
+		Select("dp.id AS detail_produk_id, p.nama_produk, m.nama AS merk, dp.ukuran, dp.warna, dp.stok, p.cabang_id").
+		Joins("JOIN produks p ON dp.produk_id = p.id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Where("dp.id IN ?", detailProdukIDs).
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetPendingRestoks returns restoks dated on or before olderThan whose
+// variants have not been inserted into stock yet.
+func (r *notificationRepository) GetPendingRestoks(ctx context.Context, tx *gorm.DB, olderThan time.Time) ([]dto.PendingRestokRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.PendingRestokRow
+	err := tx.WithContext(ctx).Table("restoks r").
+		Select("r.id AS restok_id, r.tanggal_restok, MAX(p.nama_produk) AS nama_produk, COALESCE(MAX(s.name), '') AS supplier, MAX(p.cabang_id) AS cabang_id, SUM(dr.jumlah) AS jumlah").
+		Joins("JOIN detail_restoks dr ON r.id = dr.restok_id").
+		Joins("JOIN detail_produks dp ON dr.detail_produk_id = dp.id").
+		Joins("JOIN produks p ON r.produk_id = p.id").
+		Joins("LEFT JOIN suppliers s ON r.supplier_id = s.id").
+		Where("dp.status = 0 AND r.deleted_at IS NULL AND dp.deleted_at IS NULL").
+		Where("r.tanggal_restok <= ?", olderThan).
+		Group("r.id, r.tanggal_restok").
+		Order("r.tanggal_restok").
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -12,7 +12,7 @@ import (
 
 type PengeluaranRepository interface {
 	CreatePengeluaran(ctx context.Context, pengeluaran entity.Pengeluaran) (dto.PengeluaranResponse, error)
-	GetAllPengeluaranWithPagination(ctx context.Context, req dto.PaginationRequest, filter string, startDate string, endDate string) (dto.GetAllPengeluaranRepositoryResponse, error)
+	GetAllPengeluaranWithPagination(ctx context.Context, req dto.PaginationRequest, filter string, startDate string, endDate string, cabang int) (dto.GetAllPengeluaranRepositoryResponse, error)
 	GetPengeluaranByID(ctx context.Context, pengeluaranID int) (dto.PengeluaranResponse, error)
 	UpdatePengeluaran(ctx context.Context, pengeluaran entity.Pengeluaran) (entity.Pengeluaran, error)
 	DeletePengeluaran(ctx context.Context, pengeluaranID int) error
@@ -41,10 +41,11 @@ func (r *pengeluaranRepository) CreatePengeluaran(ctx context.Context, pengeluar
 		KategoriPengeluaran: pengeluaran.KategoriPengeluaran,
 		Jumlah:              pengeluaran.Jumlah,
 		Tujuan:              pengeluaran.Tujuan,
+		CabangID:            pengeluaran.CabangID,
 	}, nil
 }
 
-func (r *pengeluaranRepository) GetAllPengeluaranWithPagination(ctx context.Context, req dto.PaginationRequest, filter string, startDate string, endDate string) (dto.GetAllPengeluaranRepositoryResponse, error) {
+func (r *pengeluaranRepository) GetAllPengeluaranWithPagination(ctx context.Context, req dto.PaginationRequest, filter string, startDate string, endDate string, cabang int) (dto.GetAllPengeluaranRepositoryResponse, error) {
 
 	var pengeluarans []entity.Pengeluaran
 	var err error
@@ -80,6 +81,10 @@ func (r *pengeluaranRepository) GetAllPengeluaranWithPagination(ctx context.Cont
 		query = query.Where("tanggal_pengeluaran BETWEEN ? AND ?", startDate, endDate)
 	}
 
+	if cabang != 0 {
+		query = query.Where("cabang_id = ?", cabang)
+	}
+
 	fmt.Println(startDate, endDate)
 
 	err = query.Count(&count).Error
@@ -123,6 +128,7 @@ func (r *pengeluaranRepository) GetPengeluaranByID(ctx context.Context, pengelua
 		KategoriPengeluaran: pengeluaran.KategoriPengeluaran,
 		Jumlah:              pengeluaran.Jumlah,
 		Tujuan:              pengeluaran.Tujuan,
+		CabangID:            pengeluaran.CabangID,
 	}, nil
 }
 
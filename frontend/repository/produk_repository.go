@@ -48,6 +48,8 @@ type ProdukRepository interface {
 	DeleteDetailPendingOnly(ctx context.Context, tx *gorm.DB, restokID int64) error
 
 	GetReturnRestok(ctx context.Context, tx *gorm.DB, restokID string) (dto.PendingStok, error)
+
+	RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 }
 
 type produkRepository struct {
@@ -146,7 +148,7 @@ func (r *produkRepository) CreateRestok(ctx context.Context, tx *gorm.DB, restok
 	today := time.Now().Format("20060102") // Format as YYYYMMDD
 
 	// Get the latest Restok ID for today
-	latestID, err := r.GetLatestRestokID(ctx, today)
+	latestID, err := r.GetLatestRestokID(ctx, tx, today)
 	if err != nil {
 		return entity.Restok{}, err
 	}
@@ -169,9 +171,15 @@ func (r *produkRepository) CreateRestok(ctx context.Context, tx *gorm.DB, restok
 	return restok, nil
 }
 
-func (r *produkRepository) GetLatestRestokID(ctx context.Context, date string) (int64, error) {
+// GetLatestRestokID reads through tx so restoks created earlier in the same
+// transaction are counted.
+func (r *produkRepository) GetLatestRestokID(ctx context.Context, tx *gorm.DB, date string) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
 	var latestID int64
-	err := r.db.WithContext(ctx).
+	err := tx.WithContext(ctx).
 		Table("restoks").
 		Select("id").
 		Where("id BETWEEN ? AND ?", date+"0000", date+"9999").
@@ -304,6 +312,20 @@ func (r *produkRepository) DeleteDetailedPendingProduks(ctx context.Context, tx
 	return nil
 }
 
+// catalogueVariantFilter narrows a query joining detail_produks c and
+// detail_merk_suppliers d to the jenis, ukuran and warna asked for.
+func catalogueVariantFilter(query *gorm.DB, req dto.ProdukPaginationRequest) *gorm.DB {
+	query = jenisFilter(query, "d.jenis_id", req.JenisID, req.Jenis)
+	if req.Ukuran != "" {
+		query = query.Where("LOWER(c.ukuran) = LOWER(?)", req.Ukuran)
+	}
+	if req.Warna != "" {
+		query = query.Where("LOWER(c.warna) = LOWER(?)", req.Warna)
+	}
+
+	return query
+}
+
 func (r *produkRepository) GetAllProdukWithPagination(ctx context.Context, req dto.ProdukPaginationRequest) (dto.GetAllProdukResponse, error) {
 	var err error
 	var count int64
@@ -320,6 +342,14 @@ func (r *produkRepository) GetAllProdukWithPagination(ctx context.Context, req d
 	if req.Search != "" {
 		queryCount = queryCount.Where("nama_produk LIKE ?", "%"+req.Search+"%")
 	}
+	if req.JenisID != 0 || req.Jenis != "" || req.Ukuran != "" || req.Warna != "" {
+		variants := r.db.Table("detail_produks c").
+			Select("1").
+			Joins("JOIN detail_merk_suppliers d ON c.detail_merk_supplier_id = d.detail_merk_supplier_id").
+			Where("c.produk_id = produks.id")
+		variants = catalogueVariantFilter(variants, req)
+		queryCount = queryCount.Where("EXISTS (?)", variants)
+	}
 
 	err = queryCount.Count(&count).Error
 	if err != nil {
@@ -348,6 +378,7 @@ func (r *produkRepository) GetAllProdukWithPagination(ctx context.Context, req d
 	if req.Search != "" {
 		query = query.Where("a.nama_produk LIKE ?", "%"+req.Search+"%")
 	}
+	query = catalogueVariantFilter(query, req)
 
 	offset := (req.Page - 1) * req.PerPage
 	maxPage := int64(math.Ceil(float64(count) / float64(req.PerPage)))
@@ -860,8 +891,18 @@ func (r *produkRepository) GetFinalStok(ctx context.Context, filter dto.FilterFi
 		query = query.Where("m.nama = ?", filter.Merk)
 	}
 
-	if filter.Jenis != "" {
-		query = query.Where("j.nama_jenis = ?", filter.Jenis)
+	query = jenisFilter(query, "j.id", filter.JenisID, filter.Jenis)
+
+	if filter.Ukuran != "" {
+		query = query.Where("LOWER(dp.ukuran) = LOWER(?)", filter.Ukuran)
+	}
+
+	if filter.Warna != "" {
+		query = query.Where("LOWER(dp.warna) = LOWER(?)", filter.Warna)
+	}
+
+	if filter.Cabang != 0 {
+		query = query.Where("p.cabang_id = ?", filter.Cabang)
 	}
 
 	if err := query.Scan(&results).Error; err != nil {
@@ -1082,3 +1123,9 @@ func getJenisDetailsByMerk(db *gorm.DB, merkID int) ([]dto.FinalGetJenisDetail,
 		Find(&details).Error
 	return details, err
 }
+
+// RunInTransaction runs fn in a transaction that is rolled back when fn
+// returns an error.
+func (r *produkRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	ReorderRepository interface {
+		GetReorderRules(ctx context.Context, tx *gorm.DB) ([]dto.ReorderRuleResponse, error)
+		GetReorderRuleByScope(ctx context.Context, tx *gorm.DB, merkID *int, jenisID *int) (entity.ReorderRule, bool, error)
+		SaveReorderRule(ctx context.Context, tx *gorm.DB, rule entity.ReorderRule) (entity.ReorderRule, error)
+		DeleteReorderRule(ctx context.Context, tx *gorm.DB, ruleID int) (bool, error)
+
+		UpdateVariantReorder(ctx context.Context, tx *gorm.DB, detailProdukID int, minStok *int, reorderQty *int) (bool, error)
+
+		GetReplenishmentVariants(ctx context.Context, tx *gorm.DB, req dto.ReplenishmentRequest, since time.Time) ([]dto.ReplenishmentVariantRow, error)
+		GetMerkJenisSuppliers(ctx context.Context, tx *gorm.DB) ([]dto.MerkJenisSupplierRow, error)
+	}
+
+	reorderRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewReorderRepository(db *gorm.DB) ReorderRepository {
+	return &reorderRepository{
+		db: db,
+	}
+}
+
+func (r *reorderRepository) GetReorderRules(ctx context.Context, tx *gorm.DB) ([]dto.ReorderRuleResponse, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.ReorderRuleResponse
+	err := tx.WithContext(ctx).Table("reorder_rules rr").
+		Select("rr.id, rr.merk_id, COALESCE(m.nama, '') AS merk, rr.jenis_id, COALESCE(j.nama_jenis, '') AS jenis, rr.min_stok, rr.reorder_qty").
+		Joins("LEFT JOIN merks m ON rr.merk_id = m.id").
+		Joins("LEFT JOIN jenis j ON rr.jenis_id = j.id").
+		Order("m.nama NULLS FIRST, j.nama_jenis NULLS FIRST").
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *reorderRepository) GetReorderRuleByScope(ctx context.Context, tx *gorm.DB, merkID *int, jenisID *int) (entity.ReorderRule, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx)
+	if merkID == nil {
+		query = query.Where("merk_id IS NULL")
+	} else {
+		query = query.Where("merk_id = ?", *merkID)
+	}
+	if jenisID == nil {
+		query = query.Where("jenis_id IS NULL")
+	} else {
+		query = query.Where("jenis_id = ?", *jenisID)
+	}
+
+	var rule entity.ReorderRule
+	if err := query.Take(&rule).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return entity.ReorderRule{}, false, nil
+		}
+		return entity.ReorderRule{}, false, err
+	}
+
+	return rule, true, nil
+}
+
+func (r *reorderRepository) SaveReorderRule(ctx context.Context, tx *gorm.DB, rule entity.ReorderRule) (entity.ReorderRule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Save(&rule).Error; err != nil {
+		return entity.ReorderRule{}, err
+	}
+
+	return rule, nil
+}
+
+func (r *reorderRepository) DeleteReorderRule(ctx context.Context, tx *gorm.DB, ruleID int) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Delete(&entity.ReorderRule{}, "id = ?", ruleID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *reorderRepository) UpdateVariantReorder(ctx context.Context, tx *gorm.DB, detailProdukID int, minStok *int, reorderQty *int) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.DetailProduk{}).
+		Where("id = ?", detailProdukID).
+		Updates(map[string]any{
+			"min_stok":    minStok,
+			"reorder_qty": reorderQty,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// GetReplenishmentVariants returns every active variant with its merk, jenis,
+// current supplier and the quantity sold since since.
+func (r *reorderRepository) GetReplenishmentVariants(ctx context.Context, tx *gorm.DB, req dto.ReplenishmentRequest, since time.Time) ([]dto.ReplenishmentVariantRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	query := tx.WithContext(ctx).Table("detail_produks dp").
+		Select(`
+			dp.id AS detail_produk_id,
+			p.id AS produk_id,
+			p.nama_produk,
+			p.barcode_id,
+			p.cabang_id,
+			m.id AS merk_id,
+			m.nama AS merk,
+			j.id AS jenis_id,
+			j.nama_jenis AS jenis,
+			dms.supplier_id,
+			dp.ukuran,
+			dp.warna,
+			dp.stok,
+			dp.harga_beli,
+			dp.min_stok,
+			dp.reorder_qty,
+			COALESCE(sales.sold_in_window, 0) AS sold_in_window
+		`).
+		Joins("JOIN produks p ON dp.produk_id = p.id").
+		Joins("JOIN detail_merk_suppliers dms ON dp.detail_merk_supplier_id = dms.detail_merk_supplier_id").
+		Joins("JOIN merks m ON dms.merk_id = m.id").
+		Joins("JOIN jenis j ON dms.jenis_id = j.id").
+		Joins(`LEFT JOIN (
+			SELECT dt.detail_produk_id, SUM(dt.jumlah_produk) AS sold_in_window
+			FROM detail_transaksis dt
+			JOIN transaksis t ON dt.transaksi_id = t.id
+			WHERE t.created_at >= ?
+			GROUP BY dt.detail_produk_id
+		) sales ON sales.detail_produk_id = dp.id`, since).
+		Where("dp.status = 1").
+		Where("dp.deleted_at IS NULL AND p.deleted_at IS NULL").
+		Order("m.nama, p.nama_produk, dp.ukuran, dp.warna")
+
+	if req.Merk != "" {
+		query = query.Where("m.nama = ?", req.Merk)
+	}
+
+	query = jenisFilter(query, "j.id", req.JenisID, req.Jenis)
+
+	if req.Cabang != 0 {
+		query = query.Where("p.cabang_id = ?", req.Cabang)
+	}
+
+	var result []dto.ReplenishmentVariantRow
+	if err := query.Scan(&result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetMerkJenisSuppliers lists which supplier carries which merk and jenis,
+// best discount first.
+func (r *reorderRepository) GetMerkJenisSuppliers(ctx context.Context, tx *gorm.DB) ([]dto.MerkJenisSupplierRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var result []dto.MerkJenisSupplierRow
+	err := tx.WithContext(ctx).Table("detail_merk_suppliers dms").
+		Select("dms.merk_id, dms.jenis_id, s.id AS supplier_id, s.name, s.no_hp, dms.discount, s.discount AS supplier_discount").
+		Joins("JOIN suppliers s ON dms.supplier_id = s.id").
+		Where("s.deleted_at IS NULL").
+		Order("dms.discount DESC, s.discount DESC, s.name").
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
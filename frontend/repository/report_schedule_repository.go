@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	ReportScheduleRepository interface {
+		GetSchedules(ctx context.Context, tx *gorm.DB) ([]entity.ReportSchedule, error)
+		GetScheduleByID(ctx context.Context, tx *gorm.DB, scheduleID int) (entity.ReportSchedule, error)
+		SaveSchedule(ctx context.Context, tx *gorm.DB, schedule entity.ReportSchedule) (entity.ReportSchedule, error)
+		DeleteSchedule(ctx context.Context, tx *gorm.DB, scheduleID int) error
+		GetDueSchedules(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.ReportSchedule, error)
+
+		CreateRun(ctx context.Context, tx *gorm.DB, run entity.ReportRun) error
+		GetRuns(ctx context.Context, tx *gorm.DB, scheduleID int, limit int) ([]entity.ReportRun, error)
+		GetDueRuns(ctx context.Context, tx *gorm.DB, now time.Time, limit int) ([]entity.ReportRun, error)
+		UpdateRun(ctx context.Context, tx *gorm.DB, run entity.ReportRun) error
+	}
+
+	reportScheduleRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewReportScheduleRepository(db *gorm.DB) ReportScheduleRepository {
+	return &reportScheduleRepository{
+		db: db,
+	}
+}
+
+func (r *reportScheduleRepository) GetSchedules(ctx context.Context, tx *gorm.DB) ([]entity.ReportSchedule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var schedules []entity.ReportSchedule
+	if err := tx.WithContext(ctx).Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (r *reportScheduleRepository) GetScheduleByID(ctx context.Context, tx *gorm.DB, scheduleID int) (entity.ReportSchedule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var schedule entity.ReportSchedule
+	if err := tx.WithContext(ctx).Where("id = ?", scheduleID).Take(&schedule).Error; err != nil {
+		return entity.ReportSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func (r *reportScheduleRepository) SaveSchedule(ctx context.Context, tx *gorm.DB, schedule entity.ReportSchedule) (entity.ReportSchedule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Save(&schedule).Error; err != nil {
+		return entity.ReportSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func (r *reportScheduleRepository) DeleteSchedule(ctx context.Context, tx *gorm.DB, scheduleID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Where("id = ?", scheduleID).Delete(&entity.ReportSchedule{}).Error
+}
+
+func (r *reportScheduleRepository) GetDueSchedules(ctx context.Context, tx *gorm.DB, now time.Time) ([]entity.ReportSchedule, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var schedules []entity.ReportSchedule
+	err := tx.WithContext(ctx).
+		Where("active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// CreateRun skips a run that already exists for the same schedule and time,
+// so a schedule firing twice only delivers once.
+func (r *reportScheduleRepository) CreateRun(ctx context.Context, tx *gorm.DB, run entity.ReportRun) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&run).Error
+}
+
+func (r *reportScheduleRepository) GetRuns(ctx context.Context, tx *gorm.DB, scheduleID int, limit int) ([]entity.ReportRun, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var runs []entity.ReportRun
+	err := tx.WithContext(ctx).
+		Where("schedule_id = ?", scheduleID).
+		Order("scheduled_for DESC").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (r *reportScheduleRepository) GetDueRuns(ctx context.Context, tx *gorm.DB, now time.Time, limit int) ([]entity.ReportRun, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var runs []entity.ReportRun
+	err := tx.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", constants.ENUM_REPORT_RUN_PENDING, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (r *reportScheduleRepository) UpdateRun(ctx context.Context, tx *gorm.DB, run entity.ReportRun) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.ReportRun{}).
+		Where("id = ?", run.ID).
+		Updates(map[string]any{
+			"status":          run.Status,
+			"attempts":        run.Attempts,
+			"next_attempt_at": run.NextAttemptAt,
+			"file_name":       run.FileName,
+			"sent_to":         run.SentTo,
+			"last_error":      run.LastError,
+			"finished_at":     run.FinishedAt,
+		}).Error
+}
@@ -131,7 +131,8 @@ func (r *returnRepository) GetReturnUserDetail(ctx context.Context, tx *gorm.DB,
 			j.nama_jenis AS jenis, 
 			dp.ukuran, 
 			dt.jumlah_produk as jumlah_item, 
-			p.harga_jual as harga_produk
+			p.harga_jual as harga_produk,
+			p.cabang_id
 		FROM transaksis t
 		JOIN detail_transaksis dt on dt.transaksi_id = t.id
 		JOIN detail_produks dp ON dt.detail_produk_id = dp.id 
@@ -140,7 +141,7 @@ func (r *returnRepository) GetReturnUserDetail(ctx context.Context, tx *gorm.DB,
 		JOIN merks m ON dms.merk_id = m.id
 		JOIN jenis j ON dms.jenis_id = j.id
 		WHERE dt.transaksi_id = ?
-		GROUP BY m.nama, p.nama_produk, j.nama_jenis, dp.ukuran, dt.jumlah_produk, p.harga_jual, dt.id, dp.id
+		GROUP BY m.nama, p.nama_produk, j.nama_jenis, dp.ukuran, dt.jumlah_produk, p.harga_jual, p.cabang_id, dt.id, dp.id
 
 	`, transaksiID).Scan(&result).Error
 
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	SessionRepository interface {
+		CreateSession(ctx context.Context, tx *gorm.DB, session entity.Session) (entity.Session, error)
+		GetSessionByID(ctx context.Context, tx *gorm.DB, sessionID int) (entity.Session, error)
+		GetSessionByTokenHash(ctx context.Context, tx *gorm.DB, hash string) (entity.Session, bool, error)
+		RotateSession(ctx context.Context, tx *gorm.DB, session entity.Session, oldHash string) (bool, error)
+		RevokeSession(ctx context.Context, tx *gorm.DB, sessionID int) (bool, error)
+		RevokeUserSessions(ctx context.Context, tx *gorm.DB, userID int) (int64, error)
+		RevokeOtherSessions(ctx context.Context, tx *gorm.DB, userID int, keepSessionID int) (int64, error)
+		CountActiveSessions(ctx context.Context, tx *gorm.DB, userID int, logAksesID int) (int64, error)
+	}
+
+	sessionRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{
+		db: db,
+	}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, tx *gorm.DB, session entity.Session) (entity.Session, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Create(&session).Error; err != nil {
+		return entity.Session{}, err
+	}
+
+	return session, nil
+}
+
+func (r *sessionRepository) GetSessionByID(ctx context.Context, tx *gorm.DB, sessionID int) (entity.Session, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var session entity.Session
+	if err := tx.WithContext(ctx).Take(&session, "id = ?", sessionID).Error; err != nil {
+		return entity.Session{}, err
+	}
+
+	return session, nil
+}
+
+// GetSessionByTokenHash finds the session whose current or previous refresh
+// token has the given hash.
+func (r *sessionRepository) GetSessionByTokenHash(ctx context.Context, tx *gorm.DB, hash string) (entity.Session, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var session entity.Session
+	err := tx.WithContext(ctx).
+		Where("refresh_token_hash = ? OR previous_token_hash = ?", hash, hash).
+		Take(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return entity.Session{}, false, nil
+		}
+		return entity.Session{}, false, err
+	}
+
+	return session, true, nil
+}
+
+// RotateSession stores the new refresh token of a session. It only succeeds
+// while oldHash is still the current token, so two concurrent refreshes with
+// the same token cannot both win.
+func (r *sessionRepository) RotateSession(ctx context.Context, tx *gorm.DB, session entity.Session, oldHash string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, oldHash).
+		Updates(map[string]any{
+			"refresh_token_hash":  session.RefreshTokenHash,
+			"previous_token_hash": oldHash,
+			"expires_at":          session.ExpiresAt,
+			"last_used_at":        session.LastUsedAt,
+			"ip":                  session.IP,
+			"user_agent":          session.UserAgent,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, tx *gorm.DB, sessionID int) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, tx *gorm.DB, userID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// RevokeOtherSessions revokes every session of a user except keepSessionID.
+func (r *sessionRepository) RevokeOtherSessions(ctx context.Context, tx *gorm.DB, userID int, keepSessionID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// CountActiveSessions counts the sessions of a user that are still open on
+// the given log akses.
+func (r *sessionRepository) CountActiveSessions(ctx context.Context, tx *gorm.DB, userID int, logAksesID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var count int64
+	err := tx.WithContext(ctx).Model(&entity.Session{}).
+		Where("user_id = ? AND log_akses_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, logAksesID, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
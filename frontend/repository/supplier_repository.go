@@ -21,6 +21,15 @@ type (
 		CreateDetailMerkSupplier(ctx context.Context, tx *gorm.DB, detailMerkSupplier entity.DetailMerkSupplier) (entity.DetailMerkSupplier, error)
 		DeleteDetailMerkSupplier(ctx context.Context, tx *gorm.DB, supplierID int) error
 		GetMerksBySupplierID(ctx context.Context, tx *gorm.DB, supplierID int) ([]dto.MerkResponse, error)
+		GetDetailMerkSuppliersBySupplierIDs(ctx context.Context, tx *gorm.DB, supplierIDs []int) ([]entity.DetailMerkSupplier, error)
+		UpdateDetailMerkSupplierDiscount(ctx context.Context, tx *gorm.DB, detailMerkSupplierID int, discount int) error
+		DeleteDetailMerkSupplierByID(ctx context.Context, tx *gorm.DB, detailMerkSupplierID int) error
+		GetReferencedDetailMerkSupplierIDs(ctx context.Context, tx *gorm.DB, detailMerkSupplierIDs []int) ([]int, error)
+
+		CreateSupplyDiscount(ctx context.Context, tx *gorm.DB, discount entity.SupplyDiscount) error
+		GetSupplyDiscounts(ctx context.Context, tx *gorm.DB, supplierID int) ([]dto.SupplyDiscountRow, error)
+
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 	}
 
 	supplierRepository struct {
@@ -236,3 +245,102 @@ func (r *supplierRepository) DeleteDetailMerkSupplier(ctx context.Context, tx *g
 
 	return nil
 }
+
+func (r *supplierRepository) GetDetailMerkSuppliersBySupplierIDs(ctx context.Context, tx *gorm.DB, supplierIDs []int) ([]entity.DetailMerkSupplier, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var details []entity.DetailMerkSupplier
+	if len(supplierIDs) == 0 {
+		return details, nil
+	}
+
+	if err := tx.WithContext(ctx).
+		Where("supplier_id IN ?", supplierIDs).
+		Preload("Merk").
+		Preload("Jenis").
+		Order("detail_merk_supplier_id").
+		Find(&details).Error; err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+func (r *supplierRepository) UpdateDetailMerkSupplierDiscount(ctx context.Context, tx *gorm.DB, detailMerkSupplierID int, discount int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.DetailMerkSupplier{}).
+		Where("detail_merk_supplier_id = ?", detailMerkSupplierID).
+		Update("discount", discount).Error
+}
+
+func (r *supplierRepository) DeleteDetailMerkSupplierByID(ctx context.Context, tx *gorm.DB, detailMerkSupplierID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Delete(&entity.DetailMerkSupplier{}, "detail_merk_supplier_id = ?", detailMerkSupplierID).Error
+}
+
+// GetReferencedDetailMerkSupplierIDs returns which of the given supplies
+// still have product variants, soft deleted ones included since the
+// foreign key would cascade to them too.
+func (r *supplierRepository) GetReferencedDetailMerkSupplierIDs(ctx context.Context, tx *gorm.DB, detailMerkSupplierIDs []int) ([]int, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var ids []int
+	if len(detailMerkSupplierIDs) == 0 {
+		return ids, nil
+	}
+
+	if err := tx.WithContext(ctx).Unscoped().Model(&entity.DetailProduk{}).
+		Where("detail_merk_supplier_id IN ?", detailMerkSupplierIDs).
+		Distinct().
+		Pluck("detail_merk_supplier_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *supplierRepository) CreateSupplyDiscount(ctx context.Context, tx *gorm.DB, discount entity.SupplyDiscount) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Create(&discount).Error
+}
+
+// GetSupplyDiscounts returns the discount history of every supply of a
+// supplier, newest first.
+func (r *supplierRepository) GetSupplyDiscounts(ctx context.Context, tx *gorm.DB, supplierID int) ([]dto.SupplyDiscountRow, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var rows []dto.SupplyDiscountRow
+	if err := tx.WithContext(ctx).Table("supply_discounts sd").
+		Select("sd.*, m.nama AS merk, j.nama_jenis AS jenis").
+		Joins("JOIN detail_merk_suppliers dms ON dms.detail_merk_supplier_id = sd.detail_merk_supplier_id").
+		Joins("JOIN merks m ON m.id = dms.merk_id").
+		Joins("JOIN jenis j ON j.id = dms.jenis_id").
+		Where("dms.supplier_id = ?", supplierID).
+		Order("sd.effective_from DESC, sd.id DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// RunInTransaction runs fn in a transaction that is rolled back when fn
+// returns an error.
+func (r *supplierRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"bumisubur-be/constants"
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"context"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type (
@@ -27,10 +29,17 @@ type (
 		GetIndexTransaksi(ctx context.Context, tx *gorm.DB) ([]dto.IndexTransaksi, error)
 		GetProdukByDetailID(ctx context.Context, tx *gorm.DB, detailProdukID int) (entity.Produk, error)
 
-		GetLatestTransaksiID(ctx context.Context, date string) (int64, error)
+		GetLatestTransaksiID(ctx context.Context, tx *gorm.DB, date string) (int64, error)
 
 		GetNotaData(ctx context.Context, tx *gorm.DB, notaID string) (entity.Transaksi, error)
 		GetNotaDataDetail(ctx context.Context, tx *gorm.DB, transaksiID string) ([]dto.DetailReturnUser, error)
+
+		GetTransaksiSyncByClientID(ctx context.Context, tx *gorm.DB, clientID string) (entity.TransaksiSync, bool, error)
+		ReserveTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync, staleBefore time.Time) (bool, error)
+		UpdateTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync) error
+		DeleteTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync) error
+
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 	}
 
 	transaksiRepository struct {
@@ -182,6 +191,15 @@ func (t *transaksiRepository) GetTransaksiByNota(ctx context.Context, tx *gorm.D
 		query = query.Where("t.id LIKE ?", "%"+req.Search+"%")
 	}
 
+	if req.Cabang != 0 {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM detail_transaksis cdt
+			JOIN detail_produks cdp ON cdt.detail_produk_id = cdp.id
+			JOIN produks cp ON cdp.produk_id = cp.id
+			WHERE cdt.transaksi_id = t.id AND cp.cabang_id = ?
+		)`, req.Cabang)
+	}
+
 	err = query.Count(&count).Error
 	if err != nil {
 		return dto.RepoGetTransaksiNota{}, err
@@ -489,9 +507,13 @@ func (t *transaksiRepository) GetIndexTransaksi(ctx context.Context, tx *gorm.DB
 	return result, nil
 }
 
-func (r *transaksiRepository) GetLatestTransaksiID(ctx context.Context, date string) (int64, error) {
+func (r *transaksiRepository) GetLatestTransaksiID(ctx context.Context, tx *gorm.DB, date string) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
 	var latestID int64
-	err := r.db.WithContext(ctx).
+	err := tx.WithContext(ctx).
 		Table("transaksis").
 		Select("id").
 		Where("id BETWEEN ? AND ?", date+"0000", date+"9999").
@@ -561,3 +583,91 @@ func (r *transaksiRepository) GetNotaDataDetail(ctx context.Context, tx *gorm.DB
 
 	return result, nil
 }
+
+func (r *transaksiRepository) GetTransaksiSyncByClientID(ctx context.Context, tx *gorm.DB, clientID string) (entity.TransaksiSync, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var sync entity.TransaksiSync
+	if err := tx.WithContext(ctx).Where("client_id = ?", clientID).Take(&sync).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return entity.TransaksiSync{}, false, nil
+		}
+		return entity.TransaksiSync{}, false, err
+	}
+
+	return sync, true, nil
+}
+
+// ReserveTransaksiSync inserts sync unless a row with its client_id already
+// exists, and reports whether it did. Concurrent uploads of the same
+// client_id race on the unique index, so only one of them gets to create
+// the transaksi. A pending row reserved before staleBefore was left by an
+// upload that died, and is taken over.
+func (r *transaksiRepository) ReserveTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync, staleBefore time.Time) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "client_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reserved_at", "tanggal_transaksi", "created_by", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+				SQL:  "transaksi_syncs.status = ? AND (transaksi_syncs.reserved_at IS NULL OR transaksi_syncs.reserved_at < ?)",
+				Vars: []any{constants.ENUM_SYNC_PENDING, staleBefore},
+			}}},
+		}).
+		Create(&sync)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateTransaksiSync writes the outcome of the upload that holds the
+// reservation of sync. It returns dto.ErrSyncInProgress when another upload
+// has taken the reservation over since.
+func (r *transaksiRepository) UpdateTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).
+		Model(&entity.TransaksiSync{}).
+		Where("client_id = ? AND status = ? AND reserved_at = ?", sync.ClientID, constants.ENUM_SYNC_PENDING, sync.ReservedAt).
+		Updates(map[string]any{
+			"status":       sync.Status,
+			"transaksi_id": sync.TransaksiID,
+			"message":      sync.Message,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dto.ErrSyncInProgress
+	}
+
+	return nil
+}
+
+// DeleteTransaksiSync releases the reservation of sync, unless another
+// upload has taken it over since.
+func (r *transaksiRepository) DeleteTransaksiSync(ctx context.Context, tx *gorm.DB, sync entity.TransaksiSync) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).
+		Unscoped().
+		Where("client_id = ? AND status = ? AND reserved_at = ?", sync.ClientID, constants.ENUM_SYNC_PENDING, sync.ReservedAt).
+		Delete(&entity.TransaksiSync{}).Error
+}
+
+// RunInTransaction runs fn in a transaction that is rolled back when fn
+// returns an error.
+func (r *transaksiRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
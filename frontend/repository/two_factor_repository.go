@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"bumisubur-be/entity"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	TwoFactorRepository interface {
+		SetTwoFactorSecret(ctx context.Context, tx *gorm.DB, userID int, secret string) error
+		EnableTwoFactor(ctx context.Context, tx *gorm.DB, userID int, counter int64) error
+		DisableTwoFactor(ctx context.Context, tx *gorm.DB, userID int) error
+		UseTOTPCounter(ctx context.Context, tx *gorm.DB, userID int, counter int64) (bool, error)
+
+		ReplaceRecoveryCodes(ctx context.Context, tx *gorm.DB, userID int, hashes []string) error
+		UseRecoveryCode(ctx context.Context, tx *gorm.DB, userID int, hash string) (bool, error)
+		CountRecoveryCodes(ctx context.Context, tx *gorm.DB, userID int) (int64, error)
+
+		CreateLoginChallenge(ctx context.Context, tx *gorm.DB, challenge entity.LoginChallenge) (entity.LoginChallenge, error)
+		GetLoginChallengeByHash(ctx context.Context, tx *gorm.DB, hash string) (entity.LoginChallenge, error)
+		AddLoginChallengeAttempt(ctx context.Context, tx *gorm.DB, challengeID int) error
+		UseLoginChallenge(ctx context.Context, tx *gorm.DB, challengeID int) (bool, error)
+
+		GetTwoFactorPolicies(ctx context.Context, tx *gorm.DB) ([]entity.TwoFactorPolicy, error)
+		ReplaceTwoFactorPolicies(ctx context.Context, tx *gorm.DB, roles []string) error
+		IsTwoFactorRequired(ctx context.Context, tx *gorm.DB, role string) (bool, error)
+
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
+	}
+
+	twoFactorRepository struct {
+		db *gorm.DB
+	}
+)
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{
+		db: db,
+	}
+}
+
+// SetTwoFactorSecret stores the secret of an enrollment that is not
+// confirmed yet. 2FA stays off until EnableTwoFactor.
+func (r *twoFactorRepository) SetTwoFactorSecret(ctx context.Context, tx *gorm.DB, userID int, secret string) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]any{
+			"two_factor_enabled":      false,
+			"two_factor_secret":       secret,
+			"two_factor_last_counter": 0,
+		}).Error
+}
+
+// EnableTwoFactor turns 2FA on with the stored secret. counter is the period
+// of the code that confirmed it.
+func (r *twoFactorRepository) EnableTwoFactor(ctx context.Context, tx *gorm.DB, userID int, counter int64) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]any{
+			"two_factor_enabled":      true,
+			"two_factor_last_counter": counter,
+		}).Error
+}
+
+// DisableTwoFactor turns 2FA off and drops the secret and recovery codes.
+func (r *twoFactorRepository) DisableTwoFactor(ctx context.Context, tx *gorm.DB, userID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{
+				"two_factor_enabled":      false,
+				"two_factor_secret":       "",
+				"two_factor_last_counter": 0,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPCounter records counter as the last accepted code. It returns false
+// when a code of that or a later period was already accepted.
+func (r *twoFactorRepository) UseTOTPCounter(ctx context.Context, tx *gorm.DB, userID int, counter int64) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND two_factor_last_counter < ?", userID, counter).
+		Update("two_factor_last_counter", counter)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ReplaceRecoveryCodes drops every recovery code of a user and stores the
+// new hashes.
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, tx *gorm.DB, userID int, hashes []string) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]entity.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, entity.RecoveryCode{
+				UserID:   userID,
+				CodeHash: hash,
+			})
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks the unused code with hash as used. It returns false
+// when the user has no such code left.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, tx *gorm.DB, userID int, hash string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user.
+func (r *twoFactorRepository) CountRecoveryCodes(ctx context.Context, tx *gorm.DB, userID int) (int64, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var count int64
+	err := tx.WithContext(ctx).Model(&entity.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *twoFactorRepository) CreateLoginChallenge(ctx context.Context, tx *gorm.DB, challenge entity.LoginChallenge) (entity.LoginChallenge, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Create(&challenge).Error; err != nil {
+		return entity.LoginChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+func (r *twoFactorRepository) GetLoginChallengeByHash(ctx context.Context, tx *gorm.DB, hash string) (entity.LoginChallenge, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var challenge entity.LoginChallenge
+	if err := tx.WithContext(ctx).Take(&challenge, "token_hash = ?", hash).Error; err != nil {
+		return entity.LoginChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// AddLoginChallengeAttempt counts a wrong code entered for a challenge.
+func (r *twoFactorRepository) AddLoginChallengeAttempt(ctx context.Context, tx *gorm.DB, challengeID int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.LoginChallenge{}).
+		Where("id = ?", challengeID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// UseLoginChallenge marks a challenge as used. It returns false when it was
+// already used, so a challenge logs in only once under concurrent use.
+func (r *twoFactorRepository) UseLoginChallenge(ctx context.Context, tx *gorm.DB, challengeID int) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.LoginChallenge{}).
+		Where("id = ? AND used_at IS NULL", challengeID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *twoFactorRepository) GetTwoFactorPolicies(ctx context.Context, tx *gorm.DB) ([]entity.TwoFactorPolicy, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var policies []entity.TwoFactorPolicy
+	if err := tx.WithContext(ctx).Order("role ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// ReplaceTwoFactorPolicies makes 2FA mandatory for exactly the given roles.
+func (r *twoFactorRepository) ReplaceTwoFactorPolicies(ctx context.Context, tx *gorm.DB, roles []string) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&entity.TwoFactorPolicy{}).Error; err != nil {
+			return err
+		}
+
+		if len(roles) == 0 {
+			return nil
+		}
+
+		policies := make([]entity.TwoFactorPolicy, 0, len(roles))
+		for _, role := range roles {
+			policies = append(policies, entity.TwoFactorPolicy{Role: role})
+		}
+
+		return tx.Create(&policies).Error
+	})
+}
+
+func (r *twoFactorRepository) IsTwoFactorRequired(ctx context.Context, tx *gorm.DB, role string) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var count int64
+	err := tx.WithContext(ctx).Model(&entity.TwoFactorPolicy{}).
+		Where("role = ?", role).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+func (r *twoFactorRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
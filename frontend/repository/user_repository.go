@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"math"
+	"time"
 
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
+	"bumisubur-be/utils"
 
 	"gorm.io/gorm"
 )
@@ -18,8 +21,18 @@ type (
 		GetUserById(ctx context.Context, tx *gorm.DB, userId int) (entity.User, error)
 		GetUserByEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, error)
 		CheckEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, bool, error)
+		CheckNIK(ctx context.Context, tx *gorm.DB, nik string) (entity.User, bool, error)
 		UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error)
 		DeleteUser(ctx context.Context, tx *gorm.DB, userId int) error
+		UpdatePassword(ctx context.Context, tx *gorm.DB, userId int, hashedPassword string, mustChange bool) error
+		UpdatePermissions(ctx context.Context, tx *gorm.DB, userId int, canExportPII bool) error
+
+		CreatePasswordReset(ctx context.Context, tx *gorm.DB, reset entity.PasswordReset) (entity.PasswordReset, error)
+		GetPasswordResetByHash(ctx context.Context, tx *gorm.DB, hash string) (entity.PasswordReset, error)
+		UsePasswordResets(ctx context.Context, tx *gorm.DB, userId int) error
+		UsePasswordReset(ctx context.Context, tx *gorm.DB, resetID int) (bool, error)
+
+		RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 	}
 
 	userRepository struct {
@@ -75,7 +88,9 @@ func (r *userRepository) GetAllUserWithPagination(ctx context.Context, tx *gorm.
 	query := tx.WithContext(ctx).Model(&entity.User{}).Where("role != ?", "owner")
 
 	if req.Search != "" {
-		query = query.Where("name LIKE ?", "%"+req.Search+"%")
+		// NIK is encrypted, so it only matches as a whole through its blind
+		// index.
+		query = query.Where("name LIKE ? OR nik_index = ?", "%"+req.Search+"%", utils.BlindIndex(req.Search))
 	}
 
 	err = query.Count(&count).Error
@@ -178,3 +193,110 @@ func (r *userRepository) DeleteUser(ctx context.Context, tx *gorm.DB, userId int
 
 	return nil
 }
+
+// CheckNIK finds the user with the given NIK through its blind index.
+func (r *userRepository) CheckNIK(ctx context.Context, tx *gorm.DB, nik string) (entity.User, bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	index := utils.BlindIndex(nik)
+	if index == "" {
+		return entity.User{}, false, nil
+	}
+
+	var user entity.User
+	err := tx.WithContext(ctx).Take(&user, "nik_index = ?", index).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.User{}, false, nil
+	}
+	if err != nil {
+		return entity.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+func (r *userRepository) UpdatePermissions(ctx context.Context, tx *gorm.DB, userId int, canExportPII bool) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", userId).
+		Update("can_export_pii", canExportPII).Error
+}
+
+// UpdatePassword stores a new password hash. mustChange marks whether the
+// user has to replace it at the next login.
+func (r *userRepository) UpdatePassword(ctx context.Context, tx *gorm.DB, userId int, hashedPassword string, mustChange bool) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{
+			"password":             hashedPassword,
+			"must_change_password": mustChange,
+			"password_changed_at":  time.Now(),
+		}).Error
+}
+
+func (r *userRepository) CreatePasswordReset(ctx context.Context, tx *gorm.DB, reset entity.PasswordReset) (entity.PasswordReset, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	if err := tx.WithContext(ctx).Create(&reset).Error; err != nil {
+		return entity.PasswordReset{}, err
+	}
+
+	return reset, nil
+}
+
+func (r *userRepository) GetPasswordResetByHash(ctx context.Context, tx *gorm.DB, hash string) (entity.PasswordReset, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	var reset entity.PasswordReset
+	if err := tx.WithContext(ctx).Take(&reset, "token_hash = ?", hash).Error; err != nil {
+		return entity.PasswordReset{}, err
+	}
+
+	return reset, nil
+}
+
+// UsePasswordResets marks every unused reset link of a user as used, so
+// only the newest link sent works.
+func (r *userRepository) UsePasswordResets(ctx context.Context, tx *gorm.DB, userId int) error {
+	if tx == nil {
+		tx = r.db
+	}
+
+	return tx.WithContext(ctx).Model(&entity.PasswordReset{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Update("used_at", time.Now()).Error
+}
+
+// UsePasswordReset marks a reset link as used. It returns false when the
+// link was already used, so a link works only once under concurrent use.
+func (r *userRepository) UsePasswordReset(ctx context.Context, tx *gorm.DB, resetID int) (bool, error) {
+	if tx == nil {
+		tx = r.db
+	}
+
+	result := tx.WithContext(ctx).Model(&entity.PasswordReset{}).
+		Where("id = ? AND used_at IS NULL", resetID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *userRepository) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
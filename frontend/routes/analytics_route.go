@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Analytics(route *gin.Engine, analyticsController controller.AnalyticsController, jwtService service.JWTService) {
+	routes := route.Group("/api/analytics")
+	{
+		routes.GET("/sales", middleware.Authenticate(jwtService), analyticsController.GetSalesAnalytics)
+		routes.GET("/products", middleware.Authenticate(jwtService), analyticsController.GetProductAnalytics)
+		routes.GET("/profit-loss", middleware.Authenticate(jwtService), analyticsController.GetProfitLoss)
+		routes.GET("/profit-loss/download", middleware.Authenticate(jwtService), analyticsController.DownloadProfitLoss)
+		routes.GET("/stock-aging", middleware.Authenticate(jwtService), analyticsController.GetStockAging)
+		routes.GET("/stock-aging/download", middleware.Authenticate(jwtService), analyticsController.DownloadStockAging)
+	}
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Export(route *gin.Engine, exportController controller.ExportController, jwtService service.JWTService) {
+	routes := route.Group("/api/export")
+	{
+		routes.POST("", middleware.Authenticate(jwtService), exportController.CreateExport)
+		routes.GET("", middleware.Authenticate(jwtService), exportController.GetExports)
+		routes.GET("/:export_id", middleware.Authenticate(jwtService), exportController.GetExport)
+		routes.GET("/:export_id/download", middleware.Authenticate(jwtService), exportController.DownloadExport)
+	}
+}
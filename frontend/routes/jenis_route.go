@@ -13,8 +13,9 @@ func Jenis(route *gin.Engine, supplierController controller.SupplierController,
 	{
 		routes.POST("", middleware.Authenticate(jwtService), supplierController.CreateJenis)
 		routes.GET("", middleware.Authenticate(jwtService), supplierController.GetAllJenis)
-		// routes.GET("/:supplier_id", middleware.Authenticate(jwtService), supplierController.GetSupplierByID)
-		// routes.PATCH("/:supplier_id", middleware.Authenticate(jwtService), supplierController.UpdateSupplier)
+		routes.POST("/merge", middleware.Authenticate(jwtService), supplierController.MergeJenis)
+		routes.GET("/:jenis_id", middleware.Authenticate(jwtService), supplierController.GetJenisByID)
+		routes.PATCH("/:jenis_id", middleware.Authenticate(jwtService), supplierController.UpdateJenis)
 		routes.DELETE("/:jenis_id", middleware.Authenticate(jwtService), supplierController.DeleteJenis)
 	}
 }
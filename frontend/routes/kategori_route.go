@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Kategori(route *gin.Engine, kategoriController controller.KategoriController, jwtService service.JWTService) {
+	routes := route.Group("/api/kategori")
+	{
+		routes.GET("", middleware.Authenticate(jwtService), kategoriController.GetKategoriTree)
+		routes.PATCH("/:jenis_id", middleware.Authenticate(jwtService), kategoriController.UpdateKategori)
+		routes.GET("/:jenis_id/atribut", middleware.Authenticate(jwtService), kategoriController.GetKategoriAttributes)
+	}
+
+	atribut := route.Group("/api/atribut")
+	{
+		atribut.GET("", middleware.Authenticate(jwtService), kategoriController.GetAllAttributeSets)
+		atribut.POST("", middleware.Authenticate(jwtService), kategoriController.CreateAttributeSet)
+		atribut.GET("/:set_id", middleware.Authenticate(jwtService), kategoriController.GetAttributeSetByID)
+		atribut.PATCH("/:set_id", middleware.Authenticate(jwtService), kategoriController.UpdateAttributeSet)
+		atribut.DELETE("/:set_id", middleware.Authenticate(jwtService), kategoriController.DeleteAttributeSet)
+	}
+}
@@ -13,5 +13,8 @@ func LogAkses(route *gin.Engine, aksesController controller.LogAksesController,
 	{
 		routes.GET("", middleware.Authenticate(jwtService), aksesController.GetAll)
 		routes.GET("/download", middleware.Authenticate(jwtService), aksesController.Download)
+		routes.GET("/stats", middleware.Authenticate(jwtService), aksesController.Stats)
+		routes.GET("/sessions", middleware.Authenticate(jwtService), aksesController.GetSessions)
+		routes.GET("/sessions/:log_akses_id", middleware.Authenticate(jwtService), aksesController.GetSession)
 	}
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Merk(route *gin.Engine, merkController controller.MerkController, jwtService service.JWTService) {
+	routes := route.Group("/api/merk")
+	{
+		routes.GET("", middleware.Authenticate(jwtService), merkController.GetAllMerk)
+		routes.POST("", middleware.Authenticate(jwtService), merkController.CreateMerk)
+		routes.POST("/merge", middleware.Authenticate(jwtService), merkController.MergeMerk)
+		routes.GET("/:merk_id", middleware.Authenticate(jwtService), merkController.GetMerkByID)
+		routes.PATCH("/:merk_id", middleware.Authenticate(jwtService), merkController.UpdateMerk)
+		routes.DELETE("/:merk_id", middleware.Authenticate(jwtService), merkController.DeleteMerk)
+	}
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Notification(route *gin.Engine, notificationController controller.NotificationController, jwtService service.JWTService) {
+	routes := route.Group("/api/notification")
+	{
+		routes.GET("/rules", middleware.Authenticate(jwtService), notificationController.GetRules)
+		routes.POST("/rules", middleware.Authenticate(jwtService), notificationController.CreateRule)
+		routes.PATCH("/rules/:rule_id", middleware.Authenticate(jwtService), notificationController.UpdateRule)
+		routes.DELETE("/rules/:rule_id", middleware.Authenticate(jwtService), notificationController.DeleteRule)
+		routes.GET("/outbox", middleware.Authenticate(jwtService), notificationController.GetOutbox)
+	}
+}
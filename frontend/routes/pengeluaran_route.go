@@ -8,10 +8,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Pengeluaran(route *gin.Engine, pengeluaranController controller.PengeluaranController, jwtService service.JWTService) {
+func Pengeluaran(route *gin.Engine, pengeluaranController controller.PengeluaranController, jwtService service.JWTService, idempotencyService service.IdempotencyService) {
 	routes := route.Group("/api/pengeluaran")
 	{
-		routes.POST("", middleware.Authenticate(jwtService), pengeluaranController.CreatePengeluaran)
+		routes.POST("", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), pengeluaranController.CreatePengeluaran)
 		routes.GET("", middleware.Authenticate(jwtService), pengeluaranController.GetAllPengeluaran)
 		routes.GET("/:pengeluaran_id", middleware.Authenticate(jwtService), pengeluaranController.GetPengeluaranByID)
 		routes.PATCH("/:pengeluaran_id", middleware.Authenticate(jwtService), pengeluaranController.UpdatePengeluaran)
@@ -8,7 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Produk(route *gin.Engine, produkController controller.ProdukController, jwtService service.JWTService) {
+func Produk(route *gin.Engine, produkController controller.ProdukController, jwtService service.JWTService, idempotencyService service.IdempotencyService) {
 	routes := route.Group("/api/produk")
 	{
 		// User
@@ -19,18 +19,21 @@ func Produk(route *gin.Engine, produkController controller.ProdukController, jwt
 		routes.GET("/index", middleware.Authenticate(jwtService), produkController.IndexRestokProduk)
 		routes.GET("/index-old", middleware.Authenticate(jwtService), produkController.IndexOldProduk)
 
-		routes.POST("/create", middleware.Authenticate(jwtService), produkController.CreateProduk)
-		routes.POST("/create-old", middleware.Authenticate(jwtService), produkController.CreateOldProduk)
+		routes.POST("/create", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), produkController.CreateProduk)
+		routes.POST("/create-old", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), produkController.CreateOldProduk)
+		routes.POST("/import", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), produkController.ImportProduk)
+		routes.GET("/import/template", middleware.Authenticate(jwtService), produkController.DownloadImportTemplate)
 
 		routes.GET("/pending", middleware.Authenticate(jwtService), produkController.GetPendingProduks)
 		routes.GET("/pending/:id", middleware.Authenticate(jwtService), produkController.GetDetailedPendingProduks)
 		routes.PATCH("/pending", middleware.Authenticate(jwtService), produkController.UpdateDetailedPendingProduks)
 		routes.DELETE("/pending/:id", middleware.Authenticate(jwtService), produkController.DeleteDetailedPendingProduks)
-		routes.POST("/pending/insert/:id", middleware.Authenticate(jwtService), produkController.InsertProduk)
+		routes.POST("/pending/insert/:id", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), produkController.InsertProduk)
 
 		routes.GET("/restok-history", middleware.Authenticate(jwtService), produkController.GetAllRestok)
 		routes.GET("/index-final-stok", middleware.Authenticate(jwtService), produkController.GetIndexFinalStok)
 		routes.GET("/final-stok", middleware.Authenticate(jwtService), produkController.FinalStokProduk)
+		routes.GET("/final-stok/download", middleware.Authenticate(jwtService), produkController.DownloadFinalStok)
 
 	}
 }
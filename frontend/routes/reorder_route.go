@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Reorder(route *gin.Engine, reorderController controller.ReorderController, jwtService service.JWTService) {
+	routes := route.Group("/api/reorder")
+	{
+		routes.GET("/rules", middleware.Authenticate(jwtService), reorderController.GetReorderRules)
+		routes.PUT("/rules", middleware.Authenticate(jwtService), reorderController.SaveReorderRule)
+		routes.DELETE("/rules/:rule_id", middleware.Authenticate(jwtService), reorderController.DeleteReorderRule)
+		routes.PATCH("/variant/:detail_produk_id", middleware.Authenticate(jwtService), reorderController.UpdateVariantReorder)
+		routes.GET("/replenishment", middleware.Authenticate(jwtService), reorderController.GetReplenishment)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"bumisubur-be/controller"
+	"bumisubur-be/middleware"
+	"bumisubur-be/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportSchedule(route *gin.Engine, reportScheduleController controller.ReportScheduleController, jwtService service.JWTService) {
+	routes := route.Group("/api/report-schedule")
+	{
+		routes.GET("", middleware.Authenticate(jwtService), reportScheduleController.GetSchedules)
+		routes.POST("", middleware.Authenticate(jwtService), reportScheduleController.CreateSchedule)
+		routes.PATCH("/:schedule_id", middleware.Authenticate(jwtService), reportScheduleController.UpdateSchedule)
+		routes.DELETE("/:schedule_id", middleware.Authenticate(jwtService), reportScheduleController.DeleteSchedule)
+		routes.POST("/:schedule_id/run", middleware.Authenticate(jwtService), reportScheduleController.RunNow)
+		routes.GET("/:schedule_id/runs", middleware.Authenticate(jwtService), reportScheduleController.GetRuns)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Return(route *gin.Engine, returnController controller.ReturnController, jwtService service.JWTService) {
+func Return(route *gin.Engine, returnController controller.ReturnController, jwtService service.JWTService, idempotencyService service.IdempotencyService) {
 	routes := route.Group("/api/return")
 	{
 		// User
@@ -18,8 +18,8 @@ func Return(route *gin.Engine, returnController controller.ReturnController, jwt
 		routes.GET("/history/user", middleware.Authenticate(jwtService), returnController.GetHistoryRestokUser)
 		routes.GET("/history/supplier", middleware.Authenticate(jwtService), returnController.GetHistoryRestokSupplier)
 
-		routes.POST("/user", middleware.Authenticate(jwtService), returnController.CreateReturnUser)
-		routes.POST("/supplier", middleware.Authenticate(jwtService), returnController.CreateReturnSupplier)
+		routes.POST("/user", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), returnController.CreateReturnUser)
+		routes.POST("/supplier", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), returnController.CreateReturnSupplier)
 
 	}
 }
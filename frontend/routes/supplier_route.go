@@ -18,8 +18,11 @@ func Supplier(route *gin.Engine, supplierController controller.SupplierControlle
 		routes.GET("/:supplier_id", middleware.Authenticate(jwtService), supplierController.GetSupplierByID)
 		routes.PATCH("/:supplier_id", middleware.Authenticate(jwtService), supplierController.UpdateSupplier)
 		routes.PATCH("/supply/:supplier_id", middleware.Authenticate(jwtService), supplierController.UpdateSupplierSupply)
+		routes.GET("/supply/:supplier_id/history", middleware.Authenticate(jwtService), supplierController.GetSupplyDiscounts)
 		routes.DELETE("/:supplier_id", middleware.Authenticate(jwtService), supplierController.DeleteSupplier)
 		routes.GET("/download", middleware.Authenticate(jwtService), supplierController.DownloadDataSupplier)
+		routes.POST("/import", middleware.Authenticate(jwtService), supplierController.ImportSupplier)
+		routes.GET("/import/template", middleware.Authenticate(jwtService), supplierController.DownloadImportTemplate)
 
 	}
 }
@@ -8,11 +8,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Transaksi(route *gin.Engine, transaksiController controller.TransaksiController, jwtService service.JWTService) {
+func Transaksi(route *gin.Engine, transaksiController controller.TransaksiController, jwtService service.JWTService, idempotencyService service.IdempotencyService) {
 	routes := route.Group("/api/transaksi")
 	{
-		routes.GET("/print/:id", transaksiController.PrintMobile)
-		routes.POST("", middleware.Authenticate(jwtService), transaksiController.CreateTransaksi)
+		routes.GET("/print/:id", middleware.Authenticate(jwtService), transaksiController.PrintMobile)
+		routes.POST("", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), transaksiController.CreateTransaksi)
+		routes.POST("/sync", middleware.Authenticate(jwtService), middleware.Idempotency(idempotencyService), transaksiController.SyncTransaksi)
 		routes.GET("", middleware.Authenticate(jwtService), transaksiController.GetHistoryTransaksi)
 		routes.GET("/index", middleware.Authenticate(jwtService), transaksiController.Index)
 		routes.GET("/download", middleware.Authenticate(jwtService), transaksiController.DownloadData)
@@ -13,10 +13,32 @@ func User(route *gin.Engine, userController controller.UserController, jwtServic
 	{
 		// User
 		routes.POST("/login", userController.Login)
+		routes.POST("/login/2fa", userController.LoginTwoFactor)
+		routes.POST("/login/2fa/setup", userController.SetupTwoFactorLogin)
+		routes.POST("/refresh", userController.Refresh)
+		routes.POST("/first-login", userController.FirstLoginPassword)
+		routes.POST("/forgot-password", userController.ForgotPassword)
+		routes.POST("/reset-password", userController.ResetPassword)
+		routes.POST("/logout", middleware.Authenticate(jwtService), userController.Logout)
+		routes.POST("/logout-all", middleware.Authenticate(jwtService), userController.LogoutAll)
 		routes.POST("", middleware.Authenticate(jwtService), userController.Register)
 		routes.GET("", middleware.Authenticate(jwtService), userController.GetAllUser)
 		routes.GET("/me", middleware.Authenticate(jwtService), userController.Me)
+		routes.PATCH("/me", middleware.Authenticate(jwtService), userController.UpdateMe)
+		routes.PATCH("/me/password", middleware.Authenticate(jwtService), middleware.SkipPayloadLog(), userController.ChangePassword)
+		routes.GET("/me/2fa", middleware.Authenticate(jwtService), userController.TwoFactorStatus)
+		routes.POST("/me/2fa/enroll", middleware.Authenticate(jwtService), userController.EnrollTwoFactor)
+		routes.POST("/me/2fa/confirm", middleware.Authenticate(jwtService), middleware.SkipPayloadLog(), userController.ConfirmTwoFactor)
+		routes.POST("/me/2fa/disable", middleware.Authenticate(jwtService), middleware.SkipPayloadLog(), userController.DisableTwoFactor)
+		routes.POST("/me/2fa/recovery-codes", middleware.Authenticate(jwtService), middleware.SkipPayloadLog(), userController.RegenerateRecoveryCodes)
+		routes.GET("/2fa/policy", middleware.Authenticate(jwtService), userController.GetTwoFactorPolicy)
+		routes.PUT("/2fa/policy", middleware.Authenticate(jwtService), userController.UpdateTwoFactorPolicy)
 		routes.DELETE("/:user_id", middleware.Authenticate(jwtService), userController.Delete)
+		routes.DELETE("/:user_id/sessions", middleware.Authenticate(jwtService), userController.RevokeSessions)
+		routes.POST("/:user_id/reset-password", middleware.Authenticate(jwtService), middleware.SkipPayloadLog(), userController.AdminResetPassword)
+		routes.POST("/:user_id/unlock", middleware.Authenticate(jwtService), userController.Unlock)
+		routes.PUT("/:user_id/permissions", middleware.Authenticate(jwtService), userController.UpdatePermissions)
+		routes.DELETE("/:user_id/2fa", middleware.Authenticate(jwtService), userController.ResetTwoFactor)
 		routes.PATCH("/:user_id", middleware.Authenticate(jwtService), userController.Update)
 		routes.GET("/:user_id", middleware.Authenticate(jwtService), userController.GetUserById)
 		routes.GET("/download", middleware.Authenticate(jwtService), userController.DownloadDataKaryawan)
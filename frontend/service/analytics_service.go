@@ -0,0 +1,733 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	ANALYTICS_MAX_BUCKETS   = 1000
+	ANALYTICS_DEFAULT_LIMIT = 10
+	ANALYTICS_MAX_LIMIT     = 100
+
+	PROFIT_LOSS_MAX_MONTHS = 36
+
+	STOCK_AGING_DEFAULT_NO_SALES_DAYS = 60
+	// Variants that still sell but would need more than this many days to
+	// sell through at the current pace are reported as slow movers.
+	STOCK_SLOW_DAYS_OF_COVER = 90
+)
+
+// stockAgingBuckets are the upper bounds in days since receipt; the last
+// bucket takes everything older.
+var stockAgingBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{"0-30", 30},
+	{"31-60", 60},
+	{"61-90", 90},
+	{"90+", -1},
+}
+
+var analyticsBucketSize = map[string]time.Duration{
+	constants.ENUM_BUCKET_HOUR:  time.Hour,
+	constants.ENUM_BUCKET_DAY:   24 * time.Hour,
+	constants.ENUM_BUCKET_WEEK:  7 * 24 * time.Hour,
+	constants.ENUM_BUCKET_MONTH: 28 * 24 * time.Hour,
+}
+
+type (
+	AnalyticsService interface {
+		GetSalesAnalytics(ctx context.Context, req dto.AnalyticsRequest) (dto.SalesAnalyticsResponse, error)
+		GetProductAnalytics(ctx context.Context, req dto.AnalyticsRequest) (dto.ProductAnalyticsResponse, error)
+		GetProfitLoss(ctx context.Context, req dto.AnalyticsRequest) (dto.ProfitLossResponse, error)
+		DownloadProfitLoss(ctx context.Context, req dto.AnalyticsRequest, format string) ([]byte, error)
+		GetStockAging(ctx context.Context, req dto.StockAgingRequest) (dto.StockAgingResponse, error)
+		DownloadStockAging(ctx context.Context, req dto.StockAgingRequest, format string) ([]byte, error)
+	}
+
+	// profitLossTotals collects the repository rows of a single month before
+	// they are turned into a statement.
+	profitLossTotals struct {
+		sales    float64
+		refunds  float64
+		cogs     float64
+		expenses map[string]float64
+	}
+
+	analyticsService struct {
+		analyticsRepo repository.AnalyticsRepository
+	}
+)
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository) AnalyticsService {
+	return &analyticsService{
+		analyticsRepo: analyticsRepo,
+	}
+}
+
+func (s *analyticsService) GetSalesAnalytics(ctx context.Context, req dto.AnalyticsRequest) (dto.SalesAnalyticsResponse, error) {
+	current, previous, err := resolveAnalyticsPeriod(req)
+	if err != nil {
+		return dto.SalesAnalyticsResponse{}, err
+	}
+
+	bucket := req.Bucket
+	if bucket == "" {
+		bucket = constants.ENUM_BUCKET_DAY
+		if req.Range == "today" {
+			bucket = constants.ENUM_BUCKET_HOUR
+		}
+	}
+
+	size, ok := analyticsBucketSize[bucket]
+	if !ok {
+		return dto.SalesAnalyticsResponse{}, dto.ErrAnalyticsInvalidBucket
+	}
+
+	if current.End.Sub(current.Start)/size > ANALYTICS_MAX_BUCKETS {
+		return dto.SalesAnalyticsResponse{}, dto.ErrAnalyticsTooManyBuckets
+	}
+
+	summary, err := s.analyticsRepo.GetSalesSummary(ctx, nil, current)
+	if err != nil {
+		return dto.SalesAnalyticsResponse{}, err
+	}
+
+	previousSummary, err := s.analyticsRepo.GetSalesSummary(ctx, nil, previous)
+	if err != nil {
+		return dto.SalesAnalyticsResponse{}, err
+	}
+
+	series, err := s.analyticsRepo.GetSalesSeries(ctx, nil, current, bucket)
+	if err != nil {
+		return dto.SalesAnalyticsResponse{}, err
+	}
+
+	summary = completeSalesSummary(summary)
+	previousSummary = completeSalesSummary(previousSummary)
+	for i := range series {
+		series[i].SalesSummary = completeSalesSummary(series[i].SalesSummary)
+	}
+
+	return dto.SalesAnalyticsResponse{
+		Bucket:         bucket,
+		Period:         dto.AnalyticsPeriod{Start: current.Start, End: current.End},
+		PreviousPeriod: dto.AnalyticsPeriod{Start: previous.Start, End: previous.End},
+		Summary:        summary,
+		Previous:       previousSummary,
+		Comparison: dto.SalesComparison{
+			GrossSales:       percentChange(summary.GrossSales, previousSummary.GrossSales),
+			Discount:         percentChange(summary.Discount, previousSummary.Discount),
+			NetSales:         percentChange(summary.NetSales, previousSummary.NetSales),
+			COGS:             percentChange(summary.COGS, previousSummary.COGS),
+			GrossMargin:      percentChange(summary.GrossMargin, previousSummary.GrossMargin),
+			TransactionCount: percentChange(float64(summary.TransactionCount), float64(previousSummary.TransactionCount)),
+			ItemsSold:        percentChange(float64(summary.ItemsSold), float64(previousSummary.ItemsSold)),
+			AverageBasket:    percentChange(summary.AverageBasket, previousSummary.AverageBasket),
+		},
+		Series: series,
+	}, nil
+}
+
+func (s *analyticsService) GetProductAnalytics(ctx context.Context, req dto.AnalyticsRequest) (dto.ProductAnalyticsResponse, error) {
+	current, previous, err := resolveAnalyticsPeriod(req)
+	if err != nil {
+		return dto.ProductAnalyticsResponse{}, err
+	}
+
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = constants.ENUM_GROUP_BY_PRODUK
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = ANALYTICS_DEFAULT_LIMIT
+	}
+	if limit > ANALYTICS_MAX_LIMIT {
+		limit = ANALYTICS_MAX_LIMIT
+	}
+
+	top, err := s.analyticsRepo.GetProductRanking(ctx, nil, current, previous, groupBy, false, limit)
+	if err != nil {
+		return dto.ProductAnalyticsResponse{}, err
+	}
+
+	bottom, err := s.analyticsRepo.GetProductRanking(ctx, nil, current, previous, groupBy, true, limit)
+	if err != nil {
+		return dto.ProductAnalyticsResponse{}, err
+	}
+
+	return dto.ProductAnalyticsResponse{
+		GroupBy:        groupBy,
+		Period:         dto.AnalyticsPeriod{Start: current.Start, End: current.End},
+		PreviousPeriod: dto.AnalyticsPeriod{Start: previous.Start, End: previous.End},
+		Top:            top,
+		Bottom:         bottom,
+	}, nil
+}
+
+func (s *analyticsService) GetProfitLoss(ctx context.Context, req dto.AnalyticsRequest) (dto.ProfitLossResponse, error) {
+	current, _, err := resolveAnalyticsPeriod(req)
+	if err != nil {
+		return dto.ProfitLossResponse{}, err
+	}
+
+	firstMonth := time.Date(current.Start.Year(), current.Start.Month(), 1, 0, 0, 0, 0, current.Start.Location())
+
+	var monthStarts []time.Time
+	for m := firstMonth; !m.After(current.End); m = m.AddDate(0, 1, 0) {
+		monthStarts = append(monthStarts, m)
+	}
+	if len(monthStarts) > PROFIT_LOSS_MAX_MONTHS {
+		return dto.ProfitLossResponse{}, dto.ErrProfitLossTooManyMonths
+	}
+
+	totals, err := s.getProfitLossTotals(ctx, current)
+	if err != nil {
+		return dto.ProfitLossResponse{}, err
+	}
+
+	// The full month before the period is only loaded so the first column
+	// has something to compare against.
+	base := dto.AnalyticsFilter{Start: firstMonth.AddDate(0, -1, 0), End: firstMonth.Add(-time.Second), Cabang: current.Cabang}
+	baseTotals, err := s.getProfitLossTotals(ctx, base)
+	if err != nil {
+		return dto.ProfitLossResponse{}, err
+	}
+
+	kategoriSet := map[string]bool{}
+	for _, month := range totals {
+		for kategori := range month.expenses {
+			kategoriSet[kategori] = true
+		}
+	}
+	kategori := make([]string, 0, len(kategoriSet))
+	for k := range kategoriSet {
+		kategori = append(kategori, k)
+	}
+	sort.Strings(kategori)
+
+	previous := baseTotals[base.Start.Format("2006-01")].statement(nil)
+	sum := &profitLossTotals{expenses: map[string]float64{}}
+
+	months := make([]dto.ProfitLossMonth, 0, len(monthStarts))
+	for _, start := range monthStarts {
+		key := start.Format("2006-01")
+		month := totals[key]
+		statement := month.statement(kategori)
+
+		end := start.AddDate(0, 1, 0).Add(-time.Second)
+		if start.Before(current.Start) {
+			start = current.Start
+		}
+		if end.After(current.End) {
+			end = current.End
+		}
+
+		months = append(months, dto.ProfitLossMonth{
+			Month:               key,
+			Start:               start,
+			End:                 end,
+			NetSalesChange:      percentChange(statement.NetSales, previous.NetSales),
+			NetProfitChange:     percentChange(statement.NetProfit, previous.NetProfit),
+			ProfitLossStatement: statement,
+		})
+
+		sum.add(month)
+		previous = statement
+	}
+
+	return dto.ProfitLossResponse{
+		Period: dto.AnalyticsPeriod{Start: current.Start, End: current.End},
+		Cabang: current.Cabang,
+		Months: months,
+		Total:  sum.statement(kategori),
+	}, nil
+}
+
+func (s *analyticsService) DownloadProfitLoss(ctx context.Context, req dto.AnalyticsRequest, format string) ([]byte, error) {
+	report, err := s.GetProfitLoss(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	cabang := "Semua Cabang"
+	if report.Cabang != 0 {
+		cabang = fmt.Sprintf("Cabang %d", report.Cabang)
+	}
+
+	table := utils.Table{
+		Title: "Laba Rugi",
+		Subtitle: []string{
+			fmt.Sprintf("Periode %s - %s", report.Period.Start.Format("02-01-2006"), report.Period.End.Format("02-01-2006")),
+			cabang,
+		},
+		Columns: []utils.Column{{Header: "Keterangan"}},
+	}
+
+	columns := make([]dto.ProfitLossStatement, 0, len(report.Months)+1)
+	for _, month := range report.Months {
+		table.Columns = append(table.Columns, utils.Column{Header: month.Month, Type: utils.ColumnMoney})
+		columns = append(columns, month.ProfitLossStatement)
+	}
+	table.Columns = append(table.Columns, utils.Column{Header: "Total", Type: utils.ColumnMoney})
+	columns = append(columns, report.Total)
+
+	return utils.RenderTable(format, table, func(tw utils.TableWriter) error {
+		var err error
+		writeRow := func(label string, value func(dto.ProfitLossStatement) any) {
+			if err != nil {
+				return
+			}
+			values := []any{label}
+			if value != nil {
+				for _, column := range columns {
+					values = append(values, value(column))
+				}
+			}
+			err = tw.WriteRow(values...)
+		}
+
+		writeRow("Penjualan", func(p dto.ProfitLossStatement) any { return p.Sales })
+		writeRow("Retur Penjualan", func(p dto.ProfitLossStatement) any { return -p.Refunds })
+		writeRow("Penjualan Bersih", func(p dto.ProfitLossStatement) any { return p.NetSales })
+		writeRow("Harga Pokok Penjualan", func(p dto.ProfitLossStatement) any { return -p.COGS })
+		writeRow("Laba Kotor", func(p dto.ProfitLossStatement) any { return p.GrossProfit })
+		writeRow("", nil)
+
+		writeRow("Beban Operasional", nil)
+		for i, expense := range report.Total.Expenses {
+			i := i
+			writeRow("  "+expense.Kategori, func(p dto.ProfitLossStatement) any { return -p.Expenses[i].Jumlah })
+		}
+		writeRow("Total Beban", func(p dto.ProfitLossStatement) any { return -p.TotalExpenses })
+		writeRow("", nil)
+
+		writeRow("Laba Bersih", func(p dto.ProfitLossStatement) any { return p.NetProfit })
+		if err != nil {
+			return err
+		}
+
+		// The change is a percentage, so it goes in as text rather than
+		// being rounded by the money format of the month columns.
+		change := []any{"Perubahan Laba Bersih (%)"}
+		for _, month := range report.Months {
+			var value any
+			if month.NetProfitChange != nil {
+				value = fmt.Sprintf("%.1f%%", *month.NetProfitChange)
+			}
+			change = append(change, value)
+		}
+		return tw.WriteRow(change...)
+	})
+}
+
+// GetStockAging spreads the stock of each variant over its restok receipts,
+// assuming the oldest units were sold first, and buckets them by days since
+// receipt. Stock that is not covered by any restok (produk entered through
+// create-old) is aged from the moment the variant was created.
+func (s *analyticsService) GetStockAging(ctx context.Context, req dto.StockAgingRequest) (dto.StockAgingResponse, error) {
+	switch req.Status {
+	case "", constants.ENUM_STOCK_ACTIVE, constants.ENUM_STOCK_SLOW, constants.ENUM_STOCK_DEAD:
+	default:
+		return dto.StockAgingResponse{}, dto.ErrStockAgingInvalidStatus
+	}
+
+	noSalesDays := req.NoSalesDays
+	if noSalesDays <= 0 {
+		noSalesDays = STOCK_AGING_DEFAULT_NO_SALES_DAYS
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -noSalesDays)
+
+	variants, err := s.analyticsRepo.GetStockAgingVariants(ctx, nil, req, since)
+	if err != nil {
+		return dto.StockAgingResponse{}, err
+	}
+
+	ids := make([]int, 0, len(variants))
+	for _, variant := range variants {
+		ids = append(ids, variant.DetailProdukID)
+	}
+
+	receiptRows, err := s.analyticsRepo.GetStockReceipts(ctx, nil, ids)
+	if err != nil {
+		return dto.StockAgingResponse{}, err
+	}
+
+	receipts := map[int][]dto.StockReceiptRow{}
+	for _, row := range receiptRows {
+		receipts[row.DetailProdukID] = append(receipts[row.DetailProdukID], row)
+	}
+
+	daysSince := func(t time.Time) int {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, today.Location())
+		days := int(today.Sub(day).Hours() / 24)
+		if days < 0 {
+			return 0
+		}
+		return days
+	}
+
+	result := dto.StockAgingResponse{
+		NoSalesDays: noSalesDays,
+		Aging:       newStockAgingBuckets(),
+		Items:       []dto.StockAgingItem{},
+	}
+
+	for _, variant := range variants {
+		item := dto.StockAgingItem{
+			DetailProdukID: variant.DetailProdukID,
+			ProdukID:       variant.ProdukID,
+			NamaProduk:     variant.NamaProduk,
+			BarcodeID:      variant.BarcodeID,
+			Merk:           variant.Merk,
+			Jenis:          variant.Jenis,
+			CabangID:       variant.CabangID,
+			Ukuran:         variant.Ukuran,
+			Warna:          variant.Warna,
+			Stok:           variant.Stok,
+			HargaBeli:      variant.HargaBeli,
+			Value:          float64(variant.Stok) * variant.HargaBeli,
+			LastSoldAt:     variant.LastSoldAt,
+			SoldInWindow:   variant.SoldInWindow,
+			Aging:          newStockAgingBuckets(),
+		}
+
+		remaining := variant.Stok
+		for _, receipt := range receipts[variant.DetailProdukID] {
+			if remaining == 0 {
+				break
+			}
+
+			qty := receipt.Jumlah
+			if qty > remaining {
+				qty = remaining
+			}
+			if qty <= 0 {
+				continue
+			}
+
+			addStockAging(item.Aging, daysSince(receipt.TanggalRestok), qty, variant.HargaBeli)
+			item.OldestReceipt = receipt.TanggalRestok
+			remaining -= qty
+		}
+
+		if remaining > 0 {
+			addStockAging(item.Aging, daysSince(variant.CreatedAt), remaining, variant.HargaBeli)
+			if item.OldestReceipt.IsZero() || variant.CreatedAt.Before(item.OldestReceipt) {
+				item.OldestReceipt = variant.CreatedAt
+			}
+		}
+
+		if variant.LastSoldAt != nil {
+			days := daysSince(*variant.LastSoldAt)
+			item.DaysSinceLastSale = &days
+		}
+
+		switch {
+		case variant.LastSoldAt == nil || variant.LastSoldAt.Before(since) || variant.SoldInWindow <= 0:
+			item.Status = constants.ENUM_STOCK_DEAD
+		default:
+			cover := float64(variant.Stok) * float64(noSalesDays) / float64(variant.SoldInWindow)
+			item.DaysOfCover = &cover
+			item.Status = constants.ENUM_STOCK_ACTIVE
+			if cover > STOCK_SLOW_DAYS_OF_COVER {
+				item.Status = constants.ENUM_STOCK_SLOW
+			}
+		}
+
+		if req.Status != "" && item.Status != req.Status {
+			continue
+		}
+
+		result.TotalQty += item.Stok
+		result.TotalValue += item.Value
+		switch item.Status {
+		case constants.ENUM_STOCK_DEAD:
+			result.DeadQty += item.Stok
+			result.DeadValue += item.Value
+		case constants.ENUM_STOCK_SLOW:
+			result.SlowQty += item.Stok
+			result.SlowValue += item.Value
+		}
+		for i, bucket := range item.Aging {
+			result.Aging[i].Qty += bucket.Qty
+			result.Aging[i].Value += bucket.Value
+		}
+
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+func (s *analyticsService) DownloadStockAging(ctx context.Context, req dto.StockAgingRequest, format string) ([]byte, error) {
+	report, err := s.GetStockAging(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	table := utils.Table{
+		Title: "Umur Stok",
+		Columns: []utils.Column{
+			{Header: "Barcode"},
+			{Header: "Nama Produk"},
+			{Header: "Merk"},
+			{Header: "Jenis"},
+			{Header: "Ukuran"},
+			{Header: "Warna"},
+			{Header: "Stok", Type: utils.ColumnInt},
+			{Header: "Harga Beli", Type: utils.ColumnMoney},
+			{Header: "Nilai Stok", Type: utils.ColumnMoney},
+		},
+	}
+	for _, bucket := range stockAgingBuckets {
+		table.Columns = append(table.Columns, utils.Column{Header: bucket.label + " hari", Type: utils.ColumnInt})
+	}
+	table.Columns = append(table.Columns,
+		utils.Column{Header: "Terakhir Terjual", Type: utils.ColumnDate},
+		utils.Column{Header: "Hari Tanpa Penjualan", Type: utils.ColumnInt},
+		utils.Column{Header: fmt.Sprintf("Terjual %d Hari", report.NoSalesDays), Type: utils.ColumnInt},
+		utils.Column{Header: "Status"},
+	)
+
+	return utils.RenderTable(format, table, func(tw utils.TableWriter) error {
+		for _, item := range report.Items {
+			values := []any{item.BarcodeID, item.NamaProduk, item.Merk, item.Jenis, item.Ukuran, item.Warna, item.Stok, item.HargaBeli, item.Value}
+			for _, bucket := range item.Aging {
+				values = append(values, bucket.Qty)
+			}
+			values = append(values, item.LastSoldAt, item.DaysSinceLastSale, item.SoldInWindow, item.Status)
+
+			if err := tw.WriteRow(values...); err != nil {
+				return err
+			}
+		}
+
+		totals := []any{"Total", nil, nil, nil, nil, nil, report.TotalQty, nil, report.TotalValue}
+		for _, bucket := range report.Aging {
+			totals = append(totals, bucket.Qty)
+		}
+		if err := tw.WriteRow(totals...); err != nil {
+			return err
+		}
+
+		// The summary keeps its values in the Nilai Stok column so they are
+		// formatted as money.
+		summary := [][]any{{}, {"Modal Tertahan"}}
+		for _, bucket := range report.Aging {
+			summary = append(summary, []any{bucket.Label + " hari", nil, nil, nil, nil, nil, nil, nil, bucket.Value})
+		}
+		summary = append(summary,
+			[]any{"Dead Stock", nil, nil, nil, nil, nil, nil, nil, report.DeadValue},
+			[]any{"Slow Mover", nil, nil, nil, nil, nil, nil, nil, report.SlowValue},
+		)
+		for _, row := range summary {
+			if err := tw.WriteRow(row...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func newStockAgingBuckets() []dto.StockAgingBucket {
+	buckets := make([]dto.StockAgingBucket, len(stockAgingBuckets))
+	for i, bucket := range stockAgingBuckets {
+		buckets[i].Label = bucket.label
+	}
+	return buckets
+}
+
+func addStockAging(buckets []dto.StockAgingBucket, days int, qty int, hargaBeli float64) {
+	i := len(stockAgingBuckets) - 1
+	for j, bucket := range stockAgingBuckets {
+		if bucket.maxDays >= 0 && days <= bucket.maxDays {
+			i = j
+			break
+		}
+	}
+
+	buckets[i].Qty += qty
+	buckets[i].Value += float64(qty) * hargaBeli
+}
+
+// getProfitLossTotals loads sales, refunds and pengeluaran of filter keyed by
+// month ("2006-01").
+func (s *analyticsService) getProfitLossTotals(ctx context.Context, filter dto.AnalyticsFilter) (map[string]*profitLossTotals, error) {
+	sales, err := s.analyticsRepo.GetMonthlySales(ctx, nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := s.analyticsRepo.GetMonthlyRefunds(ctx, nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	expenses, err := s.analyticsRepo.GetMonthlyExpenses(ctx, nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]*profitLossTotals{}
+	month := func(key string) *profitLossTotals {
+		if totals[key] == nil {
+			totals[key] = &profitLossTotals{expenses: map[string]float64{}}
+		}
+		return totals[key]
+	}
+
+	for _, row := range sales {
+		month(row.Month).sales += row.Amount
+		month(row.Month).cogs += row.COGS
+	}
+
+	for _, row := range refunds {
+		month(row.Month).refunds += row.Amount
+		month(row.Month).cogs -= row.COGS
+	}
+
+	for _, row := range expenses {
+		month(row.Month).expenses[row.Kategori] += row.Jumlah
+	}
+
+	return totals, nil
+}
+
+func (t *profitLossTotals) add(other *profitLossTotals) {
+	if other == nil {
+		return
+	}
+
+	t.sales += other.sales
+	t.refunds += other.refunds
+	t.cogs += other.cogs
+	for kategori, jumlah := range other.expenses {
+		t.expenses[kategori] += jumlah
+	}
+}
+
+// statement builds the P&L lines, listing expenses in the order of kategori
+// so every column of a report lines up. With a nil kategori all expenses of
+// the month are only counted in the total.
+func (t *profitLossTotals) statement(kategori []string) dto.ProfitLossStatement {
+	if t == nil {
+		t = &profitLossTotals{}
+	}
+
+	statement := dto.ProfitLossStatement{
+		Sales:    t.sales,
+		Refunds:  t.refunds,
+		NetSales: t.sales - t.refunds,
+		COGS:     t.cogs,
+		Expenses: make([]dto.ProfitLossExpense, 0, len(kategori)),
+	}
+	statement.GrossProfit = statement.NetSales - statement.COGS
+
+	for _, jumlah := range t.expenses {
+		statement.TotalExpenses += jumlah
+	}
+	for _, k := range kategori {
+		statement.Expenses = append(statement.Expenses, dto.ProfitLossExpense{
+			Kategori: k,
+			Jumlah:   t.expenses[k],
+		})
+	}
+
+	statement.NetProfit = statement.GrossProfit - statement.TotalExpenses
+
+	return statement
+}
+
+// resolveAnalyticsPeriod turns the range or start/end dates of the request
+// into the current period and the period right before it. A "month" range
+// compares against the previous calendar month, everything else against a
+// window of the same length. Without any filter the current month is used.
+func resolveAnalyticsPeriod(req dto.AnalyticsRequest) (dto.AnalyticsFilter, dto.AnalyticsFilter, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var start, end time.Time
+	calendarMonth := false
+	switch {
+	case req.StartDate != "" && req.EndDate != "":
+		var err error
+		start, err = time.ParseInLocation("2006-01-02", req.StartDate, now.Location())
+		if err != nil {
+			return dto.AnalyticsFilter{}, dto.AnalyticsFilter{}, err
+		}
+
+		end, err = time.ParseInLocation("2006-01-02", req.EndDate, now.Location())
+		if err != nil {
+			return dto.AnalyticsFilter{}, dto.AnalyticsFilter{}, err
+		}
+		end = end.AddDate(0, 0, 1).Add(-time.Second)
+
+		if end.Before(start) {
+			return dto.AnalyticsFilter{}, dto.AnalyticsFilter{}, dto.ErrAnalyticsInvalidPeriod
+		}
+	case req.Range == "today":
+		start = today
+		end = start.AddDate(0, 0, 1).Add(-time.Second)
+	case req.Range == "week":
+		start = today.AddDate(0, 0, -int(today.Weekday()))
+		end = start.AddDate(0, 0, 7).Add(-time.Second)
+	default:
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		end = start.AddDate(0, 1, 0).Add(-time.Second)
+		calendarMonth = true
+	}
+
+	var previousStart time.Time
+	if calendarMonth {
+		previousStart = start.AddDate(0, -1, 0)
+	} else {
+		previousStart = start.Add(-(end.Sub(start) + time.Second))
+	}
+
+	current := dto.AnalyticsFilter{Start: start, End: end, Cabang: req.Cabang}
+	previous := dto.AnalyticsFilter{Start: previousStart, End: start.Add(-time.Second), Cabang: req.Cabang}
+
+	return current, previous, nil
+}
+
+// completeSalesSummary fills in the figures derived from the totals that the
+// repository sums up.
+func completeSalesSummary(summary dto.SalesSummary) dto.SalesSummary {
+	summary.NetSales = summary.GrossSales - summary.Discount
+	summary.GrossMargin = summary.NetSales - summary.COGS
+
+	if summary.NetSales != 0 {
+		summary.GrossMarginPct = summary.GrossMargin / summary.NetSales * 100
+	}
+
+	if summary.TransactionCount > 0 {
+		summary.AverageBasket = summary.NetSales / float64(summary.TransactionCount)
+		summary.AverageItems = float64(summary.ItemsSold) / float64(summary.TransactionCount)
+	}
+
+	return summary
+}
+
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+
+	change := (current - previous) / previous * 100
+	return &change
+}
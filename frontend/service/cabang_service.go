@@ -4,11 +4,8 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
-	"bytes"
+	"bumisubur-be/utils"
 	"context"
-	"fmt"
-
-	"github.com/xuri/excelize/v2"
 )
 
 type (
@@ -19,7 +16,7 @@ type (
 		UpdateCabang(ctx context.Context, req dto.CabangRequest, cabangID int) (dto.CabangResponse, error)
 		DeleteCabang(ctx context.Context, cabangID int) error
 
-		DownloadDataCabang(ctx context.Context) ([]byte, error)
+		DownloadDataCabang(ctx context.Context, format string) ([]byte, error)
 	}
 
 	cabangService struct {
@@ -143,40 +140,28 @@ func (s *cabangService) DeleteCabang(ctx context.Context, cabangID int) error {
 	return nil
 }
 
-func (s *cabangService) DownloadDataCabang(ctx context.Context) ([]byte, error) {
-	cabangs, err := s.cabangRepo.GetAllCabang(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
+var cabangTable = utils.Table{
+	Title: "Data Cabang",
+	Columns: []utils.Column{
+		{Header: "ID", Type: utils.ColumnInt},
+		{Header: "Nama Cabang"},
+		{Header: "Alamat"},
+		{Header: "Keterangan"},
+	},
+}
 
-	f := excelize.NewFile()
-	f.SetSheetName("Sheet1", "Data Cabang")
-	sheetIndex, err := f.GetSheetIndex("Data Cabang")
+func (s *cabangService) DownloadDataCabang(ctx context.Context, format string) ([]byte, error) {
+	cabangs, err := s.cabangRepo.GetAllCabang(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	f.SetActiveSheet(sheetIndex)
-
-	headers := []string{"ID", "Nama Cabang", "Alamat", "Keterangan"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c%d", 'A'+i, 1)
-		f.SetCellValue("Data Cabang", cell, header)
-	}
-
-	for i, cabang := range cabangs {
-		f.SetCellValue("Data Cabang", fmt.Sprintf("A%d", i+2), cabang.ID)
-		f.SetCellValue("Data Cabang", fmt.Sprintf("B%d", i+2), cabang.Name)
-		f.SetCellValue("Data Cabang", fmt.Sprintf("C%d", i+2), cabang.Alamat)
-		f.SetCellValue("Data Cabang", fmt.Sprintf("D%d", i+2), cabang.Keterangan)
-
-	}
-
-	// Save Excel content to a buffer
-	buf := new(bytes.Buffer)
-	if err := f.Write(buf); err != nil {
-		return nil, err
-	}
 
-	// Return the buffer's bytes
-	return buf.Bytes(), nil
+	return utils.RenderTable(format, cabangTable, func(tw utils.TableWriter) error {
+		for _, cabang := range cabangs {
+			if err := tw.WriteRow(cabang.ID, cabang.Name, cabang.Alamat, cabang.Keterangan); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Exports are kept outside the public assets directory and only served by
+// GetExportFile, which checks the owner and the expiry. EXPORT_LEGACY_DIR is
+// where they used to be written.
+const (
+	EXPORT_DIR           = "storage/exports"
+	EXPORT_LEGACY_DIR    = "assets/exports"
+	EXPORT_TTL           = 24 * time.Hour
+	EXPORT_POLL_INTERVAL = 5 * time.Second
+	EXPORT_SWEEP_EVERY   = 10 * time.Minute
+	EXPORT_LIST_LIMIT    = 20
+)
+
+type (
+	ExportService interface {
+		CreateExport(ctx context.Context, req dto.ExportRequest, userID string) (dto.ExportJobResponse, error)
+		GetExport(ctx context.Context, jobID string, userID string) (dto.ExportJobResponse, error)
+		GetExports(ctx context.Context, userID string) ([]dto.ExportJobResponse, error)
+		GetExportFile(ctx context.Context, jobID string, userID string) (string, string, error)
+
+		RunNextJob(ctx context.Context) (bool, error)
+		RemoveExpired(ctx context.Context) error
+		StartWorker(ctx context.Context)
+	}
+
+	exportService struct {
+		exportRepo repository.ExportRepository
+		wake       chan struct{}
+	}
+)
+
+func NewExportService(exportRepo repository.ExportRepository) ExportService {
+	return &exportService{
+		exportRepo: exportRepo,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (s *exportService) CreateExport(ctx context.Context, req dto.ExportRequest, userID string) (dto.ExportJobResponse, error) {
+	switch req.Type {
+	case constants.ENUM_EXPORT_NOTA, constants.ENUM_EXPORT_LOG_AKSES, constants.ENUM_EXPORT_PENGELUARAN, constants.ENUM_EXPORT_FINAL_STOK:
+	case constants.ENUM_EXPORT_PRODUK:
+		if req.Cabang == 0 {
+			return dto.ExportJobResponse{}, dto.ErrExportCabang
+		}
+	default:
+		return dto.ExportJobResponse{}, dto.ErrExportInvalidType
+	}
+
+	format, err := utils.TableFormat(req.Format)
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+	req.Format = format
+
+	params, err := json.Marshal(req)
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+
+	job, err := s.exportRepo.CreateJob(ctx, nil, entity.ExportJob{
+		ID:          hex.EncodeToString(id),
+		Type:        req.Type,
+		Params:      string(params),
+		Status:      constants.ENUM_EXPORT_QUEUED,
+		RequestedBy: userID,
+	})
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return toExportJobResponse(job), nil
+}
+
+func (s *exportService) GetExport(ctx context.Context, jobID string, userID string) (dto.ExportJobResponse, error) {
+	job, err := s.getOwnJob(ctx, jobID, userID)
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+
+	return toExportJobResponse(job), nil
+}
+
+func (s *exportService) GetExports(ctx context.Context, userID string) ([]dto.ExportJobResponse, error) {
+	jobs, err := s.exportRepo.GetJobsByUser(ctx, nil, userID, EXPORT_LIST_LIMIT)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ExportJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, toExportJobResponse(job))
+	}
+
+	return result, nil
+}
+
+// GetExportFile returns the path on disk and the download name of a finished
+// export.
+func (s *exportService) GetExportFile(ctx context.Context, jobID string, userID string) (string, string, error) {
+	job, err := s.getOwnJob(ctx, jobID, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	// The sweep only runs every EXPORT_SWEEP_EVERY, so the expiry is checked
+	// here too.
+	if job.ExpiresAt != nil && !time.Now().Before(*job.ExpiresAt) {
+		return "", "", dto.ErrExportExpired
+	}
+
+	switch job.Status {
+	case constants.ENUM_EXPORT_DONE:
+	case constants.ENUM_EXPORT_EXPIRED:
+		return "", "", dto.ErrExportExpired
+	case constants.ENUM_EXPORT_FAILED:
+		return "", "", errors.New(job.Error)
+	default:
+		return "", "", dto.ErrExportNotReady
+	}
+
+	return job.FilePath, job.FileName, nil
+}
+
+// RunNextJob builds the oldest queued export. It reports whether there was a
+// job to run.
+func (s *exportService) RunNextJob(ctx context.Context) (bool, error) {
+	job, found, err := s.exportRepo.ClaimNextJob(ctx, nil, time.Now())
+	if err != nil || !found {
+		return false, err
+	}
+
+	var req dto.ExportRequest
+	if err := json.Unmarshal([]byte(job.Params), &req); err != nil {
+		return true, s.finishJob(ctx, job, err)
+	}
+
+	if err := os.MkdirAll(EXPORT_DIR, 0o700); err != nil {
+		return true, s.finishJob(ctx, job, err)
+	}
+
+	// Jobs queued before formats existed have none and are built as xlsx.
+	format, err := utils.TableFormat(req.Format)
+	if err != nil {
+		return true, s.finishJob(ctx, job, err)
+	}
+	req.Format = format
+
+	job.FileName = fmt.Sprintf("export_%s_%s.%s", job.Type, job.CreatedAt.Format("20060102_150405"), format)
+	job.FilePath = filepath.Join(EXPORT_DIR, job.ID+"."+format)
+
+	rows, err := s.writeExport(ctx, job, req)
+	job.Rows = rows
+	if err != nil {
+		os.Remove(job.FilePath)
+	}
+
+	return true, s.finishJob(ctx, job, err)
+}
+
+// RemoveExpired deletes the files of exports past their expiry and marks
+// the jobs expired. The rows stay for the history.
+func (s *exportService) RemoveExpired(ctx context.Context) error {
+	jobs, err := s.exportRepo.GetExpiredJobs(ctx, nil, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		job.Status = constants.ENUM_EXPORT_EXPIRED
+		job.FilePath = ""
+		if err := s.exportRepo.UpdateJob(ctx, nil, job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartWorker builds queued exports one at a time and removes expired ones.
+// New jobs wake it straight away; otherwise it polls so jobs queued by
+// another instance are picked up too. It blocks until ctx is done.
+func (s *exportService) StartWorker(ctx context.Context) {
+	if err := s.exportRepo.RequeueRunningJobs(ctx, nil); err != nil {
+		log.Printf("export requeue: %v", err)
+	}
+
+	if err := s.moveLegacyExports(ctx); err != nil {
+		log.Printf("export move: %v", err)
+	}
+
+	ticker := time.NewTicker(EXPORT_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	var lastSweep time.Time
+	for {
+		for {
+			ran, err := s.RunNextJob(ctx)
+			if err != nil {
+				log.Printf("export job: %v", err)
+			}
+			if !ran || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if time.Since(lastSweep) >= EXPORT_SWEEP_EVERY {
+			if err := s.RemoveExpired(ctx); err != nil {
+				log.Printf("export cleanup: %v", err)
+			}
+			lastSweep = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// moveLegacyExports moves the files of exports that were written to
+// EXPORT_LEGACY_DIR into EXPORT_DIR, where they are no longer publicly
+// served. A file that cannot be moved is deleted and its job expired.
+func (s *exportService) moveLegacyExports(ctx context.Context) error {
+	jobs, err := s.exportRepo.GetJobsByFileDir(ctx, nil, EXPORT_LEGACY_DIR)
+	if err != nil || len(jobs) == 0 {
+		return err
+	}
+
+	if err := os.MkdirAll(EXPORT_DIR, 0o700); err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		path := filepath.Join(EXPORT_DIR, filepath.Base(job.FilePath))
+		if err := os.Rename(job.FilePath, path); err != nil {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			job.Status = constants.ENUM_EXPORT_EXPIRED
+			path = ""
+		}
+
+		job.FilePath = path
+		if err := s.exportRepo.UpdateJob(ctx, nil, job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *exportService) finishJob(ctx context.Context, job entity.ExportJob, jobErr error) error {
+	now := time.Now()
+	expiresAt := now.Add(EXPORT_TTL)
+
+	job.Status = constants.ENUM_EXPORT_DONE
+	job.FinishedAt = &now
+	job.ExpiresAt = &expiresAt
+	if jobErr != nil {
+		job.Status = constants.ENUM_EXPORT_FAILED
+		job.Error = jobErr.Error()
+		job.FilePath = ""
+	}
+
+	return s.exportRepo.UpdateJob(ctx, nil, job)
+}
+
+// writeExport streams the rows of the export straight from the database
+// into the file, using the same columns as the download endpoints.
+func (s *exportService) writeExport(ctx context.Context, job entity.ExportJob, req dto.ExportRequest) (int, error) {
+	var table utils.Table
+	switch job.Type {
+	case constants.ENUM_EXPORT_NOTA:
+		table = transaksiNotaTable
+	case constants.ENUM_EXPORT_PRODUK:
+		table = transaksiProdukTable
+	case constants.ENUM_EXPORT_LOG_AKSES:
+		table = logAksesTable
+	case constants.ENUM_EXPORT_PENGELUARAN:
+		table = pengeluaranTable
+	case constants.ENUM_EXPORT_FINAL_STOK:
+		table = finalStokTable
+	default:
+		return 0, dto.ErrExportInvalidType
+	}
+
+	file, err := os.Create(job.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	tw, err := utils.NewTableWriter(file, req.Format, table)
+	if err != nil {
+		return 0, err
+	}
+
+	switch job.Type {
+	case constants.ENUM_EXPORT_NOTA:
+		err = s.exportRepo.StreamNota(ctx, nil, req, func(row dto.ExportNotaRow) error {
+			return tw.WriteRow(row.IDTransaksi, row.Merk, row.NamaProduk, row.Jenis, row.Ukuran, row.JumlahItem, row.HargaProduk, row.TotalProduk, row.TotalPendapatan, row.TanggalTransaksi)
+		})
+	case constants.ENUM_EXPORT_PRODUK:
+		err = s.exportRepo.StreamProduk(ctx, nil, req, func(row dto.GetTransaksiProduk) error {
+			return tw.WriteRow(row.BarcodeID, row.NamaProduk, row.Merk, row.Jenis, row.Ukuran, row.Warna, row.TotalBarang, row.TotalPendapatan, row.TanggalTransaksi)
+		})
+	case constants.ENUM_EXPORT_LOG_AKSES:
+		err = s.exportRepo.StreamLogAkses(ctx, nil, req, func(row dto.ExportLogAksesRow) error {
+			return tw.WriteRow(row.ID, row.Name, row.Email, row.IP, row.Activity, row.Token, row.Payload, row.CreatedAt)
+		})
+	case constants.ENUM_EXPORT_PENGELUARAN:
+		err = s.exportRepo.StreamPengeluaran(ctx, nil, req, func(row entity.Pengeluaran) error {
+			return tw.WriteRow(row.ID, row.NamaPengeluaran, row.TipePembayaran, row.TanggalPengeluaran, row.Description, row.Jumlah, row.Tujuan)
+		})
+	case constants.ENUM_EXPORT_FINAL_STOK:
+		err = s.exportRepo.StreamFinalStok(ctx, nil, req, func(row dto.FinalStokResponse) error {
+			return tw.WriteRow(row.MerkNama, row.NamaJenis, row.NamaProduk, row.BarcodeID, row.Ukuran, row.Warna, row.Stok, row.HargaJual, row.TotalNotional)
+		})
+	}
+	if err != nil {
+		tw.Close()
+		return tw.Rows(), err
+	}
+
+	if err := tw.Close(); err != nil {
+		return tw.Rows(), err
+	}
+
+	return tw.Rows(), file.Close()
+}
+
+func (s *exportService) getOwnJob(ctx context.Context, jobID string, userID string) (entity.ExportJob, error) {
+	job, err := s.exportRepo.GetJob(ctx, nil, jobID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ExportJob{}, dto.ErrExportNotFound
+		}
+		return entity.ExportJob{}, err
+	}
+
+	// Someone else's export is reported as missing rather than forbidden so
+	// job IDs cannot be probed.
+	if job.RequestedBy != userID {
+		return entity.ExportJob{}, dto.ErrExportNotFound
+	}
+
+	return job, nil
+}
+
+func toExportJobResponse(job entity.ExportJob) dto.ExportJobResponse {
+	return dto.ExportJobResponse{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Rows:       job.Rows,
+		Error:      job.Error,
+		FileName:   job.FileName,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+		ExpiresAt:  job.ExpiresAt,
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+const (
+	IDEMPOTENCY_KEY_TTL              = 24 * time.Hour
+	IDEMPOTENCY_KEY_MAX_LENGTH       = 255
+	IDEMPOTENCY_KEY_CLEANUP_INTERVAL = time.Hour
+
+	// A request holds its key for IDEMPOTENCY_KEY_LEASE. A key still in
+	// progress after that was left by a request that died, and a retry takes
+	// it over. The outcome of a request gets IDEMPOTENCY_WRITE_TIMEOUT to be
+	// stored.
+	IDEMPOTENCY_KEY_LEASE     = 5 * time.Minute
+	IDEMPOTENCY_WRITE_TIMEOUT = 10 * time.Second
+)
+
+type (
+	IdempotencyService interface {
+		// Begin reserves key for the user. When the key was already used for
+		// the same request and a response is stored, that record is returned
+		// with replay set to true.
+		Begin(ctx context.Context, userID int, key string, method string, path string, body []byte) (record entity.IdempotencyKey, replay bool, err error)
+		Complete(ctx context.Context, id int, statusCode int, contentType string, response []byte) error
+		Release(ctx context.Context, id int) error
+
+		// StartCleanupWorker deletes expired keys every
+		// IDEMPOTENCY_KEY_CLEANUP_INTERVAL until ctx is done.
+		StartCleanupWorker(ctx context.Context)
+	}
+
+	idempotencyService struct {
+		idempotencyRepo repository.IdempotencyRepository
+	}
+)
+
+func NewIdempotencyService(idempotencyRepo repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepo: idempotencyRepo,
+	}
+}
+
+func (s *idempotencyService) Begin(ctx context.Context, userID int, key string, method string, path string, body []byte) (entity.IdempotencyKey, bool, error) {
+	if len(key) > IDEMPOTENCY_KEY_MAX_LENGTH {
+		return entity.IdempotencyKey{}, false, dto.ErrIdempotencyKeyTooLong
+	}
+
+	now := time.Now()
+	hash := sha256.Sum256(append([]byte(method+" "+path+"\n"), body...))
+	requestHash := hex.EncodeToString(hash[:])
+
+	reserve := entity.IdempotencyKey{
+		Key:         key,
+		UserID:      userID,
+		Method:      method,
+		Path:        path,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(IDEMPOTENCY_KEY_TTL),
+	}
+	lockedUntil := now.Add(IDEMPOTENCY_KEY_LEASE)
+	reserve.LockedUntil = &lockedUntil
+
+	record, created, err := s.idempotencyRepo.ReserveKey(ctx, nil, reserve)
+	if err != nil {
+		return entity.IdempotencyKey{}, false, err
+	}
+
+	// Expired keys are only cleaned up periodically, so one that is still
+	// around is dropped here and the key reserved afresh. So is a key whose
+	// request died before storing a response. It is deleted by id, so of two
+	// retries taking it over only one gets the new reservation.
+	if !created && (record.ExpiresAt.Before(now) || leaseExpired(record, now)) {
+		if err := s.idempotencyRepo.DeleteKey(ctx, nil, record.ID); err != nil {
+			return entity.IdempotencyKey{}, false, err
+		}
+
+		record, created, err = s.idempotencyRepo.ReserveKey(ctx, nil, reserve)
+		if err != nil {
+			return entity.IdempotencyKey{}, false, err
+		}
+	}
+
+	if created {
+		return record, false, nil
+	}
+
+	if record.RequestHash != requestHash {
+		return entity.IdempotencyKey{}, false, dto.ErrIdempotencyKeyReused
+	}
+
+	if record.StatusCode == 0 {
+		return entity.IdempotencyKey{}, false, dto.ErrIdempotencyKeyInProgress
+	}
+
+	return record, true, nil
+}
+
+// leaseExpired reports whether record is in progress but no longer held by
+// its request.
+func leaseExpired(record entity.IdempotencyKey, now time.Time) bool {
+	return record.StatusCode == 0 && (record.LockedUntil == nil || record.LockedUntil.Before(now))
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, id int, statusCode int, contentType string, response []byte) error {
+	return s.idempotencyRepo.CompleteKey(ctx, nil, id, statusCode, contentType, response)
+}
+
+func (s *idempotencyService) Release(ctx context.Context, id int) error {
+	return s.idempotencyRepo.DeleteKey(ctx, nil, id)
+}
+
+func (s *idempotencyService) StartCleanupWorker(ctx context.Context) {
+	ticker := time.NewTicker(IDEMPOTENCY_KEY_CLEANUP_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		if err := s.idempotencyRepo.DeleteExpiredKeys(ctx, nil, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("failed to delete expired idempotency keys: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
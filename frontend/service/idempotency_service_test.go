@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type fakeIdempotencyRepo struct {
+	repository.IdempotencyRepository
+	keys   map[string]entity.IdempotencyKey
+	lastID int
+}
+
+func (r *fakeIdempotencyRepo) ReserveKey(ctx context.Context, tx *gorm.DB, key entity.IdempotencyKey) (entity.IdempotencyKey, bool, error) {
+	if existing, ok := r.keys[key.Key]; ok {
+		return existing, false, nil
+	}
+	r.lastID++
+	key.ID = r.lastID
+	r.keys[key.Key] = key
+	return key, true, nil
+}
+
+func (r *fakeIdempotencyRepo) CompleteKey(ctx context.Context, tx *gorm.DB, id int, statusCode int, contentType string, response []byte) error {
+	for name, key := range r.keys {
+		if key.ID == id {
+			key.StatusCode = statusCode
+			key.ContentType = contentType
+			key.Response = response
+			key.LockedUntil = nil
+			r.keys[name] = key
+		}
+	}
+	return nil
+}
+
+func (r *fakeIdempotencyRepo) DeleteKey(ctx context.Context, tx *gorm.DB, id int) error {
+	for name, key := range r.keys {
+		if key.ID == id {
+			delete(r.keys, name)
+		}
+	}
+	return nil
+}
+
+func TestIdempotencyBeginLease(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeIdempotencyRepo{keys: map[string]entity.IdempotencyKey{}}
+	service := NewIdempotencyService(repo)
+	body := []byte(`{"total_harga":10000}`)
+
+	first, replay, err := service.Begin(ctx, 1, "kunci-1", "POST", "/api/transaksi", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+
+	// While the first request holds the key, a retry has to wait.
+	_, _, err = service.Begin(ctx, 1, "kunci-1", "POST", "/api/transaksi", body)
+	assert.ErrorIs(t, err, dto.ErrIdempotencyKeyInProgress)
+
+	// Once its lease runs out, the first request is taken to have died and
+	// the retry gets the key.
+	stale := repo.keys["kunci-1"]
+	lockedUntil := time.Now().Add(-time.Second)
+	stale.LockedUntil = &lockedUntil
+	repo.keys["kunci-1"] = stale
+
+	retry, replay, err := service.Begin(ctx, 1, "kunci-1", "POST", "/api/transaksi", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+	assert.NotEqual(t, first.ID, retry.ID)
+
+	// A late outcome of the first request does not touch the new one.
+	assert.NoError(t, service.Complete(ctx, first.ID, 201, "application/json", []byte(`{}`)))
+	_, _, err = service.Begin(ctx, 1, "kunci-1", "POST", "/api/transaksi", body)
+	assert.ErrorIs(t, err, dto.ErrIdempotencyKeyInProgress)
+
+	// A stored response is replayed however old its lease is.
+	assert.NoError(t, service.Complete(ctx, retry.ID, 201, "application/json", []byte(`{"id":1}`)))
+	record, replay, err := service.Begin(ctx, 1, "kunci-1", "POST", "/api/transaksi", body)
+	assert.NoError(t, err)
+	assert.True(t, replay)
+	assert.Equal(t, []byte(`{"id":1}`), record.Response)
+
+	// A key stored before keys had a lease has none, and is taken over.
+	repo.keys["kunci-2"] = entity.IdempotencyKey{ID: 99, Key: "kunci-2", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	_, replay, err = service.Begin(ctx, 1, "kunci-2", "POST", "/api/transaksi", body)
+	assert.NoError(t, err)
+	assert.False(t, replay)
+}
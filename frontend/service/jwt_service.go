@@ -1,59 +1,75 @@
 package service
 
 import (
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+const (
+	// ACCESS_TOKEN_TTL is kept short because an access token stays valid
+	// until it expires only if its session is still active.
+	ACCESS_TOKEN_TTL = 15 * time.Minute
+	// REFRESH_TOKEN_TTL is how long a session lives without being
+	// refreshed; every refresh extends it again.
+	REFRESH_TOKEN_TTL = 12 * time.Hour
+)
+
 type JWTService interface {
-	GenerateToken(userId string, role string) string
-	ValidateToken(token string) (*jwt.Token, error)
-	GetUserIDByToken(token string) (string, error)
+	GenerateToken(userId string, role string, sessionID int) string
+	ValidateToken(ctx context.Context, token string) (*jwt.Token, error)
+	ValidateSession(ctx context.Context, token string) (*jwt.Token, entity.Session, error)
+	GetUserIDByToken(ctx context.Context, token string) (string, error)
+	GetSessionIDByToken(ctx context.Context, token string) (int, error)
 }
 
 type jwtCustomClaim struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 type jwtService struct {
-	secretKey string
-	issuer    string
+	keys        utils.KeySet
+	issuer      string
+	sessionRepo repository.SessionRepository
 }
 
-func NewJWTService() JWTService {
+// NewJWTService signs tokens with the active JWT key and names it in the
+// kid header, so tokens signed before a rotation still verify while their
+// key is kept.
+func NewJWTService(sessionRepo repository.SessionRepository, keyring *utils.Keyring) JWTService {
 	return &jwtService{
-		secretKey: getSecretKey(),
-		issuer:    "Template",
+		keys:        keyring.JWT,
+		issuer:      "Template",
+		sessionRepo: sessionRepo,
 	}
 }
 
-func getSecretKey() string {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		secretKey = "Template"
-	}
-	return secretKey
-}
-
-func (j *jwtService) GenerateToken(userId string, role string) string {
+func (j *jwtService) GenerateToken(userId string, role string, sessionID int) string {
 	claims := jwtCustomClaim{
 		userId,
 		role,
+		sessionID,
 		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 12)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ACCESS_TOKEN_TTL)),
 			Issuer:    j.issuer,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	kid, key := j.keys.Current()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tx, err := token.SignedString([]byte(j.secretKey))
+	token.Header["kid"] = kid
+	tx, err := token.SignedString(key)
 	if err != nil {
 		log.Println(err)
 	}
@@ -64,15 +80,45 @@ func (j *jwtService) parseToken(t_ *jwt.Token) (any, error) {
 	if _, ok := t_.Method.(*jwt.SigningMethodHMAC); !ok {
 		return nil, fmt.Errorf("unexpected signing method %v", t_.Header["alg"])
 	}
-	return []byte(j.secretKey), nil
+
+	kid, _ := t_.Header["kid"].(string)
+	key, ok := j.keys.Get(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
 }
 
-func (j *jwtService) ValidateToken(token string) (*jwt.Token, error) {
-	return jwt.Parse(token, j.parseToken)
+// ValidateToken checks the signature and expiry of a token and that the
+// session it belongs to has not been logged out or revoked.
+func (j *jwtService) ValidateToken(ctx context.Context, token string) (*jwt.Token, error) {
+	t_Token, _, err := j.ValidateSession(ctx, token)
+	return t_Token, err
+}
+
+// ValidateSession is ValidateToken that also returns the session, for
+// callers that need more of it than the claims carry.
+func (j *jwtService) ValidateSession(ctx context.Context, token string) (*jwt.Token, entity.Session, error) {
+	t_Token, err := jwt.Parse(token, j.parseToken)
+	if err != nil {
+		return t_Token, entity.Session{}, err
+	}
+
+	sessionID, err := sessionIDFromClaims(t_Token)
+	if err != nil {
+		return t_Token, entity.Session{}, err
+	}
+
+	session, err := j.sessionRepo.GetSessionByID(ctx, nil, sessionID)
+	if err != nil || !session.Active(time.Now()) {
+		return t_Token, entity.Session{}, dto.ErrSessionRevoked
+	}
+
+	return t_Token, session, nil
 }
 
-func (j *jwtService) GetUserIDByToken(token string) (string, error) {
-	t_Token, err := j.ValidateToken(token)
+func (j *jwtService) GetUserIDByToken(ctx context.Context, token string) (string, error) {
+	t_Token, err := j.ValidateToken(ctx, token)
 	if err != nil {
 		return "", err
 	}
@@ -81,3 +127,26 @@ func (j *jwtService) GetUserIDByToken(token string) (string, error) {
 	id := fmt.Sprintf("%v", claims["user_id"])
 	return id, nil
 }
+
+func (j *jwtService) GetSessionIDByToken(ctx context.Context, token string) (int, error) {
+	t_Token, err := j.ValidateToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	return sessionIDFromClaims(t_Token)
+}
+
+func sessionIDFromClaims(t_Token *jwt.Token) (int, error) {
+	claims, ok := t_Token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, dto.ErrTokenInvalid
+	}
+
+	sid, ok := claims["sid"].(float64)
+	if !ok || sid <= 0 {
+		return 0, dto.ErrTokenInvalid
+	}
+
+	return int(sid), nil
+}
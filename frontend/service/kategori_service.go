@@ -0,0 +1,557 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type (
+	KategoriService interface {
+		GetKategoriTree(ctx context.Context) ([]dto.KategoriNode, error)
+		UpdateKategori(ctx context.Context, req dto.KategoriRequest, jenisID int) (dto.KategoriNode, error)
+		GetKategoriAttributes(ctx context.Context, jenisID int) (dto.KategoriAttributesResponse, error)
+
+		GetAllAttributeSets(ctx context.Context) ([]dto.AttributeSetResponse, error)
+		GetAttributeSetByID(ctx context.Context, setID int) (dto.AttributeSetResponse, error)
+		CreateAttributeSet(ctx context.Context, req dto.AttributeSetRequest) (dto.AttributeSetResponse, error)
+		UpdateAttributeSet(ctx context.Context, req dto.AttributeSetRequest, setID int) (dto.AttributeSetResponse, error)
+		DeleteAttributeSet(ctx context.Context, setID int) error
+	}
+
+	kategoriService struct {
+		jenisRepo     repository.JenisRepository
+		attributeRepo repository.AttributeRepository
+	}
+
+	// kategoriTree is every jenis by ID, for walking up and down the tree.
+	kategoriTree map[int]entity.Jenis
+
+	// jenisLookup finds a jenis typed in a supply update or an import file.
+	// A name may repeat under different parents, so a jenis can also be
+	// written as its path, like "Kaos > Lengan Pendek".
+	jenisLookup struct {
+		tree   kategoriTree
+		byPath map[string]int
+		byName map[string][]int
+	}
+)
+
+func NewKategoriService(jenisRepo repository.JenisRepository, attributeRepo repository.AttributeRepository) KategoriService {
+	return &kategoriService{
+		jenisRepo:     jenisRepo,
+		attributeRepo: attributeRepo,
+	}
+}
+
+func loadKategoriTree(ctx context.Context, tx *gorm.DB, jenisRepo repository.JenisRepository) (kategoriTree, error) {
+	allJenis, err := jenisRepo.GetAllJenis(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := make(kategoriTree, len(allJenis))
+	for _, jenis := range allJenis {
+		tree[jenis.ID] = jenis
+	}
+
+	return tree, nil
+}
+
+// ancestors returns the jenis followed by its parents up to the root. A
+// parent that no longer exists ends the walk, so it is treated as a root.
+func (t kategoriTree) ancestors(jenisID int) []entity.Jenis {
+	var path []entity.Jenis
+	seen := map[int]bool{}
+	for id := &jenisID; id != nil && !seen[*id]; {
+		jenis, ok := t[*id]
+		if !ok {
+			break
+		}
+		seen[*id] = true
+		path = append(path, jenis)
+		id = jenis.ParentID
+	}
+
+	return path
+}
+
+func (t kategoriTree) path(jenisID int) string {
+	ancestors := t.ancestors(jenisID)
+	names := make([]string, len(ancestors))
+	for i, jenis := range ancestors {
+		names[len(ancestors)-1-i] = jenis.NamaJenis
+	}
+
+	return strings.Join(names, " > ")
+}
+
+func newJenisLookup(tree kategoriTree) *jenisLookup {
+	l := &jenisLookup{tree: tree, byPath: map[string]int{}, byName: map[string][]int{}}
+
+	ids := make([]int, 0, len(tree))
+	for id := range tree {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		l.index(id)
+	}
+
+	return l
+}
+
+// index makes the jenis findable. Of older duplicates with the same path,
+// the first one is used.
+func (l *jenisLookup) index(jenisID int) {
+	key := kategoriPathKey(l.tree.path(jenisID))
+	if _, exists := l.byPath[key]; exists {
+		return
+	}
+	l.byPath[key] = jenisID
+
+	name := strings.ToLower(strings.TrimSpace(l.tree[jenisID].NamaJenis))
+	l.byName[name] = append(l.byName[name], jenisID)
+}
+
+// add makes a jenis created after the lookup was loaded findable.
+func (l *jenisLookup) add(jenis entity.Jenis) {
+	l.tree[jenis.ID] = jenis
+	l.index(jenis.ID)
+}
+
+// find resolves text as a path first, so a top level jenis is found by its
+// name, and then as the name of a sub-jenis. A name used under more than one
+// parent gives dto.ErrJenisAmbiguous with the paths to choose from.
+func (l *jenisLookup) find(text string) (entity.Jenis, error) {
+	if id, ok := l.byPath[kategoriPathKey(text)]; ok {
+		return l.tree[id], nil
+	}
+
+	if !strings.Contains(text, ">") {
+		ids := l.byName[strings.ToLower(strings.TrimSpace(text))]
+		if len(ids) == 1 {
+			return l.tree[ids[0]], nil
+		}
+		if len(ids) > 1 {
+			paths := make([]string, len(ids))
+			for i, id := range ids {
+				paths[i] = l.tree.path(id)
+			}
+			return entity.Jenis{}, fmt.Errorf("%w: %s", dto.ErrJenisAmbiguous, strings.Join(paths, ", "))
+		}
+	}
+
+	return entity.Jenis{}, dto.ErrJenisNotFound
+}
+
+// path is the path of jenis, or its name when it is not in the tree.
+func (l *jenisLookup) path(jenis entity.Jenis) string {
+	if _, ok := l.tree[jenis.ID]; ok {
+		return l.tree.path(jenis.ID)
+	}
+	return jenis.NamaJenis
+}
+
+// kategoriPathKey compares paths ignoring case and the spaces around ">".
+func kategoriPathKey(path string) string {
+	parts := strings.Split(path, ">")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.TrimSpace(part))
+	}
+	return strings.Join(parts, " > ")
+}
+
+// attributeSets returns the ukuran and warna set IDs a jenis uses, taken
+// from the jenis itself or its nearest ancestor that has one.
+func (t kategoriTree) attributeSets(jenisID int) (*int, *int) {
+	var ukuran, warna *int
+	for _, jenis := range t.ancestors(jenisID) {
+		if ukuran == nil {
+			ukuran = jenis.UkuranSetID
+		}
+		if warna == nil {
+			warna = jenis.WarnaSetID
+		}
+	}
+
+	return ukuran, warna
+}
+
+func (t kategoriTree) node(jenis entity.Jenis) dto.KategoriNode {
+	return dto.KategoriNode{
+		ID:          jenis.ID,
+		NamaJenis:   jenis.NamaJenis,
+		Path:        t.path(jenis.ID),
+		ParentID:    jenis.ParentID,
+		UkuranSetID: jenis.UkuranSetID,
+		WarnaSetID:  jenis.WarnaSetID,
+		Children:    []dto.KategoriNode{},
+	}
+}
+
+func (s *kategoriService) GetKategoriTree(ctx context.Context) ([]dto.KategoriNode, error) {
+	tree, err := loadKategoriTree(ctx, nil, s.jenisRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[int][]entity.Jenis{}
+	var roots []entity.Jenis
+	for _, jenis := range tree {
+		if jenis.ParentID != nil {
+			if _, ok := tree[*jenis.ParentID]; ok {
+				children[*jenis.ParentID] = append(children[*jenis.ParentID], jenis)
+				continue
+			}
+		}
+		roots = append(roots, jenis)
+	}
+
+	byName := func(list []entity.Jenis) {
+		sort.Slice(list, func(i, j int) bool {
+			return strings.ToLower(list[i].NamaJenis) < strings.ToLower(list[j].NamaJenis)
+		})
+	}
+
+	var build func(list []entity.Jenis) []dto.KategoriNode
+	build = func(list []entity.Jenis) []dto.KategoriNode {
+		byName(list)
+		nodes := make([]dto.KategoriNode, 0, len(list))
+		for _, jenis := range list {
+			node := tree.node(jenis)
+			node.Children = build(children[jenis.ID])
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	return build(roots), nil
+}
+
+func (s *kategoriService) UpdateKategori(ctx context.Context, req dto.KategoriRequest, jenisID int) (dto.KategoriNode, error) {
+	tree, err := loadKategoriTree(ctx, nil, s.jenisRepo)
+	if err != nil {
+		return dto.KategoriNode{}, err
+	}
+
+	jenis, ok := tree[jenisID]
+	if !ok {
+		return dto.KategoriNode{}, dto.ErrJenisNotFound
+	}
+
+	if req.ParentID != nil {
+		if _, ok := tree[*req.ParentID]; !ok {
+			return dto.KategoriNode{}, dto.ErrJenisNotFound
+		}
+		for _, ancestor := range tree.ancestors(*req.ParentID) {
+			if ancestor.ID == jenisID {
+				return dto.KategoriNode{}, dto.ErrKategoriCycle
+			}
+		}
+	}
+
+	// Names only have to be unique among siblings.
+	for _, sibling := range tree {
+		if sibling.ID != jenisID && sameParent(sibling.ParentID, req.ParentID) &&
+			strings.EqualFold(strings.TrimSpace(sibling.NamaJenis), strings.TrimSpace(jenis.NamaJenis)) {
+			return dto.KategoriNode{}, dto.ErrJenisAlreadyExists
+		}
+	}
+
+	if err := s.checkAttributeSet(ctx, req.UkuranSetID, constants.ENUM_ATTRIBUTE_UKURAN); err != nil {
+		return dto.KategoriNode{}, err
+	}
+	if err := s.checkAttributeSet(ctx, req.WarnaSetID, constants.ENUM_ATTRIBUTE_WARNA); err != nil {
+		return dto.KategoriNode{}, err
+	}
+
+	if err := s.jenisRepo.UpdateJenisKategori(ctx, nil, jenisID, req.ParentID, req.UkuranSetID, req.WarnaSetID); err != nil {
+		return dto.KategoriNode{}, err
+	}
+
+	jenis.ParentID = req.ParentID
+	jenis.UkuranSetID = req.UkuranSetID
+	jenis.WarnaSetID = req.WarnaSetID
+	tree[jenisID] = jenis
+
+	return tree.node(jenis), nil
+}
+
+func (s *kategoriService) checkAttributeSet(ctx context.Context, setID *int, attribute string) error {
+	if setID == nil {
+		return nil
+	}
+
+	set, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, *setID)
+	if err != nil {
+		return dto.ErrAttributeSetNotFound
+	}
+	if set.Attribute != attribute {
+		return dto.ErrAttributeSetMismatch
+	}
+
+	return nil
+}
+
+func (s *kategoriService) GetKategoriAttributes(ctx context.Context, jenisID int) (dto.KategoriAttributesResponse, error) {
+	tree, err := loadKategoriTree(ctx, nil, s.jenisRepo)
+	if err != nil {
+		return dto.KategoriAttributesResponse{}, err
+	}
+
+	jenis, ok := tree[jenisID]
+	if !ok {
+		return dto.KategoriAttributesResponse{}, dto.ErrJenisNotFound
+	}
+
+	response := dto.KategoriAttributesResponse{
+		JenisID:   jenis.ID,
+		NamaJenis: jenis.NamaJenis,
+		Path:      tree.path(jenis.ID),
+	}
+
+	ukuranSetID, warnaSetID := tree.attributeSets(jenis.ID)
+	if ukuranSetID != nil {
+		set, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, *ukuranSetID)
+		if err == nil {
+			ukuran := toAttributeSetResponse(set)
+			response.Ukuran = &ukuran
+		}
+	}
+	if warnaSetID != nil {
+		set, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, *warnaSetID)
+		if err == nil {
+			warna := toAttributeSetResponse(set)
+			response.Warna = &warna
+		}
+	}
+
+	return response, nil
+}
+
+func (s *kategoriService) GetAllAttributeSets(ctx context.Context) ([]dto.AttributeSetResponse, error) {
+	sets, err := s.attributeRepo.GetAllAttributeSets(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.AttributeSetResponse, 0, len(sets))
+	for _, set := range sets {
+		responses = append(responses, toAttributeSetResponse(set))
+	}
+
+	return responses, nil
+}
+
+func (s *kategoriService) GetAttributeSetByID(ctx context.Context, setID int) (dto.AttributeSetResponse, error) {
+	set, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, setID)
+	if err != nil {
+		return dto.AttributeSetResponse{}, dto.ErrAttributeSetNotFound
+	}
+
+	return toAttributeSetResponse(set), nil
+}
+
+func (s *kategoriService) CreateAttributeSet(ctx context.Context, req dto.AttributeSetRequest) (dto.AttributeSetResponse, error) {
+	set, err := attributeSetFromRequest(req)
+	if err != nil {
+		return dto.AttributeSetResponse{}, err
+	}
+
+	set, err = s.attributeRepo.CreateAttributeSet(ctx, nil, set)
+	if err != nil {
+		return dto.AttributeSetResponse{}, err
+	}
+
+	return toAttributeSetResponse(set), nil
+}
+
+// UpdateAttributeSet replaces the name and values of a set. Its attribute
+// cannot change while jenis use it, since they chose it for that attribute.
+func (s *kategoriService) UpdateAttributeSet(ctx context.Context, req dto.AttributeSetRequest, setID int) (dto.AttributeSetResponse, error) {
+	existing, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, setID)
+	if err != nil {
+		return dto.AttributeSetResponse{}, dto.ErrAttributeSetNotFound
+	}
+
+	set, err := attributeSetFromRequest(req)
+	if err != nil {
+		return dto.AttributeSetResponse{}, err
+	}
+	set.ID = existing.ID
+
+	if set.Attribute != existing.Attribute {
+		usage, err := s.attributeRepo.CountAttributeSetUsage(ctx, nil, setID)
+		if err != nil {
+			return dto.AttributeSetResponse{}, err
+		}
+		if usage > 0 {
+			return dto.AttributeSetResponse{}, dto.ErrAttributeSetInUse
+		}
+	}
+
+	set, err = s.attributeRepo.UpdateAttributeSet(ctx, nil, set)
+	if err != nil {
+		return dto.AttributeSetResponse{}, err
+	}
+
+	return toAttributeSetResponse(set), nil
+}
+
+func (s *kategoriService) DeleteAttributeSet(ctx context.Context, setID int) error {
+	if _, err := s.attributeRepo.GetAttributeSetByID(ctx, nil, setID); err != nil {
+		return dto.ErrAttributeSetNotFound
+	}
+
+	usage, err := s.attributeRepo.CountAttributeSetUsage(ctx, nil, setID)
+	if err != nil {
+		return err
+	}
+	if usage > 0 {
+		return dto.ErrAttributeSetInUse
+	}
+
+	return s.attributeRepo.DeleteAttributeSet(ctx, nil, setID)
+}
+
+// attributeSetFromRequest validates a set and numbers its values in the
+// order given.
+func attributeSetFromRequest(req dto.AttributeSetRequest) (entity.AttributeSet, error) {
+	attribute := strings.ToLower(strings.TrimSpace(req.Attribute))
+	if attribute != constants.ENUM_ATTRIBUTE_UKURAN && attribute != constants.ENUM_ATTRIBUTE_WARNA {
+		return entity.AttributeSet{}, dto.ErrAttributeInvalid
+	}
+
+	set := entity.AttributeSet{
+		Name:      strings.TrimSpace(req.Name),
+		Attribute: attribute,
+	}
+
+	seen := map[string]bool{}
+	for i, value := range req.Values {
+		text := strings.TrimSpace(value.Value)
+		if text == "" {
+			return entity.AttributeSet{}, dto.ErrAttributeValueEmpty
+		}
+		if seen[strings.ToLower(text)] {
+			return entity.AttributeSet{}, dto.ErrAttributeValueDuplicate
+		}
+		seen[strings.ToLower(text)] = true
+
+		set.Values = append(set.Values, entity.AttributeValue{
+			Value:  text,
+			Code:   strings.TrimSpace(value.Code),
+			Urutan: i + 1,
+		})
+	}
+
+	return set, nil
+}
+
+func toAttributeSetResponse(set entity.AttributeSet) dto.AttributeSetResponse {
+	values := make([]dto.AttributeValueResponse, 0, len(set.Values))
+	for _, value := range set.Values {
+		values = append(values, dto.AttributeValueResponse{
+			Value:  value.Value,
+			Code:   value.Code,
+			Urutan: value.Urutan,
+		})
+	}
+
+	return dto.AttributeSetResponse{
+		ID:        set.ID,
+		Name:      set.Name,
+		Attribute: set.Attribute,
+		Values:    values,
+	}
+}
+
+// variantStandard holds the standard ukuran and warna values of a jenis in
+// set order. A nil slice means the jenis has no set for that attribute and
+// any value is accepted.
+type variantStandard struct {
+	ukuran []string
+	warna  []string
+}
+
+func loadVariantStandard(ctx context.Context, tx *gorm.DB, jenisRepo repository.JenisRepository, attributeRepo repository.AttributeRepository, jenisID int) (variantStandard, error) {
+	var standard variantStandard
+
+	tree, err := loadKategoriTree(ctx, tx, jenisRepo)
+	if err != nil {
+		return standard, err
+	}
+
+	load := func(setID *int) ([]string, error) {
+		if setID == nil {
+			return nil, nil
+		}
+		set, err := attributeRepo.GetAttributeSetByID(ctx, tx, *setID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, nil
+			}
+			return nil, err
+		}
+		values := make([]string, 0, len(set.Values))
+		for _, value := range set.Values {
+			values = append(values, value.Value)
+		}
+		return values, nil
+	}
+
+	ukuranSetID, warnaSetID := tree.attributeSets(jenisID)
+	if standard.ukuran, err = load(ukuranSetID); err != nil {
+		return standard, err
+	}
+	if standard.warna, err = load(warnaSetID); err != nil {
+		return standard, err
+	}
+
+	return standard, nil
+}
+
+// standardize returns the values as written in the sets of the jenis, or
+// an error naming the allowed values when one is not in its set.
+func (v variantStandard) standardize(ukuran string, warna string) (string, string, error) {
+	ukuran, err := standardValue(v.ukuran, ukuran, dto.ErrVariantUkuran)
+	if err != nil {
+		return "", "", err
+	}
+
+	warna, err = standardValue(v.warna, warna, dto.ErrVariantWarna)
+	if err != nil {
+		return "", "", err
+	}
+
+	return ukuran, warna, nil
+}
+
+func standardValue(values []string, value string, notFound error) (string, error) {
+	value = strings.TrimSpace(value)
+	if values == nil {
+		return value, nil
+	}
+	for _, standard := range values {
+		if strings.EqualFold(standard, value) {
+			return standard, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %q, pilihan: %s", notFound, value, strings.Join(values, ", "))
+}
+
+func sameParent(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKategoriTree() kategoriTree {
+	parent := func(id int) *int { return &id }
+
+	tree := kategoriTree{}
+	for _, jenis := range []entity.Jenis{
+		{ID: 1, NamaJenis: "Kaos"},
+		{ID: 2, NamaJenis: "Kemeja"},
+		{ID: 3, NamaJenis: "Lengan Pendek", ParentID: parent(1)},
+		{ID: 4, NamaJenis: "Lengan Pendek", ParentID: parent(2)},
+		{ID: 5, NamaJenis: "Polo", ParentID: parent(1)},
+		{ID: 6, NamaJenis: "Celana"},
+		// An older duplicate of Celana, from before names were checked.
+		{ID: 7, NamaJenis: "celana "},
+	} {
+		tree[jenis.ID] = jenis
+	}
+	return tree
+}
+
+func TestJenisLookupFind(t *testing.T) {
+	lookup := newJenisLookup(testKategoriTree())
+
+	tests := []struct {
+		text    string
+		wantID  int
+		wantErr error
+	}{
+		{"Kaos > Lengan Pendek", 3, nil},
+		{" kemeja>lengan pendek ", 4, nil},
+		{"Kaos", 1, nil},
+		{"polo", 5, nil},
+		{"Kaos > Polo", 5, nil},
+		{"Celana", 6, nil},
+		{"Lengan Pendek", 0, dto.ErrJenisAmbiguous},
+		{"Kemeja > Polo", 0, dto.ErrJenisNotFound},
+		{"Jaket", 0, dto.ErrJenisNotFound},
+		{"3", 0, dto.ErrJenisNotFound},
+	}
+
+	for _, tt := range tests {
+		jenis, err := lookup.find(tt.text)
+		if tt.wantErr != nil {
+			assert.ErrorIs(t, err, tt.wantErr, tt.text)
+			continue
+		}
+		if assert.NoError(t, err, tt.text) {
+			assert.Equal(t, tt.wantID, jenis.ID, tt.text)
+		}
+	}
+
+	// The error names the paths to choose from.
+	_, err := lookup.find("Lengan Pendek")
+	assert.ErrorContains(t, err, "Kaos > Lengan Pendek, Kemeja > Lengan Pendek")
+}
+
+func TestJenisLookupAdd(t *testing.T) {
+	lookup := newJenisLookup(testKategoriTree())
+
+	lookup.add(entity.Jenis{ID: 8, NamaJenis: "Jaket"})
+	jenis, err := lookup.find("jaket")
+	assert.NoError(t, err)
+	assert.Equal(t, 8, jenis.ID)
+	assert.Equal(t, "Jaket", lookup.path(jenis))
+
+	// A jenis outside the tree, like a deleted one, keeps its name.
+	assert.Equal(t, "Topi", lookup.path(entity.Jenis{ID: 99, NamaJenis: "Topi"}))
+}
+
+func TestFindImportJenis(t *testing.T) {
+	lookup := newJenisLookup(testKategoriTree())
+
+	jenis, err := findImportJenis(lookup, "4")
+	assert.NoError(t, err)
+	assert.Equal(t, "Kemeja > Lengan Pendek", lookup.path(jenis))
+
+	_, err = findImportJenis(lookup, "404")
+	assert.ErrorIs(t, err, dto.ErrJenisNotFound)
+
+	_, err = findImportJenis(lookup, "Lengan Pendek")
+	assert.ErrorIs(t, err, dto.ErrJenisAmbiguous)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
+	"log"
+)
+
+const KEY_ROTATION_BATCH_SIZE = 200
+
+type (
+	KeyRotationService interface {
+		// Reencrypt moves every stored ciphertext to the active AES key and
+		// returns how many it changed.
+		Reencrypt(ctx context.Context) (int, error)
+	}
+
+	keyRotationService struct {
+		keyRotationRepo repository.KeyRotationRepository
+	}
+
+	encryptedColumn struct {
+		table  string
+		column string
+	}
+)
+
+// encryptedColumns lists every column that holds utils.AESEncrypt output.
+var encryptedColumns = []encryptedColumn{
+	{table: "users", column: "two_factor_secret"},
+	{table: "users", column: "nik"},
+	{table: "users", column: "no_hp"},
+	{table: "users", column: "tanggal_lahir"},
+	{table: "users", column: "alamat"},
+}
+
+func NewKeyRotationService(keyRotationRepo repository.KeyRotationRepository) KeyRotationService {
+	return &keyRotationService{
+		keyRotationRepo: keyRotationRepo,
+	}
+}
+
+func (s *keyRotationService) Reencrypt(ctx context.Context) (int, error) {
+	prefix, err := utils.AESActivePrefix()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, target := range encryptedColumns {
+		changed, err := s.reencryptColumn(ctx, target, prefix)
+		total += changed
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// reencryptColumn rewrites one column in batches. A value no key can read is
+// logged and skipped so one bad row does not stop the rotation.
+func (s *keyRotationService) reencryptColumn(ctx context.Context, target encryptedColumn, prefix string) (int, error) {
+	changed := 0
+	afterID := 0
+	for {
+		values, err := s.keyRotationRepo.GetStaleCiphertexts(ctx, nil, target.table, target.column, prefix, afterID, KEY_ROTATION_BATCH_SIZE)
+		if err != nil {
+			return changed, err
+		}
+
+		for _, value := range values {
+			afterID = value.ID
+
+			reencrypted, ok, err := utils.AESReencrypt(value.Value)
+			if err != nil {
+				log.Printf("gagal enkripsi ulang %s.%s id %d: %v", target.table, target.column, value.ID, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+
+			updated, err := s.keyRotationRepo.UpdateCiphertext(ctx, nil, target.table, target.column, value.ID, value.Value, reencrypted)
+			if err != nil {
+				return changed, err
+			}
+			if updated {
+				changed++
+			}
+		}
+
+		if len(values) < KEY_ROTATION_BATCH_SIZE {
+			return changed, nil
+		}
+	}
+}
@@ -4,79 +4,545 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
-	"bytes"
+	"bumisubur-be/utils"
+	"compress/gzip"
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
 )
 
+// Requests are logged off the request path: RecordAccess only queues the
+// entry and StartWorker writes the queue to the database in batches of up to
+// ACCESS_LOG_BATCH_SIZE, at least every ACCESS_LOG_FLUSH_INTERVAL. When the
+// queue is full or an insert fails, entries are appended as JSON lines to
+// ACCESS_LOG_FALLBACK_FILE (default ACCESS_LOG_FALLBACK_FILE_DEFAULT) so a
+// slow or unavailable database never holds up a request.
+const (
+	ACCESS_LOG_QUEUE_SIZE     = 4096
+	ACCESS_LOG_BATCH_SIZE     = 200
+	ACCESS_LOG_FLUSH_INTERVAL = time.Second
+	ACCESS_LOG_WRITE_TIMEOUT  = 5 * time.Second
+	ACCESS_LOG_DRAIN_TIMEOUT  = 10 * time.Second
+
+	ACCESS_LOG_FALLBACK_FILE_ENV     = "ACCESS_LOG_FALLBACK_FILE"
+	ACCESS_LOG_FALLBACK_FILE_DEFAULT = "storage/logs/log_akses_fallback.jsonl"
+)
+
+// Details older than LOG_RETENTION_DAYS (default LOG_RETENTION_DAYS_DEFAULT,
+// 0 keeps everything) are removed once a day. LOG_RETENTION_MODE archive,
+// the default, first writes them as gzipped JSON lines to a new file in
+// LOG_ARCHIVE_DIR; purge only deletes them. Sessions themselves are kept.
+const (
+	LOG_RETENTION_DAYS_ENV = "LOG_RETENTION_DAYS"
+	LOG_RETENTION_MODE_ENV = "LOG_RETENTION_MODE"
+	LOG_ARCHIVE_DIR_ENV    = "LOG_ARCHIVE_DIR"
+
+	LOG_RETENTION_DAYS_DEFAULT = 90
+	LOG_ARCHIVE_DIR_DEFAULT    = "storage/logs/archive"
+	LOG_RETENTION_MODE_ARCHIVE = "archive"
+	LOG_RETENTION_MODE_PURGE   = "purge"
+	LOG_RETENTION_INTERVAL     = 24 * time.Hour
+	LOG_RETENTION_BATCH_SIZE   = 1000
+)
+
+// Details are listed newest first unless the filter asks for LOG_ORDER_ASC.
+const (
+	LOG_ORDER_ASC  = "asc"
+	LOG_ORDER_DESC = "desc"
+)
+
+// logStatusPattern accepts a status code such as 404 or a class such as 4xx.
+var logStatusPattern = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
 type LogAksesService interface {
-	LogDetailAkses(ctx context.Context, detailAkses entity.DetailAkses)
-	GetActiveLogAksesForUser(ctx context.Context, userID int) int
+	// RecordAccess queues a request of userID for the access log. It never
+	// blocks; detailAkses already carries the log akses of the session the
+	// request was made in.
+	RecordAccess(userID int, detailAkses entity.DetailAkses)
+	// StartWorker writes the queue until ctx is done, then drains what is
+	// left and returns. Run it in its own goroutine and cancel ctx only
+	// once the server stopped taking requests.
+	StartWorker(ctx context.Context)
+	Stats() dto.AccessLogStatsResponse
+	// StartRetentionWorker applies the retention policy now and then once
+	// every LOG_RETENTION_INTERVAL until ctx is done.
+	StartRetentionWorker(ctx context.Context)
+	ApplyRetention(ctx context.Context) (dto.LogRetentionResult, error)
 	GetAllLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) (dto.LogAksesPaginationResponse, error)
-	Download(ctx context.Context, filter dto.LogAksesPaginationRequest) ([]byte, error)
+	GetSessions(ctx context.Context, filter dto.LogAksesSessionRequest) (dto.LogAksesSessionPaginationResponse, error)
+	GetSession(ctx context.Context, logAksesID int, page dto.PaginationRequest) (dto.LogAksesSessionDetailResponse, error)
+	Download(ctx context.Context, filter dto.LogAksesPaginationRequest, format string) ([]byte, error)
 }
 
-type logAksesService struct {
-	logAksesRepo repository.LogAksesRepository
-}
+type (
+	logAksesService struct {
+		logAksesRepo repository.LogAksesRepository
+		queue        chan accessLogEntry
+		fallbackPath string
+		fallbackMu   sync.Mutex
+		stats        accessLogStats
+		retention    logRetention
+	}
+
+	logRetention struct {
+		days       int
+		mode       string
+		archiveDir string
+	}
+
+	accessLogEntry struct {
+		UserID int                `json:"user_id"`
+		Detail entity.DetailAkses `json:"detail"`
+		Reason string             `json:"reason,omitempty"`
+	}
+
+	accessLogStats struct {
+		enqueued        atomic.Int64
+		written         atomic.Int64
+		batches         atomic.Int64
+		overflowed      atomic.Int64
+		fallbackWritten atomic.Int64
+		lost            atomic.Int64
+
+		mu          sync.Mutex
+		lastError   string
+		lastErrorAt time.Time
+	}
+)
 
 func NewLogAksesService(logAksesRepo repository.LogAksesRepository) LogAksesService {
-	return &logAksesService{logAksesRepo: logAksesRepo}
+	fallbackPath := os.Getenv(ACCESS_LOG_FALLBACK_FILE_ENV)
+	if fallbackPath == "" {
+		fallbackPath = ACCESS_LOG_FALLBACK_FILE_DEFAULT
+	}
+
+	return &logAksesService{
+		logAksesRepo: logAksesRepo,
+		queue:        make(chan accessLogEntry, ACCESS_LOG_QUEUE_SIZE),
+		fallbackPath: fallbackPath,
+		retention:    loadLogRetention(),
+	}
 }
 
-func (s *logAksesService) LogDetailAkses(ctx context.Context, detailAkses entity.DetailAkses) {
-	s.logAksesRepo.CreateDetailAkses(ctx, nil, detailAkses)
+func loadLogRetention() logRetention {
+	retention := logRetention{
+		days:       LOG_RETENTION_DAYS_DEFAULT,
+		mode:       LOG_RETENTION_MODE_ARCHIVE,
+		archiveDir: LOG_ARCHIVE_DIR_DEFAULT,
+	}
+
+	if env := os.Getenv(LOG_RETENTION_DAYS_ENV); env != "" {
+		if days, err := strconv.Atoi(env); err == nil && days >= 0 {
+			retention.days = days
+		} else {
+			log.Printf("PERINGATAN: %s tidak valid, memakai %d hari", LOG_RETENTION_DAYS_ENV, retention.days)
+		}
+	}
+
+	switch mode := strings.ToLower(os.Getenv(LOG_RETENTION_MODE_ENV)); mode {
+	case "":
+	case LOG_RETENTION_MODE_ARCHIVE, LOG_RETENTION_MODE_PURGE:
+		retention.mode = mode
+	default:
+		// Archiving loses nothing, so it is the safe fallback.
+		log.Printf("PERINGATAN: %s: %v, memakai %s", LOG_RETENTION_MODE_ENV, dto.ErrLogRetentionInvalid, retention.mode)
+	}
+
+	if dir := os.Getenv(LOG_ARCHIVE_DIR_ENV); dir != "" {
+		retention.archiveDir = dir
+	}
+
+	return retention
 }
 
-func (s *logAksesService) GetActiveLogAksesForUser(ctx context.Context, userID int) int {
-	return s.logAksesRepo.FindActiveLogAksesByUserID(ctx, nil, userID)
+func (s *logAksesService) RecordAccess(userID int, detailAkses entity.DetailAkses) {
+	// The entry may be written a while later, so it keeps the time of the
+	// request.
+	if detailAkses.CreatedAt.IsZero() {
+		detailAkses.CreatedAt = time.Now()
+	}
+
+	entry := accessLogEntry{UserID: userID, Detail: detailAkses}
+	select {
+	case s.queue <- entry:
+		s.stats.enqueued.Add(1)
+	default:
+		s.stats.overflowed.Add(1)
+		entry.Reason = "antrean penuh"
+		s.writeFallback([]accessLogEntry{entry})
+	}
 }
 
-func (s *logAksesService) GetAllLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) (dto.LogAksesPaginationResponse, error) {
-	return s.logAksesRepo.GetAllLogAkses(ctx, filter)
+func (s *logAksesService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(ACCESS_LOG_FLUSH_INTERVAL)
+	defer ticker.Stop()
+
+	batch := make([]accessLogEntry, 0, ACCESS_LOG_BATCH_SIZE)
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= ACCESS_LOG_BATCH_SIZE {
+				s.flush(context.Background(), batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(context.Background(), batch)
+				batch = batch[:0]
+			}
+		case <-ctx.Done():
+			s.drain(batch)
+			return
+		}
+	}
+}
+
+// drain writes batch and whatever is still queued. Past
+// ACCESS_LOG_DRAIN_TIMEOUT the rest goes to the fallback file so shutdown
+// does not hang on a database that is down.
+func (s *logAksesService) drain(batch []accessLogEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), ACCESS_LOG_DRAIN_TIMEOUT)
+	defer cancel()
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) < ACCESS_LOG_BATCH_SIZE {
+				continue
+			}
+		default:
+		}
+
+		if len(batch) == 0 {
+			return
+		}
+
+		if ctx.Err() != nil {
+			s.fallback(batch, ctx.Err())
+		} else {
+			s.flush(ctx, batch)
+		}
+		batch = batch[:0]
+	}
+}
+
+// flush writes one batch to the database, or to the fallback file when that
+// fails.
+func (s *logAksesService) flush(ctx context.Context, batch []accessLogEntry) {
+	ctx, cancel := context.WithTimeout(ctx, ACCESS_LOG_WRITE_TIMEOUT)
+	defer cancel()
+
+	details := make([]entity.DetailAkses, 0, len(batch))
+	for _, entry := range batch {
+		details = append(details, entry.Detail)
+	}
+
+	if err := s.logAksesRepo.CreateDetailAksesBatch(ctx, nil, details); err != nil {
+		s.fallback(batch, err)
+		return
+	}
+
+	s.stats.written.Add(int64(len(details)))
+	s.stats.batches.Add(1)
+}
+
+func (s *logAksesService) fallback(batch []accessLogEntry, err error) {
+	log.Printf("gagal menulis %d log akses ke database: %v", len(batch), err)
+	s.stats.setError(err)
+
+	entries := make([]accessLogEntry, len(batch))
+	for i, entry := range batch {
+		entry.Reason = err.Error()
+		entries[i] = entry
+	}
+	s.writeFallback(entries)
 }
 
-func (s *logAksesService) Download(ctx context.Context, filter dto.LogAksesPaginationRequest) ([]byte, error) {
-	filtered := dto.LogAksesPaginationRequest{
-		Search:    filter.Search,
-		StartDate: filter.StartDate,
-		EndDate:   filter.EndDate,
+// writeFallback appends entries to the fallback file. Its payloads were
+// redacted like those in the database, but the file is still kept private.
+func (s *logAksesService) writeFallback(entries []accessLogEntry) {
+	s.fallbackMu.Lock()
+	defer s.fallbackMu.Unlock()
+
+	if err := s.appendFallback(entries); err != nil {
+		log.Printf("gagal menulis %d log akses ke %s: %v", len(entries), s.fallbackPath, err)
+		s.stats.setError(err)
+		s.stats.lost.Add(int64(len(entries)))
+		return
 	}
 
-	logs, _ := s.logAksesRepo.GetAllLogAkses(ctx, filtered)
+	s.stats.fallbackWritten.Add(int64(len(entries)))
+}
+
+func (s *logAksesService) appendFallback(entries []accessLogEntry) error {
+	if err := os.MkdirAll(filepath.Dir(s.fallbackPath), 0o700); err != nil {
+		return err
+	}
 
-	f := excelize.NewFile()
-	f.SetSheetName("Sheet1", "Data Log Akses")
-	sheetIndex, err := f.GetSheetIndex("Data Log Akses")
+	file, err := os.OpenFile(s.fallbackPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	return file.Close()
+}
+
+func (s *logAksesService) Stats() dto.AccessLogStatsResponse {
+	stats := dto.AccessLogStatsResponse{
+		QueueLength:     len(s.queue),
+		QueueCapacity:   cap(s.queue),
+		Enqueued:        s.stats.enqueued.Load(),
+		Written:         s.stats.written.Load(),
+		Batches:         s.stats.batches.Load(),
+		Overflowed:      s.stats.overflowed.Load(),
+		FallbackWritten: s.stats.fallbackWritten.Load(),
+		Lost:            s.stats.lost.Load(),
+	}
+
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	if s.stats.lastError != "" {
+		lastErrorAt := s.stats.lastErrorAt
+		stats.LastError = s.stats.lastError
+		stats.LastErrorAt = &lastErrorAt
+	}
+
+	return stats
+}
+
+func (s *accessLogStats) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+}
+
+func (s *logAksesService) StartRetentionWorker(ctx context.Context) {
+	ticker := time.NewTicker(LOG_RETENTION_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		result, err := s.ApplyRetention(ctx)
+		if err != nil {
+			log.Printf("gagal menerapkan retensi log akses: %v", err)
+		} else if result.Removed > 0 {
+			log.Printf("retensi log akses: %d log sebelum %s dihapus (%s)", result.Removed, result.Before.Format(time.RFC3339), result.Mode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ApplyRetention removes the details older than the retention age in
+// batches, archiving each batch first when the mode is archive. A batch is
+// only deleted once it is safely in the archive.
+func (s *logAksesService) ApplyRetention(ctx context.Context) (dto.LogRetentionResult, error) {
+	if s.retention.days <= 0 {
+		return dto.LogRetentionResult{}, nil
+	}
+
+	now := time.Now()
+	result := dto.LogRetentionResult{
+		Before: now.AddDate(0, 0, -s.retention.days),
+		Mode:   s.retention.mode,
+	}
+
+	var archive *logArchive
+	defer func() {
+		if archive != nil {
+			if err := archive.Close(); err != nil {
+				log.Printf("gagal menutup arsip log akses %s: %v", archive.path, err)
+			}
+		}
+	}()
+
+	for {
+		details, err := s.logAksesRepo.GetDetailAksesBefore(ctx, nil, result.Before, LOG_RETENTION_BATCH_SIZE)
+		if err != nil {
+			return result, err
+		}
+		if len(details) == 0 {
+			return result, nil
+		}
+
+		if s.retention.mode == LOG_RETENTION_MODE_ARCHIVE {
+			if archive == nil {
+				path := filepath.Join(s.retention.archiveDir, "detail_akses-"+now.Format("20060102-150405")+".jsonl.gz")
+				if archive, err = openLogArchive(path); err != nil {
+					return result, err
+				}
+				result.Archive = path
+			}
+			if err := archive.Write(details); err != nil {
+				return result, err
+			}
+		}
+
+		ids := make([]int, 0, len(details))
+		for _, detail := range details {
+			ids = append(ids, detail.ID)
+		}
+
+		removed, err := s.logAksesRepo.DeleteDetailAkses(ctx, nil, ids)
+		result.Removed += removed
+		if err != nil {
+			return result, err
+		}
+
+		if len(details) < LOG_RETENTION_BATCH_SIZE {
+			return result, nil
+		}
+	}
+}
+
+type logArchive struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+func openLogArchive(path string) (*logArchive, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
 	if err != nil {
 		return nil, err
 	}
-	f.SetActiveSheet(sheetIndex)
 
-	headers := []string{"ID", "Nama", "Email", "IP", "Aktivitas", "Token", "Payload", "Tanggal Akses"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c%d", 'A'+i, 1)
-		f.SetCellValue("Data Log Akses", cell, header)
+	return &logArchive{path: path, file: file, gz: gzip.NewWriter(file)}, nil
+}
+
+// Write appends details and syncs them to disk before returning.
+func (a *logArchive) Write(details []entity.DetailAkses) error {
+	encoder := json.NewEncoder(a.gz)
+	for _, detail := range details {
+		if err := encoder.Encode(detail); err != nil {
+			return err
+		}
+	}
+
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *logArchive) Close() error {
+	if err := a.gz.Close(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
+
+func (s *logAksesService) GetAllLogAkses(ctx context.Context, filter dto.LogAksesPaginationRequest) (dto.LogAksesPaginationResponse, error) {
+	if err := validateLogFilter(filter); err != nil {
+		return dto.LogAksesPaginationResponse{}, err
+	}
+
+	return s.logAksesRepo.GetAllLogAkses(ctx, filter)
+}
+
+func validateLogFilter(filter dto.LogAksesPaginationRequest) error {
+	if filter.Status != "" && !logStatusPattern.MatchString(strings.ToLower(filter.Status)) {
+		return dto.ErrLogStatusInvalid
+	}
+
+	if filter.Order != "" && filter.Order != LOG_ORDER_ASC && filter.Order != LOG_ORDER_DESC {
+		return dto.ErrLogOrderInvalid
 	}
 
-	for i, log := range logs.Data {
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("A%d", i+2), log.ID)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("B%d", i+2), log.Name)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("C%d", i+2), log.Email)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("D%d", i+2), log.IP)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("E%d", i+2), log.Activity)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("F%d", i+2), log.Token)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("G%d", i+2), log.Payload)
-		f.SetCellValue("Data Log Akses", fmt.Sprintf("H%d", i+2), log.Created_At)
+	return nil
+}
+
+func (s *logAksesService) GetSessions(ctx context.Context, filter dto.LogAksesSessionRequest) (dto.LogAksesSessionPaginationResponse, error) {
+	return s.logAksesRepo.GetLogAksesSessions(ctx, filter)
+}
+
+// GetSession returns one log akses with a page of its actions in the order
+// they happened.
+func (s *logAksesService) GetSession(ctx context.Context, logAksesID int, page dto.PaginationRequest) (dto.LogAksesSessionDetailResponse, error) {
+	session, err := s.logAksesRepo.GetLogAksesSession(ctx, logAksesID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dto.LogAksesSessionDetailResponse{}, dto.ErrLogSessionNotFound
+	}
+	if err != nil {
+		return dto.LogAksesSessionDetailResponse{}, err
+	}
+
+	actions, err := s.logAksesRepo.GetAllLogAkses(ctx, dto.LogAksesPaginationRequest{
+		LogAksesID: logAksesID,
+		Order:      LOG_ORDER_ASC,
+		Page:       page.Page,
+		PerPage:    page.PerPage,
+	})
+	if err != nil {
+		return dto.LogAksesSessionDetailResponse{}, err
 	}
 
-	buf := new(bytes.Buffer)
-	if err := f.Write(buf); err != nil {
+	return dto.LogAksesSessionDetailResponse{
+		Session: session,
+		Actions: actions,
+	}, nil
+}
+
+var logAksesTable = utils.Table{
+	Title: "Data Log Akses",
+	Columns: []utils.Column{
+		{Header: "ID", Type: utils.ColumnInt},
+		{Header: "Nama"},
+		{Header: "Email"},
+		{Header: "IP"},
+		{Header: "Aktivitas"},
+		{Header: "Token"},
+		{Header: "Payload"},
+		{Header: "Tanggal Akses", Type: utils.ColumnDateTime},
+	},
+}
+
+func (s *logAksesService) Download(ctx context.Context, filter dto.LogAksesPaginationRequest, format string) ([]byte, error) {
+	if err := validateLogFilter(filter); err != nil {
 		return nil, err
 	}
 
-	return buf.Bytes(), nil
+	logs, err := s.logAksesRepo.ListLogAkses(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 
+	return utils.RenderTable(format, logAksesTable, func(tw utils.TableWriter) error {
+		for _, log := range logs {
+			if err := tw.WriteRow(log.ID, log.Name, log.Email, log.IP, log.Activity, log.Token, log.Payload, log.Created_At); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"context"
+	"strings"
+)
+
+type (
+	MerkService interface {
+		GetAllMerk(ctx context.Context, search string) ([]dto.MerkDataResponse, error)
+		GetMerkByID(ctx context.Context, merkID int) (dto.MerkDataResponse, error)
+		CreateMerk(ctx context.Context, req dto.MerkNameRequest) (dto.MerkDataResponse, error)
+		UpdateMerk(ctx context.Context, req dto.MerkNameRequest, merkID int) (dto.MerkDataResponse, error)
+		DeleteMerk(ctx context.Context, merkID int) error
+		MergeMerk(ctx context.Context, req dto.MergeRequest) (dto.MerkDataResponse, error)
+	}
+
+	merkService struct {
+		merkRepo repository.MerkRepository
+	}
+)
+
+func NewMerkService(merkRepo repository.MerkRepository) MerkService {
+	return &merkService{
+		merkRepo: merkRepo,
+	}
+}
+
+func (s *merkService) GetAllMerk(ctx context.Context, search string) ([]dto.MerkDataResponse, error) {
+	merks, err := s.merkRepo.SearchMerk(ctx, nil, search)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.MerkDataResponse, 0, len(merks))
+	for _, merk := range merks {
+		responses = append(responses, toMerkDataResponse(merk))
+	}
+
+	return responses, nil
+}
+
+func (s *merkService) GetMerkByID(ctx context.Context, merkID int) (dto.MerkDataResponse, error) {
+	merk, err := s.merkRepo.GetMerkByID(ctx, nil, merkID)
+	if err != nil {
+		return dto.MerkDataResponse{}, dto.ErrMerkNotFound
+	}
+
+	return toMerkDataResponse(merk), nil
+}
+
+func (s *merkService) CreateMerk(ctx context.Context, req dto.MerkNameRequest) (dto.MerkDataResponse, error) {
+	_, flag, _ := s.merkRepo.CheckMerkName(ctx, nil, req.NamaMerk)
+	if flag {
+		return dto.MerkDataResponse{}, dto.ErrMerkAlreadyExists
+	}
+
+	merk, err := s.merkRepo.CreateMerk(ctx, nil, entity.Merk{Nama: req.NamaMerk})
+	if err != nil {
+		return dto.MerkDataResponse{}, err
+	}
+
+	return toMerkDataResponse(merk), nil
+}
+
+// UpdateMerk renames a merk. Renaming to the name of another merk is
+// refused; the two should be merged instead.
+func (s *merkService) UpdateMerk(ctx context.Context, req dto.MerkNameRequest, merkID int) (dto.MerkDataResponse, error) {
+	merk, err := s.merkRepo.GetMerkByID(ctx, nil, merkID)
+	if err != nil {
+		return dto.MerkDataResponse{}, dto.ErrMerkNotFound
+	}
+
+	existing, flag, _ := s.merkRepo.CheckMerkName(ctx, nil, req.NamaMerk)
+	if flag && existing.ID != merk.ID {
+		return dto.MerkDataResponse{}, dto.ErrMerkAlreadyExists
+	}
+
+	merk.Nama = strings.TrimSpace(req.NamaMerk)
+	merk, err = s.merkRepo.UpdateMerk(ctx, nil, merk)
+	if err != nil {
+		return dto.MerkDataResponse{}, err
+	}
+
+	return toMerkDataResponse(merk), nil
+}
+
+// DeleteMerk soft deletes a merk that no supply or reorder rule uses.
+func (s *merkService) DeleteMerk(ctx context.Context, merkID int) error {
+	merk, err := s.merkRepo.GetMerkByID(ctx, nil, merkID)
+	if err != nil {
+		return dto.ErrMerkNotFound
+	}
+
+	references, err := s.merkRepo.CountMerkReferences(ctx, nil, merk.ID)
+	if err != nil {
+		return err
+	}
+	if references > 0 {
+		return dto.ErrMerkInUse
+	}
+
+	return s.merkRepo.DeleteMerk(ctx, nil, merk.ID)
+}
+
+// MergeMerk folds duplicate merks, such as typos of a brand, into the
+// target so their supplies and stock are reported under one merk.
+func (s *merkService) MergeMerk(ctx context.Context, req dto.MergeRequest) (dto.MerkDataResponse, error) {
+	target, err := s.merkRepo.GetMerkByID(ctx, nil, req.TargetID)
+	if err != nil {
+		return dto.MerkDataResponse{}, dto.ErrMerkNotFound
+	}
+
+	for _, sourceID := range req.SourceIDs {
+		if sourceID == target.ID {
+			return dto.MerkDataResponse{}, dto.ErrMergeSelf
+		}
+		if _, err := s.merkRepo.GetMerkByID(ctx, nil, sourceID); err != nil {
+			return dto.MerkDataResponse{}, dto.ErrMerkNotFound
+		}
+	}
+
+	if err := s.merkRepo.MergeMerk(ctx, nil, req.SourceIDs, target.ID); err != nil {
+		return dto.MerkDataResponse{}, err
+	}
+
+	return toMerkDataResponse(target), nil
+}
+
+func toMerkDataResponse(merk entity.Merk) dto.MerkDataResponse {
+	return dto.MerkDataResponse{
+		ID:       merk.ID,
+		NamaMerk: merk.Nama,
+	}
+}
@@ -0,0 +1,606 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	NOTIFICATION_WORKER_INTERVAL    = time.Minute
+	NOTIFICATION_RESTOK_INTERVAL    = time.Hour
+	NOTIFICATION_BATCH_SIZE         = 50
+	NOTIFICATION_MAX_ATTEMPTS       = 6
+	NOTIFICATION_RETRY_BASE         = time.Minute
+	NOTIFICATION_RETRY_MAX          = time.Hour
+	NOTIFICATION_DEFAULT_DIGEST_AT  = 7
+	NOTIFICATION_DEFAULT_OUTBOX_MAX = 100
+	NOTIFICATION_EVENT_QUEUE_SIZE   = 1024
+	NOTIFICATION_DRAIN_TIMEOUT      = 10 * time.Second
+
+	// A claimed batch is held for NOTIFICATION_CLAIM_LEASE, long enough to
+	// send every notification in it even when the mail server is slow.
+	NOTIFICATION_CLAIM_LEASE = 30 * time.Minute
+)
+
+type (
+	NotificationService interface {
+		GetRules(ctx context.Context) ([]dto.NotificationRuleResponse, error)
+		CreateRule(ctx context.Context, req dto.NotificationRuleRequest) (dto.NotificationRuleResponse, error)
+		UpdateRule(ctx context.Context, ruleID int, req dto.NotificationRuleRequest) (dto.NotificationRuleResponse, error)
+		DeleteRule(ctx context.Context, ruleID int) error
+		GetOutbox(ctx context.Context, req dto.NotificationOutboxRequest) ([]entity.NotificationOutbox, error)
+
+		// The Notify methods only queue the event and never block; the
+		// worker matches it against the rules and queues the emails.
+		// Failures are logged and never returned so they cannot break the
+		// request that triggered them.
+		NotifyStockChanged(detailProdukIDs []int)
+		NotifyPengeluaran(pengeluaran dto.PengeluaranResponse)
+		NotifyReturnUser(returnID int64, transaksiID int64, amount float64, cabangID *int)
+
+		CheckPendingRestoks(ctx context.Context) error
+		DeliverDue(ctx context.Context) error
+		SendDigest(ctx context.Context) error
+		StartWorker(ctx context.Context)
+	}
+
+	notificationService struct {
+		notificationRepo repository.NotificationRepository
+		send             func(toEmail string, subject string, body string) error
+		digestHour       int
+		events           chan notificationEvent
+	}
+
+	// notificationEvent matches one event against the rules and queues the
+	// resulting emails. It runs on the worker.
+	notificationEvent struct {
+		name   string
+		handle func(ctx context.Context)
+	}
+
+	// notificationMessage is the content of one alert before it is fanned
+	// out to the recipients of a rule.
+	notificationMessage struct {
+		dedup   string
+		subject string
+		lines   []string
+	}
+)
+
+// NewNotificationService sends through utils.SendMail, so the SMTP_* settings
+// decide where mail goes. The daily digest goes out at NOTIFICATION_DIGEST_HOUR
+// (local time, default 07).
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	digestHour := NOTIFICATION_DEFAULT_DIGEST_AT
+	if hour, err := strconv.Atoi(os.Getenv("NOTIFICATION_DIGEST_HOUR")); err == nil && hour >= 0 && hour < 24 {
+		digestHour = hour
+	}
+
+	return &notificationService{
+		notificationRepo: notificationRepo,
+		send:             utils.SendMail,
+		digestHour:       digestHour,
+		events:           make(chan notificationEvent, NOTIFICATION_EVENT_QUEUE_SIZE),
+	}
+}
+
+func (s *notificationService) GetRules(ctx context.Context) ([]dto.NotificationRuleResponse, error) {
+	rules, err := s.notificationRepo.GetRules(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.NotificationRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, toNotificationRuleResponse(rule))
+	}
+
+	return result, nil
+}
+
+func (s *notificationService) CreateRule(ctx context.Context, req dto.NotificationRuleRequest) (dto.NotificationRuleResponse, error) {
+	rule := entity.NotificationRule{Active: true}
+	if err := applyNotificationRule(&rule, req); err != nil {
+		return dto.NotificationRuleResponse{}, err
+	}
+
+	rule, err := s.notificationRepo.SaveRule(ctx, nil, rule)
+	if err != nil {
+		return dto.NotificationRuleResponse{}, err
+	}
+
+	return toNotificationRuleResponse(rule), nil
+}
+
+func (s *notificationService) UpdateRule(ctx context.Context, ruleID int, req dto.NotificationRuleRequest) (dto.NotificationRuleResponse, error) {
+	rule, err := s.notificationRepo.GetRuleByID(ctx, nil, ruleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.NotificationRuleResponse{}, dto.ErrNotificationRuleNotFound
+		}
+		return dto.NotificationRuleResponse{}, err
+	}
+
+	if err := applyNotificationRule(&rule, req); err != nil {
+		return dto.NotificationRuleResponse{}, err
+	}
+
+	rule, err = s.notificationRepo.SaveRule(ctx, nil, rule)
+	if err != nil {
+		return dto.NotificationRuleResponse{}, err
+	}
+
+	return toNotificationRuleResponse(rule), nil
+}
+
+func (s *notificationService) DeleteRule(ctx context.Context, ruleID int) error {
+	if _, err := s.notificationRepo.GetRuleByID(ctx, nil, ruleID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.ErrNotificationRuleNotFound
+		}
+		return err
+	}
+
+	return s.notificationRepo.DeleteRule(ctx, nil, ruleID)
+}
+
+func (s *notificationService) GetOutbox(ctx context.Context, req dto.NotificationOutboxRequest) ([]entity.NotificationOutbox, error) {
+	limit := req.PerPage
+	if limit <= 0 || limit > NOTIFICATION_DEFAULT_OUTBOX_MAX {
+		limit = NOTIFICATION_DEFAULT_OUTBOX_MAX
+	}
+
+	return s.notificationRepo.GetOutbox(ctx, nil, req.Status, limit)
+}
+
+func (s *notificationService) NotifyStockChanged(detailProdukIDs []int) {
+	s.queueEvent("low stock", func(ctx context.Context) {
+		s.notifyStockChanged(ctx, detailProdukIDs)
+	})
+}
+
+func (s *notificationService) NotifyPengeluaran(pengeluaran dto.PengeluaranResponse) {
+	s.queueEvent("pengeluaran", func(ctx context.Context) {
+		s.notifyPengeluaran(ctx, pengeluaran)
+	})
+}
+
+func (s *notificationService) NotifyReturnUser(returnID int64, transaksiID int64, amount float64, cabangID *int) {
+	s.queueEvent("return", func(ctx context.Context) {
+		s.notifyReturnUser(ctx, returnID, transaksiID, amount, cabangID)
+	})
+}
+
+// queueEvent hands an event to the worker. When the queue is full the event
+// is dropped and logged rather than holding up the request.
+func (s *notificationService) queueEvent(name string, handle func(ctx context.Context)) {
+	select {
+	case s.events <- notificationEvent{name: name, handle: handle}:
+	default:
+		logNotificationError(name+" event", errors.New("queue full, event dropped"))
+	}
+}
+
+func (s *notificationService) notifyStockChanged(ctx context.Context, detailProdukIDs []int) {
+	rules, err := s.notificationRepo.GetActiveRulesByEvent(ctx, nil, constants.ENUM_NOTIFICATION_LOW_STOCK)
+	if err != nil || len(rules) == 0 {
+		logNotificationError("low stock rules", err)
+		return
+	}
+
+	variants, err := s.notificationRepo.GetNotificationVariants(ctx, nil, detailProdukIDs)
+	if err != nil {
+		logNotificationError("low stock variants", err)
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, rule := range rules {
+		var messages []notificationMessage
+		for _, variant := range variants {
+			if !notificationRuleMatchesCabang(rule, &variant.CabangID) || float64(variant.Stok) > rule.Threshold {
+				continue
+			}
+
+			messages = append(messages, notificationMessage{
+				dedup:   fmt.Sprintf("%d:%s", variant.DetailProdukID, today),
+				subject: fmt.Sprintf("Stok menipis: %s %s %s", variant.NamaProduk, variant.Ukuran, variant.Warna),
+				lines: []string{
+					fmt.Sprintf("Produk: %s (%s)", variant.NamaProduk, variant.Merk),
+					fmt.Sprintf("Ukuran / warna: %s / %s", variant.Ukuran, variant.Warna),
+					fmt.Sprintf("Sisa stok: %d (batas %.0f)", variant.Stok, rule.Threshold),
+				},
+			})
+		}
+
+		s.enqueue(ctx, rule, messages)
+	}
+}
+
+func (s *notificationService) notifyPengeluaran(ctx context.Context, pengeluaran dto.PengeluaranResponse) {
+	rules, err := s.notificationRepo.GetActiveRulesByEvent(ctx, nil, constants.ENUM_NOTIFICATION_LARGE_PENGELUARAN)
+	if err != nil {
+		logNotificationError("pengeluaran rules", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if !notificationRuleMatchesCabang(rule, pengeluaran.CabangID) || pengeluaran.Jumlah < rule.Threshold {
+			continue
+		}
+
+		s.enqueue(ctx, rule, []notificationMessage{{
+			dedup:   strconv.Itoa(pengeluaran.ID),
+			subject: fmt.Sprintf("Pengeluaran besar: %s", pengeluaran.NamaPengeluaran),
+			lines: []string{
+				fmt.Sprintf("Nama: %s", pengeluaran.NamaPengeluaran),
+				fmt.Sprintf("Kategori: %s", pengeluaran.KategoriPengeluaran),
+				fmt.Sprintf("Jumlah: %.2f (batas %.2f)", pengeluaran.Jumlah, rule.Threshold),
+				fmt.Sprintf("Tanggal: %s", pengeluaran.TanggalPengeluaran.Format("02-01-2006")),
+			},
+		}})
+	}
+}
+
+// notifyReturnUser alerts on large refunds. cabangID is nil when the
+// returned lines come from more than one cabang; such a return only matches
+// rules without a cabang.
+func (s *notificationService) notifyReturnUser(ctx context.Context, returnID int64, transaksiID int64, amount float64, cabangID *int) {
+	rules, err := s.notificationRepo.GetActiveRulesByEvent(ctx, nil, constants.ENUM_NOTIFICATION_LARGE_RETURN)
+	if err != nil {
+		logNotificationError("return rules", err)
+		return
+	}
+
+	for _, rule := range rules {
+		if !notificationRuleMatchesCabang(rule, cabangID) || amount < rule.Threshold {
+			continue
+		}
+
+		s.enqueue(ctx, rule, []notificationMessage{{
+			dedup:   strconv.FormatInt(returnID, 10),
+			subject: fmt.Sprintf("Retur besar pada nota %d", transaksiID),
+			lines: []string{
+				fmt.Sprintf("Nota: %d", transaksiID),
+				fmt.Sprintf("Nilai retur: %.2f (batas %.2f)", amount, rule.Threshold),
+			},
+		}})
+	}
+}
+
+// CheckPendingRestoks alerts once a day for every restok that has been
+// waiting longer than the threshold (in days) of a rule.
+func (s *notificationService) CheckPendingRestoks(ctx context.Context) error {
+	rules, err := s.notificationRepo.GetActiveRulesByEvent(ctx, nil, constants.ENUM_NOTIFICATION_PENDING_RESTOK)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	now := time.Now()
+	restoks, err := s.notificationRepo.GetPendingRestoks(ctx, nil, now)
+	if err != nil {
+		return err
+	}
+
+	today := now.Format("2006-01-02")
+	for _, rule := range rules {
+		var messages []notificationMessage
+		for _, restok := range restoks {
+			days := int(now.Sub(restok.TanggalRestok).Hours() / 24)
+			if !notificationRuleMatchesCabang(rule, &restok.CabangID) || float64(days) < rule.Threshold {
+				continue
+			}
+
+			messages = append(messages, notificationMessage{
+				dedup:   fmt.Sprintf("%d:%s", restok.RestokID, today),
+				subject: fmt.Sprintf("Restok %d belum dimasukkan selama %d hari", restok.RestokID, days),
+				lines: []string{
+					fmt.Sprintf("Restok: %d", restok.RestokID),
+					fmt.Sprintf("Produk: %s", restok.NamaProduk),
+					fmt.Sprintf("Supplier: %s", restok.Supplier),
+					fmt.Sprintf("Jumlah: %d", restok.Jumlah),
+					fmt.Sprintf("Tanggal restok: %s (%d hari)", restok.TanggalRestok.Format("02-01-2006"), days),
+				},
+			})
+		}
+
+		s.enqueue(ctx, rule, messages)
+	}
+
+	return nil
+}
+
+// DeliverDue sends queued notifications that are due. The batch is claimed
+// first, so another API instance or an overlapping run does not send the
+// same notification again. A failed send is retried with exponential backoff
+// until NOTIFICATION_MAX_ATTEMPTS.
+func (s *notificationService) DeliverDue(ctx context.Context) error {
+	now := time.Now()
+	notifications, err := s.notificationRepo.ClaimDueNotifications(ctx, nil, now, now.Add(NOTIFICATION_CLAIM_LEASE), NOTIFICATION_BATCH_SIZE)
+	if err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		notification.Attempts++
+
+		if err := s.send(notification.Recipient, notification.Subject, notification.Body); err != nil {
+			notification.LastError = err.Error()
+			if notification.Attempts >= NOTIFICATION_MAX_ATTEMPTS {
+				notification.Status = constants.ENUM_OUTBOX_FAILED
+			} else {
+				backoff := NOTIFICATION_RETRY_BASE << (notification.Attempts - 1)
+				if backoff > NOTIFICATION_RETRY_MAX {
+					backoff = NOTIFICATION_RETRY_MAX
+				}
+				notification.NextAttemptAt = now.Add(backoff)
+			}
+		} else {
+			sentAt := time.Now()
+			notification.Status = constants.ENUM_OUTBOX_SENT
+			notification.SentAt = &sentAt
+			notification.LastError = ""
+		}
+
+		if err := s.notificationRepo.UpdateNotification(ctx, nil, notification); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendDigest combines every notification waiting for the digest into one
+// email per recipient. A recipient whose digest fails is tried again on the
+// next run.
+func (s *notificationService) SendDigest(ctx context.Context) error {
+	notifications, err := s.notificationRepo.GetDigestNotifications(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	byRecipient := map[string][]entity.NotificationOutbox{}
+	var recipients []string
+	for _, notification := range notifications {
+		if _, ok := byRecipient[notification.Recipient]; !ok {
+			recipients = append(recipients, notification.Recipient)
+		}
+		byRecipient[notification.Recipient] = append(byRecipient[notification.Recipient], notification)
+	}
+
+	title := fmt.Sprintf("Ringkasan notifikasi %s", time.Now().Format("02-01-2006"))
+	for _, recipient := range recipients {
+		items := byRecipient[recipient]
+
+		lines := make([]string, 0, len(items))
+		ids := make([]int, 0, len(items))
+		for _, item := range items {
+			lines = append(lines, item.Subject)
+			ids = append(ids, item.ID)
+		}
+
+		body, err := utils.RenderNotificationMail(title, lines)
+		if err != nil {
+			return err
+		}
+
+		if err := s.send(recipient, title, body); err != nil {
+			logNotificationError("digest to "+recipient, err)
+			continue
+		}
+
+		if err := s.notificationRepo.MarkNotificationsDigested(ctx, nil, ids, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartWorker handles queued events as they come in, delivers the outbox
+// every minute, checks pending restoks every hour and sends the digest once
+// a day from digestHour on. It blocks until ctx is done, then handles the
+// events still queued and returns, so run it in its own goroutine.
+func (s *notificationService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(NOTIFICATION_WORKER_INTERVAL)
+	defer ticker.Stop()
+
+	var lastRestokCheck time.Time
+	var lastDigest string
+
+	for {
+		now := time.Now()
+
+		if now.Sub(lastRestokCheck) >= NOTIFICATION_RESTOK_INTERVAL {
+			logNotificationError("pending restok check", s.CheckPendingRestoks(ctx))
+			lastRestokCheck = now
+		}
+
+		if today := now.Format("2006-01-02"); now.Hour() >= s.digestHour && lastDigest != today {
+			logNotificationError("digest", s.SendDigest(ctx))
+			lastDigest = today
+		}
+
+		logNotificationError("delivery", s.DeliverDue(ctx))
+
+		if !s.handleEventsUntil(ctx, ticker.C) {
+			s.drainEvents()
+			return
+		}
+	}
+}
+
+// handleEventsUntil handles queued events until the next tick. It returns
+// false once ctx is done.
+func (s *notificationService) handleEventsUntil(ctx context.Context, tick <-chan time.Time) bool {
+	for {
+		select {
+		case event := <-s.events:
+			event.handle(ctx)
+		case <-tick:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// drainEvents handles the events queued before shutdown, giving up after
+// NOTIFICATION_DRAIN_TIMEOUT so a database that is down does not hang it.
+func (s *notificationService) drainEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), NOTIFICATION_DRAIN_TIMEOUT)
+	defer cancel()
+
+	for ctx.Err() == nil {
+		select {
+		case event := <-s.events:
+			event.handle(ctx)
+		default:
+			return
+		}
+	}
+
+	if dropped := len(s.events); dropped > 0 {
+		logNotificationError("drain", fmt.Errorf("%d events dropped: %w", dropped, ctx.Err()))
+	}
+}
+
+// enqueue queues messages for every recipient of rule, either for direct
+// delivery or for the next digest.
+func (s *notificationService) enqueue(ctx context.Context, rule entity.NotificationRule, messages []notificationMessage) {
+	status := constants.ENUM_OUTBOX_PENDING
+	if rule.Digest {
+		status = constants.ENUM_OUTBOX_DIGEST
+	}
+
+	var notifications []entity.NotificationOutbox
+	for _, message := range messages {
+		body, err := utils.RenderNotificationMail(message.subject, message.lines)
+		if err != nil {
+			logNotificationError("render", err)
+			continue
+		}
+
+		for _, recipient := range splitRecipients(rule.Recipients) {
+			dedup := fmt.Sprintf("%s:%d:%s:%s", rule.Event, rule.ID, recipient, message.dedup)
+			notifications = append(notifications, entity.NotificationOutbox{
+				RuleID:        rule.ID,
+				Event:         rule.Event,
+				Recipient:     recipient,
+				Subject:       message.subject,
+				Body:          body,
+				DedupKey:      &dedup,
+				Status:        status,
+				NextAttemptAt: time.Now(),
+			})
+		}
+	}
+
+	logNotificationError("enqueue", s.notificationRepo.EnqueueNotifications(ctx, nil, notifications))
+}
+
+func applyNotificationRule(rule *entity.NotificationRule, req dto.NotificationRuleRequest) error {
+	switch req.Event {
+	case constants.ENUM_NOTIFICATION_LOW_STOCK, constants.ENUM_NOTIFICATION_LARGE_PENGELUARAN,
+		constants.ENUM_NOTIFICATION_LARGE_RETURN, constants.ENUM_NOTIFICATION_PENDING_RESTOK:
+	default:
+		return dto.ErrNotificationInvalidEvent
+	}
+
+	if req.Threshold < 0 {
+		return dto.ErrNotificationNegativeLimit
+	}
+
+	recipients, err := normalizeRecipients(req.Recipients)
+	if err != nil {
+		return err
+	}
+
+	rule.Name = req.Name
+	rule.Event = req.Event
+	rule.Threshold = req.Threshold
+	rule.CabangID = req.CabangID
+	rule.Recipients = strings.Join(recipients, ",")
+	rule.Digest = req.Digest
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+
+	return nil
+}
+
+// normalizeRecipients validates the email addresses and drops empty ones.
+// At least one address is required.
+func normalizeRecipients(list []string) ([]string, error) {
+	recipients := make([]string, 0, len(list))
+	for _, recipient := range list {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" {
+			continue
+		}
+
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", dto.ErrNotificationInvalidEmail, recipient)
+		}
+		recipients = append(recipients, address.Address)
+	}
+
+	if len(recipients) == 0 {
+		return nil, dto.ErrNotificationNoRecipient
+	}
+
+	return recipients, nil
+}
+
+func toNotificationRuleResponse(rule entity.NotificationRule) dto.NotificationRuleResponse {
+	return dto.NotificationRuleResponse{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Event:      rule.Event,
+		Threshold:  rule.Threshold,
+		CabangID:   rule.CabangID,
+		Recipients: splitRecipients(rule.Recipients),
+		Digest:     rule.Digest,
+		Active:     rule.Active,
+	}
+}
+
+func notificationRuleMatchesCabang(rule entity.NotificationRule, cabangID *int) bool {
+	if rule.CabangID == nil {
+		return true
+	}
+
+	return cabangID != nil && *cabangID == *rule.CabangID
+}
+
+func splitRecipients(recipients string) []string {
+	result := []string{}
+	for _, recipient := range strings.Split(recipients, ",") {
+		if recipient = strings.TrimSpace(recipient); recipient != "" {
+			result = append(result, recipient)
+		}
+	}
+
+	return result
+}
+
+func logNotificationError(action string, err error) {
+	if err != nil {
+		log.Printf("notification %s: %v", action, err)
+	}
+}
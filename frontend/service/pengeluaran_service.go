@@ -4,12 +4,9 @@ import (
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
-	"bytes"
+	"bumisubur-be/utils"
 	"context"
-	"fmt"
 	"time"
-
-	"github.com/xuri/excelize/v2"
 )
 
 type PengeluaranService interface {
@@ -19,15 +16,16 @@ type PengeluaranService interface {
 	UpdatePengeluaran(ctx context.Context, req dto.PengeluaranRequest, pengeluaranID int) (dto.PengeluaranResponse, error)
 	DeletePengeluaran(ctx context.Context, pengeluaranID int) error
 
-	DownloadDataPengeluaran(ctx context.Context, filter string, start string, end string) ([]byte, error)
+	DownloadDataPengeluaran(ctx context.Context, filter string, start string, end string, cabang int, format string) ([]byte, error)
 }
 
 type pengeluaranService struct {
-	pengeluaranRepo repository.PengeluaranRepository
+	pengeluaranRepo     repository.PengeluaranRepository
+	notificationService NotificationService
 }
 
-func NewPengeluaranService(pengeluaranRepo repository.PengeluaranRepository) PengeluaranService {
-	return &pengeluaranService{pengeluaranRepo: pengeluaranRepo}
+func NewPengeluaranService(pengeluaranRepo repository.PengeluaranRepository, notificationService NotificationService) PengeluaranService {
+	return &pengeluaranService{pengeluaranRepo: pengeluaranRepo, notificationService: notificationService}
 }
 
 func (s *pengeluaranService) CreatePengeluaran(ctx context.Context, req dto.PengeluaranRequest) (dto.PengeluaranResponse, error) {
@@ -39,6 +37,7 @@ func (s *pengeluaranService) CreatePengeluaran(ctx context.Context, req dto.Peng
 		KategoriPengeluaran: req.KategoriPengeluaran,
 		Jumlah:              req.Jumlah,
 		Tujuan:              req.Tujuan,
+		CabangID:            req.CabangID,
 	}
 
 	result, err := s.pengeluaranRepo.CreatePengeluaran(ctx, pengeluaran)
@@ -46,12 +45,14 @@ func (s *pengeluaranService) CreatePengeluaran(ctx context.Context, req dto.Peng
 		return dto.PengeluaranResponse{}, err
 	}
 
+	s.notificationService.NotifyPengeluaran(result)
+
 	return result, nil
 }
 
 func (s *pengeluaranService) GetAllPengeluaran(ctx context.Context, req dto.PaginationRequest, filter string, startDate string, endDate string) (dto.PengeluaranPaginationResponse, error) {
 
-	dataWithPaginate, err := s.pengeluaranRepo.GetAllPengeluaranWithPagination(ctx, req, filter, startDate, endDate)
+	dataWithPaginate, err := s.pengeluaranRepo.GetAllPengeluaranWithPagination(ctx, req, filter, startDate, endDate, 0)
 	if err != nil {
 		return dto.PengeluaranPaginationResponse{}, err
 	}
@@ -70,6 +71,7 @@ func (s *pengeluaranService) GetAllPengeluaran(ctx context.Context, req dto.Pagi
 			KategoriPengeluaran: pengeluaran.KategoriPengeluaran,
 			Jumlah:              pengeluaran.Jumlah,
 			Tujuan:              pengeluaran.Tujuan,
+			CabangID:            pengeluaran.CabangID,
 		}
 		pengeluaranResponses = append(pengeluaranResponses, pengeluaranResponse)
 
@@ -116,6 +118,7 @@ func (s *pengeluaranService) GetPengeluaranByID(ctx context.Context, pengeluaran
 		KategoriPengeluaran: pengeluaran.KategoriPengeluaran,
 		Jumlah:              pengeluaran.Jumlah,
 		Tujuan:              pengeluaran.Tujuan,
+		CabangID:            pengeluaran.CabangID,
 	}, nil
 }
 
@@ -134,6 +137,7 @@ func (s *pengeluaranService) UpdatePengeluaran(ctx context.Context, req dto.Peng
 		KategoriPengeluaran: req.KategoriPengeluaran,
 		Jumlah:              req.Jumlah,
 		Tujuan:              req.Tujuan,
+		CabangID:            req.CabangID,
 	}
 
 	pengeluaranUpdate, err := s.pengeluaranRepo.UpdatePengeluaran(ctx, data)
@@ -150,6 +154,7 @@ func (s *pengeluaranService) UpdatePengeluaran(ctx context.Context, req dto.Peng
 		KategoriPengeluaran: pengeluaranUpdate.KategoriPengeluaran,
 		Jumlah:              pengeluaranUpdate.Jumlah,
 		Tujuan:              pengeluaranUpdate.Tujuan,
+		CabangID:            pengeluaranUpdate.CabangID,
 	}, nil
 }
 
@@ -167,48 +172,37 @@ func (s *pengeluaranService) DeletePengeluaran(ctx context.Context, pengeluaranI
 	return nil
 }
 
-func (s *pengeluaranService) DownloadDataPengeluaran(ctx context.Context, filter string, start string, end string) ([]byte, error) {
+var pengeluaranTable = utils.Table{
+	Title: "Data Pengeluaran",
+	Columns: []utils.Column{
+		{Header: "ID", Type: utils.ColumnInt},
+		{Header: "Nama Pengeluaran"},
+		{Header: "Tipe Pembayaran"},
+		{Header: "Tanggal Pengeluaran", Type: utils.ColumnDate},
+		{Header: "Description"},
+		{Header: "Jumlah", Type: utils.ColumnMoney},
+		{Header: "Tujuan"},
+	},
+}
+
+func (s *pengeluaranService) DownloadDataPengeluaran(ctx context.Context, filter string, start string, end string, cabang int, format string) ([]byte, error) {
 	queryFilters := dto.PaginationRequest{
 		Search:  "",
 		Page:    1,
 		PerPage: 10000,
 	}
 
-	pengeluarans, err := s.pengeluaranRepo.GetAllPengeluaranWithPagination(ctx, queryFilters, filter, start, end)
-	if err != nil {
-		return nil, err
-	}
-
-	f := excelize.NewFile()
-	f.SetSheetName("Sheet1", "Data Pengeluaran")
-	sheetIndex, err := f.GetSheetIndex("Data Pengeluaran")
+	pengeluarans, err := s.pengeluaranRepo.GetAllPengeluaranWithPagination(ctx, queryFilters, filter, start, end, cabang)
 	if err != nil {
 		return nil, err
 	}
-	f.SetActiveSheet(sheetIndex)
 
-	headers := []string{"ID", "Nama Pengeluaran", "Tipe Pembayaran", "Tanggal Pengeluaran", "Description", "Jumlah", "Tujuan"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c%d", 'A'+i, 1)
-		f.SetCellValue("Data Pengeluaran", cell, header)
-	}
-
-	for i, pengeluaran := range pengeluarans.Data {
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("A%d", i+2), pengeluaran.ID)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("B%d", i+2), pengeluaran.NamaPengeluaran)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("C%d", i+2), pengeluaran.TipePembayaran)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("D%d", i+2), pengeluaran.TanggalPengeluaran)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("E%d", i+2), pengeluaran.Description)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("F%d", i+2), pengeluaran.Jumlah)
-		f.SetCellValue("Data Pengeluaran", fmt.Sprintf("G%d", i+2), pengeluaran.Tujuan)
-	}
-
-	// Save Excel content to a buffer
-	buf := new(bytes.Buffer)
-	if err := f.Write(buf); err != nil {
-		return nil, err
-	}
-
-	// Return the buffer's bytes
-	return buf.Bytes(), nil
+	return utils.RenderTable(format, pengeluaranTable, func(tw utils.TableWriter) error {
+		for _, pengeluaran := range pengeluarans.Data {
+			if err := tw.WriteRow(pengeluaran.ID, pengeluaran.NamaPengeluaran, pengeluaran.TipePembayaran, pengeluaran.TanggalPengeluaran, pengeluaran.Description, pengeluaran.Jumlah, pengeluaran.Tujuan); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bumisubur-be/constants"
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
@@ -9,7 +10,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type ProdukService interface {
@@ -32,23 +38,47 @@ type ProdukService interface {
 
 	GetIndexFinalStok(ctx context.Context) (dto.IndexFinalStok, error)
 	FinalStokProduk(ctx context.Context, filter dto.FilterFinalStok) (any, error)
+	DownloadFinalStok(ctx context.Context, filter dto.FilterFinalStok, format string) ([]byte, error)
 	InsertProduk(ctx context.Context, restokID string) (entity.Produk, error)
+
+	ImportProduk(ctx context.Context, req dto.ProdukImportRequest) (dto.ProdukImportResponse, error)
+	ImportProdukTemplate(ctx context.Context, format string) ([]byte, error)
 }
 
 type produkService struct {
-	produkRepo   repository.ProdukRepository
-	jenisRepo    repository.JenisRepository
-	merkRepo     repository.MerkRepository
-	supplierRepo repository.SupplierRepository
+	produkRepo    repository.ProdukRepository
+	jenisRepo     repository.JenisRepository
+	merkRepo      repository.MerkRepository
+	supplierRepo  repository.SupplierRepository
+	attributeRepo repository.AttributeRepository
 }
 
-func NewProdukService(produkRepo repository.ProdukRepository, jenisRepo repository.JenisRepository, merkRepo repository.MerkRepository, supplierRepo repository.SupplierRepository) ProdukService {
+func NewProdukService(produkRepo repository.ProdukRepository, jenisRepo repository.JenisRepository, merkRepo repository.MerkRepository, supplierRepo repository.SupplierRepository, attributeRepo repository.AttributeRepository) ProdukService {
 	return &produkService{
-		produkRepo:   produkRepo,
-		jenisRepo:    jenisRepo,
-		merkRepo:     merkRepo,
-		supplierRepo: supplierRepo,
+		produkRepo:    produkRepo,
+		jenisRepo:     jenisRepo,
+		merkRepo:      merkRepo,
+		supplierRepo:  supplierRepo,
+		attributeRepo: attributeRepo,
+	}
+}
+
+// standardizeDetails rewrites the ukuran and warna of each detail to the
+// values of the jenis' attribute sets, rejecting values outside them.
+func (s *produkService) standardizeDetails(ctx context.Context, tx *gorm.DB, jenisID int, details []dto.DetailRequest) error {
+	standard, err := loadVariantStandard(ctx, tx, s.jenisRepo, s.attributeRepo, jenisID)
+	if err != nil {
+		return err
+	}
+
+	for i := range details {
+		details[i].Ukuran, details[i].Warna, err = standard.standardize(details[i].Ukuran, details[i].Warna)
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 func (s *produkService) IndexRestokProduk(ctx context.Context) (dto.IndexRestok, error) {
@@ -118,6 +148,12 @@ func (s *produkService) IndexRestokProduk(ctx context.Context) (dto.IndexRestok,
 }
 
 func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukRequest) (dto.CreateProdukResponse, error) {
+	return s.createProduk(ctx, nil, produk)
+}
+
+// createProduk registers a new product with its variants as a pending
+// restok. tx may be nil; the bulk import passes its transaction.
+func (s *produkService) createProduk(ctx context.Context, tx *gorm.DB, produk dto.ProdukRequest) (dto.CreateProdukResponse, error) {
 	existingProduk, err := s.produkRepo.GetProdukByBarcodeID(ctx, produk.BarcodeId)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
@@ -126,7 +162,11 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 		return dto.CreateProdukResponse{}, errors.New("product with the same barcode ID already exists")
 	}
 
-	DetailMerkSupply, err := s.produkRepo.GetDetailMerkSupplier(ctx, nil, produk.MerkId, produk.JenisId, produk.SupplierId)
+	if err := s.standardizeDetails(ctx, tx, produk.JenisId, produk.Detail); err != nil {
+		return dto.CreateProdukResponse{}, err
+	}
+
+	DetailMerkSupply, err := s.produkRepo.GetDetailMerkSupplier(ctx, tx, produk.MerkId, produk.JenisId, produk.SupplierId)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
 	}
@@ -140,7 +180,7 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 		HargaJual:  produk.HargaJual,
 	}
 
-	createdProduk, err := s.produkRepo.CreateProduk(ctx, nil, produkEntity)
+	createdProduk, err := s.produkRepo.CreateProduk(ctx, tx, produkEntity)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
 	}
@@ -151,7 +191,7 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 		TanggalRestok: tanggalRestok,
 	}
 
-	Restok, err := s.produkRepo.CreateRestok(ctx, nil, restokEntity)
+	Restok, err := s.produkRepo.CreateRestok(ctx, tx, restokEntity)
 	if err != nil {
 		log.Printf("Error creating Restok: %v\n", err)
 		return dto.CreateProdukResponse{}, err
@@ -170,7 +210,7 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 			HargaBeli:            createdProduk.HargaJual - (createdProduk.HargaJual * (float64(DetailMerkSupply.Discount) / 100)),
 		}
 
-		createdDetailProduk, err := s.produkRepo.CreateDetailProduk(ctx, nil, produkItemEntity)
+		createdDetailProduk, err := s.produkRepo.CreateDetailProduk(ctx, tx, produkItemEntity)
 		if err != nil {
 			log.Printf("Error creating DetailProduk: %v\n", err)
 			return dto.CreateProdukResponse{}, err
@@ -182,7 +222,7 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 			DetailProdukID: createdDetailProduk.ID,
 		}
 
-		_, err = s.produkRepo.CreateDetailRestok(ctx, nil, detailRestok)
+		_, err = s.produkRepo.CreateDetailRestok(ctx, tx, detailRestok)
 		if err != nil {
 			log.Printf("Error creating DetailRestok: %v\n", err)
 			return dto.CreateProdukResponse{}, err
@@ -211,6 +251,16 @@ func (s *produkService) CreateProduk(ctx context.Context, produk dto.ProdukReque
 }
 
 func (s *produkService) CreateOldProduk(ctx context.Context, produk dto.OldProdukRequest) (dto.CreateProdukResponse, error) {
+	return s.createOldProduk(ctx, nil, produk)
+}
+
+// createOldProduk adds a pending restok of new variants to an existing
+// product. tx may be nil; the bulk import passes its transaction.
+func (s *produkService) createOldProduk(ctx context.Context, tx *gorm.DB, produk dto.OldProdukRequest) (dto.CreateProdukResponse, error) {
+	if err := s.standardizeDetails(ctx, tx, produk.JenisId, produk.Details); err != nil {
+		return dto.CreateProdukResponse{}, err
+	}
+
 	Produk, err := s.produkRepo.GetProdukByID(ctx, produk.ProdukId)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
@@ -226,7 +276,7 @@ func (s *produkService) CreateOldProduk(ctx context.Context, produk dto.OldProdu
 		HargaJual:  produk.HargaJual,
 	}
 
-	Produk, err = s.produkRepo.UpdateProduk(ctx, nil, entityProduk)
+	Produk, err = s.produkRepo.UpdateProduk(ctx, tx, entityProduk)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
 	}
@@ -237,12 +287,12 @@ func (s *produkService) CreateOldProduk(ctx context.Context, produk dto.OldProdu
 		TanggalRestok: tanggalRestok,
 	}
 
-	Restok, err := s.produkRepo.CreateRestok(ctx, nil, restokEntity)
+	Restok, err := s.produkRepo.CreateRestok(ctx, tx, restokEntity)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
 	}
 
-	DetailMerkSupply, err := s.produkRepo.GetDetailMerkSupplier(ctx, nil, produk.MerkId, produk.JenisId, produk.SupplierId)
+	DetailMerkSupply, err := s.produkRepo.GetDetailMerkSupplier(ctx, tx, produk.MerkId, produk.JenisId, produk.SupplierId)
 	if err != nil {
 		return dto.CreateProdukResponse{}, err
 	}
@@ -260,7 +310,7 @@ func (s *produkService) CreateOldProduk(ctx context.Context, produk dto.OldProdu
 			Status:               0,
 		}
 
-		createdDetailProduk, err := s.produkRepo.CreateDetailProduk(ctx, nil, produkItemEntity)
+		createdDetailProduk, err := s.produkRepo.CreateDetailProduk(ctx, tx, produkItemEntity)
 		if err != nil {
 			return dto.CreateProdukResponse{}, err
 		}
@@ -271,7 +321,7 @@ func (s *produkService) CreateOldProduk(ctx context.Context, produk dto.OldProdu
 			DetailProdukID: createdDetailProduk.ID,
 		}
 
-		_, err = s.produkRepo.CreateDetailRestok(ctx, nil, detailRestok)
+		_, err = s.produkRepo.CreateDetailRestok(ctx, tx, detailRestok)
 		if err != nil {
 			log.Printf("Error creating DetailRestok: %v\n", err)
 			return dto.CreateProdukResponse{}, err
@@ -309,6 +359,9 @@ func (s *produkService) GetAllProdukWithPagination(ctx context.Context, req dto.
 }
 
 func (s *produkService) UpdateDetailedPendingProduks(ctx context.Context, produk dto.EditPendingRestok) (dto.PendingStok, error) {
+	if err := s.standardizeDetails(ctx, nil, produk.JenisId, produk.Details); err != nil {
+		return dto.PendingStok{}, err
+	}
 
 	err := s.produkRepo.DeleteDetailPendingOnly(ctx, nil, produk.RestokID)
 	if err != nil {
@@ -519,3 +572,500 @@ func (s *produkService) FinalStokProduk(ctx context.Context, filter dto.FilterFi
 		return s.produkRepo.GetFinalStok(ctx, filter)
 	}
 }
+
+var finalStokTable = utils.Table{
+	Title: "Stok Final",
+	Columns: []utils.Column{
+		{Header: "Merk"},
+		{Header: "Jenis"},
+		{Header: "Nama Produk"},
+		{Header: "Barcode"},
+		{Header: "Ukuran"},
+		{Header: "Warna"},
+		{Header: "Stok", Type: utils.ColumnInt},
+		{Header: "Harga Jual", Type: utils.ColumnMoney},
+		{Header: "Total Notional", Type: utils.ColumnMoney},
+	},
+}
+
+func (s *produkService) DownloadFinalStok(ctx context.Context, filter dto.FilterFinalStok, format string) ([]byte, error) {
+	stoks, err := s.produkRepo.GetFinalStok(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.RenderTable(format, finalStokTable, func(tw utils.TableWriter) error {
+		var totalStok int
+		var totalNotional float64
+		for _, stok := range stoks {
+			if err := tw.WriteRow(stok.MerkNama, stok.NamaJenis, stok.NamaProduk, stok.BarcodeID, stok.Ukuran, stok.Warna, stok.Stok, stok.HargaJual, stok.TotalNotional); err != nil {
+				return err
+			}
+			totalStok += stok.Stok
+			totalNotional += stok.TotalNotional
+		}
+
+		return tw.WriteRow("Total", nil, nil, nil, nil, nil, totalStok, nil, totalNotional)
+	})
+}
+
+const PRODUK_IMPORT_MAX_ROWS = 2000
+
+// produkImportTable is the template of the bulk import. Rows that share a
+// barcode are the variants of one product.
+var produkImportTable = utils.Table{
+	Title: "Impor Produk",
+	Columns: []utils.Column{
+		{Header: "Barcode"},
+		{Header: "Nama Produk"},
+		{Header: "Merk"},
+		{Header: "Jenis"},
+		{Header: "Supplier"},
+		{Header: "Cabang"},
+		{Header: "Harga Jual", Type: utils.ColumnMoney},
+		{Header: "Ukuran"},
+		{Header: "Warna"},
+		{Header: "Qty", Type: utils.ColumnInt},
+	},
+}
+
+const (
+	importBarcode = iota
+	importNama
+	importMerk
+	importJenis
+	importSupplier
+	importCabang
+	importHargaJual
+	importUkuran
+	importWarna
+	importQty
+)
+
+// produkImportFields are the header names each template column is also
+// accepted under, in the order of produkImportTable.
+var produkImportFields = [][]string{
+	{"barcode", "barcode_id"},
+	{"nama_produk", "nama"},
+	{"merk"},
+	{"jenis", "kategori"},
+	{"supplier"},
+	{"cabang"},
+	{"harga_jual", "harga"},
+	{"ukuran"},
+	{"warna"},
+	{"qty", "jumlah", "stok"},
+}
+
+type (
+	// importNames resolves master data typed into an import file, by name
+	// ignoring case or by ID.
+	importNames struct {
+		byName map[string]int
+		names  map[int]string
+	}
+
+	produkImportGroup struct {
+		result     dto.ProdukImportResult
+		merkID     int
+		jenisID    int
+		supplierID int
+		cabangID   int
+		existingID int
+		firstRow   int
+		variants   map[string]int
+	}
+)
+
+func newImportNames() *importNames {
+	return &importNames{byName: map[string]int{}, names: map[int]string{}}
+}
+
+func (n *importNames) add(id int, name string) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if _, exists := n.byName[key]; !exists {
+		n.byName[key] = id
+	}
+	n.names[id] = name
+}
+
+func (n *importNames) find(text string) (int, string, bool) {
+	if id, ok := n.byName[strings.ToLower(text)]; ok {
+		return id, n.names[id], true
+	}
+	if id, err := strconv.Atoi(text); err == nil {
+		if name, ok := n.names[id]; ok {
+			return id, name, true
+		}
+	}
+
+	return 0, "", false
+}
+
+// findImportJenis finds a jenis by path or name as jenisLookup.find does, or
+// by ID like importNames.
+func findImportJenis(lookup *jenisLookup, text string) (entity.Jenis, error) {
+	jenis, err := lookup.find(text)
+	if errors.Is(err, dto.ErrJenisNotFound) {
+		if id, convErr := strconv.Atoi(text); convErr == nil {
+			if byID, ok := lookup.tree[id]; ok {
+				return byID, nil
+			}
+		}
+	}
+	return jenis, err
+}
+
+func (s *produkService) ImportProdukTemplate(ctx context.Context, format string) ([]byte, error) {
+	if format == constants.ENUM_FORMAT_PDF {
+		return nil, dto.ErrImportTemplatePDF
+	}
+
+	return utils.RenderTable(format, produkImportTable, func(tw utils.TableWriter) error {
+		return nil
+	})
+}
+
+// ImportProduk checks every row of an uploaded product file and reports the
+// errors per cell. With Confirm and no errors, each product is saved as a
+// pending restok the same way CreateProduk and CreateOldProduk do, all in
+// one transaction.
+func (s *produkService) ImportProduk(ctx context.Context, req dto.ProdukImportRequest) (dto.ProdukImportResponse, error) {
+	tanggalRestok := time.Now().Format("2006-01-02")
+	if req.TanggalRestok != "" {
+		if _, err := time.Parse("2006-01-02", req.TanggalRestok); err != nil {
+			return dto.ProdukImportResponse{}, dto.ErrImportTanggalRestok
+		}
+		tanggalRestok = req.TanggalRestok
+	}
+
+	rows, err := utils.ReadTableFile(req.File)
+	if err != nil {
+		return dto.ProdukImportResponse{}, err
+	}
+	if len(rows)-1 > PRODUK_IMPORT_MAX_ROWS {
+		return dto.ProdukImportResponse{}, dto.ErrImportTooManyRows
+	}
+
+	groups, response, err := s.planProdukImport(ctx, rows)
+	if err != nil {
+		return dto.ProdukImportResponse{}, err
+	}
+	if !req.Confirm {
+		return response, nil
+	}
+	if len(response.Errors) > 0 {
+		return response, dto.ErrImportHasErrors
+	}
+
+	err = s.produkRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		for i, group := range groups {
+			details := group.result.Details
+
+			var created dto.CreateProdukResponse
+			var err error
+			if group.existingID != 0 {
+				created, err = s.createOldProduk(ctx, tx, dto.OldProdukRequest{
+					ProdukId:      group.existingID,
+					MerkId:        group.merkID,
+					JenisId:       group.jenisID,
+					CabangId:      group.cabangID,
+					SupplierId:    group.supplierID,
+					HargaJual:     group.result.HargaJual,
+					TanggalRestok: tanggalRestok,
+					Details:       details,
+				})
+			} else {
+				created, err = s.createProduk(ctx, tx, dto.ProdukRequest{
+					BarcodeId:     group.result.BarcodeID,
+					NamaProduk:    group.result.NamaProduk,
+					SupplierId:    group.supplierID,
+					MerkId:        group.merkID,
+					HargaJual:     group.result.HargaJual,
+					CabangId:      group.cabangID,
+					JenisId:       group.jenisID,
+					TanggalRestok: tanggalRestok,
+					Detail:        details,
+				})
+			}
+			if err != nil {
+				return fmt.Errorf("baris %d: %w", group.firstRow, err)
+			}
+
+			response.Produks[i].ProdukID = created.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return dto.ProdukImportResponse{}, err
+	}
+
+	response.Confirmed = true
+	return response, nil
+}
+
+// planProdukImport validates the rows against the merk, jenis, supplier and
+// cabang master data and groups them by barcode. Row errors are returned in
+// the response; err is only set when the check itself fails.
+func (s *produkService) planProdukImport(ctx context.Context, rows [][]string) ([]*produkImportGroup, dto.ProdukImportResponse, error) {
+	response := dto.ProdukImportResponse{
+		Errors:  []dto.ImportRowError{},
+		Produks: []dto.ProdukImportResult{},
+	}
+
+	index := utils.ImportColumns(rows[0], produkImportFields)
+	for field, column := range index {
+		if column < 0 {
+			response.Errors = append(response.Errors, dto.ImportRowError{
+				Row:     1,
+				Column:  produkImportTable.Columns[field].Header,
+				Message: dto.ErrImportMissingColumn.Error(),
+			})
+		}
+	}
+	if len(response.Errors) > 0 {
+		return nil, response, nil
+	}
+
+	merks, suppliers, cabangs := newImportNames(), newImportNames(), newImportNames()
+
+	allMerk, err := s.merkRepo.GetAllMerk(ctx, nil)
+	if err != nil {
+		return nil, response, err
+	}
+	for _, merk := range allMerk {
+		merks.add(merk.ID, merk.Nama)
+	}
+
+	tree, err := loadKategoriTree(ctx, nil, s.jenisRepo)
+	if err != nil {
+		return nil, response, err
+	}
+	jenis := newJenisLookup(tree)
+
+	allSupplier, err := s.supplierRepo.GetAllSupplier(ctx, nil)
+	if err != nil {
+		return nil, response, err
+	}
+	for _, supplier := range allSupplier {
+		suppliers.add(supplier.ID, supplier.Name)
+	}
+
+	allCabang, err := s.produkRepo.GetAllCabang(ctx, nil)
+	if err != nil {
+		return nil, response, err
+	}
+	for _, cabang := range allCabang {
+		cabangs.add(cabang.ID, cabang.Name)
+	}
+
+	// supplies caches, per supplier, the merk and jenis pairs it delivers.
+	supplies := map[int]map[[2]int]bool{}
+	supplyOf := func(supplierID int) (map[[2]int]bool, error) {
+		if supply, ok := supplies[supplierID]; ok {
+			return supply, nil
+		}
+		details, err := s.produkRepo.GetDetailMerkSuppliersBySupplierID(ctx, supplierID)
+		if err != nil {
+			return nil, err
+		}
+		supply := map[[2]int]bool{}
+		for _, detail := range details {
+			supply[[2]int{detail.MerkID, detail.JenisID}] = true
+		}
+		supplies[supplierID] = supply
+		return supply, nil
+	}
+
+	// standards caches the ukuran and warna sets per jenis.
+	standards := map[int]variantStandard{}
+	standardOf := func(jenisID int) (variantStandard, error) {
+		if standard, ok := standards[jenisID]; ok {
+			return standard, nil
+		}
+		standard, err := loadVariantStandard(ctx, nil, s.jenisRepo, s.attributeRepo, jenisID)
+		if err != nil {
+			return standard, err
+		}
+		standards[jenisID] = standard
+		return standard, nil
+	}
+
+	var groups []*produkImportGroup
+	byBarcode := map[string]*produkImportGroup{}
+
+	for n, row := range rows[1:] {
+		if utils.ImportRowBlank(row) {
+			continue
+		}
+		response.Rows++
+
+		rowNumber := n + 2
+		before := len(response.Errors)
+		cell := func(field int) string {
+			return utils.ImportCell(row, index[field])
+		}
+		fail := func(field int, format string, args ...any) {
+			response.Errors = append(response.Errors, dto.ImportRowError{
+				Row:     rowNumber,
+				Column:  produkImportTable.Columns[field].Header,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		for _, field := range []int{importBarcode, importNama, importMerk, importJenis, importSupplier, importCabang, importHargaJual, importUkuran, importWarna, importQty} {
+			if cell(field) == "" {
+				fail(field, "wajib diisi")
+			}
+		}
+		if len(response.Errors) > before {
+			continue
+		}
+
+		merkID, merkName, ok := merks.find(cell(importMerk))
+		if !ok {
+			fail(importMerk, "merk %q tidak terdaftar", cell(importMerk))
+		}
+		var jenisID int
+		var jenisName string
+		found, err := findImportJenis(jenis, cell(importJenis))
+		switch {
+		case err == nil:
+			jenisID, jenisName = found.ID, tree.path(found.ID)
+		case errors.Is(err, dto.ErrJenisNotFound):
+			fail(importJenis, "jenis %q tidak terdaftar", cell(importJenis))
+		default:
+			fail(importJenis, "%s", err.Error())
+		}
+		supplierID, supplierName, ok := suppliers.find(cell(importSupplier))
+		if !ok {
+			fail(importSupplier, "supplier %q tidak terdaftar", cell(importSupplier))
+		}
+		cabangID, cabangName, ok := cabangs.find(cell(importCabang))
+		if !ok {
+			fail(importCabang, "cabang %q tidak terdaftar", cell(importCabang))
+		}
+
+		hargaJual, err := utils.ParseImportNumber(cell(importHargaJual))
+		if err != nil {
+			fail(importHargaJual, "%s", err.Error())
+		} else if hargaJual <= 0 {
+			fail(importHargaJual, "harus lebih dari 0")
+		}
+
+		qty, err := utils.ParseImportNumber(cell(importQty))
+		if err != nil {
+			fail(importQty, "%s", err.Error())
+		} else if qty <= 0 || qty != math.Trunc(qty) {
+			fail(importQty, "harus bilangan bulat lebih dari 0")
+		}
+
+		ukuran, warna := cell(importUkuran), cell(importWarna)
+		if jenisID != 0 {
+			standard, err := standardOf(jenisID)
+			if err != nil {
+				return nil, response, err
+			}
+			if ukuran, err = standardValue(standard.ukuran, ukuran, dto.ErrVariantUkuran); err != nil {
+				fail(importUkuran, "%s", err.Error())
+			}
+			if warna, err = standardValue(standard.warna, warna, dto.ErrVariantWarna); err != nil {
+				fail(importWarna, "%s", err.Error())
+			}
+		}
+
+		if merkID != 0 && jenisID != 0 && supplierID != 0 {
+			supply, err := supplyOf(supplierID)
+			if err != nil {
+				return nil, response, err
+			}
+			if !supply[[2]int{merkID, jenisID}] {
+				fail(importSupplier, "supplier %s tidak memasok merk %s jenis %s", supplierName, merkName, jenisName)
+			}
+		}
+		if len(response.Errors) > before {
+			continue
+		}
+
+		barcode := cell(importBarcode)
+		nama := cell(importNama)
+		group := byBarcode[barcode]
+		if group == nil {
+			existing, err := s.produkRepo.GetProdukByBarcodeID(ctx, barcode)
+			if err != nil {
+				return nil, response, err
+			}
+			if existing.ID != 0 {
+				if existing.CabangID != cabangID {
+					fail(importCabang, "barcode sudah terdaftar di cabang lain")
+				}
+				if !strings.EqualFold(existing.NamaProduk, nama) {
+					fail(importNama, "barcode sudah terdaftar untuk produk %q", existing.NamaProduk)
+				}
+				if len(response.Errors) > before {
+					continue
+				}
+				nama = existing.NamaProduk
+			}
+
+			group = &produkImportGroup{
+				result: dto.ProdukImportResult{
+					BarcodeID:  barcode,
+					NamaProduk: nama,
+					Merk:       merkName,
+					Jenis:      jenisName,
+					Supplier:   supplierName,
+					Cabang:     cabangName,
+					HargaJual:  hargaJual,
+					Existing:   existing.ID != 0,
+					Rows:       []int{},
+					Details:    []dto.DetailRequest{},
+				},
+				merkID:     merkID,
+				jenisID:    jenisID,
+				supplierID: supplierID,
+				cabangID:   cabangID,
+				existingID: existing.ID,
+				firstRow:   rowNumber,
+				variants:   map[string]int{},
+			}
+			byBarcode[barcode] = group
+			groups = append(groups, group)
+		} else {
+			different := func(field int, same bool) {
+				if !same {
+					fail(field, "berbeda dengan baris %d untuk barcode yang sama", group.firstRow)
+				}
+			}
+			different(importNama, strings.EqualFold(group.result.NamaProduk, nama))
+			different(importMerk, group.merkID == merkID)
+			different(importJenis, group.jenisID == jenisID)
+			different(importSupplier, group.supplierID == supplierID)
+			different(importCabang, group.cabangID == cabangID)
+			different(importHargaJual, group.result.HargaJual == hargaJual)
+		}
+
+		variant := strings.ToLower(ukuran) + "\x00" + strings.ToLower(warna)
+		if previous, exists := group.variants[variant]; exists {
+			fail(importUkuran, "ukuran dan warna sudah ada di baris %d", previous)
+		}
+		if len(response.Errors) > before {
+			continue
+		}
+
+		group.variants[variant] = rowNumber
+		group.result.Rows = append(group.result.Rows, rowNumber)
+		group.result.Qty += int(qty)
+		group.result.Details = append(group.result.Details, dto.DetailRequest{
+			Ukuran: ukuran,
+			Warna:  warna,
+			Stok:   int(qty),
+		})
+	}
+
+	for _, group := range groups {
+		response.Produks = append(response.Produks, group.result)
+	}
+
+	return groups, response, nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	REPLENISHMENT_DEFAULT_DAYS       = 30
+	REPLENISHMENT_DEFAULT_LEAD_DAYS  = 7
+	REPLENISHMENT_DEFAULT_COVER_DAYS = 30
+)
+
+type (
+	ReorderService interface {
+		GetReorderRules(ctx context.Context) ([]dto.ReorderRuleResponse, error)
+		SaveReorderRule(ctx context.Context, req dto.ReorderRuleRequest) (entity.ReorderRule, error)
+		DeleteReorderRule(ctx context.Context, ruleID int) error
+		UpdateVariantReorder(ctx context.Context, detailProdukID int, req dto.VariantReorderRequest) error
+		GetReplenishment(ctx context.Context, req dto.ReplenishmentRequest) (dto.ReplenishmentResponse, error)
+	}
+
+	reorderService struct {
+		reorderRepo repository.ReorderRepository
+	}
+
+	reorderRuleKey struct {
+		merkID  int
+		jenisID int
+	}
+)
+
+func NewReorderService(reorderRepo repository.ReorderRepository) ReorderService {
+	return &reorderService{
+		reorderRepo: reorderRepo,
+	}
+}
+
+func (s *reorderService) GetReorderRules(ctx context.Context) ([]dto.ReorderRuleResponse, error) {
+	return s.reorderRepo.GetReorderRules(ctx, nil)
+}
+
+// SaveReorderRule creates the rule for the merk/jenis scope or overwrites the
+// one that is already there.
+func (s *reorderService) SaveReorderRule(ctx context.Context, req dto.ReorderRuleRequest) (entity.ReorderRule, error) {
+	if req.MerkID == nil && req.JenisID == nil {
+		return entity.ReorderRule{}, dto.ErrReorderRuleScope
+	}
+
+	if req.MinStok < 0 || req.ReorderQty < 0 {
+		return entity.ReorderRule{}, dto.ErrReorderNegative
+	}
+
+	rule, _, err := s.reorderRepo.GetReorderRuleByScope(ctx, nil, req.MerkID, req.JenisID)
+	if err != nil {
+		return entity.ReorderRule{}, err
+	}
+
+	rule.MerkID = req.MerkID
+	rule.JenisID = req.JenisID
+	rule.MinStok = req.MinStok
+	rule.ReorderQty = req.ReorderQty
+
+	return s.reorderRepo.SaveReorderRule(ctx, nil, rule)
+}
+
+func (s *reorderService) DeleteReorderRule(ctx context.Context, ruleID int) error {
+	deleted, err := s.reorderRepo.DeleteReorderRule(ctx, nil, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return dto.ErrReorderRuleNotFound
+	}
+
+	return nil
+}
+
+func (s *reorderService) UpdateVariantReorder(ctx context.Context, detailProdukID int, req dto.VariantReorderRequest) error {
+	if (req.MinStok != nil && *req.MinStok < 0) || (req.ReorderQty != nil && *req.ReorderQty < 0) {
+		return dto.ErrReorderNegative
+	}
+
+	updated, err := s.reorderRepo.UpdateVariantReorder(ctx, nil, detailProdukID, req.MinStok, req.ReorderQty)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return dto.ErrVariantNotFound
+	}
+
+	return nil
+}
+
+// GetReplenishment suggests what to reorder. The reorder point of a variant
+// is its MinStok (variant, then merk+jenis, merk and jenis rule) or, without
+// any rule, what it sells during the lead time. Variants at or below their
+// reorder point are topped up to cover lead time plus cover days at the
+// recent sales pace, unless a fixed ReorderQty is configured.
+func (s *reorderService) GetReplenishment(ctx context.Context, req dto.ReplenishmentRequest) (dto.ReplenishmentResponse, error) {
+	if req.Days <= 0 {
+		req.Days = REPLENISHMENT_DEFAULT_DAYS
+	}
+	if req.LeadDays <= 0 {
+		req.LeadDays = REPLENISHMENT_DEFAULT_LEAD_DAYS
+	}
+	if req.CoverDays <= 0 {
+		req.CoverDays = REPLENISHMENT_DEFAULT_COVER_DAYS
+	}
+
+	since := time.Now().AddDate(0, 0, -req.Days)
+
+	variants, err := s.reorderRepo.GetReplenishmentVariants(ctx, nil, req, since)
+	if err != nil {
+		return dto.ReplenishmentResponse{}, err
+	}
+
+	ruleRows, err := s.reorderRepo.GetReorderRules(ctx, nil)
+	if err != nil {
+		return dto.ReplenishmentResponse{}, err
+	}
+
+	rules := map[reorderRuleKey]dto.ReorderRuleResponse{}
+	for _, rule := range ruleRows {
+		key := reorderRuleKey{}
+		if rule.MerkID != nil {
+			key.merkID = *rule.MerkID
+		}
+		if rule.JenisID != nil {
+			key.jenisID = *rule.JenisID
+		}
+		rules[key] = rule
+	}
+
+	supplierRows, err := s.reorderRepo.GetMerkJenisSuppliers(ctx, nil)
+	if err != nil {
+		return dto.ReplenishmentResponse{}, err
+	}
+
+	suppliers := map[reorderRuleKey][]dto.MerkJenisSupplierRow{}
+	for _, row := range supplierRows {
+		key := reorderRuleKey{merkID: row.MerkID, jenisID: row.JenisID}
+		suppliers[key] = append(suppliers[key], row)
+	}
+
+	result := dto.ReplenishmentResponse{
+		Days:      req.Days,
+		LeadDays:  req.LeadDays,
+		CoverDays: req.CoverDays,
+		Items:     []dto.ReplenishmentSuggestion{},
+	}
+
+	for _, variant := range variants {
+		velocity := float64(variant.SoldInWindow) / float64(req.Days)
+
+		minStok, reorderQty, source := resolveReorderRule(variant, rules)
+		reorderPoint := int(math.Ceil(velocity * float64(req.LeadDays)))
+		if source != constants.ENUM_REORDER_SOURCE_VELOCITY {
+			reorderPoint = minStok
+		}
+
+		if reorderPoint <= 0 || variant.Stok > reorderPoint {
+			continue
+		}
+
+		target := int(math.Ceil(velocity * float64(req.LeadDays+req.CoverDays)))
+		if target <= reorderPoint {
+			target = reorderPoint + 1
+		}
+
+		qty := target - variant.Stok
+		if reorderQty > 0 {
+			qty = reorderQty
+		}
+		if qty <= 0 {
+			continue
+		}
+
+		suggestion := dto.ReplenishmentSuggestion{
+			DetailProdukID: variant.DetailProdukID,
+			ProdukID:       variant.ProdukID,
+			NamaProduk:     variant.NamaProduk,
+			BarcodeID:      variant.BarcodeID,
+			CabangID:       variant.CabangID,
+			Merk:           variant.Merk,
+			Jenis:          variant.Jenis,
+			Ukuran:         variant.Ukuran,
+			Warna:          variant.Warna,
+			Stok:           variant.Stok,
+			SoldInWindow:   variant.SoldInWindow,
+			DailyVelocity:  velocity,
+			ReorderPoint:   reorderPoint,
+			SuggestedQty:   qty,
+			RuleSource:     source,
+			HargaBeli:      variant.HargaBeli,
+			EstimatedCost:  float64(qty) * variant.HargaBeli,
+			Suppliers:      rankReplenishmentSuppliers(variant, suppliers[reorderRuleKey{merkID: variant.MerkID, jenisID: variant.JenisID}]),
+		}
+
+		if velocity > 0 {
+			cover := float64(variant.Stok) / velocity
+			suggestion.DaysOfCover = &cover
+		}
+
+		result.TotalQty += qty
+		result.TotalCost += suggestion.EstimatedCost
+		result.Items = append(result.Items, suggestion)
+	}
+
+	// Most urgent first: the fewest days of stock left, then variants that
+	// only show up because of their MinStok.
+	sort.SliceStable(result.Items, func(i, j int) bool {
+		a, b := result.Items[i].DaysOfCover, result.Items[j].DaysOfCover
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	return result, nil
+}
+
+func resolveReorderRule(variant dto.ReplenishmentVariantRow, rules map[reorderRuleKey]dto.ReorderRuleResponse) (int, int, string) {
+	if variant.MinStok != nil || variant.ReorderQty != nil {
+		minStok, reorderQty := 0, 0
+		if variant.MinStok != nil {
+			minStok = *variant.MinStok
+		}
+		if variant.ReorderQty != nil {
+			reorderQty = *variant.ReorderQty
+		}
+		return minStok, reorderQty, constants.ENUM_REORDER_SOURCE_VARIANT
+	}
+
+	scopes := []struct {
+		key    reorderRuleKey
+		source string
+	}{
+		{reorderRuleKey{merkID: variant.MerkID, jenisID: variant.JenisID}, constants.ENUM_REORDER_SOURCE_MERK_JENIS},
+		{reorderRuleKey{merkID: variant.MerkID}, constants.ENUM_REORDER_SOURCE_MERK},
+		{reorderRuleKey{jenisID: variant.JenisID}, constants.ENUM_REORDER_SOURCE_JENIS},
+	}
+	for _, scope := range scopes {
+		if rule, ok := rules[scope.key]; ok {
+			return rule.MinStok, rule.ReorderQty, scope.source
+		}
+	}
+
+	return 0, 0, constants.ENUM_REORDER_SOURCE_VELOCITY
+}
+
+// rankReplenishmentSuppliers keeps the discount order of rows and marks the
+// supplier the variant was last bought from.
+func rankReplenishmentSuppliers(variant dto.ReplenishmentVariantRow, rows []dto.MerkJenisSupplierRow) []dto.ReplenishmentSupplier {
+	seen := map[int]bool{}
+	result := []dto.ReplenishmentSupplier{}
+
+	for _, row := range rows {
+		if seen[row.SupplierID] {
+			continue
+		}
+		seen[row.SupplierID] = true
+
+		result = append(result, dto.ReplenishmentSupplier{
+			SupplierID:       row.SupplierID,
+			Name:             row.Name,
+			NoHp:             row.NoHp,
+			Discount:         row.Discount,
+			SupplierDiscount: row.SupplierDiscount,
+			Current:          row.SupplierID == variant.SupplierID,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,471 @@
+package service
+
+import (
+	"bumisubur-be/constants"
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	REPORT_WORKER_INTERVAL   = time.Minute
+	REPORT_BATCH_SIZE        = 10
+	REPORT_MAX_ATTEMPTS      = 5
+	REPORT_RETRY_BASE        = 5 * time.Minute
+	REPORT_RETRY_MAX         = 2 * time.Hour
+	REPORT_DEFAULT_RUNS_SHOW = 50
+	REPORT_MAX_ROWS          = 100000
+)
+
+type (
+	ReportScheduleService interface {
+		GetSchedules(ctx context.Context) ([]dto.ReportScheduleResponse, error)
+		CreateSchedule(ctx context.Context, req dto.ReportScheduleRequest) (dto.ReportScheduleResponse, error)
+		UpdateSchedule(ctx context.Context, scheduleID int, req dto.ReportScheduleRequest) (dto.ReportScheduleResponse, error)
+		DeleteSchedule(ctx context.Context, scheduleID int) error
+		RunNow(ctx context.Context, scheduleID int) error
+		GetRuns(ctx context.Context, scheduleID int, req dto.ReportRunRequest) ([]entity.ReportRun, error)
+
+		QueueDueSchedules(ctx context.Context) error
+		DeliverDueRuns(ctx context.Context) error
+		StartWorker(ctx context.Context)
+	}
+
+	reportScheduleService struct {
+		reportScheduleRepo repository.ReportScheduleRepository
+		transaksiService   TransaksiService
+		pengeluaranService PengeluaranService
+		produkService      ProdukService
+		analyticsService   AnalyticsService
+		send               func(toEmail string, subject string, body string, fileName string, content []byte) error
+	}
+)
+
+func NewReportScheduleService(reportScheduleRepo repository.ReportScheduleRepository, transaksiService TransaksiService, pengeluaranService PengeluaranService, produkService ProdukService, analyticsService AnalyticsService) ReportScheduleService {
+	return &reportScheduleService{
+		reportScheduleRepo: reportScheduleRepo,
+		transaksiService:   transaksiService,
+		pengeluaranService: pengeluaranService,
+		produkService:      produkService,
+		analyticsService:   analyticsService,
+		send:               utils.SendMailWithAttachment,
+	}
+}
+
+func (s *reportScheduleService) GetSchedules(ctx context.Context) ([]dto.ReportScheduleResponse, error) {
+	schedules, err := s.reportScheduleRepo.GetSchedules(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]dto.ReportScheduleResponse, 0, len(schedules))
+	for _, schedule := range schedules {
+		result = append(result, toReportScheduleResponse(schedule))
+	}
+
+	return result, nil
+}
+
+func (s *reportScheduleService) CreateSchedule(ctx context.Context, req dto.ReportScheduleRequest) (dto.ReportScheduleResponse, error) {
+	schedule := entity.ReportSchedule{Active: true}
+	if err := applyReportSchedule(&schedule, req, time.Now()); err != nil {
+		return dto.ReportScheduleResponse{}, err
+	}
+
+	schedule, err := s.reportScheduleRepo.SaveSchedule(ctx, nil, schedule)
+	if err != nil {
+		return dto.ReportScheduleResponse{}, err
+	}
+
+	return toReportScheduleResponse(schedule), nil
+}
+
+func (s *reportScheduleService) UpdateSchedule(ctx context.Context, scheduleID int, req dto.ReportScheduleRequest) (dto.ReportScheduleResponse, error) {
+	schedule, err := s.getSchedule(ctx, scheduleID)
+	if err != nil {
+		return dto.ReportScheduleResponse{}, err
+	}
+
+	if err := applyReportSchedule(&schedule, req, time.Now()); err != nil {
+		return dto.ReportScheduleResponse{}, err
+	}
+
+	schedule, err = s.reportScheduleRepo.SaveSchedule(ctx, nil, schedule)
+	if err != nil {
+		return dto.ReportScheduleResponse{}, err
+	}
+
+	return toReportScheduleResponse(schedule), nil
+}
+
+func (s *reportScheduleService) DeleteSchedule(ctx context.Context, scheduleID int) error {
+	if _, err := s.getSchedule(ctx, scheduleID); err != nil {
+		return err
+	}
+
+	return s.reportScheduleRepo.DeleteSchedule(ctx, nil, scheduleID)
+}
+
+// RunNow queues a delivery for right now. The worker picks it up within a
+// minute, the same way it handles scheduled runs.
+func (s *reportScheduleService) RunNow(ctx context.Context, scheduleID int) error {
+	if _, err := s.getSchedule(ctx, scheduleID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.reportScheduleRepo.CreateRun(ctx, nil, entity.ReportRun{
+		ScheduleID:    scheduleID,
+		ScheduledFor:  now,
+		Manual:        true,
+		Status:        constants.ENUM_REPORT_RUN_PENDING,
+		NextAttemptAt: now,
+	})
+}
+
+func (s *reportScheduleService) GetRuns(ctx context.Context, scheduleID int, req dto.ReportRunRequest) ([]entity.ReportRun, error) {
+	if _, err := s.getSchedule(ctx, scheduleID); err != nil {
+		return nil, err
+	}
+
+	limit := req.PerPage
+	if limit <= 0 || limit > REPORT_DEFAULT_RUNS_SHOW {
+		limit = REPORT_DEFAULT_RUNS_SHOW
+	}
+
+	return s.reportScheduleRepo.GetRuns(ctx, nil, scheduleID, limit)
+}
+
+// QueueDueSchedules creates a run for every schedule whose time has come and
+// moves the schedule to its next time. Times missed while the server was
+// down are collapsed into a single run.
+func (s *reportScheduleService) QueueDueSchedules(ctx context.Context) error {
+	now := time.Now()
+	schedules, err := s.reportScheduleRepo.GetDueSchedules(ctx, nil, now)
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		err := s.reportScheduleRepo.CreateRun(ctx, nil, entity.ReportRun{
+			ScheduleID:    schedule.ID,
+			ScheduledFor:  *schedule.NextRunAt,
+			Status:        constants.ENUM_REPORT_RUN_PENDING,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+
+		lastRunAt := *schedule.NextRunAt
+		schedule.LastRunAt = &lastRunAt
+		schedule.NextRunAt = nextReportRun(schedule.Cron, now)
+
+		if _, err := s.reportScheduleRepo.SaveSchedule(ctx, nil, schedule); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeliverDueRuns builds the report of every pending run and mails it to the
+// recipients that have not received it yet. Failed runs are retried with
+// exponential backoff until REPORT_MAX_ATTEMPTS.
+func (s *reportScheduleService) DeliverDueRuns(ctx context.Context) error {
+	runs, err := s.reportScheduleRepo.GetDueRuns(ctx, nil, time.Now(), REPORT_BATCH_SIZE)
+	if err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		run.Attempts++
+
+		if err := s.deliverRun(ctx, &run); err != nil {
+			run.LastError = err.Error()
+			// A deleted schedule will not come back, so stop retrying.
+			if run.Attempts >= REPORT_MAX_ATTEMPTS || errors.Is(err, gorm.ErrRecordNotFound) {
+				finishedAt := time.Now()
+				run.Status = constants.ENUM_REPORT_RUN_FAILED
+				run.FinishedAt = &finishedAt
+			} else {
+				backoff := REPORT_RETRY_BASE << (run.Attempts - 1)
+				if backoff > REPORT_RETRY_MAX {
+					backoff = REPORT_RETRY_MAX
+				}
+				run.NextAttemptAt = time.Now().Add(backoff)
+			}
+		} else {
+			finishedAt := time.Now()
+			run.Status = constants.ENUM_REPORT_RUN_SUCCESS
+			run.FinishedAt = &finishedAt
+			run.LastError = ""
+		}
+
+		if err := s.reportScheduleRepo.UpdateRun(ctx, nil, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartWorker queues and delivers scheduled reports every minute. It blocks
+// until ctx is done, so run it in its own goroutine.
+func (s *reportScheduleService) StartWorker(ctx context.Context) {
+	ticker := time.NewTicker(REPORT_WORKER_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		if err := s.QueueDueSchedules(ctx); err != nil {
+			log.Printf("report schedule queue: %v", err)
+		}
+
+		if err := s.DeliverDueRuns(ctx); err != nil {
+			log.Printf("report schedule delivery: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *reportScheduleService) deliverRun(ctx context.Context, run *entity.ReportRun) error {
+	schedule, err := s.reportScheduleRepo.GetScheduleByID(ctx, nil, run.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	start, end := resolveReportPeriod(schedule.Report, schedule.Period, run.ScheduledFor)
+
+	content, err := s.buildReport(ctx, schedule, start, end)
+	if err != nil {
+		return err
+	}
+
+	run.FileName = reportFileName(schedule.Report, start, end)
+	subject := fmt.Sprintf("%s (%s)", schedule.Name, reportPeriodLabel(start, end))
+	body, err := utils.RenderNotificationMail(subject, []string{
+		fmt.Sprintf("Laporan %s terlampir sebagai %s.", schedule.Report, run.FileName),
+	})
+	if err != nil {
+		return err
+	}
+
+	sent := map[string]bool{}
+	for _, recipient := range splitRecipients(run.SentTo) {
+		sent[recipient] = true
+	}
+
+	var failed []string
+	for _, recipient := range splitRecipients(schedule.Recipients) {
+		if sent[recipient] {
+			continue
+		}
+
+		if err := s.send(recipient, subject, body, run.FileName, content); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", recipient, err))
+			continue
+		}
+
+		sent[recipient] = true
+		if run.SentTo == "" {
+			run.SentTo = recipient
+		} else {
+			run.SentTo += "," + recipient
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+func (s *reportScheduleService) buildReport(ctx context.Context, schedule entity.ReportSchedule, start time.Time, end time.Time) ([]byte, error) {
+	cabang := 0
+	if schedule.CabangID != nil {
+		cabang = *schedule.CabangID
+	}
+
+	startDate, endDate := start.Format("2006-01-02"), end.Format("2006-01-02")
+	transaksiReq := dto.TransactionPaginationRequest{
+		Page:      1,
+		PerPage:   REPORT_MAX_ROWS,
+		Cabang:    cabang,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+
+	switch schedule.Report {
+	case constants.ENUM_REPORT_NOTA:
+		return s.transaksiService.DownloadByNota(ctx, transaksiReq, constants.ENUM_FORMAT_XLSX)
+	case constants.ENUM_REPORT_PRODUK:
+		return s.transaksiService.DownloadByProduk(ctx, transaksiReq, constants.ENUM_FORMAT_XLSX)
+	case constants.ENUM_REPORT_PENGELUARAN:
+		return s.pengeluaranService.DownloadDataPengeluaran(ctx, "", startDate, endDate, cabang, constants.ENUM_FORMAT_XLSX)
+	case constants.ENUM_REPORT_FINAL_STOK:
+		return s.produkService.DownloadFinalStok(ctx, dto.FilterFinalStok{Cabang: cabang}, constants.ENUM_FORMAT_XLSX)
+	case constants.ENUM_REPORT_PROFIT_LOSS:
+		return s.analyticsService.DownloadProfitLoss(ctx, dto.AnalyticsRequest{StartDate: startDate, EndDate: endDate, Cabang: cabang}, constants.ENUM_FORMAT_XLSX)
+	}
+
+	return nil, dto.ErrReportInvalidType
+}
+
+func (s *reportScheduleService) getSchedule(ctx context.Context, scheduleID int) (entity.ReportSchedule, error) {
+	schedule, err := s.reportScheduleRepo.GetScheduleByID(ctx, nil, scheduleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ReportSchedule{}, dto.ErrReportScheduleNotFound
+		}
+		return entity.ReportSchedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func applyReportSchedule(schedule *entity.ReportSchedule, req dto.ReportScheduleRequest, now time.Time) error {
+	switch req.Report {
+	case constants.ENUM_REPORT_NOTA, constants.ENUM_REPORT_PRODUK, constants.ENUM_REPORT_PENGELUARAN,
+		constants.ENUM_REPORT_FINAL_STOK, constants.ENUM_REPORT_PROFIT_LOSS:
+	default:
+		return dto.ErrReportInvalidType
+	}
+
+	if req.Report == constants.ENUM_REPORT_PRODUK && req.CabangID == nil {
+		return dto.ErrReportCabangRequired
+	}
+
+	period := req.Period
+	if period == "" {
+		period = defaultReportPeriod(req.Report)
+	}
+	switch period {
+	case constants.ENUM_REPORT_PERIOD_TODAY, constants.ENUM_REPORT_PERIOD_YESTERDAY, constants.ENUM_REPORT_PERIOD_LAST_WEEK,
+		constants.ENUM_REPORT_PERIOD_THIS_MONTH, constants.ENUM_REPORT_PERIOD_LAST_MONTH:
+	default:
+		return dto.ErrReportInvalidPeriod
+	}
+
+	cron, err := utils.ParseCron(req.Cron)
+	if err != nil {
+		return err
+	}
+	next, ok := cron.Next(now)
+	if !ok {
+		return dto.ErrReportCronNeverFires
+	}
+
+	recipients, err := normalizeRecipients(req.Recipients)
+	if err != nil {
+		return err
+	}
+
+	schedule.Name = req.Name
+	schedule.Report = req.Report
+	schedule.Period = period
+	schedule.Cron = strings.Join(strings.Fields(req.Cron), " ")
+	schedule.CabangID = req.CabangID
+	schedule.Recipients = strings.Join(recipients, ",")
+	schedule.NextRunAt = &next
+	if req.Active != nil {
+		schedule.Active = *req.Active
+	}
+
+	return nil
+}
+
+func nextReportRun(expr string, after time.Time) *time.Time {
+	cron, err := utils.ParseCron(expr)
+	if err != nil {
+		return nil
+	}
+
+	next, ok := cron.Next(after)
+	if !ok {
+		return nil
+	}
+
+	return &next
+}
+
+// defaultReportPeriod covers the day before for the daily reports and the
+// month before for the P&L, which suits schedules that fire in the morning.
+func defaultReportPeriod(report string) string {
+	if report == constants.ENUM_REPORT_PROFIT_LOSS {
+		return constants.ENUM_REPORT_PERIOD_LAST_MONTH
+	}
+
+	return constants.ENUM_REPORT_PERIOD_YESTERDAY
+}
+
+// resolveReportPeriod returns the first and last day a report covers when
+// its schedule fired at at. Final stok is a snapshot, so it only has the day
+// it was taken.
+func resolveReportPeriod(report string, period string, at time.Time) (time.Time, time.Time) {
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	firstOfMonth := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+
+	if report == constants.ENUM_REPORT_FINAL_STOK {
+		return today, today
+	}
+
+	switch period {
+	case constants.ENUM_REPORT_PERIOD_TODAY:
+		return today, today
+	case constants.ENUM_REPORT_PERIOD_LAST_WEEK:
+		return today.AddDate(0, 0, -7), today.AddDate(0, 0, -1)
+	case constants.ENUM_REPORT_PERIOD_THIS_MONTH:
+		return firstOfMonth, today
+	case constants.ENUM_REPORT_PERIOD_LAST_MONTH:
+		return firstOfMonth.AddDate(0, -1, 0), firstOfMonth.AddDate(0, 0, -1)
+	}
+
+	yesterday := today.AddDate(0, 0, -1)
+	return yesterday, yesterday
+}
+
+func reportFileName(report string, start time.Time, end time.Time) string {
+	if start.Equal(end) {
+		return fmt.Sprintf("laporan_%s_%s.xlsx", report, start.Format("2006-01-02"))
+	}
+
+	return fmt.Sprintf("laporan_%s_%s_%s.xlsx", report, start.Format("2006-01-02"), end.Format("2006-01-02"))
+}
+
+func reportPeriodLabel(start time.Time, end time.Time) string {
+	if start.Equal(end) {
+		return start.Format("02-01-2006")
+	}
+
+	return fmt.Sprintf("%s - %s", start.Format("02-01-2006"), end.Format("02-01-2006"))
+}
+
+func toReportScheduleResponse(schedule entity.ReportSchedule) dto.ReportScheduleResponse {
+	return dto.ReportScheduleResponse{
+		ID:         schedule.ID,
+		Name:       schedule.Name,
+		Report:     schedule.Report,
+		Period:     schedule.Period,
+		Cron:       schedule.Cron,
+		CabangID:   schedule.CabangID,
+		Recipients: splitRecipients(schedule.Recipients),
+		Active:     schedule.Active,
+		NextRunAt:  schedule.NextRunAt,
+		LastRunAt:  schedule.LastRunAt,
+	}
+}
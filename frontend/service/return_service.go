@@ -21,18 +21,20 @@ type ReturnService interface {
 }
 
 type restokService struct {
-	returnRepo   repository.ReturnRepository
-	jenisRepo    repository.JenisRepository
-	merkRepo     repository.MerkRepository
-	supplierRepo repository.SupplierRepository
+	returnRepo          repository.ReturnRepository
+	jenisRepo           repository.JenisRepository
+	merkRepo            repository.MerkRepository
+	supplierRepo        repository.SupplierRepository
+	notificationService NotificationService
 }
 
-func NewReturnService(returnRepo repository.ReturnRepository, jenisRepo repository.JenisRepository, merkRepo repository.MerkRepository, supplierRepo repository.SupplierRepository) ReturnService {
+func NewReturnService(returnRepo repository.ReturnRepository, jenisRepo repository.JenisRepository, merkRepo repository.MerkRepository, supplierRepo repository.SupplierRepository, notificationService NotificationService) ReturnService {
 	return &restokService{
-		returnRepo:   returnRepo,
-		jenisRepo:    jenisRepo,
-		merkRepo:     merkRepo,
-		supplierRepo: supplierRepo,
+		returnRepo:          returnRepo,
+		jenisRepo:           jenisRepo,
+		merkRepo:            merkRepo,
+		supplierRepo:        supplierRepo,
+		notificationService: notificationService,
 	}
 }
 
@@ -103,7 +105,8 @@ func (rs *restokService) CreateReturnUser(ctx context.Context, returnData dto.Cr
 	}
 
 	// Process database updates
-	if err := rs.processReturnsUser(ctx, returnSummaries, oldData.TransaksiID, oldData.Diskon); err != nil {
+	totalReturnAmount, err := rs.processReturnsUser(ctx, returnSummaries, oldData.TransaksiID, oldData.Diskon)
+	if err != nil {
 		return nil, err
 	}
 
@@ -133,6 +136,8 @@ func (rs *restokService) CreateReturnUser(ctx context.Context, returnData dto.Cr
 
 	}
 
+	rs.notificationService.NotifyReturnUser(returnRes.ID, returnData.TransaksiID, totalReturnAmount, returnCabangID(returnSummaries, oldDetailMap))
+
 	var returnResponses []dto.CreateReturnUserResponse
 	for _, summary := range returnSummaries {
 		oldItem, exists := oldDetailMap[summary.DetailTransaksiID]
@@ -153,7 +158,9 @@ func (rs *restokService) CreateReturnUser(ctx context.Context, returnData dto.Cr
 	return returnResponses, nil
 }
 
-func (rs *restokService) processReturnsUser(ctx context.Context, returnSummaries []dto.ReturnSummary, transaksiID int64, diskon float64) error {
+// processReturnsUser puts the returned items back in stock and lowers the
+// transaksi total. It returns the amount refunded.
+func (rs *restokService) processReturnsUser(ctx context.Context, returnSummaries []dto.ReturnSummary, transaksiID int64, diskon float64) (float64, error) {
 	var totalReturnAmount float64
 
 	// Loop through return summaries and process returns
@@ -161,7 +168,7 @@ func (rs *restokService) processReturnsUser(ctx context.Context, returnSummaries
 		// Get the product price for the returned item
 		productPrice, err := rs.returnRepo.GetProductPrice(ctx, nil, item.DetailProdukID)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		adjustedReturnAmount := float64(item.JumlahReturn) * productPrice
@@ -171,19 +178,19 @@ func (rs *restokService) processReturnsUser(ctx context.Context, returnSummaries
 		totalReturnAmount += adjustedReturnAmount
 
 		if err := rs.returnRepo.IncreaseStock(ctx, item.DetailProdukID, item.JumlahReturn); err != nil {
-			return err
+			return 0, err
 		}
 
 		if err := rs.returnRepo.ReduceTransactionItem(ctx, item.DetailTransaksiID, item.JumlahReturn); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	if err := rs.returnRepo.UpdateTransactionTotal(ctx, transaksiID, totalReturnAmount); err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return totalReturnAmount, nil
 }
 
 func (rs *restokService) CreateReturnSupplier(ctx context.Context, returnData dto.CreateReturnSupplier) (any, error) {
@@ -264,6 +271,7 @@ func (rs *restokService) CreateReturnSupplier(ctx context.Context, returnData dt
 }
 
 func (rs *restokService) processReturnsSupplier(ctx context.Context, returnSummaries []dto.ReturnSummarySupplier) error {
+	detailProdukIDs := make([]int, 0, len(returnSummaries))
 	for _, item := range returnSummaries {
 
 		if err := rs.returnRepo.DecreaseStock(ctx, item.DetailProdukID, item.JumlahReturn); err != nil {
@@ -273,8 +281,11 @@ func (rs *restokService) processReturnsSupplier(ctx context.Context, returnSumma
 		if err := rs.returnRepo.ReduceRestokItem(ctx, item.DetailRestokID, item.JumlahReturn); err != nil {
 			return err
 		}
+		detailProdukIDs = append(detailProdukIDs, item.DetailProdukID)
 	}
 
+	rs.notificationService.NotifyStockChanged(detailProdukIDs)
+
 	return nil
 }
 
@@ -285,3 +296,19 @@ func (rs *restokService) GetHistoryReturnUser(ctx context.Context, req dto.GetHi
 func (rs *restokService) GetHistoryReturnSupplier(ctx context.Context, req dto.GetHistoryReturnFilter) ([]dto.HistoryReturnSupplier, error) {
 	return rs.returnRepo.GetReturnSupplierHistoryWithDetails(ctx, req.StartDate, req.EndDate)
 }
+
+// returnCabangID is the cabang of the returned lines, or nil when they come
+// from more than one cabang.
+func returnCabangID(returnSummaries []dto.ReturnSummary, details map[int]dto.DetailReturnUser) *int {
+	var cabangID *int
+	for _, summary := range returnSummaries {
+		detail := details[summary.DetailTransaksiID]
+		if cabangID == nil {
+			cabangID = &detail.CabangID
+		} else if *cabangID != detail.CabangID {
+			return nil
+		}
+	}
+
+	return cabangID
+}
@@ -1,31 +1,43 @@
 package service
 
 import (
+	"bumisubur-be/constants"
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
-	"bytes"
+	"bumisubur-be/utils"
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
-	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
 )
 
 type (
 	SupplierService interface {
-		CreateSupplier(ctx context.Context, req dto.SupplierRequest) (dto.SupplierResponse, error)
+		CreateSupplier(ctx context.Context, req dto.SupplierRequest, userID string) (dto.SupplierResponse, error)
 		GetAllSupplier(ctx context.Context, req dto.PaginationRequest) (dto.GetAllSupplierResponse, error)
 		GetSupplierByID(ctx context.Context, supplierID int) (dto.SupplierResponse, error)
-		UpdateSupplier(ctx context.Context, req dto.SupplierRequest, supplierID int) (dto.SupplierResponse, error)
-		UpdateSupplierSupply(ctx context.Context, req dto.UpdateSupplyRequest, supplierID int) (dto.SupplierResponse, error)
+		UpdateSupplier(ctx context.Context, req dto.SupplierRequest, supplierID int, userID string) (dto.SupplierResponse, error)
+		UpdateSupplierSupply(ctx context.Context, req dto.UpdateSupplyRequest, supplierID int, userID string) (dto.SupplierResponse, error)
+		GetSupplyDiscounts(ctx context.Context, supplierID int) ([]dto.SupplyDiscountResponse, error)
 		DeleteSupplier(ctx context.Context, supplierID int) error
 		Index(ctx context.Context) (dto.IndexResponse, error)
 
 		CreateJenis(ctx context.Context, req dto.JenisRequest) (dto.JenisResponse, error)
 		DeleteJenis(ctx context.Context, jenisID int) error
-		GetAllJenis(ctx context.Context) ([]dto.JenisResponse, error)
+		GetAllJenis(ctx context.Context, search string) ([]dto.JenisResponse, error)
+		GetJenisByID(ctx context.Context, jenisID int) (dto.JenisResponse, error)
+		UpdateJenis(ctx context.Context, req dto.JenisRequest, jenisID int) (dto.JenisResponse, error)
+		MergeJenis(ctx context.Context, req dto.MergeRequest) (dto.JenisResponse, error)
 
-		DownloadDataSupplier(ctx context.Context) ([]byte, error)
+		DownloadDataSupplier(ctx context.Context, format string) ([]byte, error)
+
+		ImportSupplier(ctx context.Context, req dto.SupplierImportRequest, userID string) (dto.SupplierImportResponse, error)
+		ImportSupplierTemplate(ctx context.Context, format string) ([]byte, error)
 	}
 
 	supplierService struct {
@@ -45,100 +57,40 @@ func NewSupplierService(supplierRepo repository.SupplierRepository, jenisRepo re
 	}
 }
 
-func (s *supplierService) CreateSupplier(ctx context.Context, req dto.SupplierRequest) (dto.SupplierResponse, error) {
+func (s *supplierService) CreateSupplier(ctx context.Context, req dto.SupplierRequest, userID string) (dto.SupplierResponse, error) {
 	_, flag, _ := s.supplierRepo.CheckSupplierName(ctx, nil, req.Name)
 	if flag {
 		return dto.SupplierResponse{}, dto.ErrSupplierAlreadyExists
 	}
 
-	supplierEntity := entity.Supplier{
-		Name:     req.Name,
-		NoHp:     req.NoHp,
-		Discount: req.Discount,
-	}
-
-	supplier, err := s.supplierRepo.CreateSupplier(ctx, nil, supplierEntity)
+	effectiveFrom, err := supplyEffectiveDate(req.EffectiveDate)
 	if err != nil {
 		return dto.SupplierResponse{}, err
 	}
 
-	var merkResponses []dto.MerkResponse
-	for _, merk := range req.MerkRequest {
-		var jenisResponses []dto.JenisResponse
-
-		merkEntity := entity.Merk{
-			Nama: merk.NamaMerk,
-		}
-
-		merkRes, err := s.merkRepo.CreateMerk(ctx, nil, merkEntity)
+	var response dto.SupplierResponse
+	err = s.supplierRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		supplier, err := s.supplierRepo.CreateSupplier(ctx, tx, entity.Supplier{
+			Name:     req.Name,
+			NoHp:     req.NoHp,
+			Discount: req.Discount,
+		})
 		if err != nil {
-			return dto.SupplierResponse{}, err
+			return err
 		}
 
-		if len(merk.JenisRequest) == 0 {
-			allJenis, err := s.jenisRepo.GetAllJenis(ctx, nil)
-			if err != nil {
-				return dto.SupplierResponse{}, err
-			}
-
-			for _, jenis := range allJenis {
-				_, err = s.supplierRepo.CreateDetailMerkSupplier(ctx, nil, entity.DetailMerkSupplier{
-					MerkID:     merkRes.ID,
-					JenisID:    jenis.ID,
-					SupplierID: supplier.ID,
-					Discount:   merk.Discount,
-				})
-
-				if err != nil {
-					continue
-				}
-
-				jenisResponses = append(jenisResponses, dto.JenisResponse{
-					NamaJenis: jenis.NamaJenis,
-				})
-			}
-
-		} else {
-			for _, jenis := range merk.JenisRequest {
-				jenisEntity := entity.Jenis{
-					NamaJenis: jenis.NamaJenis,
-				}
-
-				jenis, err := s.jenisRepo.CreateJenis(ctx, nil, jenisEntity)
-				if err != nil {
-					continue
-				}
-
-				_, err = s.supplierRepo.CreateDetailMerkSupplier(ctx, nil, entity.DetailMerkSupplier{
-					MerkID:     merkRes.ID,
-					JenisID:    jenis.ID,
-					SupplierID: supplier.ID,
-					Discount:   merk.Discount,
-				})
-
-				if err != nil {
-					continue
-				}
-				jenisResponses = append(jenisResponses, dto.JenisResponse{
-					NamaJenis: jenis.NamaJenis,
-				})
-			}
+		if _, err := s.syncSupplies(ctx, tx, supplier.ID, req.MerkRequest, effectiveFrom, userID); err != nil {
+			return err
 		}
 
-		merkResponses = append(merkResponses, dto.MerkResponse{
-			NamaMerk: merk.NamaMerk,
-			Discount: merk.Discount,
-			Jenis:    jenisResponses,
-		})
+		response, err = s.supplierResponse(ctx, tx, supplier)
+		return err
+	})
+	if err != nil {
+		return dto.SupplierResponse{}, err
 	}
 
-	return dto.SupplierResponse{
-		ID:       supplier.ID,
-		Name:     supplier.Name,
-		NoHp:     supplier.NoHp,
-		Discount: supplier.Discount,
-		Merk:     merkResponses,
-	}, nil
+	return response, nil
 }
 
 func (s *supplierService) Index(ctx context.Context) (dto.IndexResponse, error) {
@@ -203,193 +155,329 @@ func (s *supplierService) GetAllSupplier(ctx context.Context, req dto.Pagination
 }
 
 func (s *supplierService) GetSupplierByID(ctx context.Context, supplierID int) (dto.SupplierResponse, error) {
-
 	supplier, err := s.supplierRepo.GetSupplierByID(ctx, nil, supplierID)
 	if err != nil {
 		return dto.SupplierResponse{}, err
 	}
 
-	response := dto.SupplierResponse{
-		ID:       supplier.ID,
-		Name:     supplier.Name,
-		NoHp:     supplier.NoHp,
-		Discount: supplier.Discount,
+	return s.supplierResponse(ctx, nil, supplier)
+}
+
+func (s *supplierService) GetSupplyDiscounts(ctx context.Context, supplierID int) ([]dto.SupplyDiscountResponse, error) {
+	if _, err := s.supplierRepo.GetSupplierByID(ctx, nil, supplierID); err != nil {
+		return nil, dto.ErrSupplierNotFound
 	}
 
-	merks, err := s.supplierRepo.GetMerksBySupplierID(ctx, nil, supplier.ID)
+	rows, err := s.supplierRepo.GetSupplyDiscounts(ctx, nil, supplierID)
 	if err != nil {
-		return dto.SupplierResponse{}, err
+		return nil, err
 	}
 
-	response.Merk = append(response.Merk, merks...)
+	responses := make([]dto.SupplyDiscountResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, dto.SupplyDiscountResponse{
+			DetailMerkSupplierID: row.DetailMerkSupplierID,
+			Merk:                 row.Merk,
+			Jenis:                row.Jenis,
+			Discount:             row.Discount,
+			OldDiscount:          row.OldDiscount,
+			EffectiveFrom:        row.EffectiveFrom.Format("2006-01-02"),
+			ChangedBy:            row.ChangedBy,
+			CreatedAt:            row.CreatedAt,
+		})
+	}
 
-	return response, nil
+	return responses, nil
 }
 
-func (s *supplierService) UpdateSupplierSupply(ctx context.Context, req dto.UpdateSupplyRequest, supplierID int) (dto.SupplierResponse, error) {
+// UpdateSupplierSupply sets the merks and jenis a supplier delivers to the
+// ones in the request. Only the difference is written, see syncSupplies.
+func (s *supplierService) UpdateSupplierSupply(ctx context.Context, req dto.UpdateSupplyRequest, supplierID int, userID string) (dto.SupplierResponse, error) {
 	supplier, err := s.supplierRepo.GetSupplierByID(ctx, nil, supplierID)
 	if err != nil {
 		return dto.SupplierResponse{}, dto.ErrSupplierNotFound
 	}
 
-	err = s.supplierRepo.DeleteDetailMerkSupplier(ctx, nil, supplierID)
+	effectiveFrom, err := supplyEffectiveDate(req.EffectiveDate)
 	if err != nil {
 		return dto.SupplierResponse{}, err
 	}
 
-	var merkResponses []dto.MerkResponse
-
-	for _, merk := range req.Data {
-		var jenisResponses []dto.JenisResponse
-
-		merkEntity := entity.Merk{
-			Nama: merk.NamaMerk,
-		}
-
-		merkRes, err := s.merkRepo.CreateMerk(ctx, nil, merkEntity)
+	var response dto.SupplierResponse
+	err = s.supplierRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		changes, err := s.syncSupplies(ctx, tx, supplier.ID, req.Data, effectiveFrom, userID)
 		if err != nil {
-			return dto.SupplierResponse{}, err
+			return err
 		}
 
-		if len(merk.JenisRequest) == 0 {
-			allJenis, err := s.jenisRepo.GetAllJenis(ctx, nil)
-			if err != nil {
-				return dto.SupplierResponse{}, err
-			}
+		response, err = s.supplierResponse(ctx, tx, supplier)
+		response.Changes = &changes
+		return err
+	})
+	if err != nil {
+		return dto.SupplierResponse{}, err
+	}
 
-			for _, jenis := range allJenis {
-				_, err = s.supplierRepo.CreateDetailMerkSupplier(ctx, nil, entity.DetailMerkSupplier{
-					MerkID:     merkRes.ID,
-					JenisID:    jenis.ID,
-					SupplierID: supplier.ID,
-					Discount:   merk.Discount,
-				})
-				if err != nil {
-					continue
-				}
+	return response, nil
+}
 
-				jenisResponses = append(jenisResponses, dto.JenisResponse{
-					NamaJenis: jenis.NamaJenis,
-				})
-			}
-		} else {
-			for _, jenis := range merk.JenisRequest {
-				jenisEntity := entity.Jenis{
-					NamaJenis: jenis.NamaJenis,
-				}
+// UpdateSupplier updates the supplier and, when the request has merks, its
+// supplies the same way UpdateSupplierSupply does. Without merks the
+// supplies are left as they are.
+func (s *supplierService) UpdateSupplier(ctx context.Context, req dto.SupplierRequest, supplierID int, userID string) (dto.SupplierResponse, error) {
+	effectiveFrom, err := supplyEffectiveDate(req.EffectiveDate)
+	if err != nil {
+		return dto.SupplierResponse{}, err
+	}
 
-				createdJenis, err := s.jenisRepo.CreateJenis(ctx, nil, jenisEntity)
-				if err != nil {
-					continue
-				}
+	updateSupplier := entity.Supplier{
+		ID:       supplierID,
+		Name:     req.Name,
+		NoHp:     req.NoHp,
+		Discount: req.Discount,
+	}
 
-				_, err = s.supplierRepo.CreateDetailMerkSupplier(ctx, nil, entity.DetailMerkSupplier{
-					MerkID:     merkRes.ID,
-					JenisID:    createdJenis.ID,
-					SupplierID: supplier.ID,
-					Discount:   merk.Discount,
-				})
+	var response dto.SupplierResponse
+	err = s.supplierRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if _, err := s.supplierRepo.UpdateSupplier(ctx, tx, updateSupplier); err != nil {
+			return dto.ErrUpdateSupplier
+		}
 
-				if err != nil {
-					continue
-				}
+		supplier, err := s.supplierRepo.GetSupplierByID(ctx, tx, supplierID)
+		if err != nil {
+			return dto.ErrSupplierNotFound
+		}
 
-				jenisResponses = append(jenisResponses, dto.JenisResponse{
-					NamaJenis: createdJenis.NamaJenis,
-				})
+		var changes *dto.SupplyChanges
+		if len(req.MerkRequest) > 0 {
+			synced, err := s.syncSupplies(ctx, tx, supplierID, req.MerkRequest, effectiveFrom, userID)
+			if err != nil {
+				return err
 			}
+			changes = &synced
 		}
 
-		merkResponses = append(merkResponses, dto.MerkResponse{
-			NamaMerk: merk.NamaMerk,
-			Discount: merk.Discount,
-			Jenis:    jenisResponses,
-		})
+		response, err = s.supplierResponse(ctx, tx, supplier)
+		response.Changes = changes
+		return err
+	})
+	if err != nil {
+		return dto.SupplierResponse{}, err
 	}
 
-	return dto.SupplierResponse{
-		ID:       supplier.ID,
-		Name:     supplier.Name,
-		NoHp:     supplier.NoHp,
-		Discount: supplier.Discount,
-		Merk:     merkResponses,
-	}, nil
+	return response, nil
 }
 
-func (s *supplierService) UpdateSupplier(ctx context.Context, req dto.SupplierRequest, supplierID int) (dto.SupplierResponse, error) {
-
-	updateSupplier := entity.Supplier{
-		ID:       supplierID,
-		Name:     req.Name,
-		NoHp:     req.NoHp,
-		Discount: req.Discount,
+// syncSupplies makes the supplies of a supplier match merks. Supplies are
+// compared by merk name and jenis path, so existing rows are updated in place
+// and products keep pointing at them. A jenis is found as jenisLookup.find
+// does, and a new name is added as a top level jenis. A merk without jenis
+// stands for every jenis. Supplies left out are removed unless product variants still use
+// them, because removing would cascade to those variants. Every added
+// supply and discount change is versioned from effectiveFrom.
+func (s *supplierService) syncSupplies(ctx context.Context, tx *gorm.DB, supplierID int, merks []dto.MerkRequest, effectiveFrom time.Time, userID string) (dto.SupplyChanges, error) {
+	changes := dto.SupplyChanges{
+		Added:   []dto.SupplyChange{},
+		Updated: []dto.SupplyChange{},
+		Removed: []dto.SupplyChange{},
+		Kept:    []dto.SupplyChange{},
 	}
 
-	supplierUpdate, err := s.supplierRepo.UpdateSupplier(ctx, nil, updateSupplier)
-	if err != nil {
-		return dto.SupplierResponse{}, dto.ErrUpdateSupplier
+	type target struct {
+		merk     entity.Merk
+		jenis    entity.Jenis
+		discount int
+		matched  bool
 	}
+	var targets []*target
+	byKey := map[string]*target{}
 
-	err = s.supplierRepo.DeleteDetailMerkSupplier(ctx, nil, supplierID)
+	tree, err := loadKategoriTree(ctx, tx, s.jenisRepo)
 	if err != nil {
-		return dto.SupplierResponse{}, err
+		return changes, err
 	}
+	lookup := newJenisLookup(tree)
 
-	var merkResponses []dto.MerkResponse
-	for _, merk := range req.MerkRequest {
-		var jenisResponses []dto.JenisResponse
-
-		merkEntity := entity.Merk{
-			Nama: merk.NamaMerk,
+	var allJenis []entity.Jenis
+	for _, merkReq := range merks {
+		if merkReq.Discount < 0 || merkReq.Discount > 100 {
+			return changes, dto.ErrSupplyDiscount
 		}
 
-		merkRes, err := s.merkRepo.CreateMerk(ctx, nil, merkEntity)
+		merk, err := s.merkRepo.CreateMerk(ctx, tx, entity.Merk{Nama: merkReq.NamaMerk})
 		if err != nil {
-			return dto.SupplierResponse{}, err
+			return changes, err
 		}
 
-		for _, jenis := range merk.JenisRequest {
-			jenisEntity := entity.Jenis{
-				NamaJenis: jenis.NamaJenis,
+		var jenisList []entity.Jenis
+		if len(merkReq.JenisRequest) == 0 {
+			if allJenis == nil {
+				if allJenis, err = s.jenisRepo.GetAllJenis(ctx, tx); err != nil {
+					return changes, err
+				}
 			}
+			jenisList = allJenis
+		} else {
+			for _, jenisReq := range merkReq.JenisRequest {
+				jenis, err := lookup.find(jenisReq.NamaJenis)
+				if errors.Is(err, dto.ErrJenisNotFound) && !strings.Contains(jenisReq.NamaJenis, ">") {
+					jenis, err = s.jenisRepo.InsertJenis(ctx, tx, entity.Jenis{NamaJenis: jenisReq.NamaJenis})
+					if err == nil {
+						lookup.add(jenis)
+					}
+				}
+				if err != nil {
+					return changes, err
+				}
+				jenisList = append(jenisList, jenis)
+			}
+		}
 
-			jenis, err := s.jenisRepo.CreateJenis(ctx, nil, jenisEntity)
-			if err != nil {
+		for _, jenis := range jenisList {
+			key := supplyKey(merk.Nama, lookup.path(jenis))
+			if t, exists := byKey[key]; exists {
+				t.discount = merkReq.Discount
 				continue
 			}
+			t := &target{merk: merk, jenis: jenis, discount: merkReq.Discount}
+			byKey[key] = t
+			targets = append(targets, t)
+		}
+	}
 
-			_, err = s.supplierRepo.CreateDetailMerkSupplier(ctx, nil, entity.DetailMerkSupplier{
-				MerkID:     merkRes.ID,
-				JenisID:    jenis.ID,
-				SupplierID: supplierID,
-				Discount:   merk.Discount,
-			})
+	existing, err := s.supplierRepo.GetDetailMerkSuppliersBySupplierIDs(ctx, tx, []int{supplierID})
+	if err != nil {
+		return changes, err
+	}
 
-			if err != nil {
-				continue
-			}
+	var unused []entity.DetailMerkSupplier
+	var unusedIDs []int
+	for _, detail := range existing {
+		t := byKey[supplyKey(detail.Merk.Nama, lookup.path(detail.Jenis))]
+		if t == nil || t.matched {
+			unused = append(unused, detail)
+			unusedIDs = append(unusedIDs, detail.DetailMerkSupplierID)
+			continue
+		}
+		t.matched = true
 
-			jenisResponses = append(jenisResponses, dto.JenisResponse{
-				NamaJenis: jenis.NamaJenis,
-			})
+		if detail.Discount == t.discount {
+			continue
 		}
+		if err := s.supplierRepo.UpdateDetailMerkSupplierDiscount(ctx, tx, detail.DetailMerkSupplierID, t.discount); err != nil {
+			return changes, err
+		}
+		oldDiscount := detail.Discount
+		if err := s.recordSupplyDiscount(ctx, tx, detail.DetailMerkSupplierID, &oldDiscount, t.discount, effectiveFrom, userID); err != nil {
+			return changes, err
+		}
+		changes.Updated = append(changes.Updated, dto.SupplyChange{
+			DetailMerkSupplierID: detail.DetailMerkSupplierID,
+			Merk:                 detail.Merk.Nama,
+			Jenis:                lookup.path(detail.Jenis),
+			Discount:             t.discount,
+			OldDiscount:          &oldDiscount,
+		})
+	}
 
-		merkResponses = append(merkResponses, dto.MerkResponse{
-			NamaMerk: merk.NamaMerk,
-			Discount: merk.Discount,
-			Jenis:    jenisResponses,
+	referenced, err := s.supplierRepo.GetReferencedDetailMerkSupplierIDs(ctx, tx, unusedIDs)
+	if err != nil {
+		return changes, err
+	}
+	inUse := map[int]bool{}
+	for _, id := range referenced {
+		inUse[id] = true
+	}
+
+	for _, detail := range unused {
+		change := dto.SupplyChange{
+			DetailMerkSupplierID: detail.DetailMerkSupplierID,
+			Merk:                 detail.Merk.Nama,
+			Jenis:                lookup.path(detail.Jenis),
+			Discount:             detail.Discount,
+		}
+		if inUse[detail.DetailMerkSupplierID] {
+			changes.Kept = append(changes.Kept, change)
+			continue
+		}
+		if err := s.supplierRepo.DeleteDetailMerkSupplierByID(ctx, tx, detail.DetailMerkSupplierID); err != nil {
+			return changes, err
+		}
+		changes.Removed = append(changes.Removed, change)
+	}
+
+	for _, t := range targets {
+		if t.matched {
+			continue
+		}
+		created, err := s.supplierRepo.CreateDetailMerkSupplier(ctx, tx, entity.DetailMerkSupplier{
+			MerkID:     t.merk.ID,
+			JenisID:    t.jenis.ID,
+			SupplierID: supplierID,
+			Discount:   t.discount,
+		})
+		if err != nil {
+			return changes, err
+		}
+		if err := s.recordSupplyDiscount(ctx, tx, created.DetailMerkSupplierID, nil, t.discount, effectiveFrom, userID); err != nil {
+			return changes, err
+		}
+		changes.Added = append(changes.Added, dto.SupplyChange{
+			DetailMerkSupplierID: created.DetailMerkSupplierID,
+			Merk:                 t.merk.Nama,
+			Jenis:                lookup.path(t.jenis),
+			Discount:             t.discount,
 		})
 	}
 
+	return changes, nil
+}
+
+func (s *supplierService) recordSupplyDiscount(ctx context.Context, tx *gorm.DB, detailMerkSupplierID int, oldDiscount *int, discount int, effectiveFrom time.Time, userID string) error {
+	return s.supplierRepo.CreateSupplyDiscount(ctx, tx, entity.SupplyDiscount{
+		DetailMerkSupplierID: detailMerkSupplierID,
+		Discount:             discount,
+		OldDiscount:          oldDiscount,
+		EffectiveFrom:        effectiveFrom,
+		ChangedBy:            userID,
+	})
+}
+
+func (s *supplierService) supplierResponse(ctx context.Context, tx *gorm.DB, supplier entity.Supplier) (dto.SupplierResponse, error) {
+	merks, err := s.supplierRepo.GetMerksBySupplierID(ctx, tx, supplier.ID)
+	if err != nil {
+		return dto.SupplierResponse{}, err
+	}
+
 	return dto.SupplierResponse{
-		ID:       supplierUpdate.ID,
-		Name:     supplierUpdate.Name,
-		NoHp:     supplierUpdate.NoHp,
-		Discount: supplierUpdate.Discount,
-		Merk:     merkResponses,
+		ID:       supplier.ID,
+		Name:     supplier.Name,
+		NoHp:     supplier.NoHp,
+		Discount: supplier.Discount,
+		Merk:     merks,
 	}, nil
 }
 
+// supplyKey identifies a supply by its merk and jenis names, ignoring case.
+func supplyKey(merk string, jenisPath string) string {
+	return strings.ToLower(strings.TrimSpace(merk)) + "\x00" + kategoriPathKey(jenisPath)
+}
+
+// supplyEffectiveDate parses the date a discount change applies from,
+// defaulting to today.
+func supplyEffectiveDate(date string) (time.Time, error) {
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+
+	effectiveFrom, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, dto.ErrSupplyEffectiveDate
+	}
+
+	return effectiveFrom, nil
+}
+
 func (s *supplierService) DeleteSupplier(ctx context.Context, supplierID int) error {
 	supplier, err := s.supplierRepo.GetSupplierByID(ctx, nil, supplierID)
 	if err != nil {
@@ -405,11 +493,30 @@ func (s *supplierService) DeleteSupplier(ctx context.Context, supplierID int) er
 }
 
 func (s *supplierService) CreateJenis(ctx context.Context, req dto.JenisRequest) (dto.JenisResponse, error) {
+	if strings.TrimSpace(req.NamaJenis) == "" {
+		return dto.JenisResponse{}, dto.ErrJenisNameEmpty
+	}
+
+	if req.ParentID != nil {
+		if _, err := s.jenisRepo.GetJenisByID(ctx, nil, *req.ParentID); err != nil {
+			return dto.JenisResponse{}, dto.ErrJenisNotFound
+		}
+	}
+
+	_, flag, err := s.jenisRepo.CheckJenisName(ctx, nil, req.NamaJenis, req.ParentID)
+	if err != nil {
+		return dto.JenisResponse{}, err
+	}
+	if flag {
+		return dto.JenisResponse{}, dto.ErrJenisAlreadyExists
+	}
+
 	jenisEntity := entity.Jenis{
 		NamaJenis: req.NamaJenis,
+		ParentID:  req.ParentID,
 	}
 
-	jenis, err := s.jenisRepo.CreateJenis(ctx, nil, jenisEntity)
+	jenis, err := s.jenisRepo.InsertJenis(ctx, nil, jenisEntity)
 	if err != nil {
 		return dto.JenisResponse{}, err
 	}
@@ -417,6 +524,7 @@ func (s *supplierService) CreateJenis(ctx context.Context, req dto.JenisRequest)
 	return dto.JenisResponse{
 		ID:        jenis.ID,
 		NamaJenis: jenis.NamaJenis,
+		ParentID:  jenis.ParentID,
 	}, nil
 }
 
@@ -426,6 +534,14 @@ func (s *supplierService) DeleteJenis(ctx context.Context, jenisID int) error {
 		return dto.ErrJenisNotFound
 	}
 
+	references, err := s.jenisRepo.CountJenisReferences(ctx, nil, jenis.ID)
+	if err != nil {
+		return err
+	}
+	if references > 0 {
+		return dto.ErrJenisInUse
+	}
+
 	err = s.jenisRepo.DeleteJenis(ctx, nil, jenis.ID)
 	if err != nil {
 		return dto.ErrDeleteJenis
@@ -434,8 +550,8 @@ func (s *supplierService) DeleteJenis(ctx context.Context, jenisID int) error {
 	return nil
 }
 
-func (s *supplierService) GetAllJenis(ctx context.Context) ([]dto.JenisResponse, error) {
-	jenis, err := s.jenisRepo.GetAllJenis(ctx, nil)
+func (s *supplierService) GetAllJenis(ctx context.Context, search string) ([]dto.JenisResponse, error) {
+	jenis, err := s.jenisRepo.SearchJenis(ctx, nil, search)
 	if err != nil {
 		return []dto.JenisResponse{}, err
 	}
@@ -451,38 +567,564 @@ func (s *supplierService) GetAllJenis(ctx context.Context) ([]dto.JenisResponse,
 	return responses, nil
 }
 
-func (s *supplierService) DownloadDataSupplier(ctx context.Context) ([]byte, error) {
+func (s *supplierService) GetJenisByID(ctx context.Context, jenisID int) (dto.JenisResponse, error) {
+	jenis, err := s.jenisRepo.GetJenisByID(ctx, nil, jenisID)
+	if err != nil {
+		return dto.JenisResponse{}, dto.ErrJenisNotFound
+	}
+
+	return dto.JenisResponse{
+		ID:        jenis.ID,
+		NamaJenis: jenis.NamaJenis,
+	}, nil
+}
+
+// UpdateJenis renames a jenis. Renaming to the name of a sibling is refused;
+// the two should be merged instead.
+func (s *supplierService) UpdateJenis(ctx context.Context, req dto.JenisRequest, jenisID int) (dto.JenisResponse, error) {
+	if strings.TrimSpace(req.NamaJenis) == "" {
+		return dto.JenisResponse{}, dto.ErrJenisNameEmpty
+	}
+
+	jenis, err := s.jenisRepo.GetJenisByID(ctx, nil, jenisID)
+	if err != nil {
+		return dto.JenisResponse{}, dto.ErrJenisNotFound
+	}
+
+	existing, flag, err := s.jenisRepo.CheckJenisName(ctx, nil, req.NamaJenis, jenis.ParentID)
+	if err != nil {
+		return dto.JenisResponse{}, err
+	}
+	if flag && existing.ID != jenis.ID {
+		return dto.JenisResponse{}, dto.ErrJenisAlreadyExists
+	}
+
+	jenis.NamaJenis = strings.TrimSpace(req.NamaJenis)
+	jenis, err = s.jenisRepo.UpdateJenis(ctx, nil, jenis)
+	if err != nil {
+		return dto.JenisResponse{}, dto.ErrUpdateJenis
+	}
+
+	return dto.JenisResponse{
+		ID:        jenis.ID,
+		NamaJenis: jenis.NamaJenis,
+	}, nil
+}
+
+// MergeJenis folds duplicate jenis into the target, re-pointing their
+// supplies to it.
+func (s *supplierService) MergeJenis(ctx context.Context, req dto.MergeRequest) (dto.JenisResponse, error) {
+	target, err := s.jenisRepo.GetJenisByID(ctx, nil, req.TargetID)
+	if err != nil {
+		return dto.JenisResponse{}, dto.ErrJenisNotFound
+	}
+
+	for _, sourceID := range req.SourceIDs {
+		if sourceID == target.ID {
+			return dto.JenisResponse{}, dto.ErrMergeSelf
+		}
+		if _, err := s.jenisRepo.GetJenisByID(ctx, nil, sourceID); err != nil {
+			return dto.JenisResponse{}, dto.ErrJenisNotFound
+		}
+	}
+
+	if err := s.jenisRepo.MergeJenis(ctx, nil, req.SourceIDs, target.ID); err != nil {
+		return dto.JenisResponse{}, err
+	}
+
+	return dto.JenisResponse{
+		ID:        target.ID,
+		NamaJenis: target.NamaJenis,
+	}, nil
+}
+
+var supplierTable = utils.Table{
+	Title: "Data Supplier",
+	Columns: []utils.Column{
+		{Header: "ID", Type: utils.ColumnInt},
+		{Header: "Nama Supplier"},
+		{Header: "No.HP"},
+	},
+}
+
+func (s *supplierService) DownloadDataSupplier(ctx context.Context, format string) ([]byte, error) {
 	suppliers, err := s.supplierRepo.GetAllSupplier(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	f := excelize.NewFile()
-	f.SetSheetName("Sheet1", "Data Supplier")
-	sheetIndex, err := f.GetSheetIndex("Data Supplier")
+	return utils.RenderTable(format, supplierTable, func(tw utils.TableWriter) error {
+		for _, supplier := range suppliers {
+			if err := tw.WriteRow(supplier.ID, supplier.Name, supplier.NoHp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+const SUPPLIER_IMPORT_MAX_ROWS = 5000
+
+// supplierImportTable is the template of the supplier price list import.
+// Each row is one merk and jenis a supplier delivers.
+var supplierImportTable = utils.Table{
+	Title: "Impor Katalog Supplier",
+	Columns: []utils.Column{
+		{Header: "Supplier"},
+		{Header: "No HP"},
+		{Header: "Merk"},
+		{Header: "Jenis"},
+		{Header: "Discount", Type: utils.ColumnInt},
+	},
+}
+
+const (
+	supplyImportSupplier = iota
+	supplyImportNoHp
+	supplyImportMerk
+	supplyImportJenis
+	supplyImportDiscount
+)
+
+var supplierImportFields = [][]string{
+	{"supplier", "nama_supplier"},
+	{"no_hp", "hp", "telepon"},
+	{"merk", "nama_merk"},
+	{"jenis", "nama_jenis", "kategori"},
+	{"discount", "diskon"},
+}
+
+type (
+	// supplierImportItem is a supplier of the file. row is where its No HP
+	// was first given.
+	supplierImportItem struct {
+		id      int
+		name    string
+		noHp    string
+		oldNoHp string
+		action  string
+		row     int
+	}
+
+	masterImportItem struct {
+		id     int
+		name   string
+		action string
+	}
+
+	supplyImportItem struct {
+		id          int
+		supplier    *supplierImportItem
+		merk        *masterImportItem
+		jenis       *masterImportItem
+		discount    int
+		oldDiscount *int
+		action      string
+		rows        []int
+	}
+
+	// supplierImportPlan holds the rows of a price list matched against the
+	// database, in file order.
+	supplierImportPlan struct {
+		suppliers []*supplierImportItem
+		merks     []*masterImportItem
+		jenis     []*masterImportItem
+		supplies  []*supplyImportItem
+	}
+)
+
+func (s *supplierService) ImportSupplierTemplate(ctx context.Context, format string) ([]byte, error) {
+	if format == constants.ENUM_FORMAT_PDF {
+		return nil, dto.ErrImportTemplatePDF
+	}
+
+	return utils.RenderTable(format, supplierImportTable, func(tw utils.TableWriter) error {
+		return nil
+	})
+}
+
+// ImportSupplier upserts the suppliers, merks, jenis and supplies of a price
+// list. Names are matched ignoring case, so existing records are reused
+// instead of duplicated, and supplies missing from the file are left alone.
+func (s *supplierService) ImportSupplier(ctx context.Context, req dto.SupplierImportRequest, userID string) (dto.SupplierImportResponse, error) {
+	effectiveFrom, err := supplyEffectiveDate(req.EffectiveDate)
 	if err != nil {
-		return nil, err
+		return dto.SupplierImportResponse{}, err
 	}
-	f.SetActiveSheet(sheetIndex)
 
-	headers := []string{"ID", "Nama Supplier", "No.HP"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c%d", 'A'+i, 1)
-		f.SetCellValue("Data Cabasupplierng", cell, header)
+	rows, err := utils.ReadTableFile(req.File)
+	if err != nil {
+		return dto.SupplierImportResponse{}, err
+	}
+	if len(rows)-1 > SUPPLIER_IMPORT_MAX_ROWS {
+		return dto.SupplierImportResponse{}, dto.ErrImportTooManyRows
 	}
 
-	for i, supplier := range suppliers {
-		f.SetCellValue("Data Cabang", fmt.Sprintf("A%d", i+2), supplier.ID)
-		f.SetCellValue("Data supplier", fmt.Sprintf("B%d", i+2), supplier.Name)
-		f.SetCellValue("Data supplier", fmt.Sprintf("C%d", i+2), supplier.NoHp)
+	plan, response, err := s.planSupplierImport(ctx, rows)
+	if err != nil {
+		return dto.SupplierImportResponse{}, err
+	}
+	if !req.Confirm {
+		return plan.response(response), nil
+	}
+	if len(response.Errors) > 0 {
+		return plan.response(response), dto.ErrImportHasErrors
 	}
 
-	// Save Excel content to a buffer
-	buf := new(bytes.Buffer)
-	if err := f.Write(buf); err != nil {
-		return nil, err
+	err = s.supplierRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		return s.applySupplierImport(ctx, tx, plan, effectiveFrom, userID)
+	})
+	if err != nil {
+		return dto.SupplierImportResponse{}, err
+	}
+
+	response.Confirmed = true
+	return plan.response(response), nil
+}
+
+func (s *supplierService) planSupplierImport(ctx context.Context, rows [][]string) (*supplierImportPlan, dto.SupplierImportResponse, error) {
+	plan := &supplierImportPlan{}
+	response := dto.SupplierImportResponse{Errors: []dto.ImportRowError{}}
+
+	index := utils.ImportColumns(rows[0], supplierImportFields)
+	for _, field := range []int{supplyImportSupplier, supplyImportMerk, supplyImportJenis} {
+		if index[field] < 0 {
+			response.Errors = append(response.Errors, dto.ImportRowError{
+				Row:     1,
+				Column:  supplierImportTable.Columns[field].Header,
+				Message: dto.ErrImportMissingColumn.Error(),
+			})
+		}
+	}
+	if len(response.Errors) > 0 {
+		return plan, response, nil
+	}
+
+	existingSuppliers := map[string]entity.Supplier{}
+	allSupplier, err := s.supplierRepo.GetAllSupplier(ctx, nil)
+	if err != nil {
+		return nil, response, err
+	}
+	for _, supplier := range allSupplier {
+		key := strings.ToLower(strings.TrimSpace(supplier.Name))
+		if _, exists := existingSuppliers[key]; !exists {
+			existingSuppliers[key] = supplier
+		}
+	}
+
+	merks := map[string]*masterImportItem{}
+	allMerk, err := s.merkRepo.GetAllMerk(ctx, nil)
+	if err != nil {
+		return nil, response, err
+	}
+	for _, merk := range allMerk {
+		key := strings.ToLower(strings.TrimSpace(merk.Nama))
+		if _, exists := merks[key]; !exists {
+			merks[key] = &masterImportItem{id: merk.ID, name: merk.Nama, action: constants.ENUM_IMPORT_UNCHANGED}
+		}
+	}
+
+	// jenis are keyed by path, since a name may repeat under different
+	// parents.
+	jenis := map[string]*masterImportItem{}
+	tree, err := loadKategoriTree(ctx, nil, s.jenisRepo)
+	if err != nil {
+		return nil, response, err
+	}
+	lookup := newJenisLookup(tree)
+	for key, id := range lookup.byPath {
+		jenis[key] = &masterImportItem{id: id, name: tree.path(id), action: constants.ENUM_IMPORT_UNCHANGED}
+	}
+
+	suppliers := map[string]*supplierImportItem{}
+	supplies := map[[3]string]*supplyImportItem{}
+	usedMerks := map[*masterImportItem]bool{}
+	usedJenis := map[*masterImportItem]bool{}
+
+	for n, row := range rows[1:] {
+		if utils.ImportRowBlank(row) {
+			continue
+		}
+		response.Rows++
+
+		rowNumber := n + 2
+		before := len(response.Errors)
+		cell := func(field int) string {
+			return utils.ImportCell(row, index[field])
+		}
+		fail := func(field int, format string, args ...any) {
+			response.Errors = append(response.Errors, dto.ImportRowError{
+				Row:     rowNumber,
+				Column:  supplierImportTable.Columns[field].Header,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		for _, field := range []int{supplyImportSupplier, supplyImportMerk, supplyImportJenis} {
+			if cell(field) == "" {
+				fail(field, "wajib diisi")
+			}
+		}
+
+		discount := 0
+		if text := strings.TrimSuffix(cell(supplyImportDiscount), "%"); text != "" {
+			value, err := utils.ParseImportNumber(text)
+			if err != nil {
+				fail(supplyImportDiscount, "%s", err.Error())
+			} else if value < 0 || value > 100 || value != math.Trunc(value) {
+				fail(supplyImportDiscount, "harus bilangan bulat 0 sampai 100")
+			}
+			discount = int(value)
+		}
+
+		// A name that is not a jenis yet is created at the top level.
+		jenisKey := kategoriPathKey(cell(supplyImportJenis))
+		if found, err := lookup.find(cell(supplyImportJenis)); err == nil {
+			jenisKey = kategoriPathKey(tree.path(found.ID))
+		} else if !errors.Is(err, dto.ErrJenisNotFound) || strings.Contains(cell(supplyImportJenis), ">") {
+			fail(supplyImportJenis, "%s", err.Error())
+		}
+		if len(response.Errors) > before {
+			continue
+		}
+
+		supplierKey := strings.ToLower(cell(supplyImportSupplier))
+		supplier := suppliers[supplierKey]
+		if supplier == nil {
+			supplier = &supplierImportItem{name: cell(supplyImportSupplier)}
+			if existing, ok := existingSuppliers[supplierKey]; ok {
+				supplier.id = existing.ID
+				supplier.name = existing.Name
+				supplier.oldNoHp = existing.NoHp
+			}
+			suppliers[supplierKey] = supplier
+			plan.suppliers = append(plan.suppliers, supplier)
+		}
+		if noHp := cell(supplyImportNoHp); noHp != "" {
+			if supplier.noHp != "" && supplier.noHp != noHp {
+				fail(supplyImportNoHp, "berbeda dengan baris %d untuk supplier yang sama", supplier.row)
+				continue
+			}
+			if supplier.noHp == "" {
+				supplier.noHp = noHp
+				supplier.row = rowNumber
+			}
+		}
+
+		merkKey := strings.ToLower(cell(supplyImportMerk))
+		merk := merks[merkKey]
+		if merk == nil {
+			merk = &masterImportItem{name: cell(supplyImportMerk), action: constants.ENUM_IMPORT_CREATE}
+			merks[merkKey] = merk
+		}
+		if !usedMerks[merk] {
+			usedMerks[merk] = true
+			plan.merks = append(plan.merks, merk)
+		}
+
+		j := jenis[jenisKey]
+		if j == nil {
+			j = &masterImportItem{name: cell(supplyImportJenis), action: constants.ENUM_IMPORT_CREATE}
+			jenis[jenisKey] = j
+		}
+		if !usedJenis[j] {
+			usedJenis[j] = true
+			plan.jenis = append(plan.jenis, j)
+		}
+
+		key := [3]string{supplierKey, merkKey, jenisKey}
+		if supply := supplies[key]; supply != nil {
+			if supply.discount != discount {
+				fail(supplyImportDiscount, "berbeda dengan baris %d untuk supplier, merk dan jenis yang sama", supply.rows[0])
+				continue
+			}
+			supply.rows = append(supply.rows, rowNumber)
+			continue
+		}
+
+		supply := &supplyImportItem{
+			supplier: supplier,
+			merk:     merk,
+			jenis:    j,
+			discount: discount,
+			action:   constants.ENUM_IMPORT_CREATE,
+			rows:     []int{rowNumber},
+		}
+		supplies[key] = supply
+		plan.supplies = append(plan.supplies, supply)
+	}
+
+	// A blank No HP keeps the number the supplier already has.
+	for _, supplier := range plan.suppliers {
+		switch {
+		case supplier.id == 0:
+			supplier.action = constants.ENUM_IMPORT_CREATE
+		case supplier.noHp == "" || supplier.noHp == supplier.oldNoHp:
+			supplier.noHp = supplier.oldNoHp
+			supplier.oldNoHp = ""
+			supplier.action = constants.ENUM_IMPORT_UNCHANGED
+		default:
+			supplier.action = constants.ENUM_IMPORT_UPDATE
+		}
+	}
+
+	// Existing supplies are matched by merk name and jenis path as well,
+	// since older supplier updates may have linked a duplicate merk or jenis
+	// row.
+	var supplierIDs []int
+	for _, supplier := range plan.suppliers {
+		if supplier.id != 0 {
+			supplierIDs = append(supplierIDs, supplier.id)
+		}
+	}
+	details, err := s.supplierRepo.GetDetailMerkSuppliersBySupplierIDs(ctx, nil, supplierIDs)
+	if err != nil {
+		return nil, response, err
+	}
+
+	supplierKeys := map[int]string{}
+	for key, supplier := range suppliers {
+		if supplier.id != 0 {
+			supplierKeys[supplier.id] = key
+		}
+	}
+	for _, detail := range details {
+		key := [3]string{
+			supplierKeys[detail.SupplierID],
+			strings.ToLower(strings.TrimSpace(detail.Merk.Nama)),
+			kategoriPathKey(lookup.path(detail.Jenis)),
+		}
+		supply := supplies[key]
+		if supply == nil || supply.id != 0 {
+			continue
+		}
+
+		oldDiscount := detail.Discount
+		supply.id = detail.DetailMerkSupplierID
+		supply.action = constants.ENUM_IMPORT_UNCHANGED
+		if oldDiscount != supply.discount {
+			supply.oldDiscount = &oldDiscount
+			supply.action = constants.ENUM_IMPORT_UPDATE
+		}
+	}
+
+	return plan, response, nil
+}
+
+// applySupplierImport writes the planned creates and updates, filling in
+// the IDs of the new records. Supply discounts are versioned like
+// syncSupplies does.
+func (s *supplierService) applySupplierImport(ctx context.Context, tx *gorm.DB, plan *supplierImportPlan, effectiveFrom time.Time, userID string) error {
+	for _, supplier := range plan.suppliers {
+		switch supplier.action {
+		case constants.ENUM_IMPORT_CREATE:
+			created, err := s.supplierRepo.CreateSupplier(ctx, tx, entity.Supplier{
+				Name: supplier.name,
+				NoHp: supplier.noHp,
+			})
+			if err != nil {
+				return err
+			}
+			supplier.id = created.ID
+		case constants.ENUM_IMPORT_UPDATE:
+			if _, err := s.supplierRepo.UpdateSupplier(ctx, tx, entity.Supplier{
+				ID:   supplier.id,
+				NoHp: supplier.noHp,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, merk := range plan.merks {
+		if merk.action != constants.ENUM_IMPORT_CREATE {
+			continue
+		}
+		created, err := s.merkRepo.CreateMerk(ctx, tx, entity.Merk{Nama: merk.name})
+		if err != nil {
+			return err
+		}
+		merk.id = created.ID
+	}
+
+	for _, j := range plan.jenis {
+		if j.action != constants.ENUM_IMPORT_CREATE {
+			continue
+		}
+		created, err := s.jenisRepo.InsertJenis(ctx, tx, entity.Jenis{NamaJenis: j.name})
+		if err != nil {
+			return err
+		}
+		j.id = created.ID
+	}
+
+	for _, supply := range plan.supplies {
+		switch supply.action {
+		case constants.ENUM_IMPORT_CREATE:
+			created, err := s.supplierRepo.CreateDetailMerkSupplier(ctx, tx, entity.DetailMerkSupplier{
+				SupplierID: supply.supplier.id,
+				MerkID:     supply.merk.id,
+				JenisID:    supply.jenis.id,
+				Discount:   supply.discount,
+			})
+			if err != nil {
+				return err
+			}
+			supply.id = created.DetailMerkSupplierID
+		case constants.ENUM_IMPORT_UPDATE:
+			if err := s.supplierRepo.UpdateDetailMerkSupplierDiscount(ctx, tx, supply.id, supply.discount); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+
+		if err := s.recordSupplyDiscount(ctx, tx, supply.id, supply.oldDiscount, supply.discount, effectiveFrom, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *supplierImportPlan) response(response dto.SupplierImportResponse) dto.SupplierImportResponse {
+	response.Suppliers = make([]dto.SupplierImportChange, 0, len(p.suppliers))
+	for _, supplier := range p.suppliers {
+		response.Suppliers = append(response.Suppliers, dto.SupplierImportChange{
+			Action:  supplier.action,
+			ID:      supplier.id,
+			Name:    supplier.name,
+			NoHp:    supplier.noHp,
+			OldNoHp: supplier.oldNoHp,
+		})
+	}
+
+	masters := func(items []*masterImportItem) []dto.MasterImportChange {
+		changes := make([]dto.MasterImportChange, 0, len(items))
+		for _, item := range items {
+			changes = append(changes, dto.MasterImportChange{
+				Action: item.action,
+				ID:     item.id,
+				Name:   item.name,
+			})
+		}
+		return changes
+	}
+	response.Merks = masters(p.merks)
+	response.Jenis = masters(p.jenis)
+
+	response.Supplies = make([]dto.SupplyImportChange, 0, len(p.supplies))
+	for _, supply := range p.supplies {
+		response.Supplies = append(response.Supplies, dto.SupplyImportChange{
+			Action:      supply.action,
+			Supplier:    supply.supplier.name,
+			Merk:        supply.merk.name,
+			Jenis:       supply.jenis.name,
+			Discount:    supply.discount,
+			OldDiscount: supply.oldDiscount,
+			Rows:        supply.rows,
+		})
 	}
 
-	// Return the buffer's bytes
-	return buf.Bytes(), nil
+	return response
 }
@@ -1,38 +1,58 @@
 package service
 
 import (
+	"bumisubur-be/constants"
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/repository"
+	"bumisubur-be/utils"
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"time"
 
-	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
 )
 
 type (
 	TransaksiService interface {
 		Index(ctx context.Context) ([]dto.IndexTransaksi, error)
 		CreateTransaksi(ctx context.Context, createTransaksi dto.CreateTransaksi, userID string) (dto.TransaksiResponse, error)
+		SyncTransaksi(ctx context.Context, req dto.SyncTransaksiRequest, userID string) (dto.SyncTransaksiResponse, error)
 		GetHistoryTransaksi(ctx context.Context, req dto.TransactionPaginationRequest) (any, error)
-		DownloadByNota(ctx context.Context, req dto.TransactionPaginationRequest) ([]byte, error)
-		DownloadByProduk(ctx context.Context, req dto.TransactionPaginationRequest) ([]byte, error)
+		DownloadByNota(ctx context.Context, req dto.TransactionPaginationRequest, format string) ([]byte, error)
+		DownloadByProduk(ctx context.Context, req dto.TransactionPaginationRequest, format string) ([]byte, error)
 
 		GetNotaData(ctx context.Context, notaID string) (dto.ReturnUser, error)
 	}
 
 	transaksiService struct {
-		transaksiRepo repository.TransaksiRepository
-		jwtService    JWTService
+		transaksiRepo       repository.TransaksiRepository
+		jwtService          JWTService
+		notificationService NotificationService
 	}
 )
 
-func NewTransaksiService(transaksiRepo repository.TransaksiRepository, jwtService JWTService) TransaksiService {
+const (
+	// A pending upload holds its client_id for SYNC_RESERVATION_LEASE. After
+	// that it is taken to have died, and a retry of the till may take the
+	// client_id over. Releasing a client_id gets SYNC_RELEASE_TIMEOUT, even
+	// when the till has hung up.
+	SYNC_RESERVATION_LEASE = 5 * time.Minute
+	SYNC_RELEASE_TIMEOUT   = 10 * time.Second
+)
+
+// syncClientIDPattern accepts a UUID in its canonical form.
+var syncClientIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func NewTransaksiService(transaksiRepo repository.TransaksiRepository, jwtService JWTService, notificationService NotificationService) TransaksiService {
 	return &transaksiService{
-		transaksiRepo: transaksiRepo,
-		jwtService:    jwtService,
+		transaksiRepo:       transaksiRepo,
+		jwtService:          jwtService,
+		notificationService: notificationService,
 	}
 }
 
@@ -46,13 +66,34 @@ func (t *transaksiService) Index(ctx context.Context) ([]dto.IndexTransaksi, err
 }
 
 func (t *transaksiService) CreateTransaksi(ctx context.Context, createTransaksi dto.CreateTransaksi, userID string) (dto.TransaksiResponse, error) {
+	var created dto.TransaksiResponse
+	if err := t.transaksiRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		var err error
+		created, err = t.createTransaksi(ctx, tx, createTransaksi, userID, time.Now())
+		return err
+	}); err != nil {
+		return dto.TransaksiResponse{}, err
+	}
+
+	t.notificationService.NotifyStockChanged(transaksiDetailProdukIDs(createTransaksi))
+
+	return created, nil
+}
+
+// createTransaksi validates and stores a transaksi that happened at tanggal.
+// The nota ID is numbered within the day of tanggal, so transaksi replayed
+// from an offline till land on the day they were actually made. It runs in
+// tx so the caller can roll the whole sale back.
+func (t *transaksiService) createTransaksi(ctx context.Context, tx *gorm.DB, createTransaksi dto.CreateTransaksi, userID string, tanggal time.Time) (dto.TransaksiResponse, error) {
 	CountHargaBe := 0.0
+	hargaJual := make(map[int]float64, len(createTransaksi.Produks))
 	for _, produk := range createTransaksi.Produks {
-		produkDetail, err := t.transaksiRepo.GetProdukByDetailID(ctx, nil, produk.DetailProdukID)
+		produkDetail, err := t.transaksiRepo.GetProdukByDetailID(ctx, tx, produk.DetailProdukID)
 		if err != nil {
 			return dto.TransaksiResponse{}, err
 		}
 		CountHargaBe += produkDetail.HargaJual * float64(produk.JumlahProduk)
+		hargaJual[produk.DetailProdukID] = produkDetail.HargaJual
 	}
 
 	if createTransaksi.Diskon > 0 && createTransaksi.Diskon <= 100 {
@@ -60,23 +101,25 @@ func (t *transaksiService) CreateTransaksi(ctx context.Context, createTransaksi
 	}
 
 	if CountHargaBe != createTransaksi.TotalHarga {
-		return dto.TransaksiResponse{}, fmt.Errorf("total price mismatch: calculated total is %.2f, but provided total is %.2f", CountHargaBe, createTransaksi.TotalHarga)
+		return dto.TransaksiResponse{}, fmt.Errorf("%w: calculated total is %.2f, but provided total is %.2f", dto.ErrTransaksiPriceMismatch, CountHargaBe, createTransaksi.TotalHarga)
 	}
 
+	hargaBeli := make(map[int]float64, len(createTransaksi.Produks))
 	for _, produk := range createTransaksi.Produks {
-		produkDetail, err := t.transaksiRepo.GetDetailProdukStok(ctx, nil, produk.DetailProdukID)
+		produkDetail, err := t.transaksiRepo.GetDetailProdukStok(ctx, tx, produk.DetailProdukID)
 		if err != nil {
 			return dto.TransaksiResponse{}, err
 		}
+		hargaBeli[produk.DetailProdukID] = produkDetail.HargaBeli
 
 		if produkDetail.Stok < produk.JumlahProduk {
-			return dto.TransaksiResponse{}, fmt.Errorf("stock is not enough for product %d", produk.DetailProdukID)
+			return dto.TransaksiResponse{}, fmt.Errorf("%w for product %d", dto.ErrTransaksiStokKurang, produk.DetailProdukID)
 		}
 	}
 
 	// Generate transaction ID as int64
-	today := time.Now().Format("20060102") // Format as YYYYMMDD
-	latestID, err := t.transaksiRepo.GetLatestTransaksiID(ctx, today)
+	today := tanggal.Format("20060102") // Format as YYYYMMDD
+	latestID, err := t.transaksiRepo.GetLatestTransaksiID(ctx, tx, today)
 	if err != nil {
 		return dto.TransaksiResponse{}, err
 	}
@@ -94,14 +137,15 @@ func (t *transaksiService) CreateTransaksi(ctx context.Context, createTransaksi
 
 	transaksi := entity.Transaksi{
 		ID:               transactionID, // Assign the generated ID
-		TanggalTransaksi: time.Now(),
+		TanggalTransaksi: tanggal,
 		TotalHarga:       createTransaksi.TotalHarga,
 		MetodeBayar:      createTransaksi.MetodeBayar,
 		CreatedBy:        userID,
 		Diskon:           createTransaksi.Diskon,
+		Timestamp:        entity.Timestamp{CreatedAt: tanggal},
 	}
 
-	Transaksi, err := t.transaksiRepo.CreateTransaksi(ctx, nil, transaksi)
+	Transaksi, err := t.transaksiRepo.CreateTransaksi(ctx, tx, transaksi)
 	if err != nil {
 		return dto.TransaksiResponse{}, err
 	}
@@ -111,9 +155,11 @@ func (t *transaksiService) CreateTransaksi(ctx context.Context, createTransaksi
 			JumlahProduk:   produk.JumlahProduk,
 			TransaksiID:    Transaksi.ID,
 			DetailProdukID: produk.DetailProdukID,
+			HargaJual:      hargaJual[produk.DetailProdukID],
+			HargaBeli:      hargaBeli[produk.DetailProdukID],
 		}
 
-		_, err := t.transaksiRepo.CreateDetailTransaksi(ctx, nil, detailTransaksi)
+		_, err := t.transaksiRepo.CreateDetailTransaksi(ctx, tx, detailTransaksi)
 		if err != nil {
 			return dto.TransaksiResponse{}, err
 		}
@@ -128,6 +174,172 @@ func (t *transaksiService) CreateTransaksi(ctx context.Context, createTransaksi
 	}, nil
 }
 
+func transaksiDetailProdukIDs(createTransaksi dto.CreateTransaksi) []int {
+	detailProdukIDs := make([]int, 0, len(createTransaksi.Produks))
+	for _, produk := range createTransaksi.Produks {
+		detailProdukIDs = append(detailProdukIDs, produk.DetailProdukID)
+	}
+	return detailProdukIDs
+}
+
+func (t *transaksiService) SyncTransaksi(ctx context.Context, req dto.SyncTransaksiRequest, userID string) (dto.SyncTransaksiResponse, error) {
+	// Replay in the order the sales happened so stock runs out the same way
+	// it would have if the till had been online.
+	offline := make([]dto.OfflineTransaksi, len(req.Transaksi))
+	copy(offline, req.Transaksi)
+	sort.SliceStable(offline, func(i, j int) bool {
+		return offline[i].TanggalTransaksi.Before(offline[j].TanggalTransaksi)
+	})
+
+	response := dto.SyncTransaksiResponse{
+		Results: []dto.SyncTransaksiResult{},
+	}
+
+	for _, item := range offline {
+		result, err := t.syncOfflineTransaksi(ctx, item, userID)
+		if err != nil {
+			return dto.SyncTransaksiResponse{}, err
+		}
+
+		if result.Status == constants.ENUM_SYNC_ACCEPTED {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+func (t *transaksiService) syncOfflineTransaksi(ctx context.Context, item dto.OfflineTransaksi, userID string) (dto.SyncTransaksiResult, error) {
+	if item.ClientID == "" {
+		return dto.SyncTransaksiResult{
+			Status:  constants.ENUM_SYNC_REJECTED,
+			Message: dto.ErrSyncClientIDRequired.Error(),
+		}, nil
+	}
+	if !syncClientIDPattern.MatchString(item.ClientID) {
+		return dto.SyncTransaksiResult{
+			ClientID: item.ClientID,
+			Status:   constants.ENUM_SYNC_REJECTED,
+			Message:  dto.ErrSyncClientIDInvalid.Error(),
+		}, nil
+	}
+
+	tanggal := item.TanggalTransaksi
+	if tanggal.IsZero() {
+		tanggal = time.Now()
+	}
+
+	// Postgres keeps microseconds, and the reservation is matched on it.
+	now := time.Now().Truncate(time.Microsecond)
+	sync := entity.TransaksiSync{
+		ClientID:         item.ClientID,
+		Status:           constants.ENUM_SYNC_PENDING,
+		TanggalTransaksi: tanggal,
+		CreatedBy:        userID,
+		ReservedAt:       &now,
+	}
+
+	// The client_id is reserved before the sale is made, so of two uploads
+	// of the same transaksi only one creates it. A till retrying an upload
+	// gets back whatever was decided the first time, or takes over a
+	// reservation whose lease has run out.
+	reserved, err := t.transaksiRepo.ReserveTransaksiSync(ctx, nil, sync, now.Add(-SYNC_RESERVATION_LEASE))
+	if err != nil {
+		return dto.SyncTransaksiResult{}, err
+	}
+	if !reserved {
+		existing, found, err := t.transaksiRepo.GetTransaksiSyncByClientID(ctx, nil, item.ClientID)
+		if err != nil {
+			return dto.SyncTransaksiResult{}, err
+		}
+		if !found || existing.Status == constants.ENUM_SYNC_PENDING {
+			return dto.SyncTransaksiResult{
+				ClientID: item.ClientID,
+				Status:   constants.ENUM_SYNC_PENDING,
+				Message:  dto.ErrSyncInProgress.Error(),
+			}, nil
+		}
+		return dto.SyncTransaksiResult{
+			ClientID:    existing.ClientID,
+			Status:      existing.Status,
+			TransaksiID: existing.TransaksiID,
+			Message:     existing.Message,
+		}, nil
+	}
+
+	// The sale and its sync outcome are committed together, so a sync row
+	// never says accepted without the transaksi, nor the other way round.
+	// When another upload took the reservation over meanwhile, the outcome
+	// cannot be written and the sale is rolled back.
+	err = t.transaksiRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		created, err := t.createTransaksi(ctx, tx, item.CreateTransaksi, userID, tanggal)
+		if err != nil {
+			return err
+		}
+
+		sync.Status = constants.ENUM_SYNC_ACCEPTED
+		sync.TransaksiID = &created.ID
+		return t.transaksiRepo.UpdateTransaksiSync(ctx, tx, sync)
+	})
+
+	// The request may have been cancelled by a till that hung up, so the
+	// outcome is written on a context of its own.
+	finishCtx, cancel := context.WithTimeout(context.Background(), SYNC_RELEASE_TIMEOUT)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		t.notificationService.NotifyStockChanged(transaksiDetailProdukIDs(item.CreateTransaksi))
+	case errors.Is(err, dto.ErrSyncInProgress):
+		return dto.SyncTransaksiResult{
+			ClientID: item.ClientID,
+			Status:   constants.ENUM_SYNC_PENDING,
+			Message:  err.Error(),
+		}, nil
+	case errors.Is(err, dto.ErrTransaksiStokKurang):
+		sync.Status = constants.ENUM_SYNC_STOCK_CONFLICT
+		sync.Message = err.Error()
+	case errors.Is(err, dto.ErrTransaksiPriceMismatch):
+		sync.Status = constants.ENUM_SYNC_PRICE_MISMATCH
+		sync.Message = err.Error()
+	default:
+		// Anything else may be transient, so the reservation is released
+		// and the till is free to upload the same client_id again.
+		if err := t.transaksiRepo.DeleteTransaksiSync(finishCtx, nil, sync); err != nil {
+			return dto.SyncTransaksiResult{}, err
+		}
+		return dto.SyncTransaksiResult{
+			ClientID: item.ClientID,
+			Status:   constants.ENUM_SYNC_REJECTED,
+			Message:  err.Error(),
+		}, nil
+	}
+
+	if sync.Status != constants.ENUM_SYNC_ACCEPTED {
+		err := t.transaksiRepo.UpdateTransaksiSync(finishCtx, nil, sync)
+		if errors.Is(err, dto.ErrSyncInProgress) {
+			return dto.SyncTransaksiResult{
+				ClientID: item.ClientID,
+				Status:   constants.ENUM_SYNC_PENDING,
+				Message:  err.Error(),
+			}, nil
+		}
+		if err != nil {
+			return dto.SyncTransaksiResult{}, err
+		}
+	}
+
+	return dto.SyncTransaksiResult{
+		ClientID:    sync.ClientID,
+		Status:      sync.Status,
+		TransaksiID: sync.TransaksiID,
+		Message:     sync.Message,
+	}, nil
+}
+
 func (t *transaksiService) GetHistoryTransaksi(ctx context.Context, req dto.TransactionPaginationRequest) (any, error) {
 
 	if req.Filter == "" || req.Filter == "produk" {
@@ -194,132 +406,76 @@ func (t *transaksiService) GetHistoryTransaksi(ctx context.Context, req dto.Tran
 	return nil, nil
 }
 
-func (s *transaksiService) DownloadByNota(ctx context.Context, req dto.TransactionPaginationRequest) ([]byte, error) {
+// transaksiNotaTable lists one row per item; the nota, its total and its
+// date span the items of the nota.
+var transaksiNotaTable = utils.Table{
+	Title: "Transaksi Nota",
+	Columns: []utils.Column{
+		{Header: "Kode Nota", Merge: true},
+		{Header: "Merk"},
+		{Header: "Nama"},
+		{Header: "Kategori Barang"},
+		{Header: "Ukuran"},
+		{Header: "Jumlah Item", Type: utils.ColumnInt},
+		{Header: "Harga per Item", Type: utils.ColumnMoney},
+		{Header: "Total Item", Type: utils.ColumnInt},
+		{Header: "Total Pendapatan", Type: utils.ColumnMoney, Merge: true},
+		{Header: "Tanggal", Type: utils.ColumnDateTime, Merge: true},
+	},
+}
+
+var transaksiProdukTable = utils.Table{
+	Title: "Transaksi Produk",
+	Columns: []utils.Column{
+		{Header: "Barcode ID"},
+		{Header: "Produk"},
+		{Header: "Merk"},
+		{Header: "Jenis"},
+		{Header: "Ukuran"},
+		{Header: "Warna"},
+		{Header: "Jumlah Produk", Type: utils.ColumnInt},
+		{Header: "Total Pendapatan", Type: utils.ColumnMoney},
+		{Header: "Tanggal", Type: utils.ColumnDateTime},
+	},
+}
+
+func (s *transaksiService) DownloadByNota(ctx context.Context, req dto.TransactionPaginationRequest, format string) ([]byte, error) {
 	transaksiData, err := s.transaksiRepo.GetTransaksiByNota(ctx, nil, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions by nota: %w", err)
 	}
 
-	// Prepare detailed data with transaction details
-	var detailedTransaksiList []dto.GetTransaksiNota
-	for _, transaksi := range transaksiData.Data {
-		details, err := s.transaksiRepo.GetTransaksiNotaDetail(ctx, nil, transaksi.IDTransaksi)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get transaction details: %w", err)
-		}
-		detailedTransaksiList = append(detailedTransaksiList, dto.GetTransaksiNota{
-			IDTransaksi:      transaksi.IDTransaksi,
-			TotalProduk:      transaksi.TotalProduk,
-			TotalPendapatan:  transaksi.TotalPendapatan,
-			DiskonTransaksi:  transaksi.DiskonTransaksi,
-			TanggalTransaksi: transaksi.TanggalTransaksi,
-			DetailTransaksi:  details,
-		})
-	}
-
-	// Create Excel file
-	file := excelize.NewFile()
-
-	// Create and activate the sheet
-	sheet := "Transaksi Nota"
-	file.NewSheet(sheet)
-	file.SetActiveSheet(1)
-
-	// Set headers
-	headers := []string{"Kode Nota", "Merk", "Nama", "Kategori Barang", "Ukuran", "Jumlah Item", "Harga per Item", "Total Item", "Total Pendapatan", "Tanggal"}
-	for colIndex, header := range headers {
-		col := string(rune('A' + colIndex))
-		file.SetCellValue(sheet, col+"1", header)
-	}
-
-	// Add rows and merge cells
-	row := 2
-	for _, transaksi := range detailedTransaksiList {
-		fmt.Println(transaksi)
-		startRow := row
-		for _, detail := range transaksi.DetailTransaksi {
-			file.SetCellValue(sheet, fmt.Sprintf("A%d", row), transaksi.IDTransaksi)
-			file.SetCellValue(sheet, fmt.Sprintf("B%d", row), detail.Merk)
-			file.SetCellValue(sheet, fmt.Sprintf("C%d", row), detail.NamaProduk)
-			file.SetCellValue(sheet, fmt.Sprintf("D%d", row), detail.Jenis)
-			file.SetCellValue(sheet, fmt.Sprintf("E%d", row), detail.Ukuran)
-			file.SetCellValue(sheet, fmt.Sprintf("F%d", row), detail.JumlahItem)
-			file.SetCellValue(sheet, fmt.Sprintf("G%d", row), detail.HargaProduk)
-			file.SetCellValue(sheet, fmt.Sprintf("H%d", row), transaksi.TotalProduk)
-			file.SetCellValue(sheet, fmt.Sprintf("I%d", row), transaksi.TotalPendapatan)
-			file.SetCellValue(sheet, fmt.Sprintf("J%d", row), transaksi.TanggalTransaksi)
-
-			row++
-		}
-		endRow := row - 1
-
-		// Merge cells for "Kode Nota" column if there are multiple rows for the same transaction
-		if endRow > startRow {
-			if err := file.MergeCell(sheet, fmt.Sprintf("A%d", startRow), fmt.Sprintf("A%d", endRow)); err != nil {
-				return nil, fmt.Errorf("failed to merge cells: %w", err)
-			}
-			if err := file.MergeCell(sheet, fmt.Sprintf("I%d", startRow), fmt.Sprintf("I%d", endRow)); err != nil {
-				return nil, fmt.Errorf("failed to merge cells: %w", err)
+	return utils.RenderTable(format, transaksiNotaTable, func(tw utils.TableWriter) error {
+		for _, transaksi := range transaksiData.Data {
+			details, err := s.transaksiRepo.GetTransaksiNotaDetail(ctx, nil, transaksi.IDTransaksi)
+			if err != nil {
+				return fmt.Errorf("failed to get transaction details: %w", err)
 			}
-			if err := file.MergeCell(sheet, fmt.Sprintf("J%d", startRow), fmt.Sprintf("J%d", endRow)); err != nil {
-				return nil, fmt.Errorf("failed to merge cells: %w", err)
+
+			for _, detail := range details {
+				if err := tw.WriteRow(transaksi.IDTransaksi, detail.Merk, detail.NamaProduk, detail.Jenis, detail.Ukuran, detail.JumlahItem, detail.HargaProduk, transaksi.TotalProduk, transaksi.TotalPendapatan, transaksi.TanggalTransaksi); err != nil {
+					return err
+				}
 			}
 		}
-	}
-
-	// Write file to memory
-	buf, err := file.WriteToBuffer()
-	if err != nil {
-		return nil, fmt.Errorf("failed to write Excel file: %w", err)
-	}
-	return buf.Bytes(), nil
+		return nil
+	})
 }
 
-func (s *transaksiService) DownloadByProduk(ctx context.Context, req dto.TransactionPaginationRequest) ([]byte, error) {
-	// Fetch main transaction data
+func (s *transaksiService) DownloadByProduk(ctx context.Context, req dto.TransactionPaginationRequest, format string) ([]byte, error) {
 	History, err := s.transaksiRepo.GetTransaksiByProduk(ctx, nil, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions by product: %w", err)
 	}
 
-	// Create Excel file
-	file := excelize.NewFile()
-
-	// Create and activate the sheet
-	sheet := "Transaksi Produk"
-	file.NewSheet(sheet)
-	file.SetActiveSheet(1)
-
-	// Set headers
-	headers := []string{"Barcode ID", "Produk", "Merk", "Jenis", "Ukuran", "Warna", "Jumlah Produk", "Total Pendapatan", "Tanggal"}
-	for colIndex, header := range headers {
-		col := string(rune('A' + colIndex))
-		file.SetCellValue(sheet, col+"1", header)
-	}
-
-	// Add rows
-	row := 2
-	for _, transaksi := range History.Data {
-		fmt.Println(transaksi)
-		file.SetCellValue(sheet, fmt.Sprintf("A%d", row), transaksi.BarcodeID)
-		file.SetCellValue(sheet, fmt.Sprintf("B%d", row), transaksi.NamaProduk)
-		file.SetCellValue(sheet, fmt.Sprintf("C%d", row), transaksi.Merk)
-		file.SetCellValue(sheet, fmt.Sprintf("D%d", row), transaksi.Jenis)
-		file.SetCellValue(sheet, fmt.Sprintf("E%d", row), transaksi.Ukuran)
-		file.SetCellValue(sheet, fmt.Sprintf("F%d", row), transaksi.Warna)
-		file.SetCellValue(sheet, fmt.Sprintf("G%d", row), transaksi.TotalBarang)
-		file.SetCellValue(sheet, fmt.Sprintf("H%d", row), transaksi.TotalPendapatan)
-		file.SetCellValue(sheet, fmt.Sprintf("I%d", row), transaksi.TanggalTransaksi)
-		row++
-	}
-
-	// Write file to memory
-	buf, err := file.WriteToBuffer()
-	if err != nil {
-		return nil, fmt.Errorf("failed to write Excel file: %w", err)
-	}
-
-	return buf.Bytes(), nil
+	return utils.RenderTable(format, transaksiProdukTable, func(tw utils.TableWriter) error {
+		for _, transaksi := range History.Data {
+			if err := tw.WriteRow(transaksi.BarcodeID, transaksi.NamaProduk, transaksi.Merk, transaksi.Jenis, transaksi.Ukuran, transaksi.Warna, transaksi.TotalBarang, transaksi.TotalPendapatan, transaksi.TanggalTransaksi); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (s *transaksiService) GetNotaData(ctx context.Context, notaID string) (dto.ReturnUser, error) {
@@ -1,59 +1,175 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"fmt"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
 	// "fmt"
 
+	"bumisubur-be/constants"
 	"bumisubur-be/dto"
 	"bumisubur-be/entity"
 	"bumisubur-be/helpers"
 	"bumisubur-be/repository"
+	"bumisubur-be/utils"
 
-	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
 )
 
 type (
 	UserService interface {
-		RegisterUser(ctx context.Context, req dto.UserCreateRequest) (dto.UserResponse, error)
-		GetAllUserWithPagination(ctx context.Context, req dto.PaginationRequest) (dto.UserPaginationResponse, error)
-		GetUserById(ctx context.Context, userId int) (dto.UserResponse, error)
-		GetUserByEmail(ctx context.Context, email string) (dto.UserResponse, error)
-		UpdateUser(ctx context.Context, req dto.UserCreateRequest, userId int) (dto.UserResponse, error)
-		DeleteUser(ctx context.Context, userId int) error
-		Verify(ctx context.Context, req dto.UserLoginRequest) (dto.UserLoginResponse, error)
-		DownloadDataKaryawan(ctx context.Context) ([]byte, error)
+		RegisterUser(ctx context.Context, actorID int, req dto.UserCreateRequest) (dto.UserResponse, error)
+		GetAllUserWithPagination(ctx context.Context, actorID int, req dto.PaginationRequest) (dto.UserPaginationResponse, error)
+		GetUserById(ctx context.Context, actorID int, userId int) (dto.UserResponse, error)
+		GetUserByEmail(ctx context.Context, actorID int, email string) (dto.UserResponse, error)
+		UpdateUser(ctx context.Context, req dto.UserUpdateRequest, actorID int, userId int) (dto.UserResponse, error)
+		DeleteUser(ctx context.Context, actorID int, userId int) error
+		Verify(ctx context.Context, req dto.UserLoginRequest, client dto.SessionClient) (dto.UserLoginResponse, error)
+		RefreshToken(ctx context.Context, req dto.RefreshTokenRequest, client dto.SessionClient) (dto.UserLoginResponse, error)
+		Logout(ctx context.Context, token string) error
+		RevokeUserSessions(ctx context.Context, actorID int, userId int) (dto.RevokeSessionsResponse, error)
+		ChangePassword(ctx context.Context, req dto.ChangePasswordRequest, userId int, token string) error
+		AdminResetPassword(ctx context.Context, req dto.AdminResetPasswordRequest, actorID int, userId int) error
+		ForgotPassword(ctx context.Context, req dto.ForgotPasswordRequest, client dto.SessionClient) error
+		ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error
+		FirstLoginPassword(ctx context.Context, req dto.FirstLoginPasswordRequest, client dto.SessionClient) (dto.UserLoginResponse, error)
+		UnlockUser(ctx context.Context, actorID int, userId int) (dto.UnlockUserResponse, error)
+		VerifyTwoFactor(ctx context.Context, req dto.TwoFactorLoginRequest, client dto.SessionClient) (dto.UserLoginResponse, error)
+		SetupTwoFactorLogin(ctx context.Context, req dto.TwoFactorSetupRequest) (dto.TwoFactorEnrollResponse, error)
+		GetTwoFactorStatus(ctx context.Context, userId int) (dto.TwoFactorStatusResponse, error)
+		EnrollTwoFactor(ctx context.Context, userId int) (dto.TwoFactorEnrollResponse, error)
+		ConfirmTwoFactor(ctx context.Context, req dto.TwoFactorCodeRequest, userId int) (dto.RecoveryCodesResponse, error)
+		DisableTwoFactor(ctx context.Context, req dto.TwoFactorDisableRequest, userId int) error
+		RegenerateRecoveryCodes(ctx context.Context, req dto.TwoFactorCodeRequest, userId int) (dto.RecoveryCodesResponse, error)
+		ResetTwoFactor(ctx context.Context, actorID int, userId int) error
+		GetTwoFactorPolicy(ctx context.Context, actorID int) (dto.TwoFactorPolicyResponse, error)
+		UpdateTwoFactorPolicy(ctx context.Context, req dto.TwoFactorPolicyRequest, actorID int) (dto.TwoFactorPolicyResponse, error)
+		SetUserPermissions(ctx context.Context, req dto.UserPermissionRequest, actorID int, userId int) (dto.UserPermissionResponse, error)
+		DownloadDataKaryawan(ctx context.Context, format string, actorID int) ([]byte, error)
 	}
 
 	userService struct {
-		userRepo   repository.UserRepository
-		logRepo    repository.LogAksesRepository
-		jwtService JWTService
+		userRepo      repository.UserRepository
+		logRepo       repository.LogAksesRepository
+		sessionRepo   repository.SessionRepository
+		throttleRepo  repository.LoginThrottleRepository
+		twoFactorRepo repository.TwoFactorRepository
+		jwtService    JWTService
+		send          func(toEmail string, subject string, body string) error
+	}
+
+	// loginLimit is when failed logins of a key start to be delayed and
+	// when they lock it.
+	loginLimit struct {
+		delayAfter int
+		lockAfter  int
 	}
 )
 
-func NewUserService(userRepo repository.UserRepository, logRepo repository.LogAksesRepository, jwtService JWTService) UserService {
+// NewUserService sends password reset links through utils.SendMail. The
+// links point at APP_URL, or LOCAL_URL when it is not set. Authenticator
+// apps show the 2FA entry under APP_NAME.
+func NewUserService(userRepo repository.UserRepository, logRepo repository.LogAksesRepository, sessionRepo repository.SessionRepository, throttleRepo repository.LoginThrottleRepository, twoFactorRepo repository.TwoFactorRepository, jwtService JWTService) UserService {
 	return &userService{
-		userRepo:   userRepo,
-		jwtService: jwtService,
-		logRepo:    logRepo,
+		userRepo:      userRepo,
+		jwtService:    jwtService,
+		logRepo:       logRepo,
+		sessionRepo:   sessionRepo,
+		throttleRepo:  throttleRepo,
+		twoFactorRepo: twoFactorRepo,
+		send:          utils.SendMail,
 	}
 }
 
 const (
-	LOCAL_URL          = "http://localhost:3000"
-	VERIFY_EMAIL_ROUTE = "register/verify_email"
+	LOCAL_URL            = "http://localhost:3000"
+	VERIFY_EMAIL_ROUTE   = "register/verify_email"
+	RESET_PASSWORD_ROUTE = "reset-password"
+
+	PASSWORD_MIN_LENGTH = 8
+	PASSWORD_RESET_TTL  = 30 * time.Minute
+
+	// A reset link is made and mailed after the request has been answered,
+	// within PASSWORD_RESET_MAIL_TIMEOUT.
+	PASSWORD_RESET_MAIL_TIMEOUT = 30 * time.Second
+
+	// Failed logins are counted per account and per IP address within
+	// LOGIN_FAILURE_WINDOW. Past the delay threshold each further attempt
+	// waits twice as long as the one before, up to LOGIN_MAX_DELAY; past the
+	// lock threshold the key is locked for LOGIN_LOCK_DURATION. An IP address
+	// gets more room since a whole store shares one.
+	LOGIN_FAILURE_WINDOW = 15 * time.Minute
+	LOGIN_MAX_DELAY      = 30 * time.Second
+	LOGIN_LOCK_DURATION  = 15 * time.Minute
+
+	// Rejected logins are logged under LOGIN_FAILED_ACTIVITY with the status
+	// the client got.
+	LOGIN_FAILED_ACTIVITY      = "login gagal"
+	TWO_FACTOR_FAILED_ACTIVITY = "2fa gagal"
+	LOGIN_FAILED_STATUS        = "400"
+	LOGIN_BLOCKED_STATUS       = "429"
+
+	APP_NAME_DEFAULT = "Bumi Subur"
+
+	// A 2FA challenge has to be answered within TWO_FACTOR_CHALLENGE_TTL
+	// and allows TWO_FACTOR_MAX_ATTEMPTS wrong codes; wrong codes also count
+	// as failed logins.
+	TWO_FACTOR_CHALLENGE_TTL = 5 * time.Minute
+	TWO_FACTOR_MAX_ATTEMPTS  = 5
+	RECOVERY_CODE_COUNT      = 10
+
+	// Masked exports keep the last PII_VISIBLE_DIGITS of NIK and phone
+	// numbers so rows can still be told apart, and replace the rest with
+	// PII_MASK.
+	PII_VISIBLE_DIGITS = 4
+	PII_MASK           = "****"
+)
+
+var (
+	loginAccountLimit = loginLimit{delayAfter: 3, lockAfter: 5}
+	loginIPLimit      = loginLimit{delayAfter: 10, lockAfter: 20}
+
+	// dummyPasswordHash is compared against when the email is unknown so a
+	// failed login takes as long whether or not the account exists.
+	dummyPasswordHash, _ = helpers.HashPassword("dummy-password-for-timing")
 )
 
-func (s *userService) RegisterUser(ctx context.Context, req dto.UserCreateRequest) (dto.UserResponse, error) {
+func (s *userService) RegisterUser(ctx context.Context, actorID int, req dto.UserCreateRequest) (dto.UserResponse, error) {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return dto.UserResponse{}, err
+	}
+
 	_, flag, _ := s.userRepo.CheckEmail(ctx, nil, req.Email)
 	if flag {
 		return dto.UserResponse{}, dto.ErrEmailAlreadyExists
 	}
 
+	if _, flag, err := s.userRepo.CheckNIK(ctx, nil, req.NIK); err != nil {
+		return dto.UserResponse{}, dto.ErrCreateUser
+	} else if flag {
+		return dto.UserResponse{}, dto.ErrNIKAlreadyExists
+	}
+
+	req.Role = strings.TrimSpace(req.Role)
+	if !validRole(req.Role) {
+		return dto.UserResponse{}, dto.ErrUserRoleInvalid
+	}
+
+	if err := validatePassword(req.Password, req.Email); err != nil {
+		return dto.UserResponse{}, err
+	}
+
 	user := entity.User{
 		NIK:          req.NIK,
 		Name:         req.Name,
@@ -65,6 +181,8 @@ func (s *userService) RegisterUser(ctx context.Context, req dto.UserCreateReques
 		TempatLahir:  req.TempatLahir,
 		TanggalLahir: req.TanggalLahir,
 		Alamat:       req.Alamat,
+		// The owner chose this password, so the user replaces it first.
+		MustChangePassword: true,
 	}
 
 	userReg, err := s.userRepo.RegisterUser(ctx, nil, user)
@@ -86,7 +204,14 @@ func (s *userService) RegisterUser(ctx context.Context, req dto.UserCreateReques
 	}, nil
 }
 
-func (s *userService) GetAllUserWithPagination(ctx context.Context, req dto.PaginationRequest) (dto.UserPaginationResponse, error) {
+// GetAllUserWithPagination lists users with their NIK and phone number
+// masked as GetUserById masks them.
+func (s *userService) GetAllUserWithPagination(ctx context.Context, actorID int, req dto.PaginationRequest) (dto.UserPaginationResponse, error) {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
+	if err != nil {
+		return dto.UserPaginationResponse{}, dto.ErrUserNotFound
+	}
+
 	dataWithPaginate, err := s.userRepo.GetAllUserWithPagination(ctx, nil, req)
 	if err != nil {
 		return dto.UserPaginationResponse{}, err
@@ -103,6 +228,9 @@ func (s *userService) GetAllUserWithPagination(ctx context.Context, req dto.Pagi
 			Role:         user.Role,
 			TanggalMasuk: user.TanggalMasuk.String(),
 		}
+		if !canSeePII(actor, user.ID) {
+			maskUserPII(&data)
+		}
 
 		datas = append(datas, data)
 	}
@@ -122,13 +250,20 @@ func (s *userService) GetAllUserWithPagination(ctx context.Context, req dto.Pagi
 	}, nil
 }
 
-func (s *userService) GetUserById(ctx context.Context, userId int) (dto.UserResponse, error) {
+// GetUserById returns a user. NIK, phone number, birth date and address are
+// masked unless the actor is that user or was given CanExportPII.
+func (s *userService) GetUserById(ctx context.Context, actorID int, userId int) (dto.UserResponse, error) {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
+	if err != nil {
+		return dto.UserResponse{}, dto.ErrUserNotFound
+	}
+
 	user, err := s.userRepo.GetUserById(ctx, nil, userId)
 	if err != nil {
 		return dto.UserResponse{}, dto.ErrGetUserById
 	}
 
-	return dto.UserResponse{
+	res := dto.UserResponse{
 		ID:           user.ID,
 		NIK:          user.NIK,
 		Name:         user.Name,
@@ -139,16 +274,28 @@ func (s *userService) GetUserById(ctx context.Context, userId int) (dto.UserResp
 		TempatLahir:  user.TempatLahir,
 		TanggalLahir: user.TanggalLahir.Format("2006-01-02"),
 		Alamat:       user.Alamat,
-	}, nil
+		CanExportPII: user.CanExportPII,
+	}
+	if !canSeePII(actor, user.ID) {
+		maskUserPII(&res)
+	}
+
+	return res, nil
 }
 
-func (s *userService) GetUserByEmail(ctx context.Context, email string) (dto.UserResponse, error) {
+// GetUserByEmail masks the same fields as GetUserById.
+func (s *userService) GetUserByEmail(ctx context.Context, actorID int, email string) (dto.UserResponse, error) {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
+	if err != nil {
+		return dto.UserResponse{}, dto.ErrUserNotFound
+	}
+
 	emails, err := s.userRepo.GetUserByEmail(ctx, nil, email)
 	if err != nil {
 		return dto.UserResponse{}, dto.ErrGetUserByEmail
 	}
 
-	return dto.UserResponse{
+	res := dto.UserResponse{
 		ID:           emails.ID,
 		NIK:          emails.NIK,
 		Name:         emails.Name,
@@ -159,54 +306,226 @@ func (s *userService) GetUserByEmail(ctx context.Context, email string) (dto.Use
 		TempatLahir:  emails.TempatLahir,
 		TanggalLahir: emails.TanggalLahir.String(),
 		Alamat:       emails.Alamat,
-	}, nil
+	}
+	if !canSeePII(actor, emails.ID) {
+		maskUserPII(&res)
+	}
+
+	return res, nil
 }
 
-func (s *userService) UpdateUser(ctx context.Context, req dto.UserCreateRequest, userId int) (dto.UserResponse, error) {
-	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+// canSeePII reports whether actor may see the PII of the user userId
+// unmasked.
+func canSeePII(actor entity.User, userId int) bool {
+	return actor.ID == userId || actor.CanExportPII
+}
+
+// maskUserPII masks res the way DownloadDataKaryawan masks an export.
+func maskUserPII(res *dto.UserResponse) {
+	res.NIK = utils.MaskTail(res.NIK, PII_VISIBLE_DIGITS)
+	res.NoHp = utils.MaskTail(res.NoHp, PII_VISIBLE_DIGITS)
+	if res.TanggalLahir != "" {
+		res.TanggalLahir = PII_MASK
+	}
+	if res.Alamat != "" {
+		res.Alamat = PII_MASK
+	}
+}
+
+// UpdateUser changes only the fields present in req. Users can edit their
+// own profile; editing someone else, or the NIK, role or start date, is left
+// to the owner.
+func (s *userService) UpdateUser(ctx context.Context, req dto.UserUpdateRequest, actorID int, userId int) (dto.UserResponse, error) {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
 	if err != nil {
 		return dto.UserResponse{}, dto.ErrUserNotFound
 	}
 
-	hashedPassword, err := helpers.HashPassword(req.Password)
+	owner := isOwner(actor.Role)
+	if actorID != userId && !owner {
+		return dto.UserResponse{}, dto.ErrUserNotOwner
+	}
+	if !owner && (req.NIK != nil || req.Role != nil || req.TanggalMasuk != nil) {
+		return dto.UserResponse{}, dto.ErrUserFieldOwnerOnly
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
 	if err != nil {
-		return dto.UserResponse{}, err
+		return dto.UserResponse{}, dto.ErrUserNotFound
 	}
 
 	data := entity.User{
-		ID:           user.ID,
-		NIK:          user.NIK,
-		Name:         req.Name,
-		Email:        req.Email,
-		NoHp:         req.NoHp,
-		Password:     hashedPassword,
-		Role:         user.Role,
-		TanggalMasuk: req.TanggalMasuk,
-		TempatLahir:  req.TempatLahir,
-		TanggalLahir: req.TanggalLahir,
-		Alamat:       req.Alamat,
+		ID: user.ID,
 	}
 
-	userUpdate, err := s.userRepo.UpdateUser(ctx, nil, data)
-	if err != nil {
+	for _, field := range []struct {
+		value *string
+		dst   *string
+	}{
+		{req.NIK, &data.NIK},
+		{req.Name, &data.Name},
+		{req.Email, &data.Email},
+		{req.NoHp, &data.NoHp},
+		{req.Role, &data.Role},
+		{req.TempatLahir, &data.TempatLahir},
+		{req.Alamat, &data.Alamat},
+	} {
+		if field.value == nil {
+			continue
+		}
+		*field.dst = strings.TrimSpace(*field.value)
+		if *field.dst == "" {
+			return dto.UserResponse{}, dto.ErrUserFieldEmpty
+		}
+	}
+	if req.Role != nil && !validRole(data.Role) {
+		return dto.UserResponse{}, dto.ErrUserRoleInvalid
+	}
+	if req.TanggalMasuk != nil {
+		data.TanggalMasuk = *req.TanggalMasuk
+	}
+	if req.TanggalLahir != nil {
+		data.TanggalLahir = *req.TanggalLahir
+	}
+
+	if data.Email != "" && !strings.EqualFold(data.Email, user.Email) {
+		if _, flag, _ := s.userRepo.CheckEmail(ctx, nil, data.Email); flag {
+			return dto.UserResponse{}, dto.ErrEmailAlreadyExists
+		}
+	}
+
+	if data.NIK != "" {
+		other, flag, err := s.userRepo.CheckNIK(ctx, nil, data.NIK)
+		if err != nil {
+			return dto.UserResponse{}, dto.ErrUpdateUser
+		}
+		if flag && other.ID != user.ID {
+			return dto.UserResponse{}, dto.ErrNIKAlreadyExists
+		}
+		data.NIKIndex = utils.BlindIndex(data.NIK)
+	}
+
+	if _, err := s.userRepo.UpdateUser(ctx, nil, data); err != nil {
 		return dto.UserResponse{}, dto.ErrUpdateUser
 	}
 
-	return dto.UserResponse{
-		ID:           userUpdate.ID,
-		NIK:          userUpdate.NIK,
-		Name:         userUpdate.Name,
-		Email:        userUpdate.Email,
-		NoHp:         userUpdate.NoHp,
-		Role:         userUpdate.Role,
-		TanggalMasuk: userUpdate.TanggalMasuk.String(),
-		TempatLahir:  userUpdate.TempatLahir,
-		TanggalLahir: userUpdate.TanggalLahir.String(),
-		Alamat:       userUpdate.Alamat,
-	}, nil
+	// Tokens carry the role, so sessions opened under the old one must end.
+	if data.Role != "" && data.Role != user.Role {
+		if _, err := s.revokeSessions(ctx, user.ID); err != nil {
+			return dto.UserResponse{}, err
+		}
+	}
+
+	return s.GetUserById(ctx, actorID, user.ID)
+}
+
+// ChangePassword replaces the password of the logged in user after checking
+// the old one. Every other session of the user is ended; token is the
+// access token of the session that stays.
+func (s *userService) ChangePassword(ctx context.Context, req dto.ChangePasswordRequest, userId int, token string) error {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.ErrUserNotFound
+	}
+
+	checkPassword, err := helpers.CheckPassword(user.Password, []byte(req.OldPassword))
+	if err != nil || !checkPassword {
+		return dto.ErrPasswordNotMatch
+	}
+
+	if req.NewPassword == req.OldPassword {
+		return dto.ErrPasswordNotChanged
+	}
+
+	if err := validatePassword(req.NewPassword, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := helpers.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, nil, user.ID, hashedPassword, false); err != nil {
+		return err
+	}
+
+	sessionID, err := s.jwtService.GetSessionIDByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sessionRepo.RevokeOtherSessions(ctx, nil, user.ID, sessionID)
+	return err
+}
+
+// AdminResetPassword lets the owner give a user a temporary password, for
+// example when they forgot it and have no access to their email. The user
+// is logged out everywhere and has to choose a new password at next login.
+func (s *userService) AdminResetPassword(ctx context.Context, req dto.AdminResetPasswordRequest, actorID int, userId int) error {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.ErrUserNotFound
+	}
+
+	if err := validatePassword(req.Password, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := helpers.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.userRepo.UpdatePassword(ctx, tx, user.ID, hashedPassword, true); err != nil {
+			return err
+		}
+		return s.userRepo.UsePasswordResets(ctx, tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.revokeSessions(ctx, user.ID)
+	return err
+}
+
+func (s *userService) requireOwner(ctx context.Context, actorID int) error {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
+	if err != nil || !isOwner(actor.Role) {
+		return dto.ErrUserNotOwner
+	}
+
+	return nil
+}
+
+// validRole reports whether role is one the app knows. Roles are compared
+// as they are written, so "Admin" or "kasir " would never match.
+func validRole(role string) bool {
+	switch role {
+	case constants.ENUM_ROLE_OWNER, constants.ENUM_ROLE_ADMIN, constants.ENUM_ROLE_USER,
+		constants.ENUM_ROLE_KASIR, constants.ENUM_ROLE_STOK, constants.ENUM_ROLE_KASIR_STOK:
+		return true
+	}
+	return false
+}
+
+// isOwner reports whether a role may manage other users. The owner account
+// is seeded with the admin role, so both count.
+func isOwner(role string) bool {
+	return role == constants.ENUM_ROLE_OWNER || role == constants.ENUM_ROLE_ADMIN
 }
 
-func (s *userService) DeleteUser(ctx context.Context, userId int) error {
+func (s *userService) DeleteUser(ctx context.Context, actorID int, userId int) error {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return err
+	}
+
 	user, err := s.userRepo.GetUserById(ctx, nil, userId)
 	if err != nil {
 		return dto.ErrUserNotFound
@@ -217,68 +536,1084 @@ func (s *userService) DeleteUser(ctx context.Context, userId int) error {
 		return dto.ErrDeleteUser
 	}
 
+	if _, err := s.revokeSessions(ctx, user.ID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (s *userService) Verify(ctx context.Context, req dto.UserLoginRequest) (dto.UserLoginResponse, error) {
-	check, flag, err := s.userRepo.CheckEmail(ctx, nil, req.Email)
+func (s *userService) Verify(ctx context.Context, req dto.UserLoginRequest, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	check, err := s.authenticate(ctx, req.Email, req.Password, client)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if check.MustChangePassword {
+		return dto.UserLoginResponse{}, dto.ErrPasswordChangeRequired
+	}
+
+	return s.completeLogin(ctx, check, client)
+}
+
+// authenticate checks the email and password of a login attempt under the
+// brute-force limits. Every wrong email or password gives the same
+// ErrEmailOrPassword so accounts cannot be enumerated, and is counted against
+// both the account and the IP address and recorded in the access log. The
+// failures of the account are only cleared once a session is started, so a
+// correct password does not reset the count of wrong 2FA codes.
+func (s *userService) authenticate(ctx context.Context, email string, password string, client dto.SessionClient) (entity.User, error) {
+	keys := loginThrottleKeys(email, client.IP)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		if errors.Is(err, dto.ErrLoginThrottled) {
+			s.recordLoginFailure(ctx, email, client, nil, LOGIN_FAILED_ACTIVITY, LOGIN_BLOCKED_STATUS)
+		}
+		return entity.User{}, err
+	}
+
+	user, flag, err := s.userRepo.CheckEmail(ctx, nil, email)
 	if err != nil || !flag {
-		return dto.UserLoginResponse{}, dto.ErrEmailNotFound
+		helpers.CheckPassword(dummyPasswordHash, []byte(password))
+		return entity.User{}, s.failLogin(ctx, keys, email, client, nil)
 	}
 
-	checkPassword, err := helpers.CheckPassword(check.Password, []byte(req.Password))
+	checkPassword, err := helpers.CheckPassword(user.Password, []byte(password))
 	if err != nil || !checkPassword {
-		return dto.UserLoginResponse{}, dto.ErrPasswordNotMatch
+		return entity.User{}, s.failLogin(ctx, keys, email, client, &user)
+	}
+
+	return user, nil
+}
+
+// checkLoginThrottle returns a LoginThrottledError while any of keys is
+// locked or still has to wait after its last failure.
+func (s *userService) checkLoginThrottle(ctx context.Context, keys []string) error {
+	throttles, err := s.throttleRepo.GetLoginThrottles(ctx, nil, keys)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if until := loginAllowedAt(throttle, now); until.After(now) && until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+
+	if wait > 0 {
+		return &dto.LoginThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// failLogin counts a failed login against every key and records it.
+func (s *userService) failLogin(ctx context.Context, keys []string, email string, client dto.SessionClient, user *entity.User) error {
+	s.countLoginFailures(ctx, keys)
+	s.recordLoginFailure(ctx, email, client, user, LOGIN_FAILED_ACTIVITY, LOGIN_FAILED_STATUS)
+	return dto.ErrEmailOrPassword
+}
+
+func (s *userService) countLoginFailures(ctx context.Context, keys []string) {
+	now := time.Now()
+	err := s.throttleRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		for _, key := range keys {
+			throttle, _, err := s.throttleRepo.GetLoginThrottleForUpdate(ctx, tx, key)
+			if err != nil {
+				return err
+			}
+
+			if err := s.throttleRepo.SaveLoginThrottle(ctx, tx, countLoginFailure(throttle, now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("gagal mencatat percobaan login:", err)
+	}
+}
+
+// recordLoginFailure writes a rejected login to the access log. The payload
+// is the email only, never the password. The entry joins the active log
+// akses of the user when they are logged in elsewhere.
+func (s *userService) recordLoginFailure(ctx context.Context, email string, client dto.SessionClient, user *entity.User, activity string, status string) {
+	detail := entity.DetailAkses{
+		Activity: activity,
+		IP:       client.IP,
+		Payload:  email,
+		Status:   status,
+	}
+	if user != nil {
+		if logAksesID := s.logRepo.FindActiveLogAksesByUserID(ctx, nil, user.ID); logAksesID != 0 {
+			detail.LogAksesID = &logAksesID
+		}
+	}
+
+	if err := s.logRepo.CreateDetailAkses(ctx, nil, detail); err != nil {
+		log.Println("gagal mencatat percobaan login:", err)
+	}
+}
+
+// UnlockUser clears the failed logins and lock of a user's account. The IP
+// addresses they came from stay throttled.
+func (s *userService) UnlockUser(ctx context.Context, actorID int, userId int) (dto.UnlockUserResponse, error) {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return dto.UnlockUserResponse{}, err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.UnlockUserResponse{}, dto.ErrUserNotFound
+	}
+
+	unlocked, err := s.throttleRepo.DeleteLoginThrottle(ctx, nil, loginAccountKey(user.Email))
+	if err != nil {
+		return dto.UnlockUserResponse{}, err
+	}
+
+	return dto.UnlockUserResponse{
+		UserID:   user.ID,
+		Unlocked: unlocked,
+	}, nil
+}
+
+func loginAccountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginThrottleKeys(email string, ip string) []string {
+	return []string{loginAccountKey(email), "ip:" + ip}
+}
+
+func loginLimitFor(key string) loginLimit {
+	if strings.HasPrefix(key, "ip:") {
+		return loginIPLimit
+	}
+	return loginAccountLimit
+}
+
+// loginAllowedAt is when the key of throttle may try to log in again.
+func loginAllowedAt(throttle entity.LoginThrottle, now time.Time) time.Time {
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return *throttle.LockedUntil
+	}
+
+	if now.Sub(throttle.WindowStart) >= LOGIN_FAILURE_WINDOW {
+		return now
+	}
+
+	return throttle.LastFailureAt.Add(loginDelay(throttle.Failures, loginLimitFor(throttle.Key)))
+}
+
+// loginDelay is the wait after the given number of failures: nothing below
+// the delay threshold, then 1s, 2s, 4s... up to LOGIN_MAX_DELAY.
+func loginDelay(failures int, limit loginLimit) time.Duration {
+	if failures < limit.delayAfter {
+		return 0
+	}
+
+	shift := failures - limit.delayAfter
+	if shift > 5 {
+		return LOGIN_MAX_DELAY
+	}
+
+	delay := time.Second << shift
+	if delay > LOGIN_MAX_DELAY {
+		return LOGIN_MAX_DELAY
+	}
+	return delay
+}
+
+// countLoginFailure adds a failure at now to throttle, starting a new window
+// once the last one or the lock has run out, and locks the key when it
+// reaches its limit.
+func countLoginFailure(throttle entity.LoginThrottle, now time.Time) entity.LoginThrottle {
+	lockExpired := throttle.LockedUntil != nil && !now.Before(*throttle.LockedUntil)
+	if throttle.Failures == 0 || lockExpired || now.Sub(throttle.WindowStart) >= LOGIN_FAILURE_WINDOW {
+		throttle.Failures = 0
+		throttle.WindowStart = now
+		throttle.LockedUntil = nil
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	if throttle.Failures >= loginLimitFor(throttle.Key).lockAfter {
+		lockedUntil := now.Add(LOGIN_LOCK_DURATION)
+		throttle.LockedUntil = &lockedUntil
+	}
+
+	return throttle
+}
+
+// completeLogin starts a session for a user who passed the password check,
+// or hands out a 2FA challenge when the user has 2FA on or their role
+// requires it.
+func (s *userService) completeLogin(ctx context.Context, user entity.User, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	required, err := s.twoFactorRepo.IsTwoFactorRequired(ctx, nil, user.Role)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if !user.TwoFactorEnabled && !required {
+		return s.startSession(ctx, user, client)
 	}
 
-	token := s.jwtService.GenerateToken(strconv.Itoa(check.ID), check.Role)
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
 
-	s.logRepo.LogAkses(ctx, nil, check.ID)
+	challenge, err := s.twoFactorRepo.CreateLoginChallenge(ctx, nil, entity.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hash,
+		Setup:     !user.TwoFactorEnabled,
+		ExpiresAt: time.Now().Add(TWO_FACTOR_CHALLENGE_TTL),
+	})
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
 
 	return dto.UserLoginResponse{
-		Token: token,
-		Role:  check.Role,
+		Role: user.Role,
+		Challenge: &dto.TwoFactorChallengeResponse{
+			Token:     token,
+			ExpiresAt: challenge.ExpiresAt,
+			Setup:     challenge.Setup,
+		},
 	}, nil
 }
 
-func (s *userService) DownloadDataKaryawan(ctx context.Context) ([]byte, error) {
-	
-	karyawan, err := s.userRepo.GetAllUser(ctx, nil)
+// VerifyTwoFactor finishes a login with the code from the authenticator app,
+// or a recovery code. On a setup challenge the code confirms the new secret,
+// which turns 2FA on, and the recovery codes come back with the tokens.
+func (s *userService) VerifyTwoFactor(ctx context.Context, req dto.TwoFactorLoginRequest, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	challenge, user, err := s.loginChallenge(ctx, req.ChallengeToken)
 	if err != nil {
-		return nil, err
+		return dto.UserLoginResponse{}, err
+	}
+
+	keys := loginThrottleKeys(user.Email, client.IP)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	var (
+		counter int64
+		ok      bool
+	)
+	if challenge.Setup {
+		if user.TwoFactorSecret == "" {
+			return dto.UserLoginResponse{}, dto.ErrTwoFactorNotEnrolled
+		}
+		counter, ok = matchTOTP(user, req.Code)
+	} else {
+		ok, err = s.checkTwoFactorCode(ctx, user, req.Code)
+		if err != nil {
+			return dto.UserLoginResponse{}, err
+		}
+	}
+
+	if !ok {
+		if err := s.twoFactorRepo.AddLoginChallengeAttempt(ctx, nil, challenge.ID); err != nil {
+			return dto.UserLoginResponse{}, err
+		}
+		s.countLoginFailures(ctx, keys)
+		s.recordLoginFailure(ctx, user.Email, client, &user, TWO_FACTOR_FAILED_ACTIVITY, LOGIN_FAILED_STATUS)
+		return dto.UserLoginResponse{}, dto.ErrTwoFactorCodeInvalid
 	}
 
-	f := excelize.NewFile()
-	f.SetSheetName("Sheet1", "Data Karyawan")
-	sheetIndex, err := f.GetSheetIndex("Data Karyawan")
+	used, err := s.twoFactorRepo.UseLoginChallenge(ctx, nil, challenge.ID)
 	if err != nil {
-		return nil, err
+		return dto.UserLoginResponse{}, err
+	}
+	if !used {
+		return dto.UserLoginResponse{}, dto.ErrTwoFactorChallengeInvalid
 	}
-	f.SetActiveSheet(sheetIndex)
 
-	headers := []string{"ID", "NIK", "Nama", "Email", "No HP", "Jabatan", "Tanggal Masuk", "Tempat Lahir", "Tanggal Lahir", "Alamat"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c%d", 'A'+i, 1)
-		f.SetCellValue("Data Karyawan", cell, header)
+	var codes []string
+	if challenge.Setup {
+		codes, err = s.enableTwoFactor(ctx, user.ID, counter)
+		if err != nil {
+			return dto.UserLoginResponse{}, err
+		}
 	}
 
-	for i, user := range karyawan {
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("A%d", i+2), user.ID)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("B%d", i+2), user.NIK)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("C%d", i+2), user.Name)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("D%d", i+2), user.Email)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("E%d", i+2), user.NoHp)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("F%d", i+2), user.Role)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("G%d", i+2), user.TanggalMasuk.String())
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("H%d", i+2), user.TempatLahir)
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("I%d", i+2), user.TanggalLahir.String())
-		f.SetCellValue("Data Karyawan", fmt.Sprintf("J%d", i+2), user.Alamat)
+	res, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
 	}
 
-	buf := new(bytes.Buffer)
-	if err := f.Write(buf); err != nil {
-		return nil, err
+	res.RecoveryCodes = codes
+	return res, nil
+}
+
+// SetupTwoFactorLogin gives a user on a setup challenge the secret to add to
+// their authenticator app. Asking again replaces the secret.
+func (s *userService) SetupTwoFactorLogin(ctx context.Context, req dto.TwoFactorSetupRequest) (dto.TwoFactorEnrollResponse, error) {
+	challenge, user, err := s.loginChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, err
+	}
+
+	if !challenge.Setup {
+		return dto.TwoFactorEnrollResponse{}, dto.ErrTwoFactorAlreadyEnabled
+	}
+
+	return s.newTwoFactorSecret(ctx, user)
+}
+
+// loginChallenge finds the challenge of token while it can still be
+// answered, with its user.
+func (s *userService) loginChallenge(ctx context.Context, token string) (entity.LoginChallenge, entity.User, error) {
+	challenge, err := s.twoFactorRepo.GetLoginChallengeByHash(ctx, nil, hashSecretToken(token))
+	if err != nil || challenge.UsedAt != nil || !time.Now().Before(challenge.ExpiresAt) || challenge.Attempts >= TWO_FACTOR_MAX_ATTEMPTS {
+		return entity.LoginChallenge{}, entity.User{}, dto.ErrTwoFactorChallengeInvalid
 	}
 
-	return buf.Bytes(), nil
+	user, err := s.userRepo.GetUserById(ctx, nil, challenge.UserID)
+	if err != nil {
+		return entity.LoginChallenge{}, entity.User{}, dto.ErrTwoFactorChallengeInvalid
+	}
+
+	return challenge, user, nil
+}
+
+func (s *userService) GetTwoFactorStatus(ctx context.Context, userId int) (dto.TwoFactorStatusResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.TwoFactorStatusResponse{}, dto.ErrUserNotFound
+	}
+
+	required, err := s.twoFactorRepo.IsTwoFactorRequired(ctx, nil, user.Role)
+	if err != nil {
+		return dto.TwoFactorStatusResponse{}, err
+	}
+
+	left, err := s.twoFactorRepo.CountRecoveryCodes(ctx, nil, user.ID)
+	if err != nil {
+		return dto.TwoFactorStatusResponse{}, err
+	}
+
+	return dto.TwoFactorStatusResponse{
+		Enabled:           user.TwoFactorEnabled,
+		Required:          required,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// EnrollTwoFactor starts turning on 2FA for a logged in user. It stays off
+// until ConfirmTwoFactor gets a code for the new secret.
+func (s *userService) EnrollTwoFactor(ctx context.Context, userId int) (dto.TwoFactorEnrollResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, dto.ErrUserNotFound
+	}
+
+	if user.TwoFactorEnabled {
+		return dto.TwoFactorEnrollResponse{}, dto.ErrTwoFactorAlreadyEnabled
+	}
+
+	return s.newTwoFactorSecret(ctx, user)
+}
+
+func (s *userService) ConfirmTwoFactor(ctx context.Context, req dto.TwoFactorCodeRequest, userId int) (dto.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.RecoveryCodesResponse{}, dto.ErrUserNotFound
+	}
+
+	if user.TwoFactorEnabled {
+		return dto.RecoveryCodesResponse{}, dto.ErrTwoFactorAlreadyEnabled
+	}
+
+	if user.TwoFactorSecret == "" {
+		return dto.RecoveryCodesResponse{}, dto.ErrTwoFactorNotEnrolled
+	}
+
+	counter, ok := matchTOTP(user, req.Code)
+	if !ok {
+		return dto.RecoveryCodesResponse{}, dto.ErrTwoFactorCodeInvalid
+	}
+
+	codes, err := s.enableTwoFactor(ctx, user.ID, counter)
+	if err != nil {
+		return dto.RecoveryCodesResponse{}, err
+	}
+
+	return dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns 2FA off after checking the password and a code. It
+// cannot be turned off while the role of the user requires it.
+func (s *userService) DisableTwoFactor(ctx context.Context, req dto.TwoFactorDisableRequest, userId int) error {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.ErrUserNotFound
+	}
+
+	if !user.TwoFactorEnabled {
+		return dto.ErrTwoFactorNotEnabled
+	}
+
+	required, err := s.twoFactorRepo.IsTwoFactorRequired(ctx, nil, user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return dto.ErrTwoFactorRequiredForRole
+	}
+
+	checkPassword, err := helpers.CheckPassword(user.Password, []byte(req.Password))
+	if err != nil || !checkPassword {
+		return dto.ErrPasswordNotMatch
+	}
+
+	ok, err := s.checkTwoFactorCode(ctx, user, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return dto.ErrTwoFactorCodeInvalid
+	}
+
+	return s.twoFactorRepo.DisableTwoFactor(ctx, nil, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a user. It takes a
+// code from the authenticator app, not a recovery code.
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, req dto.TwoFactorCodeRequest, userId int) (dto.RecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.RecoveryCodesResponse{}, dto.ErrUserNotFound
+	}
+
+	if !user.TwoFactorEnabled {
+		return dto.RecoveryCodesResponse{}, dto.ErrTwoFactorNotEnabled
+	}
+
+	counter, ok := matchTOTP(user, req.Code)
+	if ok {
+		ok, err = s.twoFactorRepo.UseTOTPCounter(ctx, nil, user.ID, counter)
+		if err != nil {
+			return dto.RecoveryCodesResponse{}, err
+		}
+	}
+	if !ok {
+		return dto.RecoveryCodesResponse{}, dto.ErrTwoFactorCodeInvalid
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return dto.RecoveryCodesResponse{}, err
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, nil, user.ID, hashes); err != nil {
+		return dto.RecoveryCodesResponse{}, err
+	}
+
+	return dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// ResetTwoFactor lets the owner turn off 2FA for a user who lost their phone
+// and their recovery codes. If their role requires 2FA they set it up again
+// at the next login.
+func (s *userService) ResetTwoFactor(ctx context.Context, actorID int, userId int) error {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.ErrUserNotFound
+	}
+
+	return s.twoFactorRepo.DisableTwoFactor(ctx, nil, user.ID)
+}
+
+func (s *userService) GetTwoFactorPolicy(ctx context.Context, actorID int) (dto.TwoFactorPolicyResponse, error) {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return dto.TwoFactorPolicyResponse{}, err
+	}
+
+	policies, err := s.twoFactorRepo.GetTwoFactorPolicies(ctx, nil)
+	if err != nil {
+		return dto.TwoFactorPolicyResponse{}, err
+	}
+
+	roles := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		roles = append(roles, policy.Role)
+	}
+
+	return dto.TwoFactorPolicyResponse{Roles: roles}, nil
+}
+
+// UpdateTwoFactorPolicy sets the roles for which 2FA is mandatory. Users of
+// those roles without 2FA are asked to set it up at their next login.
+func (s *userService) UpdateTwoFactorPolicy(ctx context.Context, req dto.TwoFactorPolicyRequest, actorID int) (dto.TwoFactorPolicyResponse, error) {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return dto.TwoFactorPolicyResponse{}, err
+	}
+
+	seen := make(map[string]bool, len(req.Roles))
+	roles := make([]string, 0, len(req.Roles))
+	for _, role := range req.Roles {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			return dto.TwoFactorPolicyResponse{}, dto.ErrTwoFactorRoleEmpty
+		}
+		if !validRole(role) {
+			return dto.TwoFactorPolicyResponse{}, dto.ErrUserRoleInvalid
+		}
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		roles = append(roles, role)
+	}
+
+	if err := s.twoFactorRepo.ReplaceTwoFactorPolicies(ctx, nil, roles); err != nil {
+		return dto.TwoFactorPolicyResponse{}, err
+	}
+
+	return s.GetTwoFactorPolicy(ctx, actorID)
+}
+
+// newTwoFactorSecret stores a new, not yet confirmed, secret for user.
+func (s *userService) newTwoFactorSecret(ctx context.Context, user entity.User) (dto.TwoFactorEnrollResponse, error) {
+	secret, err := helpers.GenerateTOTPSecret()
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, err
+	}
+
+	encrypted, err := utils.AESEncrypt(secret)
+	if err != nil {
+		return dto.TwoFactorEnrollResponse{}, err
+	}
+
+	if err := s.twoFactorRepo.SetTwoFactorSecret(ctx, nil, user.ID, encrypted); err != nil {
+		return dto.TwoFactorEnrollResponse{}, err
+	}
+
+	return dto.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: helpers.TOTPProvisioningURI(appName(), user.Email, secret),
+	}, nil
+}
+
+// enableTwoFactor turns 2FA on once a code for the new secret was entered
+// and returns the first recovery codes.
+func (s *userService) enableTwoFactor(ctx context.Context, userId int, counter int64) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.twoFactorRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.twoFactorRepo.EnableTwoFactor(ctx, tx, userId, counter); err != nil {
+			return err
+		}
+		return s.twoFactorRepo.ReplaceRecoveryCodes(ctx, tx, userId, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkTwoFactorCode accepts a TOTP code that was not used before or an
+// unused recovery code, which is then used up.
+func (s *userService) checkTwoFactorCode(ctx context.Context, user entity.User, code string) (bool, error) {
+	if counter, ok := matchTOTP(user, code); ok {
+		return s.twoFactorRepo.UseTOTPCounter(ctx, nil, user.ID, counter)
+	}
+
+	return s.twoFactorRepo.UseRecoveryCode(ctx, nil, user.ID, hashRecoveryCode(code))
+}
+
+// matchTOTP checks code against the stored secret of user and returns the
+// period it belongs to.
+func matchTOTP(user entity.User, code string) (int64, bool) {
+	if user.TwoFactorSecret == "" {
+		return 0, false
+	}
+
+	secret, err := utils.AESDecrypt(user.TwoFactorSecret)
+	if err != nil {
+		return 0, false
+	}
+
+	return helpers.ValidateTOTP(secret, code, time.Now())
+}
+
+// newRecoveryCodes returns RECOVERY_CODE_COUNT codes like "abcd-efgh" and
+// the hashes stored for them.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, RECOVERY_CODE_COUNT)
+	hashes := make([]string, 0, RECOVERY_CODE_COUNT)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < RECOVERY_CODE_COUNT; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(buf))
+		code = code[:4] + "-" + code[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so a code typed from a
+// printout still matches.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashSecretToken(code)
+}
+
+func appName() string {
+	if name := os.Getenv("APP_NAME"); name != "" {
+		return name
+	}
+	return APP_NAME_DEFAULT
+}
+
+// startSession records the login of a user, opens a new session for the
+// device and clears the failed logins of the account.
+func (s *userService) startSession(ctx context.Context, user entity.User, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	logAkses, err := s.logRepo.LogAkses(ctx, nil, user.ID)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	refreshToken, hash, err := newSecretToken()
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	now := time.Now()
+	session, err := s.sessionRepo.CreateSession(ctx, nil, entity.Session{
+		UserID:           user.ID,
+		LogAksesID:       logAkses.ID,
+		RefreshTokenHash: hash,
+		IP:               client.IP,
+		UserAgent:        client.UserAgent,
+		ExpiresAt:        now.Add(REFRESH_TOKEN_TTL),
+		LastUsedAt:       now,
+	})
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if err := s.logRepo.ExtendLogAkses(ctx, nil, logAkses.ID, session.ExpiresAt); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if _, err := s.throttleRepo.DeleteLoginThrottle(ctx, nil, loginAccountKey(user.Email)); err != nil {
+		log.Println("gagal menghapus percobaan login:", err)
+	}
+
+	return s.loginResponse(user, session, refreshToken, now), nil
+}
+
+// FirstLoginPassword lets a user whose password was chosen by the owner
+// replace it with their own, then logs them in.
+func (s *userService) FirstLoginPassword(ctx context.Context, req dto.FirstLoginPasswordRequest, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	user, err := s.authenticate(ctx, req.Email, req.OldPassword, client)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if !user.MustChangePassword {
+		return dto.UserLoginResponse{}, dto.ErrPasswordChangeNotRequired
+	}
+
+	if req.NewPassword == req.OldPassword {
+		return dto.UserLoginResponse{}, dto.ErrPasswordNotChanged
+	}
+
+	if err := validatePassword(req.NewPassword, user.Email); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	hashedPassword, err := helpers.HashPassword(req.NewPassword)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, nil, user.ID, hashedPassword, false); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// ForgotPassword emails a reset link when the email belongs to a user. It
+// reports success either way and looks the email up only after answering,
+// so neither the answer nor its timing tells which emails are registered.
+// Every request counts as a failed login of the email and the IP address,
+// so the login limits also keep an inbox from being flooded.
+func (s *userService) ForgotPassword(ctx context.Context, req dto.ForgotPasswordRequest, client dto.SessionClient) error {
+	email := strings.TrimSpace(req.Email)
+	keys := loginThrottleKeys(email, client.IP)
+	if err := s.checkLoginThrottle(ctx, keys); err != nil {
+		return err
+	}
+	s.countLoginFailures(ctx, keys)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), PASSWORD_RESET_MAIL_TIMEOUT)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Println("gagal mengirim link reset password:", err)
+		}
+	}()
+
+	return nil
+}
+
+// sendPasswordReset replaces the reset links of the user with email by a new
+// one and mails it. An unknown email is ignored.
+func (s *userService) sendPasswordReset(ctx context.Context, email string) error {
+	user, flag, err := s.userRepo.CheckEmail(ctx, nil, email)
+	if err != nil || !flag {
+		return err
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		if err := s.userRepo.UsePasswordResets(ctx, tx, user.ID); err != nil {
+			return err
+		}
+
+		_, err := s.userRepo.CreatePasswordReset(ctx, tx, entity.PasswordReset{
+			UserID:    user.ID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(PASSWORD_RESET_TTL),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	link := appURL() + "/" + RESET_PASSWORD_ROUTE + "?token=" + token
+	body, err := utils.RenderResetPasswordMail(user.Name, link, "30 menit")
+	if err != nil {
+		return err
+	}
+
+	return s.send(user.Email, "Reset Password", body)
+}
+
+// ResetPassword sets a new password with a reset link. The link works once,
+// and every session of the user is ended.
+func (s *userService) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	reset, err := s.userRepo.GetPasswordResetByHash(ctx, nil, hashSecretToken(req.Token))
+	if err != nil || reset.UsedAt != nil || !time.Now().Before(reset.ExpiresAt) {
+		return dto.ErrResetTokenInvalid
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, reset.UserID)
+	if err != nil {
+		return dto.ErrResetTokenInvalid
+	}
+
+	if err := validatePassword(req.Password, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := helpers.HashPassword(req.Password)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.RunInTransaction(ctx, func(tx *gorm.DB) error {
+		used, err := s.userRepo.UsePasswordReset(ctx, tx, reset.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return dto.ErrResetTokenInvalid
+		}
+
+		return s.userRepo.UpdatePassword(ctx, tx, user.ID, hashedPassword, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The reset requests counted against the account, and the link proves
+	// the user owns it.
+	if _, err := s.throttleRepo.DeleteLoginThrottle(ctx, nil, loginAccountKey(user.Email)); err != nil {
+		log.Println("gagal menghapus percobaan login:", err)
+	}
+
+	_, err = s.revokeSessions(ctx, user.ID)
+	return err
+}
+
+// validatePassword enforces the password policy: at least
+// PASSWORD_MIN_LENGTH characters with a letter and a digit, and not the
+// email address itself.
+func validatePassword(password string, email string) error {
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+
+	if len([]rune(password)) < PASSWORD_MIN_LENGTH || !letter || !digit {
+		return dto.ErrPasswordPolicy
+	}
+
+	if strings.EqualFold(password, strings.TrimSpace(email)) {
+		return dto.ErrPasswordSameAsEmail
+	}
+
+	return nil
+}
+
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return strings.TrimRight(url, "/")
+	}
+	return LOCAL_URL
+}
+
+// RefreshToken trades a refresh token for a new access and refresh token.
+// The old refresh token stops working; presenting it again means it was
+// copied, so the whole session is revoked.
+func (s *userService) RefreshToken(ctx context.Context, req dto.RefreshTokenRequest, client dto.SessionClient) (dto.UserLoginResponse, error) {
+	oldHash := hashSecretToken(req.RefreshToken)
+	session, found, err := s.sessionRepo.GetSessionByTokenHash(ctx, nil, oldHash)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	if !found {
+		return dto.UserLoginResponse{}, dto.ErrRefreshTokenInvalid
+	}
+
+	now := time.Now()
+	if session.RefreshTokenHash != oldHash {
+		if session.Active(now) {
+			if err := s.endSession(ctx, session); err != nil {
+				return dto.UserLoginResponse{}, err
+			}
+		}
+		return dto.UserLoginResponse{}, dto.ErrRefreshTokenReused
+	}
+	if !session.Active(now) {
+		return dto.UserLoginResponse{}, dto.ErrRefreshTokenInvalid
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, session.UserID)
+	if err != nil {
+		if err := s.endSession(ctx, session); err != nil {
+			return dto.UserLoginResponse{}, err
+		}
+		return dto.UserLoginResponse{}, dto.ErrRefreshTokenInvalid
+	}
+
+	refreshToken, hash, err := newSecretToken()
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	session.RefreshTokenHash = hash
+	session.ExpiresAt = now.Add(REFRESH_TOKEN_TTL)
+	session.LastUsedAt = now
+	session.IP = client.IP
+	session.UserAgent = client.UserAgent
+	rotated, err := s.sessionRepo.RotateSession(ctx, nil, session, oldHash)
+	if err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+	if !rotated {
+		return dto.UserLoginResponse{}, dto.ErrRefreshTokenInvalid
+	}
+
+	if err := s.logRepo.ExtendLogAkses(ctx, nil, session.LogAksesID, session.ExpiresAt); err != nil {
+		return dto.UserLoginResponse{}, err
+	}
+
+	return s.loginResponse(user, session, refreshToken, now), nil
+}
+
+func (s *userService) Logout(ctx context.Context, token string) error {
+	sessionID, err := s.jwtService.GetSessionIDByToken(ctx, token)
+	if err != nil {
+		return dto.ErrSessionRevoked
+	}
+
+	session, err := s.sessionRepo.GetSessionByID(ctx, nil, sessionID)
+	if err != nil {
+		return dto.ErrSessionRevoked
+	}
+
+	return s.endSession(ctx, session)
+}
+
+// RevokeUserSessions logs a user out of every device at once. Users can do
+// this for themselves; only an owner can do it for someone else.
+func (s *userService) RevokeUserSessions(ctx context.Context, actorID int, userId int) (dto.RevokeSessionsResponse, error) {
+	if actorID != userId {
+		if err := s.requireOwner(ctx, actorID); err != nil {
+			return dto.RevokeSessionsResponse{}, err
+		}
+	}
+
+	return s.revokeSessions(ctx, userId)
+}
+
+func (s *userService) revokeSessions(ctx context.Context, userId int) (dto.RevokeSessionsResponse, error) {
+	revoked, err := s.sessionRepo.RevokeUserSessions(ctx, nil, userId)
+	if err != nil {
+		return dto.RevokeSessionsResponse{}, err
+	}
+
+	if logAksesID := s.logRepo.FindActiveLogAksesByUserID(ctx, nil, userId); logAksesID != 0 {
+		if err := s.logRepo.EndLogAkses(ctx, nil, logAksesID); err != nil {
+			return dto.RevokeSessionsResponse{}, err
+		}
+	}
+
+	return dto.RevokeSessionsResponse{
+		Revoked: revoked,
+	}, nil
+}
+
+// endSession revokes one session and closes its log akses once no other
+// session of the user is still open on it.
+func (s *userService) endSession(ctx context.Context, session entity.Session) error {
+	if _, err := s.sessionRepo.RevokeSession(ctx, nil, session.ID); err != nil {
+		return err
+	}
+
+	open, err := s.sessionRepo.CountActiveSessions(ctx, nil, session.UserID, session.LogAksesID)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+
+	return s.logRepo.EndLogAkses(ctx, nil, session.LogAksesID)
+}
+
+func (s *userService) loginResponse(user entity.User, session entity.Session, refreshToken string, now time.Time) dto.UserLoginResponse {
+	return dto.UserLoginResponse{
+		Token:            s.jwtService.GenerateToken(strconv.Itoa(user.ID), user.Role, session.ID),
+		Role:             user.Role,
+		ExpiresAt:        now.Add(ACCESS_TOKEN_TTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}
+}
+
+// newSecretToken returns a random token for a refresh token or reset link
+// and the hash stored for it.
+func newSecretToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var karyawanTable = utils.Table{
+	Title: "Data Karyawan",
+	Columns: []utils.Column{
+		{Header: "ID", Type: utils.ColumnInt},
+		{Header: "NIK"},
+		{Header: "Nama"},
+		{Header: "Email"},
+		{Header: "No HP"},
+		{Header: "Jabatan"},
+		{Header: "Tanggal Masuk", Type: utils.ColumnDate},
+		{Header: "Tempat Lahir"},
+		{Header: "Tanggal Lahir", Type: utils.ColumnDate},
+		{Header: "Alamat"},
+	},
+}
+
+// SetUserPermissions lets the owner grant or take back permissions that
+// are not tied to a role, such as exporting unmasked karyawan data.
+func (s *userService) SetUserPermissions(ctx context.Context, req dto.UserPermissionRequest, actorID int, userId int) (dto.UserPermissionResponse, error) {
+	if err := s.requireOwner(ctx, actorID); err != nil {
+		return dto.UserPermissionResponse{}, err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, nil, userId)
+	if err != nil {
+		return dto.UserPermissionResponse{}, dto.ErrUserNotFound
+	}
+
+	if err := s.userRepo.UpdatePermissions(ctx, nil, user.ID, *req.CanExportPII); err != nil {
+		return dto.UserPermissionResponse{}, err
+	}
+
+	return dto.UserPermissionResponse{
+		UserID:       user.ID,
+		CanExportPII: *req.CanExportPII,
+	}, nil
+}
+
+// DownloadDataKaryawan exports every user. NIK, phone number, birth date and
+// address are masked unless the actor was given CanExportPII.
+func (s *userService) DownloadDataKaryawan(ctx context.Context, format string, actorID int) ([]byte, error) {
+	actor, err := s.userRepo.GetUserById(ctx, nil, actorID)
+	if err != nil {
+		return nil, dto.ErrUserNotFound
+	}
+
+	karyawan, err := s.userRepo.GetAllUser(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.RenderTable(format, karyawanTable, func(tw utils.TableWriter) error {
+		for _, user := range karyawan {
+			var (
+				nik          any = user.NIK
+				noHp         any = user.NoHp
+				tanggalLahir any = user.TanggalLahir
+				alamat       any = user.Alamat
+			)
+			if !actor.CanExportPII {
+				nik = utils.MaskTail(user.NIK, PII_VISIBLE_DIGITS)
+				noHp = utils.MaskTail(user.NoHp, PII_VISIBLE_DIGITS)
+				tanggalLahir = PII_MASK
+				alamat = PII_MASK
+			}
+
+			if err := tw.WriteRow(user.ID, nik, user.Name, user.Email, noHp, user.Role, user.TanggalMasuk, user.TempatLahir, tanggalLahir, alamat); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,533 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bumisubur-be/dto"
+	"bumisubur-be/entity"
+	"bumisubur-be/helpers"
+	"bumisubur-be/repository"
+	"bumisubur-be/utils"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// The fakes embed the repository interfaces and only implement what the 2FA
+// login uses; anything else panics.
+type (
+	fakeUserRepo struct {
+		repository.UserRepository
+		users map[int]entity.User
+	}
+
+	fakeTwoFactorRepo struct {
+		repository.TwoFactorRepository
+		lastCounter   map[int]int64
+		recoveryCodes map[string]bool
+		challenges    map[string]*entity.LoginChallenge
+	}
+
+	fakeThrottleRepo struct {
+		repository.LoginThrottleRepository
+		throttles map[string]entity.LoginThrottle
+	}
+
+	fakeLogAksesRepo struct {
+		repository.LogAksesRepository
+	}
+
+	fakeSessionRepo struct {
+		repository.SessionRepository
+		revoked []int
+	}
+
+	fakeJWTService struct {
+		JWTService
+	}
+)
+
+func (r *fakeUserRepo) GetUserById(ctx context.Context, tx *gorm.DB, userId int) (entity.User, error) {
+	user, ok := r.users[userId]
+	if !ok {
+		return entity.User{}, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepo) CheckEmail(ctx context.Context, tx *gorm.DB, email string) (entity.User, bool, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, true, nil
+		}
+	}
+	return entity.User{}, false, nil
+}
+
+// UpdateUser saves the fields that are set, like gorm's Updates.
+func (r *fakeUserRepo) UpdateUser(ctx context.Context, tx *gorm.DB, user entity.User) (entity.User, error) {
+	stored := r.users[user.ID]
+	if user.Name != "" {
+		stored.Name = user.Name
+	}
+	if user.Role != "" {
+		stored.Role = user.Role
+	}
+	r.users[user.ID] = stored
+	return user, nil
+}
+
+func (r *fakeTwoFactorRepo) UseTOTPCounter(ctx context.Context, tx *gorm.DB, userID int, counter int64) (bool, error) {
+	if r.lastCounter[userID] >= counter {
+		return false, nil
+	}
+	r.lastCounter[userID] = counter
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepo) UseRecoveryCode(ctx context.Context, tx *gorm.DB, userID int, hash string) (bool, error) {
+	if !r.recoveryCodes[hash] {
+		return false, nil
+	}
+	r.recoveryCodes[hash] = false
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepo) IsTwoFactorRequired(ctx context.Context, tx *gorm.DB, role string) (bool, error) {
+	return false, nil
+}
+
+func (r *fakeTwoFactorRepo) CreateLoginChallenge(ctx context.Context, tx *gorm.DB, challenge entity.LoginChallenge) (entity.LoginChallenge, error) {
+	challenge.ID = len(r.challenges) + 1
+	r.challenges[challenge.TokenHash] = &challenge
+	return challenge, nil
+}
+
+func (r *fakeTwoFactorRepo) GetLoginChallengeByHash(ctx context.Context, tx *gorm.DB, hash string) (entity.LoginChallenge, error) {
+	challenge, ok := r.challenges[hash]
+	if !ok {
+		return entity.LoginChallenge{}, gorm.ErrRecordNotFound
+	}
+	return *challenge, nil
+}
+
+func (r *fakeTwoFactorRepo) AddLoginChallengeAttempt(ctx context.Context, tx *gorm.DB, challengeID int) error {
+	for _, challenge := range r.challenges {
+		if challenge.ID == challengeID {
+			challenge.Attempts++
+		}
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) UseLoginChallenge(ctx context.Context, tx *gorm.DB, challengeID int) (bool, error) {
+	for _, challenge := range r.challenges {
+		if challenge.ID == challengeID && challenge.UsedAt == nil {
+			now := time.Now()
+			challenge.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeThrottleRepo) GetLoginThrottles(ctx context.Context, tx *gorm.DB, keys []string) ([]entity.LoginThrottle, error) {
+	var throttles []entity.LoginThrottle
+	for _, key := range keys {
+		if throttle, ok := r.throttles[key]; ok {
+			throttles = append(throttles, throttle)
+		}
+	}
+	return throttles, nil
+}
+
+func (r *fakeThrottleRepo) GetLoginThrottleForUpdate(ctx context.Context, tx *gorm.DB, key string) (entity.LoginThrottle, bool, error) {
+	if throttle, ok := r.throttles[key]; ok {
+		return throttle, true, nil
+	}
+	return entity.LoginThrottle{Key: key}, false, nil
+}
+
+func (r *fakeThrottleRepo) SaveLoginThrottle(ctx context.Context, tx *gorm.DB, throttle entity.LoginThrottle) error {
+	if r.throttles == nil {
+		r.throttles = map[string]entity.LoginThrottle{}
+	}
+	r.throttles[throttle.Key] = throttle
+	return nil
+}
+
+func (r *fakeThrottleRepo) DeleteLoginThrottle(ctx context.Context, tx *gorm.DB, key string) (bool, error) {
+	_, ok := r.throttles[key]
+	delete(r.throttles, key)
+	return ok, nil
+}
+
+func (r *fakeThrottleRepo) RunInTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return fn(nil)
+}
+
+func (r *fakeLogAksesRepo) FindActiveLogAksesByUserID(ctx context.Context, tx *gorm.DB, userID int) int {
+	return 0
+}
+
+func (r *fakeLogAksesRepo) CreateDetailAkses(ctx context.Context, tx *gorm.DB, detailAkses entity.DetailAkses) error {
+	return nil
+}
+
+func (r *fakeLogAksesRepo) LogAkses(ctx context.Context, tx *gorm.DB, userId int) (entity.LogAkses, error) {
+	return entity.LogAkses{ID: 1, UserID: userId}, nil
+}
+
+func (r *fakeLogAksesRepo) ExtendLogAkses(ctx context.Context, tx *gorm.DB, logAksesID int, until time.Time) error {
+	return nil
+}
+
+func (r *fakeSessionRepo) CreateSession(ctx context.Context, tx *gorm.DB, session entity.Session) (entity.Session, error) {
+	session.ID = 1
+	return session, nil
+}
+
+func (r *fakeSessionRepo) RevokeUserSessions(ctx context.Context, tx *gorm.DB, userID int) (int64, error) {
+	r.revoked = append(r.revoked, userID)
+	return 1, nil
+}
+
+func (s *fakeJWTService) GenerateToken(userId string, role string, sessionID int) string {
+	return "token-" + userId
+}
+
+const testPassword = "rahasia123"
+
+type twoFactorTest struct {
+	service   UserService
+	twoFactor *fakeTwoFactorRepo
+	throttle  *fakeThrottleRepo
+	user      entity.User
+	secret    string
+}
+
+func newTwoFactorTest(t *testing.T) *twoFactorTest {
+	t.Helper()
+
+	secret, err := helpers.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	encrypted, err := utils.AESEncrypt(secret)
+	assert.NoError(t, err)
+	password, err := helpers.HashPassword(testPassword)
+	assert.NoError(t, err)
+
+	user := entity.User{
+		ID:               7,
+		Email:            "kasir@example.com",
+		Password:         password,
+		Role:             "user",
+		TwoFactorEnabled: true,
+		TwoFactorSecret:  encrypted,
+	}
+
+	twoFactor := &fakeTwoFactorRepo{
+		lastCounter:   map[int]int64{},
+		recoveryCodes: map[string]bool{},
+		challenges:    map[string]*entity.LoginChallenge{},
+	}
+	throttle := &fakeThrottleRepo{}
+
+	return &twoFactorTest{
+		service: NewUserService(
+			&fakeUserRepo{users: map[int]entity.User{user.ID: user}},
+			&fakeLogAksesRepo{},
+			&fakeSessionRepo{},
+			throttle,
+			twoFactor,
+			&fakeJWTService{},
+		),
+		twoFactor: twoFactor,
+		throttle:  throttle,
+		user:      user,
+		secret:    secret,
+	}
+}
+
+// challenge opens a login challenge for the user, as a correct password
+// would, and returns its token.
+func (tt *twoFactorTest) challenge(t *testing.T) string {
+	t.Helper()
+
+	token, hash, err := newSecretToken()
+	assert.NoError(t, err)
+
+	tt.twoFactor.challenges[hash] = &entity.LoginChallenge{
+		ID:        len(tt.twoFactor.challenges) + 1,
+		UserID:    tt.user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(TWO_FACTOR_CHALLENGE_TTL),
+	}
+	return token
+}
+
+// login sends the right password and returns the token of the challenge
+// it gets back.
+func (tt *twoFactorTest) login(t *testing.T) (string, error) {
+	t.Helper()
+
+	res, err := tt.service.Verify(context.Background(), dto.UserLoginRequest{
+		Email:    tt.user.Email,
+		Password: testPassword,
+	}, dto.SessionClient{IP: "127.0.0.1"})
+	if err != nil {
+		return "", err
+	}
+	if !assert.NotNil(t, res.Challenge) {
+		t.FailNow()
+	}
+	return res.Challenge.Token, nil
+}
+
+// waitOutDelay moves the last failures back as if the client had waited
+// out the delay after them. Locks stay.
+func (tt *twoFactorTest) waitOutDelay() {
+	for key, throttle := range tt.throttle.throttles {
+		throttle.LastFailureAt = throttle.LastFailureAt.Add(-LOGIN_MAX_DELAY)
+		tt.throttle.throttles[key] = throttle
+	}
+}
+
+func (tt *twoFactorTest) verify(t *testing.T, token string, code string) (dto.UserLoginResponse, error) {
+	t.Helper()
+
+	return tt.service.VerifyTwoFactor(context.Background(), dto.TwoFactorLoginRequest{
+		ChallengeToken: token,
+		Code:           code,
+	}, dto.SessionClient{IP: "127.0.0.1"})
+}
+
+func (tt *twoFactorTest) currentCode(t *testing.T) string {
+	t.Helper()
+
+	code, err := helpers.TOTPCode(tt.secret, time.Now().Unix()/helpers.TOTP_PERIOD)
+	assert.NoError(t, err)
+	return code
+}
+
+func TestVerifyTwoFactorRefusesReplayedCode(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	code := tt.currentCode(t)
+
+	res, err := tt.verify(t, tt.challenge(t), code)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-7", res.Token)
+
+	// The same code on a new login is refused, even within its period.
+	_, err = tt.verify(t, tt.challenge(t), code)
+	assert.ErrorIs(t, err, dto.ErrTwoFactorCodeInvalid)
+}
+
+func TestVerifyTwoFactorRecoveryCodeIsSingleUse(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	tt.twoFactor.recoveryCodes[hashRecoveryCode("abcd-efgh")] = true
+
+	// Recovery codes are matched without case, spaces or dashes.
+	_, err := tt.verify(t, tt.challenge(t), "ABCD EFGH")
+	assert.NoError(t, err)
+
+	_, err = tt.verify(t, tt.challenge(t), "abcd-efgh")
+	assert.ErrorIs(t, err, dto.ErrTwoFactorCodeInvalid)
+}
+
+func TestVerifyTwoFactorChallengeAttemptLimit(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	token := tt.challenge(t)
+
+	for i := 0; i < TWO_FACTOR_MAX_ATTEMPTS; i++ {
+		_, err := tt.verify(t, token, "wrong-code")
+		assert.ErrorIs(t, err, dto.ErrTwoFactorCodeInvalid, "attempt %d", i+1)
+		// Only the limit of the challenge is under test here.
+		tt.throttle.throttles = nil
+	}
+
+	// Once the attempts are used up, not even the right code is taken.
+	_, err := tt.verify(t, token, tt.currentCode(t))
+	assert.ErrorIs(t, err, dto.ErrTwoFactorChallengeInvalid)
+	assert.Empty(t, tt.twoFactor.lastCounter)
+
+	// A new challenge starts over.
+	_, err = tt.verify(t, tt.challenge(t), tt.currentCode(t))
+	assert.NoError(t, err)
+}
+
+func TestVerifyTwoFactorRefusesUsedChallenge(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	tt.twoFactor.recoveryCodes[hashRecoveryCode("abcd-efgh")] = true
+	tt.twoFactor.recoveryCodes[hashRecoveryCode("ijkl-mnop")] = true
+	token := tt.challenge(t)
+
+	_, err := tt.verify(t, token, "abcd-efgh")
+	assert.NoError(t, err)
+
+	_, err = tt.verify(t, token, "ijkl-mnop")
+	assert.ErrorIs(t, err, dto.ErrTwoFactorChallengeInvalid)
+	assert.True(t, tt.twoFactor.recoveryCodes[hashRecoveryCode("ijkl-mnop")])
+}
+
+func TestVerifyTwoFactorGuessesAcrossChallengesLockAccount(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	accountKey := loginAccountKey(tt.user.Email)
+
+	// A correct password does not clear the wrong codes before it, so
+	// logging in again for a fresh challenge does not buy more guesses.
+	for i := 0; i < loginAccountLimit.lockAfter; i++ {
+		token, err := tt.login(t)
+		if !assert.NoError(t, err, "login %d", i+1) {
+			return
+		}
+
+		_, err = tt.verify(t, token, "wrong-code")
+		assert.ErrorIs(t, err, dto.ErrTwoFactorCodeInvalid, "guess %d", i+1)
+		assert.Equal(t, i+1, tt.throttle.throttles[accountKey].Failures)
+		tt.waitOutDelay()
+	}
+
+	_, err := tt.login(t)
+	assert.ErrorIs(t, err, dto.ErrLoginThrottled)
+	assert.NotNil(t, tt.throttle.throttles[accountKey].LockedUntil)
+}
+
+func TestVerifyTwoFactorClearsFailuresOnLogin(t *testing.T) {
+	tt := newTwoFactorTest(t)
+	accountKey := loginAccountKey(tt.user.Email)
+
+	token, err := tt.login(t)
+	assert.NoError(t, err)
+	_, err = tt.verify(t, token, "wrong-code")
+	assert.ErrorIs(t, err, dto.ErrTwoFactorCodeInvalid)
+
+	_, err = tt.verify(t, token, tt.currentCode(t))
+	assert.NoError(t, err)
+	assert.NotContains(t, tt.throttle.throttles, accountKey)
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	const (
+		ownerID = 1
+		kasirID = 7
+		otherID = 8
+	)
+	role := func(value string) *string { return &value }
+
+	tests := []struct {
+		name        string
+		actorID     int
+		userID      int
+		req         dto.UserUpdateRequest
+		wantErr     error
+		wantRole    string
+		wantRevoked bool
+	}{
+		{"user cannot change their own role", kasirID, kasirID, dto.UserUpdateRequest{Role: role("admin")}, dto.ErrUserFieldOwnerOnly, "user", false},
+		{"user keeps their role", kasirID, kasirID, dto.UserUpdateRequest{Name: role("Budi")}, nil, "user", false},
+		{"user cannot update another user", kasirID, otherID, dto.UserUpdateRequest{Name: role("Budi")}, dto.ErrUserNotOwner, "user", false},
+		{"owner keeps the role when none is given", ownerID, kasirID, dto.UserUpdateRequest{Name: role("Budi")}, nil, "user", false},
+		{"owner changes the role", ownerID, kasirID, dto.UserUpdateRequest{Role: role(" admin ")}, nil, "admin", true},
+		{"owner sets the same role", ownerID, kasirID, dto.UserUpdateRequest{Role: role("user")}, nil, "user", false},
+		{"owner sets a known role", ownerID, kasirID, dto.UserUpdateRequest{Role: role("kasirstok")}, nil, "kasirstok", true},
+		{"owner cannot set an unknown role", ownerID, kasirID, dto.UserUpdateRequest{Role: role("Admin")}, dto.ErrUserRoleInvalid, "user", false},
+		{"owner cannot make up a role", ownerID, kasirID, dto.UserUpdateRequest{Role: role("manajer")}, dto.ErrUserRoleInvalid, "user", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{users: map[int]entity.User{
+				ownerID: {ID: ownerID, Email: "owner@example.com", Role: "owner"},
+				kasirID: {ID: kasirID, Email: "kasir@example.com", Role: "user"},
+				otherID: {ID: otherID, Email: "gudang@example.com", Role: "user"},
+			}}
+			sessions := &fakeSessionRepo{}
+			service := NewUserService(users, &fakeLogAksesRepo{}, sessions, &fakeThrottleRepo{}, &fakeTwoFactorRepo{}, &fakeJWTService{})
+
+			_, err := service.UpdateUser(context.Background(), tt.req, tt.actorID, tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantRole, users.users[tt.userID].Role)
+			if tt.wantRevoked {
+				assert.Equal(t, []int{tt.userID}, sessions.revoked)
+			} else {
+				assert.Empty(t, sessions.revoked)
+			}
+		})
+	}
+}
+
+func TestGetUserByIdMasksPII(t *testing.T) {
+	const (
+		kasirID  = 7
+		otherID  = 8
+		exportID = 9
+	)
+	lahir := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	users := &fakeUserRepo{users: map[int]entity.User{
+		kasirID:  {ID: kasirID, Role: "user"},
+		otherID:  {ID: otherID, Role: "user", NIK: "3201010101010001", NoHp: "081234567890", TanggalLahir: lahir, Alamat: "Jl. Merdeka No. 5"},
+		exportID: {ID: exportID, Role: "user", CanExportPII: true},
+	}}
+	service := NewUserService(users, &fakeLogAksesRepo{}, &fakeSessionRepo{}, &fakeThrottleRepo{}, &fakeTwoFactorRepo{}, &fakeJWTService{})
+
+	tests := []struct {
+		name    string
+		actorID int
+		masked  bool
+	}{
+		{"colleague", kasirID, true},
+		{"the user themselves", otherID, false},
+		{"actor allowed to export PII", exportID, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := service.GetUserById(context.Background(), tt.actorID, otherID)
+			assert.NoError(t, err)
+
+			if tt.masked {
+				assert.Equal(t, "********7890", res.NoHp)
+				assert.Equal(t, "************0001", res.NIK)
+				assert.Equal(t, PII_MASK, res.TanggalLahir)
+				assert.Equal(t, PII_MASK, res.Alamat)
+			} else {
+				assert.Equal(t, "081234567890", res.NoHp)
+				assert.Equal(t, "3201010101010001", res.NIK)
+				assert.Equal(t, "1990-05-17", res.TanggalLahir)
+				assert.Equal(t, "Jl. Merdeka No. 5", res.Alamat)
+			}
+		})
+	}
+
+	_, err := service.GetUserById(context.Background(), 404, otherID)
+	assert.ErrorIs(t, err, dto.ErrUserNotFound)
+}
+
+func TestForgotPasswordThrottled(t *testing.T) {
+	throttle := &fakeThrottleRepo{}
+	service := NewUserService(&fakeUserRepo{users: map[int]entity.User{}}, &fakeLogAksesRepo{}, &fakeSessionRepo{}, throttle, &fakeTwoFactorRepo{}, &fakeJWTService{})
+	req := dto.ForgotPasswordRequest{Email: "kasir@example.com"}
+	client := dto.SessionClient{IP: "127.0.0.1"}
+
+	// Every request counts, whether or not the email is registered.
+	for i := 0; i < loginAccountLimit.delayAfter; i++ {
+		assert.NoError(t, service.ForgotPassword(context.Background(), req, client), "request %d", i+1)
+	}
+	assert.Equal(t, loginAccountLimit.delayAfter, throttle.throttles[loginAccountKey(req.Email)].Failures)
+
+	err := service.ForgotPassword(context.Background(), req, client)
+	var throttled *dto.LoginThrottledError
+	if assert.ErrorAs(t, err, &throttled) {
+		assert.Positive(t, throttled.RetryAfter)
+	}
+
+	// The limit is per email, so another address can still ask.
+	assert.NoError(t, service.ForgotPassword(context.Background(), dto.ForgotPasswordRequest{Email: "gudang@example.com"}, client))
+}
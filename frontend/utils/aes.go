@@ -8,79 +8,133 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
-const (
-	// todo
-	KEY = "8e71bbce7451ba2835de5aea73e4f3f96821455240823d2fd8174975b8321bfc!"
-)
+// Ciphertexts are "<key version>:<hex of nonce and AES-GCM sealed data>", so
+// a value can be read with the key that wrote it after the active AES key
+// has been rotated.
 
-// https://www.melvinvivas.com/how-to-encrypt-and-decrypt-data-using-aes
+var ErrCiphertextInvalid = errors.New("data terenkripsi tidak valid")
 
-func AESEncrypt(stringToEncrypt string) (encryptedString string, err error) {
-	//Since the key is in string, we need to convert decode it to bytes
-	key, _ := hex.DecodeString(KEY)
-	plaintext := []byte(stringToEncrypt)
+// aesMinCiphertextHex is the hex length of the GCM nonce and tag around an
+// empty plaintext.
+const aesMinCiphertextHex = (12 + 16) * 2
 
-	//Create a new Cipher Block from the key
-	block, err := aes.NewCipher(key)
+func AESEncrypt(stringToEncrypt string) (string, error) {
+	keyring, err := LoadKeyring()
 	if err != nil {
 		return "", err
 	}
 
-	//Create a new GCM - https://en.wikipedia.org/wiki/Galois/Counter_Mode
-	//https://golang.org/pkg/crypto/cipher/#NewGCM
-	aesGCM, err := cipher.NewGCM(block)
+	version, key := keyring.AES.Current()
+	aesGCM, err := newGCM(key)
 	if err != nil {
 		return "", err
 	}
 
-	//Create a nonce. Nonce should be from GCM
 	nonce := make([]byte, aesGCM.NonceSize())
-	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	//Encrypt the data using aesGCM.Seal
-	//Since we don't want to save the nonce somewhere else in this case, we add it as a prefix to the encrypted data. The first nonce argument in Seal is the prefix.
-	ciphertext := aesGCM.Seal(nonce, nonce, plaintext, nil)
-	return fmt.Sprintf("%x", ciphertext), nil
+	// The nonce is kept as the prefix of the sealed data.
+	ciphertext := aesGCM.Seal(nonce, nonce, []byte(stringToEncrypt), nil)
+	return fmt.Sprintf("%s:%x", version, ciphertext), nil
 }
 
-func AESDecrypt(encryptedString string) (decryptedString string, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			decryptedString = ""
-			err = errors.New("error in decrypting")
-		}
-	}()
+func AESDecrypt(encryptedString string) (string, error) {
+	keyring, err := LoadKeyring()
+	if err != nil {
+		return "", err
+	}
 
-	key, _ := hex.DecodeString(KEY)
-	enc, _ := hex.DecodeString(encryptedString)
+	version, data, ok := strings.Cut(encryptedString, ":")
+	if !ok {
+		return "", ErrCiphertextInvalid
+	}
 
-	//Create a new Cipher Block from the key
-	block, err := aes.NewCipher(key)
+	key, ok := keyring.AES.Get(version)
+	if !ok {
+		return "", fmt.Errorf("%w: versi %q", ErrKeyNotFound, version)
+	}
+
+	enc, err := hex.DecodeString(data)
 	if err != nil {
-		return "", err
+		return "", ErrCiphertextInvalid
 	}
 
-	//Create a new GCM
-	aesGCM, err := cipher.NewGCM(block)
+	aesGCM, err := newGCM(key)
 	if err != nil {
 		return "", err
 	}
 
-	//Get the nonce size
 	nonceSize := aesGCM.NonceSize()
+	if len(enc) < nonceSize {
+		return "", ErrCiphertextInvalid
+	}
 
-	//Extract the nonce from the encrypted data
 	nonce, ciphertext := enc[:nonceSize], enc[nonceSize:]
-
-	//Decrypt the data
 	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", nil
+		return "", ErrCiphertextInvalid
 	}
 
 	return string(plaintext), nil
-}
\ No newline at end of file
+}
+
+// AESIsCiphertext reports whether value has the form AESEncrypt writes, as
+// opposed to plaintext stored before a field was encrypted.
+func AESIsCiphertext(value string) bool {
+	version, data, ok := strings.Cut(value, ":")
+	if !ok || !validKeyID(version) || len(data) < aesMinCiphertextHex || len(data)%2 != 0 {
+		return false
+	}
+
+	_, err := hex.DecodeString(data)
+	return err == nil
+}
+
+// AESActivePrefix is what every ciphertext of the active key starts with.
+func AESActivePrefix() (string, error) {
+	keyring, err := LoadKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	return keyring.AES.Active + ":", nil
+}
+
+// AESReencrypt moves a ciphertext to the active key. The bool is false when
+// it already uses it.
+func AESReencrypt(encryptedString string) (string, bool, error) {
+	prefix, err := AESActivePrefix()
+	if err != nil {
+		return "", false, err
+	}
+
+	if strings.HasPrefix(encryptedString, prefix) {
+		return encryptedString, false, nil
+	}
+
+	plaintext, err := AESDecrypt(encryptedString)
+	if err != nil {
+		return "", false, err
+	}
+
+	reencrypted, err := AESEncrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+
+	return reencrypted, true, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cronTime(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"step within the hour", "*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"step into the next hour", "*/15 * * * *", "2024-01-01 10:45", "2024-01-01 11:00"},
+		{"strictly after", "0 10 * * *", "2024-01-01 10:00", "2024-01-02 10:00"},
+		{"range with step", "0 9-17/4 * * *", "2024-01-01 10:00", "2024-01-01 13:00"},
+		{"value with step", "0 20/2 * * *", "2024-01-01 21:00", "2024-01-01 22:00"},
+		{"list of days", "30 8 1,15 * *", "2024-01-02 00:00", "2024-01-15 08:30"},
+		{"list of hours", "0 6,18 * * *", "2024-01-01 07:00", "2024-01-01 18:00"},
+		{"weekday range skips the weekend", "0 0 * * 1-5", "2024-01-05 12:00", "2024-01-08 00:00"},
+		{"weekday range with step", "0 12 * * 1-5/2", "2024-01-02 13:00", "2024-01-03 12:00"},
+		{"sunday as 7", "0 0 * * 7", "2024-01-01 00:00", "2024-01-07 00:00"},
+		{"sunday as 0", "0 0 * * 0", "2024-01-01 00:00", "2024-01-07 00:00"},
+		{"day of month or day of week, week first", "0 0 13 * 5", "2024-01-01 00:00", "2024-01-05 00:00"},
+		{"day of month or day of week, month first", "0 0 13 * 5", "2024-01-12 00:00", "2024-01-13 00:00"},
+		{"day of week with any day of month", "0 0 * * 5", "2024-01-12 00:00", "2024-01-19 00:00"},
+		{"day of month with any day of week", "0 0 13 * *", "2024-01-12 00:00", "2024-01-13 00:00"},
+		{"day rollover", "0 0 * * *", "2024-01-31 23:30", "2024-02-01 00:00"},
+		{"month rollover skips short months", "0 0 31 * *", "2024-01-31 00:00", "2024-03-31 00:00"},
+		{"year rollover", "0 0 1 * *", "2024-12-15 00:00", "2025-01-01 00:00"},
+		{"last minute of the year", "59 23 31 12 *", "2024-12-31 23:59", "2025-12-31 23:59"},
+		{"month list", "0 0 1 1,7 *", "2024-02-01 00:00", "2024-07-01 00:00"},
+		{"leap day", "0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			assert.NoError(t, err)
+
+			next, ok := schedule.Next(cronTime(tt.from))
+			assert.True(t, ok)
+			assert.Equal(t, cronTime(tt.want), next)
+		})
+	}
+}
+
+func TestCronScheduleNextKeepsLocation(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	schedule, err := ParseCron("0 7 * * *")
+	assert.NoError(t, err)
+
+	next, ok := schedule.Next(time.Date(2024, 1, 1, 8, 0, 0, 0, jakarta))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 2, 7, 0, 0, 0, jakarta), next)
+}
+
+func TestCronScheduleNextNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 2 *")
+	assert.NoError(t, err)
+
+	_, ok := schedule.Next(cronTime("2024-01-01 00:00"))
+	assert.False(t, ok)
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-x * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := ParseCron(expr)
+			assert.ErrorIs(t, err, ErrCronInvalid)
+		})
+	}
+}
@@ -2,6 +2,10 @@ package utils
 
 import (
 	"bumisubur-be/config"
+	"bytes"
+	_ "embed"
+	"html/template"
+	"io"
 
 	"gopkg.in/gomail.v2"
 )
@@ -32,3 +36,79 @@ func SendMail(toEmail string, subject string, body string) error {
 
 	return nil
 }
+
+// SendMailWithAttachment works like SendMail and attaches content as
+// fileName.
+func SendMailWithAttachment(toEmail string, subject string, body string, fileName string, content []byte) error {
+	emailConfig, err := config.NewEmailConfig()
+	if err != nil {
+		return err
+	}
+
+	mailer := gomail.NewMessage()
+	mailer.SetHeader("From", emailConfig.AuthEmail)
+	mailer.SetHeader("To", toEmail)
+	mailer.SetHeader("Subject", subject)
+	mailer.SetBody("text/html", body)
+	mailer.Attach(fileName, gomail.SetCopyFunc(func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	}))
+
+	dialer := gomail.NewDialer(
+		emailConfig.Host,
+		emailConfig.Port,
+		emailConfig.AuthEmail,
+		emailConfig.AuthPassword,
+	)
+
+	return dialer.DialAndSend(mailer)
+}
+
+//go:embed email-template/notification_mail.html
+var notificationMailTemplate string
+
+var notificationMail = template.Must(template.New("notification_mail").Parse(notificationMailTemplate))
+
+// RenderNotificationMail renders the HTML body of a notification email with
+// one list item per line.
+func RenderNotificationMail(title string, lines []string) (string, error) {
+	var buf bytes.Buffer
+	err := notificationMail.Execute(&buf, struct {
+		Title string
+		Lines []string
+	}{
+		Title: title,
+		Lines: lines,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+//go:embed email-template/reset_password_mail.html
+var resetPasswordMailTemplate string
+
+var resetPasswordMail = template.Must(template.New("reset_password_mail").Parse(resetPasswordMailTemplate))
+
+// RenderResetPasswordMail renders the HTML body of a password reset email.
+// validFor is shown to the user as is, e.g. "30 menit".
+func RenderResetPasswordMail(name string, link string, validFor string) (string, error) {
+	var buf bytes.Buffer
+	err := resetPasswordMail.Execute(&buf, struct {
+		Name     string
+		Link     string
+		ValidFor string
+	}{
+		Name:     name,
+		Link:     link,
+		ValidFor: validFor,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
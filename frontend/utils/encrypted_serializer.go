@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// EncryptedSerializer stores a string or time.Time field AES encrypted, for
+// fields tagged `gorm:"type:text;serializer:encrypted"`. Empty values stay
+// empty. Plaintext written before the field was encrypted is still read, and
+// is encrypted the next time the row is saved.
+type EncryptedSerializer struct{}
+
+// legacyTimeLayouts are how Postgres prints a timestamptz that was turned
+// into text, besides RFC 3339.
+var legacyTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02",
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var plaintext string
+	switch v := dbValue.(type) {
+	case nil:
+	case time.Time:
+		// The column has not been migrated to text yet.
+		return field.Set(ctx, dst, v)
+	case []byte:
+		plaintext = string(v)
+	case string:
+		plaintext = v
+	default:
+		return fmt.Errorf("%s: tipe %T tidak bisa didekripsi", field.Name, dbValue)
+	}
+
+	if AESIsCiphertext(plaintext) {
+		decrypted, err := AESDecrypt(plaintext)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+		plaintext = decrypted
+	}
+
+	if field.FieldType == reflect.TypeOf(time.Time{}) {
+		var t time.Time
+		if plaintext != "" {
+			parsed, err := parseLegacyTime(plaintext)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field.Name, err)
+			}
+			t = parsed
+		}
+		return field.Set(ctx, dst, t)
+	}
+
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext string
+	switch v := fieldValue.(type) {
+	case string:
+		plaintext = v
+	case time.Time:
+		if !v.IsZero() {
+			plaintext = v.Format(time.RFC3339Nano)
+		}
+	default:
+		return nil, fmt.Errorf("%s: tipe %T tidak bisa dienkripsi", field.Name, fieldValue)
+	}
+
+	if plaintext == "" {
+		return "", nil
+	}
+
+	return AESEncrypt(plaintext)
+}
+
+func parseLegacyTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range legacyTimeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// BlindIndex is a keyed hash of an encrypted value, so rows can still be
+// found by the exact value without decrypting them. Spaces are ignored.
+func BlindIndex(value string) string {
+	value = strings.Join(strings.Fields(value), "")
+	if value == "" {
+		return ""
+	}
+
+	keyring, err := LoadKeyring()
+	if err != nil {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, keyring.BlindIndex)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaskTail hides all but the last visible characters of value.
+func MaskTail(value string, visible int) string {
+	runes := []rune(value)
+	if len(runes) <= visible {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-visible) + string(runes[len(runes)-visible:])
+}
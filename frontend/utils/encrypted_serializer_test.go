@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/schema"
+)
+
+type encryptedRow struct {
+	ID           int
+	NIK          string    `gorm:"type:text;serializer:encrypted"`
+	TanggalLahir time.Time `gorm:"type:text;serializer:encrypted"`
+}
+
+func encryptedField(t *testing.T, name string) *schema.Field {
+	t.Helper()
+
+	s, err := schema.Parse(&encryptedRow{}, &sync.Map{}, schema.NamingStrategy{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return s.LookUpField(name)
+}
+
+// scanEncrypted reads dbValue into the field the way gorm does when it
+// scans a row.
+func scanEncrypted(t *testing.T, name string, dbValue any) (encryptedRow, error) {
+	t.Helper()
+
+	field := encryptedField(t, name)
+	value := field.NewValuePool.Get()
+	if err := value.(sql.Scanner).Scan(dbValue); err != nil {
+		return encryptedRow{}, err
+	}
+
+	var row encryptedRow
+	err := field.Set(context.Background(), reflect.ValueOf(&row).Elem(), value)
+	return row, err
+}
+
+func valueEncrypted(t *testing.T, name string, row encryptedRow) (any, error) {
+	t.Helper()
+
+	dst := reflect.ValueOf(&row).Elem()
+	return EncryptedSerializer{}.Value(context.Background(), encryptedField(t, name), dst, dst.FieldByName(name).Interface())
+}
+
+func TestEncryptedSerializerString(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	stored, err := valueEncrypted(t, "NIK", encryptedRow{NIK: "3201010101010001"})
+	assert.NoError(t, err)
+	assert.True(t, AESIsCiphertext(stored.(string)))
+	assert.NotContains(t, stored, "3201010101010001")
+
+	for _, dbValue := range []any{stored, []byte(stored.(string))} {
+		row, err := scanEncrypted(t, "NIK", dbValue)
+		assert.NoError(t, err)
+		assert.Equal(t, "3201010101010001", row.NIK)
+	}
+}
+
+func TestEncryptedSerializerTime(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+	lahir := time.Date(1990, 5, 17, 0, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+	stored, err := valueEncrypted(t, "TanggalLahir", encryptedRow{TanggalLahir: lahir})
+	assert.NoError(t, err)
+	assert.True(t, AESIsCiphertext(stored.(string)))
+
+	row, err := scanEncrypted(t, "TanggalLahir", stored)
+	assert.NoError(t, err)
+	assert.True(t, lahir.Equal(row.TanggalLahir))
+
+	// A column that is still timestamptz hands over a time.Time.
+	row, err = scanEncrypted(t, "TanggalLahir", lahir)
+	assert.NoError(t, err)
+	assert.True(t, lahir.Equal(row.TanggalLahir))
+}
+
+func TestEncryptedSerializerEmpty(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	stored, err := valueEncrypted(t, "NIK", encryptedRow{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", stored)
+
+	stored, err = valueEncrypted(t, "TanggalLahir", encryptedRow{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", stored)
+
+	for _, dbValue := range []any{nil, "", []byte{}} {
+		row, err := scanEncrypted(t, "TanggalLahir", dbValue)
+		assert.NoError(t, err)
+		assert.True(t, row.TanggalLahir.IsZero())
+
+		row, err = scanEncrypted(t, "NIK", dbValue)
+		assert.NoError(t, err)
+		assert.Empty(t, row.NIK)
+	}
+}
+
+func TestEncryptedSerializerLegacyPlaintext(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	row, err := scanEncrypted(t, "NIK", "3201010101010001")
+	assert.NoError(t, err)
+	assert.Equal(t, "3201010101010001", row.NIK)
+
+	// What Postgres prints for a timestamptz column turned into text.
+	row, err = scanEncrypted(t, "TanggalLahir", "1990-05-17 00:00:00+07")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(1990, 5, 17, 0, 0, 0, 0, time.FixedZone("", 7*60*60)).Equal(row.TanggalLahir))
+
+	_, err = scanEncrypted(t, "TanggalLahir", "17/05/1990")
+	assert.Error(t, err)
+}
+
+func TestEncryptedSerializerErrors(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	_, err := scanEncrypted(t, "NIK", 42)
+	assert.Error(t, err)
+
+	// A ciphertext of a key that is gone is an error, not plaintext.
+	_, err = scanEncrypted(t, "NIK", "9:"+strings.Repeat("ab", aesMinCiphertextHex))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	field := encryptedField(t, "NIK")
+	_, err = EncryptedSerializer{}.Value(context.Background(), field, reflect.Value{}, 42)
+	assert.Error(t, err)
+}
+
+func TestAESIsCiphertext(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	encrypted, err := AESEncrypt("")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{encrypted, true},
+		{"2026-10:" + strings.Repeat("0f", aesMinCiphertextHex/2), true},
+		{"", false},
+		{"3201010101010001", false},
+		{"Jl. Merdeka: No. 5", false},
+		{"0812:3456", false},
+		{"1:" + strings.Repeat("0f", aesMinCiphertextHex/2-1), false},
+		{"1:" + strings.Repeat("0f", aesMinCiphertextHex/2) + "0", false},
+		{"1:" + strings.Repeat("zz", aesMinCiphertextHex/2), false},
+		{"a b:" + strings.Repeat("0f", aesMinCiphertextHex/2), false},
+		{"1990-05-17 00:00:00+07", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, AESIsCiphertext(tt.value), "%q", tt.value)
+	}
+}
+
+func TestParseLegacyTime(t *testing.T) {
+	wib := time.FixedZone("", 7*60*60)
+	ist := time.FixedZone("", 5*60*60+30*60)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"1990-05-17T00:00:00+07:00", time.Date(1990, 5, 17, 0, 0, 0, 0, wib)},
+		{"1990-05-17T00:00:00.5Z", time.Date(1990, 5, 17, 0, 0, 0, 500000000, time.UTC)},
+		{"1990-05-17 00:00:00+07", time.Date(1990, 5, 17, 0, 0, 0, 0, wib)},
+		{"1990-05-17 00:00:00.123456+07", time.Date(1990, 5, 17, 0, 0, 0, 123456000, wib)},
+		{"1990-05-17 00:00:00+05:30", time.Date(1990, 5, 17, 0, 0, 0, 0, ist)},
+		{"1990-05-17", time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLegacyTime(tt.value)
+		if assert.NoError(t, err, tt.value) {
+			assert.True(t, tt.want.Equal(got), "%s: %v", tt.value, got)
+		}
+	}
+
+	for _, value := range []string{"", "17-05-1990", "1990-05-17 00:00:00"} {
+		_, err := parseLegacyTime(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	index := BlindIndex("3201010101010001")
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, BlindIndex(" 3201 0101 0101 0001 "))
+	assert.Equal(t, index, BlindIndex("3201010101010001\t"))
+	assert.NotEqual(t, index, BlindIndex("3201010101010002"))
+	assert.Empty(t, BlindIndex(""))
+	assert.Empty(t, BlindIndex("   "))
+
+	// The index is keyed: another key gives another index.
+	keyring := testKeyring("1")
+	keyring.BlindIndex, _ = parseAESKey(testAESKey2)
+	useKeyring(t, keyring)
+	assert.NotEqual(t, index, BlindIndex("3201010101010001"))
+}
+
+func TestMaskTail(t *testing.T) {
+	assert.Equal(t, "************0001", MaskTail("3201010101010001", 4))
+	assert.Equal(t, "***", MaskTail("abc", 4))
+	assert.Equal(t, "", MaskTail("", 4))
+}
@@ -0,0 +1,308 @@
+package utils
+
+import (
+	"bumisubur-be/constants"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Keys are read from the JSON file at KEYS_FILE, usually a mounted secret:
+//
+//	{
+//	  "jwt": {"active": "2026-10", "keys": {"2026-10": "...", "2026-04": "..."}},
+//	  "aes": {"active": "2", "keys": {"2": "<64 hex>", "1": "<64 hex>"}},
+//	  "blind_index": "<64 hex>"
+//	}
+//
+// or from the environment, which wins over the file for each set:
+// JWT_KEYS="2026-10:secret,2026-04:secret" with JWT_ACTIVE_KEY,
+// AES_KEYS="2:hex,1:hex" with AES_ACTIVE_KEY, and BLIND_INDEX_KEY=hex. The
+// active key defaults to the first one listed. A lone JWT_SECRET is still read
+// as the JWT key "default".
+//
+// The blind index key has no versions: changing it only takes a restart,
+// since the blind indexes are rebuilt from the decrypted values at startup.
+//
+// To rotate, add the new key, make it active and keep the old one until
+// everything it produced is gone: 15 minutes for JWT keys, and for AES keys
+// until the re-encryption at startup has moved every stored value over.
+const (
+	KEYS_FILE_ENV      = "KEYS_FILE"
+	JWT_KEYS_ENV       = "JWT_KEYS"
+	JWT_ACTIVE_KEY_ENV = "JWT_ACTIVE_KEY"
+	JWT_SECRET_ENV     = "JWT_SECRET"
+	AES_KEYS_ENV       = "AES_KEYS"
+	AES_ACTIVE_KEY_ENV = "AES_ACTIVE_KEY"
+	BLIND_INDEX_ENV    = "BLIND_INDEX_KEY"
+
+	JWT_LEGACY_KEY_ID = "default"
+	DEV_KEY_ID        = "dev"
+
+	JWT_MIN_KEY_LENGTH = 32
+	AES_KEY_LENGTH     = 32
+)
+
+var (
+	ErrKeysMissing   = errors.New("kunci tidak dikonfigurasi")
+	ErrKeyInvalid    = errors.New("kunci tidak valid")
+	ErrKeyIDInvalid  = errors.New("id kunci hanya boleh berisi huruf, angka, titik dan tanda hubung")
+	ErrKeyNotFound   = errors.New("kunci tidak ditemukan")
+	ErrActiveMissing = errors.New("kunci aktif tidak ada dalam daftar kunci")
+)
+
+type (
+	// KeySet is a set of keys by ID. Active signs or encrypts anything new;
+	// the others only read what they produced before a rotation.
+	KeySet struct {
+		Active string
+		Keys   map[string][]byte
+	}
+
+	// Keyring holds every key of the app. BlindIndex keys the HMAC that
+	// lets encrypted fields be looked up by exact value.
+	Keyring struct {
+		JWT        KeySet
+		AES        KeySet
+		BlindIndex []byte
+	}
+
+	keySetFile struct {
+		Active string            `json:"active"`
+		Keys   map[string]string `json:"keys"`
+	}
+
+	keysFile struct {
+		JWT        *keySetFile `json:"jwt"`
+		AES        *keySetFile `json:"aes"`
+		BlindIndex string      `json:"blind_index"`
+	}
+)
+
+var (
+	defaultKeyring *Keyring
+	defaultErr     error
+	defaultOnce    sync.Once
+)
+
+// Current returns the active key and its ID.
+func (s KeySet) Current() (string, []byte) {
+	return s.Active, s.Keys[s.Active]
+}
+
+// Get returns the key with the given ID.
+func (s KeySet) Get(id string) ([]byte, bool) {
+	key, ok := s.Keys[id]
+	return key, ok
+}
+
+// IDs lists the key IDs in order, for logging.
+func (s KeySet) IDs() []string {
+	ids := make([]string, 0, len(s.Keys))
+	for id := range s.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LoadKeyring loads the keys once; later calls return the same keyring. In
+// production it fails when a key set is missing. Elsewhere missing sets get
+// fixed development keys so local data survives restarts.
+func LoadKeyring() (*Keyring, error) {
+	defaultOnce.Do(func() {
+		defaultKeyring, defaultErr = loadKeyring(os.Getenv("APP_ENV") == constants.ENUM_RUN_PRODUCTION)
+		if defaultErr == nil {
+			log.Printf("kunci dimuat: jwt aktif %q dari %v, aes aktif %q dari %v",
+				defaultKeyring.JWT.Active, defaultKeyring.JWT.IDs(),
+				defaultKeyring.AES.Active, defaultKeyring.AES.IDs())
+		}
+	})
+
+	return defaultKeyring, defaultErr
+}
+
+// MustLoadKeyring is LoadKeyring for startup: the app does not run without
+// its keys.
+func MustLoadKeyring() *Keyring {
+	keyring, err := LoadKeyring()
+	if err != nil {
+		log.Fatalf("error loading keys: %v", err)
+	}
+	return keyring
+}
+
+func loadKeyring(production bool) (*Keyring, error) {
+	var file keysFile
+	if path := os.Getenv(KEYS_FILE_ENV); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", KEYS_FILE_ENV, err)
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%s: %w", KEYS_FILE_ENV, err)
+		}
+	}
+
+	jwtSet, err := loadKeySet("jwt", file.JWT, JWT_KEYS_ENV, JWT_ACTIVE_KEY_ENV, parseJWTKey)
+	if err != nil {
+		return nil, err
+	}
+	if jwtSet == nil {
+		if secret := os.Getenv(JWT_SECRET_ENV); secret != "" {
+			if _, err := parseJWTKey(secret); err != nil {
+				if production {
+					return nil, fmt.Errorf("%s: %w", JWT_SECRET_ENV, err)
+				}
+				log.Printf("PERINGATAN: %s: %v", JWT_SECRET_ENV, err)
+			}
+			jwtSet = &KeySet{Active: JWT_LEGACY_KEY_ID, Keys: map[string][]byte{JWT_LEGACY_KEY_ID: []byte(secret)}}
+		}
+	}
+
+	aesSet, err := loadKeySet("aes", file.AES, AES_KEYS_ENV, AES_ACTIVE_KEY_ENV, parseAESKey)
+	if err != nil {
+		return nil, err
+	}
+
+	blindIndex := file.BlindIndex
+	if env := os.Getenv(BLIND_INDEX_ENV); env != "" {
+		blindIndex = env
+	}
+
+	var blindIndexKey []byte
+	if blindIndex != "" {
+		blindIndexKey, err = parseAESKey(blindIndex)
+		if err != nil {
+			return nil, fmt.Errorf("blind index: %w", err)
+		}
+	}
+
+	if jwtSet == nil {
+		if production {
+			return nil, fmt.Errorf("jwt: %w, isi %s, %s atau %s", ErrKeysMissing, JWT_KEYS_ENV, JWT_SECRET_ENV, KEYS_FILE_ENV)
+		}
+		log.Println("PERINGATAN: kunci JWT tidak dikonfigurasi, memakai kunci development")
+		jwtSet = devKeySet("jwt")
+	}
+
+	if aesSet == nil {
+		if production {
+			return nil, fmt.Errorf("aes: %w, isi %s atau %s", ErrKeysMissing, AES_KEYS_ENV, KEYS_FILE_ENV)
+		}
+		log.Println("PERINGATAN: kunci AES tidak dikonfigurasi, memakai kunci development")
+		aesSet = devKeySet("aes")
+	}
+
+	if blindIndexKey == nil {
+		if production {
+			return nil, fmt.Errorf("blind index: %w, isi %s atau %s", ErrKeysMissing, BLIND_INDEX_ENV, KEYS_FILE_ENV)
+		}
+		log.Println("PERINGATAN: kunci blind index tidak dikonfigurasi, memakai kunci development")
+		blindIndexKey = devKeySet("blind index").Keys[DEV_KEY_ID]
+	}
+
+	return &Keyring{JWT: *jwtSet, AES: *aesSet, BlindIndex: blindIndexKey}, nil
+}
+
+// loadKeySet reads one key set from the environment, or else from the file.
+// It returns nil when neither has it.
+func loadKeySet(name string, file *keySetFile, keysEnv string, activeEnv string, parse func(string) ([]byte, error)) (*KeySet, error) {
+	var (
+		active   string
+		ids      []string
+		raw      = map[string]string{}
+		fromFile bool
+	)
+
+	if env := os.Getenv(keysEnv); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			id, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				return nil, fmt.Errorf("%s: %w: format id:kunci", keysEnv, ErrKeyInvalid)
+			}
+			ids = append(ids, id)
+			raw[id] = value
+		}
+		active = os.Getenv(activeEnv)
+	} else if file != nil && len(file.Keys) > 0 {
+		for id, value := range file.Keys {
+			ids = append(ids, id)
+			raw[id] = value
+		}
+		sort.Strings(ids)
+		active = file.Active
+		fromFile = true
+	} else {
+		return nil, nil
+	}
+
+	if active == "" {
+		// A JSON object has no order, so the file has to name it.
+		if fromFile && len(ids) > 1 {
+			return nil, fmt.Errorf("%s: %w", name, ErrActiveMissing)
+		}
+		active = ids[0]
+	}
+
+	set := KeySet{Active: active, Keys: make(map[string][]byte, len(raw))}
+	for _, id := range ids {
+		if !validKeyID(id) {
+			return nil, fmt.Errorf("%s %q: %w", name, id, ErrKeyIDInvalid)
+		}
+		key, err := parse(raw[id])
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %w", name, id, err)
+		}
+		set.Keys[id] = key
+	}
+
+	if _, ok := set.Keys[active]; !ok {
+		return nil, fmt.Errorf("%s %q: %w", name, active, ErrActiveMissing)
+	}
+
+	return &set, nil
+}
+
+func parseJWTKey(value string) ([]byte, error) {
+	if len(value) < JWT_MIN_KEY_LENGTH {
+		return nil, fmt.Errorf("%w: minimal %d karakter", ErrKeyInvalid, JWT_MIN_KEY_LENGTH)
+	}
+	return []byte(value), nil
+}
+
+func parseAESKey(value string) ([]byte, error) {
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) != AES_KEY_LENGTH {
+		return nil, fmt.Errorf("%w: harus %d karakter hex", ErrKeyInvalid, AES_KEY_LENGTH*2)
+	}
+	return key, nil
+}
+
+// validKeyID keeps IDs safe to put in a JWT header, in front of a
+// ciphertext and in a LIKE pattern.
+func validKeyID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// devKeySet derives a fixed key from name. It is public knowledge, which is
+// why production refuses to start without real keys.
+func devKeySet(name string) *KeySet {
+	sum := sha256.Sum256([]byte("bumisubur-be development " + name + " key"))
+	return &KeySet{Active: DEV_KEY_ID, Keys: map[string][]byte{DEV_KEY_ID: sum[:]}}
+}
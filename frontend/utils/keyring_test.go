@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testAESKey1 = strings.Repeat("11", AES_KEY_LENGTH)
+	testAESKey2 = strings.Repeat("22", AES_KEY_LENGTH)
+	testJWTKey1 = strings.Repeat("a", JWT_MIN_KEY_LENGTH)
+	testJWTKey2 = strings.Repeat("b", JWT_MIN_KEY_LENGTH)
+)
+
+// useKeyring makes keyring the one LoadKeyring returns until the test ends.
+func useKeyring(t *testing.T, keyring *Keyring) {
+	t.Helper()
+
+	// Load first so the real keys cannot replace keyring later.
+	LoadKeyring()
+	saved, savedErr := defaultKeyring, defaultErr
+	defaultKeyring, defaultErr = keyring, nil
+	t.Cleanup(func() {
+		defaultKeyring, defaultErr = saved, savedErr
+	})
+}
+
+func testKeyring(active string) *Keyring {
+	key1, _ := parseAESKey(testAESKey1)
+	key2, _ := parseAESKey(testAESKey2)
+
+	return &Keyring{
+		JWT:        KeySet{Active: "1", Keys: map[string][]byte{"1": []byte(testJWTKey1)}},
+		AES:        KeySet{Active: active, Keys: map[string][]byte{"1": key1, "2": key2}},
+		BlindIndex: key1,
+	}
+}
+
+// clearKeyEnv unsets every key variable for the test, so the machine's own
+// environment does not leak in.
+func clearKeyEnv(t *testing.T) {
+	t.Helper()
+
+	for _, env := range []string{KEYS_FILE_ENV, JWT_KEYS_ENV, JWT_ACTIVE_KEY_ENV, JWT_SECRET_ENV, AES_KEYS_ENV, AES_ACTIVE_KEY_ENV, BLIND_INDEX_ENV} {
+		t.Setenv(env, "")
+	}
+}
+
+func writeKeysFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadKeyring(t *testing.T) {
+	complete := map[string]string{
+		JWT_KEYS_ENV:    "2026-10:" + testJWTKey1,
+		AES_KEYS_ENV:    "1:" + testAESKey1,
+		BLIND_INDEX_ENV: testAESKey2,
+	}
+	with := func(env map[string]string) map[string]string {
+		merged := map[string]string{}
+		for key, value := range complete {
+			merged[key] = value
+		}
+		for key, value := range env {
+			merged[key] = value
+		}
+		return merged
+	}
+
+	tests := []struct {
+		name       string
+		production bool
+		env        map[string]string
+		file       string
+		wantErr    error
+		wantJWT    string
+		wantAES    string
+		wantAESIDs []string
+	}{
+		{
+			name:       "production refuses missing keys",
+			production: true,
+			wantErr:    ErrKeysMissing,
+		},
+		{
+			name:       "production refuses a missing aes set",
+			production: true,
+			env:        map[string]string{JWT_KEYS_ENV: "1:" + testJWTKey1, BLIND_INDEX_ENV: testAESKey1},
+			wantErr:    ErrKeysMissing,
+		},
+		{
+			name:       "production refuses a missing blind index key",
+			production: true,
+			env:        map[string]string{JWT_KEYS_ENV: "1:" + testJWTKey1, AES_KEYS_ENV: "1:" + testAESKey1},
+			wantErr:    ErrKeysMissing,
+		},
+		{
+			name:    "development falls back to dev keys",
+			wantJWT: DEV_KEY_ID,
+			wantAES: DEV_KEY_ID,
+		},
+		{
+			name:       "production with every key",
+			production: true,
+			env:        complete,
+			wantJWT:    "2026-10",
+			wantAES:    "1",
+			wantAESIDs: []string{"1"},
+		},
+		{
+			name:       "first listed key is active",
+			env:        with(map[string]string{AES_KEYS_ENV: "2:" + testAESKey2 + ",1:" + testAESKey1}),
+			wantJWT:    "2026-10",
+			wantAES:    "2",
+			wantAESIDs: []string{"1", "2"},
+		},
+		{
+			name:       "active key is chosen",
+			env:        with(map[string]string{AES_KEYS_ENV: "2:" + testAESKey2 + ", 1:" + testAESKey1, AES_ACTIVE_KEY_ENV: "1"}),
+			wantJWT:    "2026-10",
+			wantAES:    "1",
+			wantAESIDs: []string{"1", "2"},
+		},
+		{
+			name:    "active key not listed",
+			env:     with(map[string]string{AES_ACTIVE_KEY_ENV: "3"}),
+			wantErr: ErrActiveMissing,
+		},
+		{
+			name:    "invalid aes hex",
+			env:     with(map[string]string{AES_KEYS_ENV: "1:" + strings.Repeat("zz", AES_KEY_LENGTH)}),
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "short aes key",
+			env:     with(map[string]string{AES_KEYS_ENV: "1:" + testAESKey1[:AES_KEY_LENGTH]}),
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "invalid blind index key",
+			env:     with(map[string]string{BLIND_INDEX_ENV: "abc"}),
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "short jwt key",
+			env:     with(map[string]string{JWT_KEYS_ENV: "1:short"}),
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "key without id",
+			env:     with(map[string]string{JWT_KEYS_ENV: testJWTKey1}),
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "invalid key id",
+			env:     with(map[string]string{JWT_KEYS_ENV: "a/b:" + testJWTKey1}),
+			wantErr: ErrKeyIDInvalid,
+		},
+		{
+			name:       "production refuses a short legacy jwt secret",
+			production: true,
+			env:        with(map[string]string{JWT_KEYS_ENV: "", JWT_SECRET_ENV: "short"}),
+			wantErr:    ErrKeyInvalid,
+		},
+		{
+			name:       "development keeps a short legacy jwt secret",
+			env:        with(map[string]string{JWT_KEYS_ENV: "", JWT_SECRET_ENV: "short"}),
+			wantJWT:    JWT_LEGACY_KEY_ID,
+			wantAES:    "1",
+			wantAESIDs: []string{"1"},
+		},
+		{
+			name:       "legacy jwt secret",
+			production: true,
+			env:        with(map[string]string{JWT_KEYS_ENV: "", JWT_SECRET_ENV: testJWTKey1}),
+			wantJWT:    JWT_LEGACY_KEY_ID,
+			wantAES:    "1",
+			wantAESIDs: []string{"1"},
+		},
+		{
+			name:       "file",
+			production: true,
+			file: `{
+				"jwt": {"active": "2026-10", "keys": {"2026-10": "` + testJWTKey1 + `", "2026-04": "` + testJWTKey2 + `"}},
+				"aes": {"active": "2", "keys": {"2": "` + testAESKey2 + `", "1": "` + testAESKey1 + `"}},
+				"blind_index": "` + testAESKey1 + `"
+			}`,
+			wantJWT:    "2026-10",
+			wantAES:    "2",
+			wantAESIDs: []string{"1", "2"},
+		},
+		{
+			name:       "environment wins over the file",
+			production: true,
+			env:        map[string]string{AES_KEYS_ENV: "3:" + testAESKey1},
+			file: `{
+				"jwt": {"keys": {"1": "` + testJWTKey1 + `"}},
+				"aes": {"active": "2", "keys": {"2": "` + testAESKey2 + `"}},
+				"blind_index": "` + testAESKey1 + `"
+			}`,
+			wantJWT:    "1",
+			wantAES:    "3",
+			wantAESIDs: []string{"3"},
+		},
+		{
+			name:    "file with several keys names the active one",
+			file:    `{"aes": {"keys": {"2": "` + testAESKey2 + `", "1": "` + testAESKey1 + `"}}}`,
+			wantErr: ErrActiveMissing,
+		},
+		{
+			name:    "file with an invalid key",
+			file:    `{"aes": {"active": "1", "keys": {"1": "not hex"}}}`,
+			wantErr: ErrKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearKeyEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if tt.file != "" {
+				t.Setenv(KEYS_FILE_ENV, writeKeysFile(t, tt.file))
+			}
+
+			keyring, err := loadKeyring(tt.production)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, keyring)
+				return
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantJWT, keyring.JWT.Active)
+			assert.Equal(t, tt.wantAES, keyring.AES.Active)
+			if tt.wantAESIDs != nil {
+				assert.Equal(t, tt.wantAESIDs, keyring.AES.IDs())
+			}
+			assert.Len(t, keyring.BlindIndex, AES_KEY_LENGTH)
+			_, key := keyring.AES.Current()
+			assert.Len(t, key, AES_KEY_LENGTH)
+		})
+	}
+}
+
+func TestLoadKeyringFileErrors(t *testing.T) {
+	clearKeyEnv(t)
+
+	t.Setenv(KEYS_FILE_ENV, filepath.Join(t.TempDir(), "missing.json"))
+	_, err := loadKeyring(false)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	t.Setenv(KEYS_FILE_ENV, writeKeysFile(t, "{"))
+	_, err = loadKeyring(false)
+	assert.Error(t, err)
+}
+
+func TestDevKeySetIsStable(t *testing.T) {
+	assert.Equal(t, devKeySet("aes"), devKeySet("aes"))
+	assert.NotEqual(t, devKeySet("aes").Keys[DEV_KEY_ID], devKeySet("jwt").Keys[DEV_KEY_ID])
+}
+
+func TestAESReencryptAfterRotation(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	old, err := AESEncrypt("3201010101010001")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(old, "1:"))
+
+	same, changed, err := AESReencrypt(old)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, old, same)
+
+	// Key 2 becomes active; key 1 stays to read what it wrote.
+	useKeyring(t, testKeyring("2"))
+
+	plaintext, err := AESDecrypt(old)
+	assert.NoError(t, err)
+	assert.Equal(t, "3201010101010001", plaintext)
+
+	moved, changed, err := AESReencrypt(old)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(moved, "2:"))
+
+	plaintext, err = AESDecrypt(moved)
+	assert.NoError(t, err)
+	assert.Equal(t, "3201010101010001", plaintext)
+
+	// Once key 1 is dropped, only the moved value can still be read.
+	keyring := testKeyring("2")
+	delete(keyring.AES.Keys, "1")
+	useKeyring(t, keyring)
+
+	_, err = AESDecrypt(old)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	plaintext, err = AESDecrypt(moved)
+	assert.NoError(t, err)
+	assert.Equal(t, "3201010101010001", plaintext)
+}
+
+func TestAESDecryptInvalid(t *testing.T) {
+	useKeyring(t, testKeyring("1"))
+
+	valid, err := AESEncrypt("rahasia")
+	assert.NoError(t, err)
+
+	tampered := valid[:len(valid)-2] + "00"
+	if tampered == valid {
+		tampered = valid[:len(valid)-2] + "11"
+	}
+
+	for _, value := range []string{"", "rahasia", "1:zz", "1:abcd", tampered} {
+		_, err := AESDecrypt(value)
+		assert.ErrorIs(t, err, ErrCiphertextInvalid, "%q", value)
+	}
+}
@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Request payloads are redacted before they are written to the access log.
+// A field is redacted when its name, or a "_" separated part of it, matches
+// a rule: "password" also covers old_password and "token" refresh_token.
+// camelCase and kebab-case names are split the same way, so newPassword and
+// X-Refresh-Token are redacted too.
+// LOG_REDACT_FIELDS="pin,npwp" adds rules to the defaults, and
+// LOG_PAYLOAD_MAX_BYTES caps what is kept of a redacted payload.
+const (
+	LOG_REDACT_FIELDS_ENV     = "LOG_REDACT_FIELDS"
+	LOG_PAYLOAD_MAX_BYTES_ENV = "LOG_PAYLOAD_MAX_BYTES"
+
+	LOG_PAYLOAD_MAX_BYTES_DEFAULT = 2048
+
+	REDACTED_VALUE     = "[disensor]"
+	PAYLOAD_TRUNCATED  = "...[terpotong]"
+	PAYLOAD_NOT_LOGGED = "[tidak dicatat]"
+
+	// TOKEN_FINGERPRINT_PREFIX marks a token that was replaced by
+	// TokenFingerprint.
+	TOKEN_FINGERPRINT_PREFIX = "sha256:"
+	tokenFingerprintHex      = 16
+)
+
+var defaultRedactFields = []string{
+	"password",
+	"token",
+	"secret",
+	"code",
+	"authorization",
+	"nik",
+	"no_hp",
+	"alamat",
+	"tanggal_lahir",
+}
+
+type Redactor struct {
+	fields   []string
+	maxBytes int
+}
+
+var (
+	defaultRedactor     *Redactor
+	defaultRedactorOnce sync.Once
+)
+
+// NewRedactor redacts the given field names and keeps at most maxBytes of a
+// payload; 0 keeps it whole.
+func NewRedactor(fields []string, maxBytes int) *Redactor {
+	r := &Redactor{maxBytes: maxBytes}
+	for _, field := range fields {
+		if field = normalizeFieldName(field); field != "" {
+			r.fields = append(r.fields, field)
+		}
+	}
+	return r
+}
+
+// DefaultRedactor is the redactor configured from the environment.
+func DefaultRedactor() *Redactor {
+	defaultRedactorOnce.Do(func() {
+		fields := append([]string{}, defaultRedactFields...)
+		if env := os.Getenv(LOG_REDACT_FIELDS_ENV); env != "" {
+			fields = append(fields, strings.Split(env, ",")...)
+		}
+
+		maxBytes := LOG_PAYLOAD_MAX_BYTES_DEFAULT
+		if env := os.Getenv(LOG_PAYLOAD_MAX_BYTES_ENV); env != "" {
+			if n, err := strconv.Atoi(env); err == nil && n >= 0 {
+				maxBytes = n
+			} else {
+				log.Printf("PERINGATAN: %s tidak valid, memakai %d", LOG_PAYLOAD_MAX_BYTES_ENV, maxBytes)
+			}
+		}
+
+		defaultRedactor = NewRedactor(fields, maxBytes)
+	})
+
+	return defaultRedactor
+}
+
+// Redact returns payload with every sensitive field replaced, read according
+// to contentType. Without a content type, as for entries logged before
+// redaction, JSON and query strings are recognised by their form. A payload
+// that cannot be read is not kept at all, since its secrets cannot be found.
+func (r *Redactor) Redact(payload []byte, contentType string) string {
+	return r.truncate(r.redact(payload, contentType))
+}
+
+// RedactRequest redacts both the query string and the body of a request.
+// When both are there they are logged as "?<query> <body>".
+func (r *Redactor) RedactRequest(rawQuery string, body []byte, contentType string) string {
+	redactedBody := r.redact(body, contentType)
+	if strings.TrimSpace(rawQuery) == "" {
+		return r.truncate(redactedBody)
+	}
+
+	redacted := "?" + r.redact([]byte(rawQuery), "application/x-www-form-urlencoded")
+	if redactedBody != "" {
+		redacted += " " + redactedBody
+	}
+	return r.truncate(redacted)
+}
+
+func (r *Redactor) redact(payload []byte, contentType string) string {
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var redacted string
+	switch {
+	case mediaType == "application/json" || mediaType == "" && json.Valid(payload):
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return r.notLogged(payload)
+		}
+		data, err := json.Marshal(r.redactJSON(value))
+		if err != nil {
+			return r.notLogged(payload)
+		}
+		redacted = string(data)
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "" && bytes.ContainsRune(payload, '='):
+		values, err := url.ParseQuery(string(payload))
+		if err != nil {
+			return r.notLogged(payload)
+		}
+		for key := range values {
+			if r.sensitive(key) {
+				values[key] = []string{REDACTED_VALUE}
+			}
+		}
+		redacted = values.Encode()
+	default:
+		return r.notLogged(payload)
+	}
+
+	return redacted
+}
+
+func (r *Redactor) redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if r.sensitive(key) {
+				v[key] = REDACTED_VALUE
+			} else {
+				v[key] = r.redactJSON(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactJSON(item)
+		}
+	}
+	return value
+}
+
+func (r *Redactor) sensitive(key string) bool {
+	key = "_" + normalizeFieldName(key) + "_"
+	for _, field := range r.fields {
+		if strings.Contains(key, "_"+field+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) truncate(payload string) string {
+	if r.maxBytes <= 0 || len(payload) <= r.maxBytes {
+		return payload
+	}
+
+	cut := r.maxBytes
+	for cut > 0 && !utf8.RuneStart(payload[cut]) {
+		cut--
+	}
+	return payload[:cut] + PAYLOAD_TRUNCATED
+}
+
+func (r *Redactor) notLogged(payload []byte) string {
+	return fmt.Sprintf("%s %d byte", PAYLOAD_NOT_LOGGED, len(payload))
+}
+
+// normalizeFieldName turns a field name into lower snake_case:
+// "newPassword", "new-password" and "NEW_PASSWORD" all become "new_password".
+// A run of capitals is one part, so "userIDToken" becomes "user_id_token".
+func normalizeFieldName(name string) string {
+	runes := []rune(strings.TrimSpace(name))
+
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return strings.ReplaceAll(b.String(), "-", "_")
+}
+
+// TokenFingerprint identifies a token in the logs without storing it: requests
+// made with the same token share a fingerprint, but the token cannot be
+// recovered from it.
+func TokenFingerprint(token string) string {
+	if token == "" || strings.HasPrefix(token, TOKEN_FINGERPRINT_PREFIX) {
+		return token
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return TOKEN_FINGERPRINT_PREFIX + hex.EncodeToString(sum[:])[:tokenFingerprintHex]
+}
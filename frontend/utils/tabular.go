@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bumisubur-be/constants"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType decides how the values of a column are stored in a spreadsheet
+// and how they are printed in CSV and PDF.
+type ColumnType int
+
+const (
+	ColumnText ColumnType = iota
+	ColumnInt
+	ColumnNumber
+	ColumnMoney
+	ColumnDate
+	ColumnDateTime
+)
+
+var ErrTableFormat = errors.New("format harus salah satu dari xlsx, csv, pdf")
+
+type (
+	Column struct {
+		Header string
+		Type   ColumnType
+		// Merge columns span consecutive rows that share the value of the
+		// first Merge column, like the items of one nota. XLSX merges the
+		// cells, PDF prints the value once and CSV repeats it on every row.
+		Merge bool
+	}
+
+	// Table describes a report once so every format renders the same
+	// columns. Title names the sheet and heads the PDF; Subtitle lines, such
+	// as the period of the report, are printed above the header in XLSX and
+	// PDF.
+	Table struct {
+		Title    string
+		Subtitle []string
+		Columns  []Column
+	}
+
+	// TableWriter receives the rows of a table in order. Values are matched
+	// to the columns by position; missing trailing values are left empty.
+	TableWriter interface {
+		WriteRow(values ...any) error
+		Rows() int
+		Close() error
+	}
+)
+
+// TableFormat normalises the format query parameter of a download. An empty
+// format means xlsx.
+func TableFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		return constants.ENUM_FORMAT_XLSX, nil
+	case constants.ENUM_FORMAT_XLSX, constants.ENUM_FORMAT_CSV, constants.ENUM_FORMAT_PDF:
+		return format, nil
+	}
+
+	return "", ErrTableFormat
+}
+
+func TableContentType(format string) string {
+	switch format {
+	case constants.ENUM_FORMAT_CSV:
+		return "text/csv; charset=utf-8"
+	case constants.ENUM_FORMAT_PDF:
+		return "application/pdf"
+	default:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+}
+
+// NewTableWriter renders table to w in the given format. Nothing is
+// guaranteed to reach w before Close.
+func NewTableWriter(w io.Writer, format string, table Table) (TableWriter, error) {
+	format, err := TableFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case constants.ENUM_FORMAT_CSV:
+		return newCSVTableWriter(w, table)
+	case constants.ENUM_FORMAT_PDF:
+		return newPDFTableWriter(w, table), nil
+	default:
+		return newXLSXTableWriter(w, table)
+	}
+}
+
+// RenderTable renders a whole table in memory, for the download endpoints.
+func RenderTable(format string, table Table, fill func(TableWriter) error) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	tw, err := NewTableWriter(buf, format, table)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fill(tw); err != nil {
+		tw.Close()
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mergeKey is the index of the column that groups Merge columns, or -1.
+func (t Table) mergeKey() int {
+	for i, column := range t.Columns {
+		if column.Merge {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// row checks the number of values and pads a short row with nils.
+func (t Table) row(values []any) ([]any, error) {
+	if len(values) > len(t.Columns) {
+		return nil, fmt.Errorf("tabel %s: %d nilai untuk %d kolom", t.Title, len(values), len(t.Columns))
+	}
+
+	row := make([]any, len(t.Columns))
+	for i, value := range values {
+		row[i] = deref(value)
+	}
+
+	return row, nil
+}
+
+func (c Column) numeric() bool {
+	return c.Type == ColumnInt || c.Type == ColumnNumber || c.Type == ColumnMoney
+}
+
+// deref unwraps pointers so optional values such as *time.Time and *int can
+// be written directly. A nil pointer becomes an empty cell.
+func deref(value any) any {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+var cellTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// cellTime reads a date cell. Some queries return dates as text, so strings
+// in the usual database layouts are accepted too.
+func cellTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case string:
+		for _, layout := range cellTimeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func cellFloat(value any) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+// plainValue is the machine readable text of a cell, used by CSV.
+func plainValue(column Column, value any) string {
+	if value == nil {
+		return ""
+	}
+
+	switch column.Type {
+	case ColumnDate:
+		if t, ok := cellTime(value); ok {
+			return t.Format("2006-01-02")
+		}
+	case ColumnDateTime:
+		if t, ok := cellTime(value); ok {
+			return t.Format("2006-01-02 15:04:05")
+		}
+	}
+
+	if f, ok := cellFloat(value); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// displayValue is the printed text of a cell, used by PDF and to size XLSX
+// columns. Numbers use Indonesian separators.
+func displayValue(column Column, value any) string {
+	if value == nil {
+		return ""
+	}
+
+	switch column.Type {
+	case ColumnDate:
+		if t, ok := cellTime(value); ok {
+			return t.Format("02-01-2006")
+		}
+	case ColumnDateTime:
+		if t, ok := cellTime(value); ok {
+			return t.Format("02-01-2006 15:04")
+		}
+	case ColumnInt, ColumnMoney:
+		if f, ok := cellFloat(value); ok {
+			return groupThousands(int64(math.Round(f)))
+		}
+	case ColumnNumber:
+		if f, ok := cellFloat(value); ok {
+			cents := int64(math.Round(math.Abs(f) * 100))
+			text := fmt.Sprintf("%s,%02d", groupThousands(cents/100), cents%100)
+			if f < 0 && cents != 0 {
+				text = "-" + text
+			}
+			return text
+		}
+	}
+
+	return fmt.Sprint(value)
+}
+
+func groupThousands(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
+	digits := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+
+	return sign + b.String()
+}
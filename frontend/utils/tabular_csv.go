@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// csvFormulaPrefixes start a cell that a spreadsheet would run as a formula
+// when the CSV is opened.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvTableWriter writes the header and the rows only, so the file stays
+// easy to import elsewhere.
+type csvTableWriter struct {
+	cw    *csv.Writer
+	table Table
+	rows  int
+}
+
+func newCSVTableWriter(w io.Writer, table Table) (*csvTableWriter, error) {
+	cw := csv.NewWriter(w)
+
+	headers := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		headers[i] = column.Header
+	}
+	if err := cw.Write(headers); err != nil {
+		return nil, err
+	}
+
+	return &csvTableWriter{cw: cw, table: table}, nil
+}
+
+func (t *csvTableWriter) WriteRow(values ...any) error {
+	row, err := t.table.row(values)
+	if err != nil {
+		return err
+	}
+
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = plainValue(t.table.Columns[i], value)
+		if _, number := cellFloat(value); !number {
+			record[i] = csvEscapeFormula(record[i])
+		}
+	}
+	t.rows++
+
+	return t.cw.Write(record)
+}
+
+func (t *csvTableWriter) Rows() int {
+	return t.rows
+}
+
+func (t *csvTableWriter) Close() error {
+	t.cw.Flush()
+	return t.cw.Error()
+}
+
+// csvEscapeFormula prefixes text that would be read as a formula with a
+// quote, so a value like =HYPERLINK(...) from user input stays text.
+// Numbers are written as they are, negative ones included.
+func csvEscapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(csvFormulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	ErrImportFileFormat = errors.New("file harus berformat xlsx atau csv")
+	ErrImportEmpty      = errors.New("file tidak berisi baris data")
+	ErrImportNumber     = errors.New("bukan angka yang valid")
+)
+
+// ReadTableFile reads an uploaded xlsx file, from its first sheet, or a csv
+// file as rows of trimmed text, header included. Blank rows are kept so row
+// numbers still match the file.
+func ReadTableFile(file *multipart.FileHeader) ([][]string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".xlsx":
+		rows, err = readXLSXRows(src)
+	case ".csv":
+		rows, err = readCSVRows(src)
+	default:
+		return nil, ErrImportFileFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	if len(rows) < 2 {
+		return nil, ErrImportEmpty
+	}
+
+	return rows, nil
+}
+
+func readXLSXRows(src io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(src)
+	if err != nil {
+		return nil, ErrImportFileFormat
+	}
+	defer f.Close()
+
+	// Raw values keep numbers free of the thousand separators a number
+	// format would add.
+	return f.GetRows(f.GetSheetName(0), excelize.Options{RawCellValue: true})
+}
+
+// readCSVRows accepts comma or semicolon separated files, since a
+// spreadsheet with Indonesian regional settings saves the latter.
+func readCSVRows(src io.Reader) ([][]string, error) {
+	br := bufio.NewReader(src)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte{0xEF, 0xBB, 0xBF}) {
+		br.Discard(3)
+	}
+
+	head, _ := br.Peek(4096)
+	first, _, _ := bytes.Cut(head, []byte("\n"))
+
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+	if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+		r.Comma = ';'
+	}
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, ErrImportFileFormat
+	}
+
+	return rows, nil
+}
+
+// ImportHeaderKey normalises a header cell so "Harga Jual", "harga_jual" and
+// "HARGA-JUAL" all match the same column.
+func ImportHeaderKey(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.Join(strings.FieldsFunc(header, func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '.'
+	}), "_")
+}
+
+var (
+	idNumber = regexp.MustCompile(`^-?\d{1,3}(\.\d{3})+(,\d+)?$`)
+	enNumber = regexp.MustCompile(`^-?\d{1,3}(,\d{3})+(\.\d+)?$`)
+)
+
+// ParseImportNumber reads a number typed into a spreadsheet, with or
+// without a Rp prefix and Indonesian (1.500.000,50) or English
+// (1,500,000.50) separators.
+func ParseImportNumber(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	if len(text) >= 2 && strings.EqualFold(text[:2], "rp") {
+		text = strings.TrimSpace(text[2:])
+	}
+	text = strings.ReplaceAll(text, " ", "")
+
+	switch {
+	case idNumber.MatchString(text):
+		text = strings.ReplaceAll(text, ".", "")
+		text = strings.Replace(text, ",", ".", 1)
+	case enNumber.MatchString(text):
+		text = strings.ReplaceAll(text, ",", "")
+	default:
+		text = strings.Replace(text, ",", ".", 1)
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, ErrImportNumber
+	}
+
+	return value, nil
+}
+
+// ImportColumns finds each field in the header row by any of its names,
+// compared with ImportHeaderKey. A field that is not found gets -1.
+func ImportColumns(header []string, fields [][]string) []int {
+	keys := make(map[string]int, len(header))
+	for i, cell := range header {
+		if key := ImportHeaderKey(cell); key != "" {
+			if _, exists := keys[key]; !exists {
+				keys[key] = i
+			}
+		}
+	}
+
+	index := make([]int, len(fields))
+	for i, names := range fields {
+		index[i] = -1
+		for _, name := range names {
+			if column, ok := keys[ImportHeaderKey(name)]; ok {
+				index[i] = column
+				break
+			}
+		}
+	}
+
+	return index
+}
+
+// ImportCell returns the cell of a row at column i, or "" when the row is
+// shorter or the column is missing.
+func ImportCell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+
+	return row[i]
+}
+
+// ImportRowBlank reports whether every cell of a row is empty.
+func ImportRowBlank(row []string) bool {
+	for _, cell := range row {
+		if cell != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,318 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The PDF is a plain table on landscape A4 using the standard Helvetica
+// fonts, which every reader has, so no font is embedded.
+const (
+	pdfPageWidth   = 842.0
+	pdfPageHeight  = 595.0
+	pdfMargin      = 36.0
+	pdfFontSize    = 8.0
+	pdfRowHeight   = 14.0
+	pdfCellPadding = 3.0
+	pdfMaxColumn   = 220.0
+	pdfFooter      = 20.0
+)
+
+type pdfTableWriter struct {
+	w     io.Writer
+	table Table
+	cells [][]string
+	keys  []string
+}
+
+func newPDFTableWriter(w io.Writer, table Table) *pdfTableWriter {
+	return &pdfTableWriter{w: w, table: table}
+}
+
+func (t *pdfTableWriter) WriteRow(values ...any) error {
+	row, err := t.table.row(values)
+	if err != nil {
+		return err
+	}
+
+	cells := make([]string, len(row))
+	for i, value := range row {
+		cells[i] = strings.Join(strings.Fields(displayValue(t.table.Columns[i], value)), " ")
+	}
+	t.cells = append(t.cells, cells)
+
+	if key := t.table.mergeKey(); key >= 0 {
+		t.keys = append(t.keys, fmt.Sprint(row[key]))
+	}
+
+	return nil
+}
+
+func (t *pdfTableWriter) Rows() int {
+	return len(t.cells)
+}
+
+// Close lays the rows out over as many pages as needed, repeating the header
+// on each page, and writes the document.
+func (t *pdfTableWriter) Close() error {
+	widths := t.columnWidths()
+
+	var pages []*bytes.Buffer
+	var page *bytes.Buffer
+	var y float64
+	var pageTop bool
+
+	newPage := func() {
+		pageTop = true
+		page = new(bytes.Buffer)
+		pages = append(pages, page)
+		y = pdfPageHeight - pdfMargin
+		page.WriteString("0.5 w 0.6 G\n")
+
+		if len(pages) == 1 {
+			y -= 14
+			pdfText(page, true, 14, pdfMargin, y, t.table.Title)
+			for _, line := range t.table.Subtitle {
+				y -= 13
+				pdfText(page, false, 9, pdfMargin, y, line)
+			}
+			y -= 12
+		}
+
+		y -= pdfRowHeight
+		x := pdfMargin
+		for i, column := range t.table.Columns {
+			fmt.Fprintf(page, "0.85 0.88 0.95 rg %.2f %.2f %.2f %.2f re f 0 g\n", x, y, widths[i], pdfRowHeight)
+			fmt.Fprintf(page, "%.2f %.2f %.2f %.2f re S\n", x, y, widths[i], pdfRowHeight)
+			pdfCell(page, true, x, y, widths[i], column.Header, column.numeric())
+			x += widths[i]
+		}
+	}
+
+	newPage()
+	for r, cells := range t.cells {
+		if y-pdfRowHeight < pdfMargin+pdfFooter {
+			newPage()
+		}
+		y -= pdfRowHeight
+
+		// Repeated values of merged columns are printed once per group, and
+		// again at the top of a page so every page can be read alone.
+		continued := len(t.keys) > 0 && r > 0 && !pageTop && t.keys[r] == t.keys[r-1]
+		pageTop = false
+
+		x := pdfMargin
+		for i, column := range t.table.Columns {
+			fmt.Fprintf(page, "%.2f %.2f %.2f %.2f re S\n", x, y, widths[i], pdfRowHeight)
+			if !(column.Merge && continued) {
+				pdfCell(page, false, x, y, widths[i], cells[i], column.numeric())
+			}
+			x += widths[i]
+		}
+	}
+
+	for i, page := range pages {
+		pdfText(page, false, 7, pdfMargin, pdfMargin, t.table.Title)
+		footer := fmt.Sprintf("Halaman %d dari %d", i+1, len(pages))
+		pdfText(page, false, 7, pdfPageWidth-pdfMargin-pdfTextWidth(footer, false, 7), pdfMargin, footer)
+	}
+
+	return writePDF(t.w, t.table.Title, pages)
+}
+
+// columnWidths gives each column room for its widest value, within limits,
+// then scales the columns to fill the width of the page.
+func (t *pdfTableWriter) columnWidths() []float64 {
+	widths := make([]float64, len(t.table.Columns))
+	var total float64
+	for i, column := range t.table.Columns {
+		width := pdfTextWidth(column.Header, true, pdfFontSize)
+		for _, cells := range t.cells {
+			if w := pdfTextWidth(cells[i], false, pdfFontSize); w > width {
+				width = w
+			}
+		}
+		width += 2 * pdfCellPadding
+		if width > pdfMaxColumn {
+			width = pdfMaxColumn
+		}
+		widths[i] = width
+		total += width
+	}
+
+	if total > 0 {
+		scale := (pdfPageWidth - 2*pdfMargin) / total
+		for i := range widths {
+			widths[i] *= scale
+		}
+	}
+
+	return widths
+}
+
+// pdfCell prints text inside a cell, cut short with an ellipsis when it does
+// not fit. Numbers are right aligned.
+func pdfCell(page *bytes.Buffer, bold bool, x, y, width float64, text string, right bool) {
+	room := width - 2*pdfCellPadding
+	if pdfTextWidth(text, bold, pdfFontSize) > room {
+		runes := []rune(text)
+		for len(runes) > 0 && pdfTextWidth(string(runes)+"...", bold, pdfFontSize) > room {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "..."
+		if len(runes) == 0 {
+			text = ""
+		}
+	}
+
+	textX := x + pdfCellPadding
+	if right {
+		textX = x + width - pdfCellPadding - pdfTextWidth(text, bold, pdfFontSize)
+	}
+	pdfText(page, bold, pdfFontSize, textX, y+(pdfRowHeight-pdfFontSize)/2+1.5, text)
+}
+
+func pdfText(page *bytes.Buffer, bold bool, size, x, y float64, text string) {
+	if text == "" {
+		return
+	}
+
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfString(text))
+}
+
+// pdfString encodes text for a literal string, escaping the characters
+// that delimit it.
+func pdfString(text string) []byte {
+	var out []byte
+	for _, b := range pdfEncode(text) {
+		if b == '(' || b == ')' || b == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, b)
+	}
+
+	return out
+}
+
+func pdfTextWidth(text string, bold bool, size float64) float64 {
+	widths := &pdfHelvetica
+	if bold {
+		widths = &pdfHelveticaBold
+	}
+
+	var units int
+	for _, b := range pdfEncode(text) {
+		if b >= 32 && b <= 126 {
+			units += widths[b-32]
+		} else {
+			units += 556
+		}
+	}
+
+	return float64(units) * size / 1000
+}
+
+// pdfEncode converts text to WinAnsiEncoding. Characters outside it are
+// printed as a question mark.
+func pdfEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 32:
+			out = append(out, ' ')
+		case r < 127, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := pdfWinAnsi[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+
+	return out
+}
+
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// writePDF writes the fonts, the pages and the cross reference table. Each
+// page takes two objects: the page and its compressed content stream.
+func writePDF(w io.Writer, title string, pages []*bytes.Buffer) error {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	object(fmt.Sprintf("<< /Producer (bumisubur) /Title (%s) >>", pdfString(title)))
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		zw := zlib.NewWriter(&content)
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 7+2*i))
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// Glyph widths of the printable ASCII range, in thousandths of the font
+// size, from the Adobe font metrics of the standard fonts.
+var pdfHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var pdfHelveticaBold = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
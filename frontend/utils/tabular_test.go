@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bumisubur-be/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+var testTable = Table{
+	Title:    "Riwayat: Nota",
+	Subtitle: []string{"Periode 01-01-2024 s/d 31-01-2024"},
+	Columns: []Column{
+		{Header: "Nota", Type: ColumnInt, Merge: true},
+		{Header: "Tanggal", Type: ColumnDate, Merge: true},
+		{Header: "Produk", Type: ColumnText},
+		{Header: "Jumlah", Type: ColumnInt},
+		{Header: "Harga", Type: ColumnMoney},
+		{Header: "Selisih", Type: ColumnNumber},
+	},
+}
+
+func testTableRows() [][]any {
+	tanggal := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var missing *time.Time
+
+	return [][]any{
+		{int64(202401020001), &tanggal, "Kaos (Putih)", 2, 150000.0, -2.5},
+		{int64(202401020001), &tanggal, "=HYPERLINK(\"http://x\")", 1, 75000.0, 0.125},
+		{int64(202401020002), missing, "Celana", 3},
+	}
+}
+
+func renderTestTable(t *testing.T, format string, table Table, rows [][]any) []byte {
+	t.Helper()
+
+	data, err := RenderTable(format, table, func(tw TableWriter) error {
+		for _, row := range rows {
+			if err := tw.WriteRow(row...); err != nil {
+				return err
+			}
+		}
+		assert.Equal(t, len(rows), tw.Rows())
+		return nil
+	})
+	assert.NoError(t, err)
+
+	return data
+}
+
+func TestTableCSVRoundTrip(t *testing.T) {
+	data := renderTestTable(t, constants.ENUM_FORMAT_CSV, testTable, testTableRows())
+
+	rows, err := readCSVRows(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Nota", "Tanggal", "Produk", "Jumlah", "Harga", "Selisih"},
+		{"202401020001", "2024-01-02", "Kaos (Putih)", "2", "150000", "-2.5"},
+		{"202401020001", "2024-01-02", "'=HYPERLINK(\"http://x\")", "1", "75000", "0.125"},
+		{"202401020002", "", "Celana", "3", "", ""},
+	}, rows)
+}
+
+func TestTableCSVEscapesFormulas(t *testing.T) {
+	table := Table{Columns: []Column{{Header: "Teks"}, {Header: "Angka", Type: ColumnNumber}}}
+
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{"=1+1", "'=1+1"},
+		{"+62812", "'+62812"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tkode", "'\tkode"},
+		{"\rkode", "'\rkode"},
+		{"a=1", "a=1"},
+		{"", ""},
+		{-5, "-5"},
+		{-2.5, "-2.5"},
+	}
+
+	for _, tt := range tests {
+		data := renderTestTable(t, constants.ENUM_FORMAT_CSV, table, [][]any{{tt.value, tt.value}})
+
+		rows, err := readCSVRows(bytes.NewReader(data))
+		assert.NoError(t, err)
+		if assert.Len(t, rows, 2) {
+			assert.Equal(t, tt.want, rows[1][0], "%q", tt.value)
+			assert.Equal(t, tt.want, rows[1][1], "%q", tt.value)
+		}
+	}
+}
+
+func TestTableXLSXRoundTrip(t *testing.T) {
+	data := renderTestTable(t, constants.ENUM_FORMAT_XLSX, testTable, testTableRows())
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	assert.Equal(t, "Riwayat Nota", sheet)
+
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	assert.NoError(t, err)
+	if !assert.Len(t, rows, 7) {
+		return
+	}
+
+	assert.Equal(t, []string{"Riwayat: Nota"}, rows[0])
+	assert.Equal(t, testTable.Subtitle, rows[1])
+	assert.Empty(t, rows[2])
+	assert.Equal(t, []string{"Nota", "Tanggal", "Produk", "Jumlah", "Harga", "Selisih"}, rows[3])
+	assert.Equal(t, []string{"202401020001", "45293", "Kaos (Putih)", "2", "150000", "-2.5"}, rows[4])
+	assert.Equal(t, []string{"202401020001", "45293", "=HYPERLINK(\"http://x\")", "1", "75000", "0.125"}, rows[5])
+	assert.Equal(t, []string{"202401020002", "", "Celana", "3"}, rows[6])
+
+	// The text is stored as a value, not as a formula.
+	formula, err := f.GetCellFormula(sheet, "C6")
+	assert.NoError(t, err)
+	assert.Empty(t, formula)
+
+	merged, err := f.GetMergeCells(sheet)
+	assert.NoError(t, err)
+	var ranges []string
+	for _, cell := range merged {
+		ranges = append(ranges, cell.GetStartAxis()+":"+cell.GetEndAxis())
+	}
+	assert.ElementsMatch(t, []string{"A5:A6", "B5:B6"}, ranges)
+
+	date, err := f.GetCellValue(sheet, "B5")
+	assert.NoError(t, err)
+	assert.Equal(t, "02-01-2024", date)
+}
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+	pdfTextPattern   = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\) Tj`)
+	pdfEscapePattern = regexp.MustCompile(`\\(.)`)
+)
+
+// pdfPageTexts decompresses the content stream of every page and returns the
+// strings printed on it, in order.
+func pdfPageTexts(t *testing.T, data []byte) [][]string {
+	t.Helper()
+
+	var pages [][]string
+	for _, match := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		length, err := strconv.Atoi(string(data[match[2]:match[3]]))
+		assert.NoError(t, err)
+
+		zr, err := zlib.NewReader(bytes.NewReader(data[match[1] : match[1]+length]))
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		content, err := io.ReadAll(zr)
+		assert.NoError(t, err)
+
+		var texts []string
+		for _, text := range pdfTextPattern.FindAllSubmatch(content, -1) {
+			texts = append(texts, string(pdfEscapePattern.ReplaceAll(text[1], []byte("$1"))))
+		}
+		pages = append(pages, texts)
+	}
+
+	return pages
+}
+
+func TestTablePDFRoundTrip(t *testing.T) {
+	data := renderTestTable(t, constants.ENUM_FORMAT_PDF, testTable, testTableRows())
+
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+	assert.Contains(t, string(data), "/Title (Riwayat: Nota)")
+
+	pages := pdfPageTexts(t, data)
+	if !assert.Len(t, pages, 1) {
+		return
+	}
+
+	assert.Equal(t, []string{
+		"Riwayat: Nota",
+		"Periode 01-01-2024 s/d 31-01-2024",
+		"Nota", "Tanggal", "Produk", "Jumlah", "Harga", "Selisih",
+		"202.401.020.001", "02-01-2024", "Kaos (Putih)", "2", "150.000", "-2,50",
+		// The merged columns are printed once per nota.
+		"=HYPERLINK(\"http://x\")", "1", "75.000", "0,13",
+		"202.401.020.002", "Celana", "3",
+		"Riwayat: Nota", "Halaman 1 dari 1",
+	}, pages[0])
+}
+
+func TestTablePDFRepeatsHeaderOnEveryPage(t *testing.T) {
+	table := Table{
+		Title:   "Stok",
+		Columns: []Column{{Header: "Nota", Type: ColumnInt, Merge: true}, {Header: "Produk"}},
+	}
+
+	var rows [][]any
+	for i := 0; i < 80; i++ {
+		rows = append(rows, []any{1, strings.Repeat("x", i%5+1)})
+	}
+
+	pages := pdfPageTexts(t, renderTestTable(t, constants.ENUM_FORMAT_PDF, table, rows))
+	if !assert.Len(t, pages, 3) {
+		return
+	}
+
+	for i, page := range pages {
+		offset := 0
+		if i == 0 {
+			offset = 1
+		}
+		assert.Equal(t, []string{"Nota", "Produk", "1"}, page[offset:offset+3], "page %d", i+1)
+		assert.Equal(t, "Halaman "+strconv.Itoa(i+1)+" dari 3", page[len(page)-1])
+	}
+}
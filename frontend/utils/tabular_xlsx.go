@@ -0,0 +1,291 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// xlsxWidthSample is how many rows are held back to size the columns,
+	// since a stream writer needs the widths before the first row.
+	xlsxWidthSample = 200
+	xlsxMinWidth    = 8
+	xlsxMaxWidth    = 50
+)
+
+type xlsxTableWriter struct {
+	w      io.Writer
+	table  Table
+	f      *excelize.File
+	sw     *excelize.StreamWriter
+	styles []int
+	header int
+	bold   int
+
+	pending [][]any
+	started bool
+	row     int
+	rows    int
+
+	mergeKey   int
+	groupStart int
+	groupValue string
+}
+
+func newXLSXTableWriter(w io.Writer, table Table) (*xlsxTableWriter, error) {
+	f := excelize.NewFile()
+	sheet := xlsxSheetName(table.Title)
+	f.SetSheetName("Sheet1", sheet)
+
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	t := &xlsxTableWriter{w: w, table: table, f: f, sw: sw, row: 1, mergeKey: table.mergeKey()}
+
+	t.bold, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.header, err = f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+		Border:    []excelize.Border{{Type: "bottom", Color: "000000", Style: 1}},
+		Alignment: &excelize.Alignment{Vertical: "center"},
+	})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	t.styles = make([]int, len(table.Columns))
+	for i, column := range table.Columns {
+		style := xlsxColumnStyle(column)
+		if style == nil {
+			continue
+		}
+		if t.styles[i], err = f.NewStyle(style); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func xlsxColumnStyle(column Column) *excelize.Style {
+	var style excelize.Style
+	switch column.Type {
+	case ColumnInt, ColumnMoney:
+		style.NumFmt = 3
+	case ColumnNumber:
+		style.NumFmt = 4
+	case ColumnDate:
+		format := "dd-mm-yyyy"
+		style.CustomNumFmt = &format
+	case ColumnDateTime:
+		format := "dd-mm-yyyy hh:mm"
+		style.CustomNumFmt = &format
+	default:
+		if !column.Merge {
+			return nil
+		}
+	}
+	if column.Merge {
+		style.Alignment = &excelize.Alignment{Vertical: "center"}
+	}
+
+	return &style
+}
+
+// xlsxSheetName drops the characters a sheet name may not contain and cuts
+// it to the 31 characters Excel allows.
+func xlsxSheetName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, title)
+
+	if utf8.RuneCountInString(name) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+
+	return name
+}
+
+func (t *xlsxTableWriter) WriteRow(values ...any) error {
+	row, err := t.table.row(values)
+	if err != nil {
+		return err
+	}
+	t.rows++
+
+	if !t.started {
+		t.pending = append(t.pending, row)
+		if len(t.pending) < xlsxWidthSample {
+			return nil
+		}
+		return t.start()
+	}
+
+	return t.writeRow(row)
+}
+
+func (t *xlsxTableWriter) Rows() int {
+	return t.rows
+}
+
+func (t *xlsxTableWriter) Close() error {
+	defer t.f.Close()
+
+	if !t.started {
+		if err := t.start(); err != nil {
+			return err
+		}
+	}
+	if err := t.mergeGroup(); err != nil {
+		return err
+	}
+	if err := t.sw.Flush(); err != nil {
+		return err
+	}
+
+	return t.f.Write(t.w)
+}
+
+// start sizes the columns from the header and the held back rows, writes the
+// subtitle and header and freezes the header in place.
+func (t *xlsxTableWriter) start() error {
+	t.started = true
+
+	for i, column := range t.table.Columns {
+		width := utf8.RuneCountInString(column.Header)
+		for _, row := range t.pending {
+			if n := utf8.RuneCountInString(displayValue(column, row[i])); n > width {
+				width = n
+			}
+		}
+		width += 2
+		if width < xlsxMinWidth {
+			width = xlsxMinWidth
+		}
+		if width > xlsxMaxWidth {
+			width = xlsxMaxWidth
+		}
+		if err := t.sw.SetColWidth(i+1, i+1, float64(width)); err != nil {
+			return err
+		}
+	}
+
+	headerRow := 1
+	if len(t.table.Subtitle) > 0 {
+		headerRow = len(t.table.Subtitle) + 3
+	}
+	if err := t.sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      headerRow,
+		TopLeftCell: fmt.Sprintf("A%d", headerRow+1),
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+
+	if len(t.table.Subtitle) > 0 {
+		if err := t.setRow([]any{excelize.Cell{StyleID: t.bold, Value: t.table.Title}}); err != nil {
+			return err
+		}
+		for _, line := range t.table.Subtitle {
+			if err := t.setRow([]any{line}); err != nil {
+				return err
+			}
+		}
+		t.row++
+	}
+
+	headers := make([]any, len(t.table.Columns))
+	for i, column := range t.table.Columns {
+		headers[i] = excelize.Cell{StyleID: t.header, Value: column.Header}
+	}
+	if err := t.setRow(headers); err != nil {
+		return err
+	}
+
+	for _, row := range t.pending {
+		if err := t.writeRow(row); err != nil {
+			return err
+		}
+	}
+	t.pending = nil
+
+	return nil
+}
+
+func (t *xlsxTableWriter) writeRow(row []any) error {
+	if t.mergeKey >= 0 {
+		key := fmt.Sprint(row[t.mergeKey])
+		if t.groupStart == 0 || key != t.groupValue {
+			if err := t.mergeGroup(); err != nil {
+				return err
+			}
+			t.groupStart, t.groupValue = t.row, key
+		}
+	}
+
+	cells := make([]any, len(row))
+	for i, value := range row {
+		column := t.table.Columns[i]
+		if column.Type == ColumnDate || column.Type == ColumnDateTime {
+			if tm, ok := cellTime(value); ok {
+				value = tm
+			}
+		}
+		cells[i] = excelize.Cell{StyleID: t.styles[i], Value: value}
+	}
+
+	return t.setRow(cells)
+}
+
+// mergeGroup merges the Merge columns of the group that ends on the row
+// before the current one.
+func (t *xlsxTableWriter) mergeGroup() error {
+	endRow := t.row - 1
+	if t.groupStart == 0 || endRow <= t.groupStart {
+		return nil
+	}
+
+	for i, column := range t.table.Columns {
+		if !column.Merge {
+			continue
+		}
+		name, err := excelize.ColumnNumberToName(i + 1)
+		if err != nil {
+			return err
+		}
+		if err := t.sw.MergeCell(fmt.Sprintf("%s%d", name, t.groupStart), fmt.Sprintf("%s%d", name, endRow)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *xlsxTableWriter) setRow(values []any) error {
+	if err := t.sw.SetRow(fmt.Sprintf("A%d", t.row), values); err != nil {
+		return err
+	}
+	t.row++
+
+	return nil
+}